@@ -0,0 +1,197 @@
+// Package client provides a typed client of the kiosk nats protocol, along with an in-memory fake of it for the unit
+// tests of the consumers.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+)
+
+// API is the typed API of the tickets and comments. Failures replied by kiosk are returned as *errors.Type, with the
+// same codes as the ones replied on nats.
+type API interface {
+	CreateTicket(ctx context.Context, request data.CreateTicketRequest) (int64, error)
+	LoadTicket(ctx context.Context, id int64) (*data.TicketResponse, error)
+	UpdateTicket(ctx context.Context, request data.UpdateTicketRequest) error
+	PatchTicket(ctx context.Context, request data.PatchTicketRequest) error
+	DeleteTicket(ctx context.Context, id int64) error
+	FilterTickets(ctx context.Context, request data.FilterTicketsRequest) (*data.FilterTicketsResponse, error)
+	Tickets(ctx context.Context, request data.FilterTicketsRequest) *TicketIterator
+	CreateComment(ctx context.Context, request data.CreateCommentRequest) error
+	LoadComment(ctx context.Context, id int64) (*data.CommentResponse, error)
+	UpdateComment(ctx context.Context, request data.UpdateCommentRequest) error
+	DeleteComment(ctx context.Context, id int64) error
+}
+
+// Client is the nats implementation of API.
+type Client struct {
+	natsClient *nc.Conn
+	timeout    time.Duration
+	apiKey     string
+	language   string
+}
+
+var _ API = (*Client)(nil)
+var _ API = (*Fake)(nil)
+
+// New returns back a newly created and ready to use Client. The timeout applies to the requests whose context has no
+// deadline.
+func New(natsClient *nc.Conn, timeout time.Duration) *Client {
+	return &Client{natsClient: natsClient, timeout: timeout}
+}
+
+// WithAPIKey returns back a copy of the client that sends the API key along with the requests, so they are authorized
+// as the issuer of the key.
+func (c *Client) WithAPIKey(key string) *Client {
+	return &Client{natsClient: c.natsClient, timeout: c.timeout, apiKey: key, language: c.language}
+}
+
+// WithLanguage returns back a copy of the client that sends the language along with the requests, so the messages of
+// the replied errors are in the language, e.g: fa.
+func (c *Client) WithLanguage(language string) *Client {
+	return &Client{natsClient: c.natsClient, timeout: c.timeout, apiKey: c.apiKey, language: language}
+}
+
+// CreateTicket creates a new ticket with specified information and returns back its id.
+func (c *Client) CreateTicket(ctx context.Context, request data.CreateTicketRequest) (int64, error) {
+	id := &data.ID{}
+	if e := c.request(ctx, "kiosk.tickets.create", request, id); e != nil {
+		return 0, e
+	}
+
+	return id.ID, nil
+}
+
+// LoadTicket loads a ticket along with its comments.
+func (c *Client) LoadTicket(ctx context.Context, id int64) (*data.TicketResponse, error) {
+	ticketResponse := &data.TicketResponse{}
+	if e := c.request(ctx, "kiosk.tickets.load", data.ID{ID: id}, ticketResponse); e != nil {
+		return nil, e
+	}
+
+	return ticketResponse, nil
+}
+
+// UpdateTicket updates the changeable information of a ticket.
+func (c *Client) UpdateTicket(ctx context.Context, request data.UpdateTicketRequest) error {
+	return c.request(ctx, "kiosk.tickets.update", request, nil)
+}
+
+// PatchTicket changes the provided information of a ticket, keeping the rest as they are.
+func (c *Client) PatchTicket(ctx context.Context, request data.PatchTicketRequest) error {
+	return c.request(ctx, "kiosk.tickets.patch", request, nil)
+}
+
+// DeleteTicket deletes a ticket along with its comments.
+func (c *Client) DeleteTicket(ctx context.Context, id int64) error {
+	return c.request(ctx, "kiosk.tickets.delete", data.ID{ID: id}, nil)
+}
+
+// FilterTickets filters a page of tickets based on provided criteria values.
+func (c *Client) FilterTickets(ctx context.Context, request data.FilterTicketsRequest) (*data.FilterTicketsResponse,
+	error) {
+
+	filterTicketsResponse := &data.FilterTicketsResponse{}
+	if e := c.request(ctx, "kiosk.tickets.filter", request, filterTicketsResponse); e != nil {
+		return nil, e
+	}
+
+	return filterTicketsResponse, nil
+}
+
+// Tickets returns back an iterator over the tickets matching provided criteria values, from the page of the request.
+func (c *Client) Tickets(ctx context.Context, request data.FilterTicketsRequest) *TicketIterator {
+	return NewTicketIterator(ctx, c.FilterTickets, request)
+}
+
+// CreateComment creates a new comment with specified information.
+func (c *Client) CreateComment(ctx context.Context, request data.CreateCommentRequest) error {
+	return c.request(ctx, "kiosk.comments.create", request, nil)
+}
+
+// LoadComment loads a comment.
+func (c *Client) LoadComment(ctx context.Context, id int64) (*data.CommentResponse, error) {
+	commentResponse := &data.CommentResponse{}
+	if e := c.request(ctx, "kiosk.comments.load", data.ID{ID: id}, commentResponse); e != nil {
+		return nil, e
+	}
+
+	return commentResponse, nil
+}
+
+// UpdateComment updates the changeable information of a comment.
+func (c *Client) UpdateComment(ctx context.Context, request data.UpdateCommentRequest) error {
+	return c.request(ctx, "kiosk.comments.update", request, nil)
+}
+
+// DeleteComment deletes a comment.
+func (c *Client) DeleteComment(ctx context.Context, id int64) error {
+	return c.request(ctx, "kiosk.comments.delete", data.ID{ID: id}, nil)
+}
+
+// request sends the request on the subject and populates the response, if any, from the reply. Replied errors and
+// timeouts are returned as *errors.Type.
+func (c *Client) request(ctx context.Context, subject string, request, response interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	in, e := json.Marshal(request)
+	if e != nil {
+		return e
+	}
+
+	if c.apiKey != "" || c.language != "" {
+		fields := make(map[string]json.RawMessage)
+		if e := json.Unmarshal(in, &fields); e != nil {
+			return e
+		}
+
+		if c.apiKey != "" {
+			fields["apiKey"], _ = json.Marshal(c.apiKey)
+		}
+
+		if c.language != "" {
+			fields["language"], _ = json.Marshal(c.language)
+		}
+
+		in, _ = json.Marshal(fields)
+	}
+
+	msg, e := c.natsClient.RequestWithContext(ctx, subject, in)
+	if e != nil {
+		if e == nc.ErrTimeout || e == context.DeadlineExceeded {
+			return errors.RequestTimeout("")
+		}
+
+		return e
+	}
+
+	et := &errors.Type{}
+	_ = json.Unmarshal(msg.Data, et)
+	if et.FingerPrint != "" {
+		return et
+	}
+
+	if response != nil {
+		return json.Unmarshal(msg.Data, response)
+	}
+
+	return nil
+}
+
+// ErrorCode returns back the code of the first error of a replied error, or empty for the other errors.
+func ErrorCode(e error) string {
+	if et, ok := e.(*errors.Type); ok && len(et.Errors) > 0 {
+		return et.Errors[0].Code
+	}
+
+	return ""
+}
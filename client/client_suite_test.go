@@ -0,0 +1,19 @@
+package client_test
+
+import (
+	"flag"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func init() {
+	// Passed to every suite by the test script.
+	flag.String("pg.host", "localhost", "")
+}
+
+func TestClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Client Suite")
+}
@@ -0,0 +1,151 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jibitters/kiosk/client"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	nc "github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var natsServer *server.Server
+	var natsClient *nc.Conn
+	var api *client.Client
+	var messages chan *nc.Msg
+
+	BeforeEach(func() {
+		options := natsserver.DefaultTestOptions
+		options.Port = -1
+		natsServer = natsserver.RunServer(&options)
+
+		c, e := nc.Connect(natsServer.ClientURL())
+		Ω(e).Should(BeNil())
+		natsClient = c
+
+		api = client.New(natsClient, time.Second)
+		messages = make(chan *nc.Msg, 100)
+	})
+
+	AfterEach(func() {
+		natsClient.Close()
+		natsServer.Shutdown()
+	})
+
+	// respond replies the requests of the subjects matching the pattern with the reply and keeps the requests.
+	respond := func(pattern string, reply interface{}) {
+		_, e := natsClient.Subscribe(pattern, func(msg *nc.Msg) {
+			messages <- msg
+
+			out, _ := json.Marshal(reply)
+			_ = msg.Respond(out)
+		})
+		Ω(e).Should(BeNil())
+		Ω(natsClient.Flush()).Should(Succeed())
+	}
+
+	received := func() *nc.Msg {
+		var msg *nc.Msg
+		Ω(messages).Should(Receive(&msg))
+		return msg
+	}
+
+	Context("When an operation called", func() {
+		It("Should request the subject of the operation", func() {
+			respond("kiosk.>", struct{}{})
+			ctx := context.Background()
+
+			operations := []struct {
+				subject string
+				call    func() error
+			}{
+				{"kiosk.tickets.create", func() error {
+					_, e := api.CreateTicket(ctx, data.CreateTicketRequest{})
+					return e
+				}},
+				{"kiosk.tickets.load", func() error { _, e := api.LoadTicket(ctx, 1); return e }},
+				{"kiosk.tickets.update", func() error { return api.UpdateTicket(ctx, data.UpdateTicketRequest{}) }},
+				{"kiosk.tickets.patch", func() error { return api.PatchTicket(ctx, data.PatchTicketRequest{}) }},
+				{"kiosk.tickets.delete", func() error { return api.DeleteTicket(ctx, 1) }},
+				{"kiosk.tickets.filter", func() error {
+					_, e := api.FilterTickets(ctx, data.FilterTicketsRequest{})
+					return e
+				}},
+				{"kiosk.comments.create", func() error { return api.CreateComment(ctx, data.CreateCommentRequest{}) }},
+				{"kiosk.comments.load", func() error { _, e := api.LoadComment(ctx, 1); return e }},
+				{"kiosk.comments.update", func() error {
+					return api.UpdateComment(ctx, data.UpdateCommentRequest{})
+				}},
+				{"kiosk.comments.delete", func() error { return api.DeleteComment(ctx, 1) }},
+			}
+
+			for _, o := range operations {
+				Ω(o.call()).Should(Succeed(), o.subject)
+				Ω(received().Subject).Should(Equal(o.subject))
+			}
+		})
+
+		It("Should decode the replied response", func() {
+			respond("kiosk.tickets.load", data.TicketResponse{ID: 1, Subject: "Technical Problem",
+				Status: models.TicketStatusNew})
+
+			ticket, e := api.LoadTicket(context.Background(), 1)
+			Ω(e).Should(BeNil())
+			Ω(ticket.ID).Should(Equal(int64(1)))
+			Ω(ticket.Subject).Should(Equal("Technical Problem"))
+			Ω(ticket.Status).Should(Equal(models.TicketStatusNew))
+
+			id := &data.ID{}
+			Ω(json.Unmarshal(received().Data, id)).Should(Succeed())
+			Ω(id.ID).Should(Equal(int64(1)))
+		})
+
+		It("Should return the replied error as it is", func() {
+			respond("kiosk.tickets.load", errors.NotFound("ticket.not_found", ""))
+
+			_, e := api.LoadTicket(context.Background(), 1)
+			Ω(e).ShouldNot(BeNil())
+			Ω(client.ErrorCode(e)).Should(Equal("ticket.not_found"))
+
+			et, ok := e.(*errors.Type)
+			Ω(ok).Should(BeTrue())
+			Ω(et.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+		})
+
+		It("Should send the API key and the language along with the request", func() {
+			respond("kiosk.tickets.delete", struct{}{})
+
+			Ω(api.WithAPIKey("kiosk_key").WithLanguage("fa").DeleteTicket(context.Background(), 1)).Should(Succeed())
+
+			request := &struct {
+				ID       int64  `json:"ID"`
+				APIKey   string `json:"apiKey"`
+				Language string `json:"language"`
+			}{}
+			Ω(json.Unmarshal(received().Data, request)).Should(Succeed())
+			Ω(request.ID).Should(Equal(int64(1)))
+			Ω(request.APIKey).Should(Equal("kiosk_key"))
+			Ω(request.Language).Should(Equal("fa"))
+		})
+
+		It("Should time out when nothing is replied in time", func() {
+			e := client.New(natsClient, 100*time.Millisecond).DeleteTicket(context.Background(), 1)
+			Ω(client.ErrorCode(e)).Should(Equal("request.timeout"))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			e = api.DeleteTicket(ctx, 1)
+			Ω(client.ErrorCode(e)).Should(Equal("request.timeout"))
+		})
+	})
+})
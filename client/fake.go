@@ -0,0 +1,343 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+)
+
+// Fake is an in-memory implementation of API for the unit tests of the consumers. It validates the requests and fails
+// with the same errors as kiosk does.
+type Fake struct {
+	mu        sync.Mutex
+	tickets   map[int64]*models.Ticket
+	comments  map[int64]*models.Comment
+	lastID    int64
+	failures  map[string]error
+	requested map[string]int
+}
+
+// NewFake returns back a newly created and ready to use Fake without any ticket.
+func NewFake() *Fake {
+	return &Fake{
+		tickets:   make(map[int64]*models.Ticket),
+		comments:  make(map[int64]*models.Comment),
+		failures:  make(map[string]error),
+		requested: make(map[string]int),
+	}
+}
+
+// Fail makes the requests of the subject, e.g: kiosk.tickets.create, fail with the error until it is called with nil.
+func (f *Fake) Fail(subject string, e error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e == nil {
+		delete(f.failures, subject)
+		return
+	}
+
+	f.failures[subject] = e
+}
+
+// Requested returns back the number of the requests of the subject, including the failed ones.
+func (f *Fake) Requested(subject string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requested[subject]
+}
+
+// CreateTicket creates a new ticket with specified information and returns back its id.
+func (f *Fake) CreateTicket(_ context.Context, request data.CreateTicketRequest) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.tickets.create"); e != nil {
+		return 0, e
+	}
+
+	if e := request.Validate(); e != nil {
+		return 0, e
+	}
+
+	ticket := request.AsTicket()
+	f.lastID++
+	ticket.ID = f.lastID
+	ticket.Status = models.TicketStatusNew
+	ticket.CreatedAt = time.Now().UTC()
+	ticket.ModifiedAt = ticket.CreatedAt
+	f.tickets[ticket.ID] = ticket
+
+	return ticket.ID, nil
+}
+
+// LoadTicket loads a ticket along with its comments.
+func (f *Fake) LoadTicket(_ context.Context, id int64) (*data.TicketResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.tickets.load"); e != nil {
+		return nil, e
+	}
+
+	ticket, ok := f.tickets[id]
+	if !ok {
+		return nil, errors.NotFound("ticket.not_found", "")
+	}
+
+	return f.ticketResponse(ticket), nil
+}
+
+// UpdateTicket updates the changeable information of a ticket.
+func (f *Fake) UpdateTicket(_ context.Context, request data.UpdateTicketRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.tickets.update"); e != nil {
+		return e
+	}
+
+	if e := request.Validate(); e != nil {
+		return e
+	}
+
+	ticket, ok := f.tickets[request.ID]
+	if !ok {
+		return errors.PreconditionFailed("ticket.not_found", "")
+	}
+
+	ticket.Subject = request.Subject
+	ticket.Metadata = request.Metadata
+	ticket.ImportanceLevel = request.ImportanceLevel
+	ticket.Status = request.Status
+	ticket.ModifiedAt = time.Now().UTC()
+
+	return nil
+}
+
+// PatchTicket changes the provided information of a ticket, keeping the rest as they are.
+func (f *Fake) PatchTicket(_ context.Context, request data.PatchTicketRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.tickets.patch"); e != nil {
+		return e
+	}
+
+	if e := request.Validate(); e != nil {
+		return e
+	}
+
+	ticket, ok := f.tickets[request.ID]
+	if !ok {
+		return errors.PreconditionFailed("ticket.not_found", "")
+	}
+
+	changed := request.AsTicket(ticket)
+	ticket.Subject = changed.Subject
+	ticket.Metadata = changed.Metadata
+	ticket.ImportanceLevel = changed.ImportanceLevel
+	ticket.Status = changed.Status
+	ticket.ModifiedAt = time.Now().UTC()
+
+	return nil
+}
+
+// DeleteTicket deletes a ticket along with its comments.
+func (f *Fake) DeleteTicket(_ context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.tickets.delete"); e != nil {
+		return e
+	}
+
+	delete(f.tickets, id)
+	for _, c := range f.comments {
+		if c.TicketID == id {
+			delete(f.comments, c.ID)
+		}
+	}
+
+	return nil
+}
+
+// FilterTickets filters a page of tickets based on provided criteria values, from the last modified one.
+func (f *Fake) FilterTickets(_ context.Context, request data.FilterTicketsRequest) (*data.FilterTicketsResponse,
+	error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.tickets.filter"); e != nil {
+		return nil, e
+	}
+
+	if e := request.Validate(); e != nil {
+		return nil, e
+	}
+
+	fromDate, e := time.Parse(time.RFC3339Nano, request.FromDate)
+	if e != nil {
+		return nil, errors.InvalidArgument("fromDate.invalid", "")
+	}
+
+	toDate, e := time.Parse(time.RFC3339Nano, request.ToDate)
+	if e != nil {
+		return nil, errors.InvalidArgument("toDate.invalid", "")
+	}
+
+	tickets := make([]*models.Ticket, 0)
+	for _, t := range f.tickets {
+		if t.ModifiedAt.Before(fromDate) || !t.ModifiedAt.Before(toDate) ||
+			(request.Issuer != "" && t.Issuer != request.Issuer) ||
+			(request.Owner != "" && t.Owner != request.Owner) ||
+			(request.ImportanceLevel != "" && t.ImportanceLevel != request.ImportanceLevel) ||
+			(request.Status != "" && t.Status != request.Status) {
+
+			continue
+		}
+
+		tickets = append(tickets, t)
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		if tickets[i].ModifiedAt.Equal(tickets[j].ModifiedAt) {
+			return tickets[i].ID > tickets[j].ID
+		}
+
+		return tickets[i].ModifiedAt.After(tickets[j].ModifiedAt)
+	})
+
+	filterTicketsResponse := &data.FilterTicketsResponse{}
+	from := (request.PageNumber - 1) * request.PageSize
+	for i := from; i < len(tickets) && i < from+request.PageSize; i++ {
+		filterTicketsResponse.Tickets = append(filterTicketsResponse.Tickets, f.ticketResponse(tickets[i]))
+	}
+	filterTicketsResponse.HasNextPage = len(tickets) > from+request.PageSize
+
+	return filterTicketsResponse, nil
+}
+
+// Tickets returns back an iterator over the tickets matching provided criteria values, from the page of the request.
+func (f *Fake) Tickets(ctx context.Context, request data.FilterTicketsRequest) *TicketIterator {
+	return NewTicketIterator(ctx, f.FilterTickets, request)
+}
+
+// CreateComment creates a new comment with specified information.
+func (f *Fake) CreateComment(_ context.Context, request data.CreateCommentRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.comments.create"); e != nil {
+		return e
+	}
+
+	if e := request.Validate(); e != nil {
+		return e
+	}
+
+	if _, ok := f.tickets[request.TicketID]; !ok {
+		return errors.PreconditionFailed("ticket.not_exists", "")
+	}
+
+	comment := request.AsComment()
+	f.lastID++
+	comment.ID = f.lastID
+	comment.CreatedAt = time.Now().UTC()
+	comment.ModifiedAt = comment.CreatedAt
+	f.comments[comment.ID] = comment
+
+	return nil
+}
+
+// LoadComment loads a comment.
+func (f *Fake) LoadComment(_ context.Context, id int64) (*data.CommentResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.comments.load"); e != nil {
+		return nil, e
+	}
+
+	comment, ok := f.comments[id]
+	if !ok {
+		return nil, errors.NotFound("comment.not_found", "")
+	}
+
+	commentResponse := &data.CommentResponse{}
+	commentResponse.LoadFromComment(comment)
+	return commentResponse, nil
+}
+
+// UpdateComment updates the changeable information of a comment.
+func (f *Fake) UpdateComment(_ context.Context, request data.UpdateCommentRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.comments.update"); e != nil {
+		return e
+	}
+
+	if e := request.Validate(); e != nil {
+		return e
+	}
+
+	comment, ok := f.comments[request.ID]
+	if !ok {
+		return errors.NotFound("comment.not_found", "")
+	}
+
+	comment.Metadata = request.Metadata
+	comment.ModifiedAt = time.Now().UTC()
+
+	return nil
+}
+
+// DeleteComment deletes a comment.
+func (f *Fake) DeleteComment(_ context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e := f.request("kiosk.comments.delete"); e != nil {
+		return e
+	}
+
+	delete(f.comments, id)
+	return nil
+}
+
+// request counts the request of the subject and returns back its failure, if any.
+func (f *Fake) request(subject string) error {
+	f.requested[subject]++
+	return f.failures[subject]
+}
+
+// ticketResponse returns back the response of the ticket along with its comments, from the newest one.
+func (f *Fake) ticketResponse(ticket *models.Ticket) *data.TicketResponse {
+	t := *ticket
+	t.Comments = nil
+	for _, c := range f.comments {
+		if c.TicketID == t.ID {
+			t.Comments = append(t.Comments, c)
+		}
+	}
+
+	sort.Slice(t.Comments, func(i, j int) bool {
+		if t.Comments[i].CreatedAt.Equal(t.Comments[j].CreatedAt) {
+			return t.Comments[i].ID > t.Comments[j].ID
+		}
+
+		return t.Comments[i].CreatedAt.After(t.Comments[j].CreatedAt)
+	})
+
+	ticketResponse := &data.TicketResponse{}
+	ticketResponse.LoadFromTicket(&t)
+	return ticketResponse
+}
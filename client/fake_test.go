@@ -0,0 +1,124 @@
+package client_test
+
+import (
+	"context"
+
+	"github.com/jibitters/kiosk/client"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fake", func() {
+	var fake *client.Fake
+
+	createTicketRequest := data.CreateTicketRequest{
+		Issuer:          "Microservice-A",
+		Owner:           "user@example.com",
+		Subject:         "Technical Problem",
+		Content:         "Hello, i have some issues with REST API Docs!",
+		Metadata:        `{"ip":"192.168.1.1"}`,
+		ImportanceLevel: models.TicketImportanceLevelMedium,
+	}
+
+	BeforeEach(func() {
+		fake = client.NewFake()
+	})
+
+	Context("When tickets and comments change", func() {
+		It("Should load them as kiosk does", func() {
+			id, e := fake.CreateTicket(context.Background(), createTicketRequest)
+			Ω(e).Should(BeNil())
+			Ω(id).Should(Equal(int64(1)))
+			Ω(fake.CreateComment(context.Background(), data.CreateCommentRequest{TicketID: 1,
+				Owner: "support@example.com", Content: "Fixed the docs!", Metadata: "{}"})).Should(Succeed())
+
+			ticket, e := fake.LoadTicket(context.Background(), id)
+			Ω(e).Should(BeNil())
+			Ω(ticket.Subject).Should(Equal(createTicketRequest.Subject))
+			Ω(ticket.Status).Should(Equal(models.TicketStatusNew))
+			Ω(len(ticket.Comments)).Should(Equal(1))
+			Ω(ticket.Comments[0].Content).Should(Equal("Fixed the docs!"))
+
+			Ω(fake.UpdateTicket(context.Background(), data.UpdateTicketRequest{ID: 1, Subject: "Docs",
+				ImportanceLevel: models.TicketImportanceLevelLow, Status: models.TicketStatusResolved})).Should(Succeed())
+			Ω(fake.UpdateComment(context.Background(), data.UpdateCommentRequest{ID: 2,
+				Metadata: `{"read":true}`})).Should(Succeed())
+
+			ticket, _ = fake.LoadTicket(context.Background(), 1)
+			Ω(ticket.Subject).Should(Equal("Docs"))
+			Ω(ticket.Status).Should(Equal(models.TicketStatusResolved))
+
+			status := models.TicketStatusClosed
+			Ω(fake.PatchTicket(context.Background(), data.PatchTicketRequest{ID: 1, Status: &status})).Should(Succeed())
+
+			ticket, _ = fake.LoadTicket(context.Background(), 1)
+			Ω(ticket.Subject).Should(Equal("Docs"))
+			Ω(ticket.ImportanceLevel).Should(Equal(models.TicketImportanceLevelLow))
+			Ω(ticket.Status).Should(Equal(models.TicketStatusClosed))
+
+			comment, e := fake.LoadComment(context.Background(), 2)
+			Ω(e).Should(BeNil())
+			Ω(comment.Metadata).Should(Equal(`{"read":true}`))
+
+			Ω(fake.DeleteTicket(context.Background(), 1)).Should(Succeed())
+			_, e = fake.LoadTicket(context.Background(), 1)
+			Ω(client.ErrorCode(e)).Should(Equal("ticket.not_found"))
+			_, e = fake.LoadComment(context.Background(), 2)
+			Ω(client.ErrorCode(e)).Should(Equal("comment.not_found"))
+		})
+
+		It("Should fail as kiosk does", func() {
+			_, e := fake.CreateTicket(context.Background(), data.CreateTicketRequest{})
+			Ω(client.ErrorCode(e)).Should(Equal("issuer.is_required"))
+
+			e = fake.CreateComment(context.Background(), data.CreateCommentRequest{TicketID: 100,
+				Owner: "support@example.com", Content: "Fixed the docs!"})
+			Ω(client.ErrorCode(e)).Should(Equal("ticket.not_exists"))
+
+			e = fake.UpdateTicket(context.Background(), data.UpdateTicketRequest{ID: 100, Subject: "Docs",
+				ImportanceLevel: models.TicketImportanceLevelLow, Status: models.TicketStatusResolved})
+			Ω(client.ErrorCode(e)).Should(Equal("ticket.not_found"))
+		})
+
+		It("Should fail with the injected errors", func() {
+			fake.Fail("kiosk.tickets.create", errors.ServiceUnavailable(""))
+			_, e := fake.CreateTicket(context.Background(), createTicketRequest)
+			Ω(client.ErrorCode(e)).Should(Equal("service.not_available"))
+
+			fake.Fail("kiosk.tickets.create", nil)
+			_, e = fake.CreateTicket(context.Background(), createTicketRequest)
+			Ω(e).Should(BeNil())
+			Ω(fake.Requested("kiosk.tickets.create")).Should(Equal(2))
+		})
+	})
+
+	Context("When Tickets called", func() {
+		It("Should iterate over the tickets of all of the pages", func() {
+			for i := 0; i < 5; i++ {
+				_, e := fake.CreateTicket(context.Background(), createTicketRequest)
+				Ω(e).Should(BeNil())
+			}
+
+			ids := make([]int64, 0)
+			it := fake.Tickets(context.Background(), data.FilterTicketsRequest{
+				ImportanceLevel: models.TicketImportanceLevelMedium, Status: models.TicketStatusNew, PageSize: 2})
+			for it.Next() {
+				ids = append(ids, it.Ticket().ID)
+			}
+
+			Ω(it.Err()).Should(BeNil())
+			Ω(ids).Should(Equal([]int64{5, 4, 3, 2, 1}))
+			Ω(fake.Requested("kiosk.tickets.filter")).Should(Equal(3))
+		})
+
+		It("Should stop on the first failure", func() {
+			it := fake.Tickets(context.Background(), data.FilterTicketsRequest{
+				ImportanceLevel: models.TicketImportanceLevelMedium, Status: models.TicketStatusNew, PageSize: 100})
+			Ω(it.Next()).Should(BeFalse())
+			Ω(client.ErrorCode(it.Err())).Should(Equal("pageSize.not_valid"))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+
+	"github.com/jibitters/kiosk/web/data"
+)
+
+// TicketIterator iterates over the tickets of the filter pages, loading the next page when the current one is done.
+type TicketIterator struct {
+	ctx         context.Context
+	filter      func(context.Context, data.FilterTicketsRequest) (*data.FilterTicketsResponse, error)
+	request     data.FilterTicketsRequest
+	page        []*data.TicketResponse
+	index       int
+	hasNextPage bool
+	e           error
+}
+
+// NewTicketIterator returns back an iterator over the pages that filter returns, from the page of the request. It
+// lets the other implementations of API provide Tickets.
+func NewTicketIterator(ctx context.Context,
+	filter func(context.Context, data.FilterTicketsRequest) (*data.FilterTicketsResponse, error),
+	request data.FilterTicketsRequest) *TicketIterator {
+
+	if request.PageNumber < 1 {
+		request.PageNumber = 1
+	}
+
+	// Start before the page of the request.
+	request.PageNumber--
+
+	return &TicketIterator{ctx: ctx, filter: filter, request: request, index: -1, hasNextPage: true}
+}
+
+// Next advances the iterator to the next ticket, loading the next page if needed. It returns false when there are no
+// more tickets or loading a page fails.
+func (it *TicketIterator) Next() bool {
+	if it.e != nil {
+		return false
+	}
+
+	it.index++
+	for it.index >= len(it.page) {
+		if !it.hasNextPage {
+			return false
+		}
+
+		it.request.PageNumber++
+		filterTicketsResponse, e := it.filter(it.ctx, it.request)
+		if e != nil {
+			it.e = e
+			return false
+		}
+
+		it.page = filterTicketsResponse.Tickets
+		it.index = 0
+		it.hasNextPage = filterTicketsResponse.HasNextPage
+	}
+
+	return true
+}
+
+// Ticket returns back the current ticket.
+func (it *TicketIterator) Ticket() *data.TicketResponse {
+	return it.page[it.index]
+}
+
+// Err returns back the error that stopped the iteration, if any.
+func (it *TicketIterator) Err() error {
+	return it.e
+}
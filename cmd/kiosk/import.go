@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/services"
+	"github.com/jibitters/kiosk/web/data"
+)
+
+// importFile implements the import subcommand, which imports an external helpdesk export file into the database.
+func importFile(arguments []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.StringVar(config, "config", *config, "configuration file")
+	source := flags.String("source", "", "name of the external system that the file is exported from")
+	format := flags.String("format", "NDJSON", "format of the file, CSV or NDJSON")
+	file := flags.String("file", "", "path of the file to import")
+	_ = flags.Parse(arguments)
+
+	kiosk := setup()
+	kiosk.configure()
+	kiosk.connectToDatabase()
+	kiosk.migrateDatabase()
+
+	importService := services.NewImportService(kiosk.logger, kiosk.config, kiosk.db, nil, nil, nil)
+	i, e := importService.ImportFile(context.Background(), *source,
+		models.ImportFormat(strings.ToUpper(*format)), *file)
+	kiosk.stop()
+
+	if e != nil {
+		out, _ := json.MarshalIndent(e, "", "  ")
+		_, _ = fmt.Fprintln(os.Stderr, string(out))
+		os.Exit(1)
+	}
+
+	importResponse := &data.ImportResponse{}
+	importResponse.LoadFromImport(i)
+
+	out, _ := json.MarshalIndent(importResponse, "", "  ")
+	fmt.Println(string(out))
+}
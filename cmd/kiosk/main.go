@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/jibitters/kiosk/db/postgres"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/messaging/nats"
+	"github.com/jibitters/kiosk/models"
 	"github.com/jibitters/kiosk/services"
 	"github.com/jibitters/kiosk/web"
 	"github.com/lireza/lib/configuring"
@@ -27,21 +29,59 @@ type Kiosk struct {
 	config     *configuring.Config
 	db         *pgxpool.Pool
 	natsClient *nc.Conn
+	catalogue  *errors.Catalogue
+	dispatcher *services.EventDispatcher
+	authorizer *services.Authorizer
 	// TODO: Should we use interface for service layer components?
-	ticketService  *services.TicketService
-	commentService *services.CommentService
-	webServer      *http.Server
+	rateLimiter           *services.RateLimiter
+	apiKeyService         *services.APIKeyService
+	ticketService         *services.TicketService
+	commentService        *services.CommentService
+	exportService         *services.ExportService
+	importService         *services.ImportService
+	cannedResponseService *services.CannedResponseService
+	macroService          *services.MacroService
+	ruleService           *services.RuleService
+	escalationService     *services.EscalationService
+	satisfactionService   *services.SatisfactionService
+	emailService          *services.EmailService
+	notificationService   *services.NotificationService
+	webhookService        *services.WebhookService
+	outboxRelay           *services.OutboxRelay
+	webServer             *http.Server
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importFile(os.Args[2:])
+		return
+	}
+
 	kiosk := setup()
 
 	kiosk.configure()
 	kiosk.connectToDatabase()
 	kiosk.migrateDatabase()
 	kiosk.prepareNatsClient()
+	kiosk.prepareCatalogue()
+	kiosk.prepareEventDispatcher()
+	kiosk.prepareAuthorizer()
+	kiosk.prepareOutboxRelay()
+	kiosk.startRateLimiter()
+	kiosk.startAPIKeyService()
+	kiosk.startNotificationService()
+	kiosk.startWebhookService()
+	kiosk.startOutboxRelay()
+	kiosk.startRuleService()
 	kiosk.startTicketService()
 	kiosk.startCommentService()
+	kiosk.startExportService()
+	kiosk.startImportService()
+	kiosk.startCannedResponseService()
+	kiosk.startMacroService()
+	kiosk.startEscalationService()
+	kiosk.startSatisfactionService()
+	kiosk.startEmailService()
 	kiosk.startWebServer()
 
 	kiosk.awaitTermination()
@@ -88,10 +128,7 @@ func (k *Kiosk) migrateDatabase() {
 }
 
 func (k *Kiosk) prepareNatsClient() {
-	addresses := k.config.Get("nats.addresses").SliceOfStringOrElse([]string{"nats://localhost:4222"})
-	k.logger.Info("nats.addresses -> ", addresses)
-
-	client, e := nc.Connect(strings.Join(addresses, ","), nc.Name("Kiosk"))
+	client, e := nats.Connect(k.logger, k.config, "Kiosk")
 	if e != nil {
 		k.stop()
 		k.logger.Fatal(e.Error())
@@ -100,8 +137,71 @@ func (k *Kiosk) prepareNatsClient() {
 	k.natsClient = client
 }
 
+func (k *Kiosk) prepareCatalogue() {
+	directory := k.config.Get("messages.directory").StringOrElse("./messages")
+	defaultLanguage := k.config.Get("messages.default_language").StringOrElse("en")
+
+	k.logger.Info("messages.directory -> ", directory)
+	k.logger.Info("messages.default_language -> ", defaultLanguage)
+
+	catalogue, e := errors.LoadCatalogue(directory, defaultLanguage)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.catalogue = catalogue
+}
+
+func (k *Kiosk) prepareEventDispatcher() {
+	k.dispatcher = services.NewEventDispatcher(k.logger)
+}
+
+func (k *Kiosk) prepareAuthorizer() {
+	policy, e := services.LoadPolicy(k.logger, k.config)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.authorizer = services.NewAuthorizer(policy, models.NewAPIKeyRepository(k.logger, k.db), k.catalogue)
+}
+
+func (k *Kiosk) prepareOutboxRelay() {
+	k.outboxRelay = services.NewOutboxRelay(k.logger, k.config, k.db, k.natsClient, k.authorizer, k.catalogue)
+}
+
+func (k *Kiosk) startRateLimiter() {
+	limits, e := services.LoadRateLimits(k.logger, k.config)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	rateLimiter := services.NewRateLimiter(k.logger, k.config, k.db, k.natsClient, limits, k.catalogue)
+
+	if e := rateLimiter.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.rateLimiter = rateLimiter
+}
+
+func (k *Kiosk) startAPIKeyService() {
+	apiKeyService := services.NewAPIKeyService(k.logger, k.config, k.db, k.natsClient, k.authorizer, k.catalogue)
+
+	if e := apiKeyService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.apiKeyService = apiKeyService
+}
+
 func (k *Kiosk) startTicketService() {
-	ticketService := services.NewTicketService(k.logger, k.db, k.natsClient)
+	ticketService := services.NewTicketService(k.logger, k.db, k.natsClient, k.dispatcher, k.authorizer,
+		k.rateLimiter, k.catalogue)
 
 	if e := ticketService.Start(); e != nil {
 		k.stop()
@@ -112,7 +212,8 @@ func (k *Kiosk) startTicketService() {
 }
 
 func (k *Kiosk) startCommentService() {
-	commentService := services.NewCommentService(k.logger, k.db, k.natsClient)
+	commentService := services.NewCommentService(k.logger, k.db, k.natsClient, k.dispatcher,
+		k.authorizer, k.rateLimiter, k.catalogue)
 
 	if e := commentService.Start(); e != nil {
 		k.stop()
@@ -122,8 +223,151 @@ func (k *Kiosk) startCommentService() {
 	k.commentService = commentService
 }
 
+func (k *Kiosk) startExportService() {
+	exportService := services.NewExportService(k.logger, k.config, k.db, k.natsClient, k.authorizer, k.catalogue)
+
+	if e := exportService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.exportService = exportService
+}
+
+func (k *Kiosk) startImportService() {
+	importService := services.NewImportService(k.logger, k.config, k.db, k.natsClient, k.authorizer, k.catalogue)
+
+	if e := importService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.importService = importService
+}
+
+func (k *Kiosk) startCannedResponseService() {
+	cannedResponseService := services.NewCannedResponseService(k.logger, k.db, k.natsClient, k.authorizer, k.catalogue)
+
+	if e := cannedResponseService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.cannedResponseService = cannedResponseService
+}
+
+func (k *Kiosk) startMacroService() {
+	macroService := services.NewMacroService(k.logger, k.db, k.natsClient, k.dispatcher, k.authorizer, k.catalogue)
+
+	if e := macroService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.macroService = macroService
+}
+
+func (k *Kiosk) startRuleService() {
+	ruleService, e := services.NewRuleService(k.logger, k.config, k.db, k.natsClient, k.dispatcher, k.authorizer,
+		k.catalogue)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	if e := ruleService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.ruleService = ruleService
+}
+
+func (k *Kiosk) startEscalationService() {
+	escalationService := services.NewEscalationService(k.logger, k.config, k.db, k.natsClient, k.dispatcher,
+		k.authorizer, k.catalogue)
+
+	if e := escalationService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.escalationService = escalationService
+}
+
+func (k *Kiosk) startSatisfactionService() {
+	satisfactionService := services.NewSatisfactionService(k.logger, k.db, k.natsClient, k.authorizer, k.catalogue)
+
+	if e := satisfactionService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.satisfactionService = satisfactionService
+}
+
+func (k *Kiosk) startEmailService() {
+	emailService, e := services.NewEmailService(k.logger, k.config, k.db, k.dispatcher)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	if e := emailService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.emailService = emailService
+}
+
+func (k *Kiosk) startNotificationService() {
+	notificationService, e := services.NewNotificationService(k.logger, k.config, k.db, k.natsClient, k.outboxRelay,
+		k.authorizer, k.catalogue)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	if e := notificationService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.notificationService = notificationService
+}
+
+func (k *Kiosk) startWebhookService() {
+	webhookService, e := services.NewWebhookService(k.logger, k.config, k.db, k.natsClient, k.outboxRelay,
+		k.authorizer, k.catalogue)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	if e := webhookService.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.webhookService = webhookService
+}
+
+func (k *Kiosk) startOutboxRelay() {
+	if e := k.outboxRelay.Start(); e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+}
+
 func (k *Kiosk) startWebServer() {
-	k.webServer = web.StartServer(k.logger, k.config, k.natsClient)
+	webServer, e := web.StartServer(k.logger, k.config, k.natsClient, k.catalogue)
+	if e != nil {
+		k.stop()
+		k.logger.Fatal(e.Error())
+	}
+
+	k.webServer = webServer
 }
 
 func (k *Kiosk) awaitTermination() {
@@ -145,6 +389,34 @@ func (k *Kiosk) stop() {
 		}
 	}
 
+	if k.emailService != nil {
+		k.emailService.Stop()
+	}
+
+	if k.satisfactionService != nil {
+		k.satisfactionService.Stop()
+	}
+
+	if k.escalationService != nil {
+		k.escalationService.Stop()
+	}
+
+	if k.macroService != nil {
+		k.macroService.Stop()
+	}
+
+	if k.cannedResponseService != nil {
+		k.cannedResponseService.Stop()
+	}
+
+	if k.importService != nil {
+		k.importService.Stop()
+	}
+
+	if k.exportService != nil {
+		k.exportService.Stop()
+	}
+
 	if k.commentService != nil {
 		k.commentService.Stop()
 	}
@@ -153,6 +425,30 @@ func (k *Kiosk) stop() {
 		k.ticketService.Stop()
 	}
 
+	if k.ruleService != nil {
+		k.ruleService.Stop()
+	}
+
+	if k.outboxRelay != nil {
+		k.outboxRelay.Stop()
+	}
+
+	if k.webhookService != nil {
+		k.webhookService.Stop()
+	}
+
+	if k.notificationService != nil {
+		k.notificationService.Stop()
+	}
+
+	if k.apiKeyService != nil {
+		k.apiKeyService.Stop()
+	}
+
+	if k.rateLimiter != nil {
+		k.rateLimiter.Stop()
+	}
+
 	if k.natsClient != nil {
 		k.natsClient.Close()
 	}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jibitters/kiosk/client"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+)
+
+func create(arguments []string) error {
+	flags, o := newFlagSet("create")
+	request := data.CreateTicketRequest{}
+	flags.StringVar(&request.Issuer, "issuer", "kioskctl", "issuer of the ticket")
+	flags.StringVar(&request.Owner, "owner", "", "owner of the ticket")
+	flags.StringVar(&request.Subject, "subject", "", "subject of the ticket")
+	flags.StringVar(&request.Content, "content", "", "content of the ticket")
+	flags.StringVar(&request.Metadata, "metadata", "{}", "JSON metadata of the ticket")
+	importanceLevel := flags.String("importance", string(models.TicketImportanceLevelMedium),
+		"importance level of the ticket")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	request.ImportanceLevel = models.TicketImportanceLevel(strings.ToUpper(*importanceLevel))
+
+	api, closer, e := o.connect()
+	if e != nil {
+		return e
+	}
+	defer closer()
+
+	id, e := api.CreateTicket(context.Background(), request)
+	if e != nil {
+		return e
+	}
+
+	return o.print(data.ID{ID: id}, table{headers: []string{"ID"}, rows: [][]string{{strconv.FormatInt(id, 10)}}})
+}
+
+func show(arguments []string) error {
+	flags, o := newFlagSet("show")
+	id := flags.Int64("id", 0, "identifier of the ticket")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	api, closer, e := o.connect()
+	if e != nil {
+		return e
+	}
+	defer closer()
+
+	ticket, e := api.LoadTicket(context.Background(), *id)
+	if e != nil {
+		return e
+	}
+
+	tables := []table{ticketsTable([]*data.TicketResponse{ticket})}
+	if len(ticket.Comments) > 0 {
+		tables = append(tables, commentsTable(ticket.Comments))
+	}
+
+	return o.print(ticket, tables...)
+}
+
+func update(arguments []string) error {
+	flags, o := newFlagSet("update")
+	id := flags.Int64("id", 0, "identifier of the ticket")
+	subject := flags.String("subject", "", "new subject of the ticket")
+	metadata := flags.String("metadata", "", "new JSON metadata of the ticket")
+	importanceLevel := flags.String("importance", "", "new importance level of the ticket")
+	status := flags.String("status", "", "new status of the ticket")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	// Only the provided flags are changed, even when they are set to empty values.
+	patchTicketRequest := &data.PatchTicketRequest{ID: *id}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "subject":
+			patchTicketRequest.Subject = subject
+		case "metadata":
+			patchTicketRequest.Metadata = metadata
+		case "importance":
+			l := models.TicketImportanceLevel(strings.ToUpper(*importanceLevel))
+			patchTicketRequest.ImportanceLevel = &l
+		case "status":
+			s := models.TicketStatus(strings.ToUpper(*status))
+			patchTicketRequest.Status = &s
+		}
+	})
+
+	if *patchTicketRequest == (data.PatchTicketRequest{ID: *id}) {
+		return fmt.Errorf("nothing to update, provide at least one of subject, metadata, importance or status")
+	}
+
+	api, closer, e := o.connect()
+	if e != nil {
+		return e
+	}
+	defer closer()
+
+	return api.PatchTicket(context.Background(), *patchTicketRequest)
+}
+
+func remove(arguments []string) error {
+	flags, o := newFlagSet("delete")
+	id := flags.Int64("id", 0, "identifier of the ticket")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	api, closer, e := o.connect()
+	if e != nil {
+		return e
+	}
+	defer closer()
+
+	return api.DeleteTicket(context.Background(), *id)
+}
+
+func filter(arguments []string) error {
+	flags, o := newFlagSet("filter")
+	request := criteriaFlags(flags)
+	flags.IntVar(&request.PageNumber, "page", 1, "page number")
+	flags.IntVar(&request.PageSize, "size", 25, "page size")
+	all := flags.Bool("all", false, "filter the tickets of all of the pages, from the page number")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	request.normalize()
+
+	api, closer, e := o.connect()
+	if e != nil {
+		return e
+	}
+	defer closer()
+
+	if *all {
+		tickets, e := collect(api, request.FilterTicketsRequest)
+		if e != nil {
+			return e
+		}
+
+		return o.print(tickets, ticketsTable(tickets))
+	}
+
+	filterTicketsResponse, e := api.FilterTickets(context.Background(), request.FilterTicketsRequest)
+	if e != nil {
+		return e
+	}
+
+	if e := o.print(filterTicketsResponse, ticketsTable(filterTicketsResponse.Tickets)); e != nil {
+		return e
+	}
+
+	if filterTicketsResponse.HasNextPage && o.output == outputTable {
+		_, _ = fmt.Fprintf(os.Stderr, "\nMore tickets on page %v, or use --all.\n", request.PageNumber+1)
+	}
+
+	return nil
+}
+
+func comment(arguments []string) error {
+	flags, o := newFlagSet("comment")
+	request := data.CreateCommentRequest{}
+	flags.Int64Var(&request.TicketID, "ticket", 0, "identifier of the ticket")
+	flags.StringVar(&request.Owner, "owner", "", "owner of the comment")
+	flags.StringVar(&request.Content, "content", "", "content of the comment")
+	flags.StringVar(&request.Metadata, "metadata", "{}", "JSON metadata of the comment")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	api, closer, e := o.connect()
+	if e != nil {
+		return e
+	}
+	defer closer()
+
+	return api.CreateComment(context.Background(), request)
+}
+
+// statusChange is the result of changing the status of a ticket by bulk-status.
+type statusChange struct {
+	ID     int64               `json:"ID"`
+	From   models.TicketStatus `json:"from"`
+	To     models.TicketStatus `json:"to"`
+	Result string              `json:"result"`
+}
+
+func bulkStatus(arguments []string) error {
+	flags, o := newFlagSet("bulk-status")
+	request := criteriaFlags(flags)
+	ids := flags.String("ids", "", "comma separated identifiers of the tickets, instead of the criteria")
+	to := flags.String("to", "", "new status of the tickets")
+	dryRun := flags.Bool("dry-run", false, "show the tickets to change without changing them")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	request.normalize()
+	status := models.TicketStatus(strings.ToUpper(*to))
+	if status == "" {
+		return fmt.Errorf("the new status is required")
+	}
+
+	api, closer, e := o.connect()
+	if e != nil {
+		return e
+	}
+	defer closer()
+
+	// The tickets are collected before changing any of them, as changing a ticket moves it between the pages.
+	tickets := make([]*data.TicketResponse, 0)
+	if *ids != "" {
+		for _, s := range strings.Split(*ids, ",") {
+			id, e := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if e != nil {
+				return fmt.Errorf("invalid ticket identifier %v", s)
+			}
+
+			ticket, e := api.LoadTicket(context.Background(), id)
+			if e != nil {
+				return e
+			}
+
+			tickets = append(tickets, ticket)
+		}
+	} else {
+		request.PageNumber = 1
+		request.PageSize = 25
+		if tickets, e = collect(api, request.FilterTicketsRequest); e != nil {
+			return e
+		}
+	}
+
+	changes := make([]*statusChange, 0, len(tickets))
+	failures := 0
+	for _, t := range tickets {
+		change := &statusChange{ID: t.ID, From: t.Status, To: status, Result: "changed"}
+		changes = append(changes, change)
+
+		if *dryRun {
+			change.Result = "dry run"
+			continue
+		}
+
+		patchTicketRequest := &data.PatchTicketRequest{ID: t.ID, Status: &status}
+		if e := api.PatchTicket(context.Background(), *patchTicketRequest); e != nil {
+			change.Result = client.ErrorCode(e)
+			if change.Result == "" {
+				change.Result = e.Error()
+			}
+
+			failures++
+		}
+	}
+
+	t := table{headers: []string{"ID", "FROM", "TO", "RESULT"}}
+	for _, c := range changes {
+		t.rows = append(t.rows, []string{strconv.FormatInt(c.ID, 10), string(c.From), string(c.To), c.Result})
+	}
+
+	if e := o.print(changes, t); e != nil {
+		return e
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to change %v of %v tickets", failures, len(changes))
+	}
+
+	return nil
+}
+
+// migrationStatus is the status of the database migration.
+type migrationStatus struct {
+	Version uint `json:"version"`
+	Latest  uint `json:"latest"`
+	Pending int  `json:"pending"`
+	Dirty   bool `json:"dirty"`
+}
+
+func migrations(arguments []string) error {
+	flags, o := newFlagSet("migrations")
+	connectionString := flags.String("db", "", "postgres connection string, defaults to "+
+		"db.postgres.connection_string of the configuration")
+	migrationDirectory := flags.String("migrations", "", "migration directory, defaults to "+
+		"db.postgres.migration_directory of the configuration")
+	if e := o.parse(flags, arguments); e != nil {
+		return e
+	}
+
+	config, e := o.loadConfig()
+	if e != nil {
+		return e
+	}
+
+	if *connectionString == "" {
+		*connectionString = config.Get("db.postgres.connection_string").
+			StringOrElse("postgres://localhost:5432/kiosk?sslmode=disable")
+	}
+
+	if *migrationDirectory == "" {
+		*migrationDirectory = config.Get("db.postgres.migration_directory").StringOrElse("file://migration/postgres")
+	}
+
+	versions, e := migrationVersions(*migrationDirectory)
+	if e != nil {
+		return e
+	}
+
+	migratory, e := migrate.New(*migrationDirectory, *connectionString)
+	if e != nil {
+		return e
+	}
+	defer func() { _, _ = migratory.Close() }()
+
+	status := &migrationStatus{}
+	version, dirty, e := migratory.Version()
+	if e != nil && e != migrate.ErrNilVersion {
+		return e
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	for _, v := range versions {
+		if v > status.Latest {
+			status.Latest = v
+		}
+
+		if v > status.Version {
+			status.Pending++
+		}
+	}
+
+	return o.print(status, table{headers: []string{"VERSION", "LATEST", "PENDING", "DIRTY"},
+		rows: [][]string{{fmt.Sprint(status.Version), fmt.Sprint(status.Latest), strconv.Itoa(status.Pending),
+			strconv.FormatBool(status.Dirty)}}})
+}
+
+// migrationVersions returns back the versions of the up migrations of the file source directory.
+func migrationVersions(directory string) ([]uint, error) {
+	files, e := ioutil.ReadDir(strings.TrimPrefix(directory, "file://"))
+	if e != nil {
+		return nil, e
+	}
+
+	versions := make([]uint, 0, len(files))
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".up.sql") {
+			continue
+		}
+
+		v, e := strconv.ParseUint(strings.SplitN(f.Name(), "_", 2)[0], 10, 64)
+		if e != nil {
+			continue
+		}
+
+		versions = append(versions, uint(v))
+	}
+
+	return versions, nil
+}
+
+// criteria holds the criteria flags of filtering tickets.
+type criteria struct {
+	data.FilterTicketsRequest
+	importanceLevel *string
+	status          *string
+}
+
+func criteriaFlags(flags *flag.FlagSet) *criteria {
+	c := &criteria{}
+	flags.StringVar(&c.Issuer, "issuer", "", "issuer of the tickets")
+	flags.StringVar(&c.Owner, "owner", "", "owner of the tickets")
+	c.importanceLevel = flags.String("importance", "", "importance level of the tickets")
+	c.status = flags.String("status", "", "status of the tickets")
+	flags.StringVar(&c.FromDate, "from-date", "", "RFC 3339 date time, from which the tickets are modified")
+	flags.StringVar(&c.ToDate, "to-date", "", "RFC 3339 date time, until which the tickets are modified")
+
+	return c
+}
+
+// normalize populates the request from the flags. The end of the period is fixed to now when not provided, so the pages
+// stay the same while they are filtered.
+func (c *criteria) normalize() {
+	c.ImportanceLevel = models.TicketImportanceLevel(strings.ToUpper(*c.importanceLevel))
+	c.Status = models.TicketStatus(strings.ToUpper(*c.status))
+
+	if c.ToDate == "" {
+		c.ToDate = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// collect returns back the tickets of all of the pages of the request, from its page.
+func collect(api client.API, request data.FilterTicketsRequest) ([]*data.TicketResponse, error) {
+	tickets := make([]*data.TicketResponse, 0)
+
+	it := api.Tickets(context.Background(), request)
+	for it.Next() {
+		tickets = append(tickets, it.Ticket())
+	}
+
+	return tickets, it.Err()
+}
+
+func ticketsTable(tickets []*data.TicketResponse) table {
+	t := table{headers: []string{"ID", "ISSUER", "OWNER", "SUBJECT", "IMPORTANCE", "STATUS", "COMMENTS", "MODIFIED AT"}}
+	for _, ticket := range tickets {
+		t.rows = append(t.rows, []string{strconv.FormatInt(ticket.ID, 10), ticket.Issuer, ticket.Owner,
+			truncate(ticket.Subject, 40), string(ticket.ImportanceLevel), string(ticket.Status),
+			strconv.Itoa(len(ticket.Comments)), ticket.ModifiedAt})
+	}
+
+	return t
+}
+
+func commentsTable(comments []*data.CommentResponse) table {
+	t := table{headers: []string{"COMMENT ID", "OWNER", "CONTENT", "CREATED AT"}}
+	for _, c := range comments {
+		t.rows = append(t.rows, []string{strconv.FormatInt(c.ID, 10), c.Owner, truncate(c.Content, 60), c.CreatedAt})
+	}
+
+	return t
+}
+
+// truncate shortens the text to the length for the tables, keeping it on a single line.
+func truncate(text string, length int) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+
+	return string(runes[:length-3]) + "..."
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jibitters/kiosk/client"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/web/data"
+)
+
+// httpAPI is the implementation of client.API over the REST API of kiosk. Requests carry the token as a bearer token,
+// if any.
+type httpAPI struct {
+	httpClient *http.Client
+	url        string
+	token      string
+}
+
+var _ client.API = (*httpAPI)(nil)
+
+func newHTTPAPI(url, token string, timeout time.Duration) *httpAPI {
+	return &httpAPI{httpClient: &http.Client{Timeout: timeout}, url: strings.TrimSuffix(url, "/") + "/v1", token: token}
+}
+
+func (a *httpAPI) CreateTicket(ctx context.Context, request data.CreateTicketRequest) (int64, error) {
+	id := &data.ID{}
+	if e := a.do(ctx, http.MethodPost, "/tickets", nil, request, id); e != nil {
+		return 0, e
+	}
+
+	return id.ID, nil
+}
+
+func (a *httpAPI) LoadTicket(ctx context.Context, id int64) (*data.TicketResponse, error) {
+	ticketResponse := &data.TicketResponse{}
+	if e := a.do(ctx, http.MethodGet, "/tickets/"+strconv.FormatInt(id, 10), nil, nil, ticketResponse); e != nil {
+		return nil, e
+	}
+
+	return ticketResponse, nil
+}
+
+func (a *httpAPI) UpdateTicket(ctx context.Context, request data.UpdateTicketRequest) error {
+	return a.do(ctx, http.MethodPut, "/tickets/"+strconv.FormatInt(request.ID, 10), nil, request, nil)
+}
+
+func (a *httpAPI) PatchTicket(ctx context.Context, request data.PatchTicketRequest) error {
+	return a.do(ctx, http.MethodPatch, "/tickets/"+strconv.FormatInt(request.ID, 10), nil, request, nil)
+}
+
+func (a *httpAPI) DeleteTicket(ctx context.Context, id int64) error {
+	return a.do(ctx, http.MethodDelete, "/tickets/"+strconv.FormatInt(id, 10), nil, nil, nil)
+}
+
+func (a *httpAPI) FilterTickets(ctx context.Context, request data.FilterTicketsRequest) (*data.FilterTicketsResponse,
+	error) {
+
+	query := url.Values{}
+	query.Set("issuer", request.Issuer)
+	query.Set("owner", request.Owner)
+	query.Set("importanceLevel", string(request.ImportanceLevel))
+	query.Set("status", string(request.Status))
+	query.Set("fromDate", request.FromDate)
+	query.Set("toDate", request.ToDate)
+	query.Set("pageNumber", strconv.Itoa(request.PageNumber))
+	query.Set("pageSize", strconv.Itoa(request.PageSize))
+
+	filterTicketsResponse := &data.FilterTicketsResponse{}
+	if e := a.do(ctx, http.MethodGet, "/tickets", query, nil, filterTicketsResponse); e != nil {
+		return nil, e
+	}
+
+	return filterTicketsResponse, nil
+}
+
+func (a *httpAPI) Tickets(ctx context.Context, request data.FilterTicketsRequest) *client.TicketIterator {
+	return client.NewTicketIterator(ctx, a.FilterTickets, request)
+}
+
+func (a *httpAPI) CreateComment(ctx context.Context, request data.CreateCommentRequest) error {
+	return a.do(ctx, http.MethodPost, "/comments", nil, request, nil)
+}
+
+func (a *httpAPI) LoadComment(ctx context.Context, id int64) (*data.CommentResponse, error) {
+	commentResponse := &data.CommentResponse{}
+	if e := a.do(ctx, http.MethodGet, "/comments/"+strconv.FormatInt(id, 10), nil, nil, commentResponse); e != nil {
+		return nil, e
+	}
+
+	return commentResponse, nil
+}
+
+func (a *httpAPI) UpdateComment(ctx context.Context, request data.UpdateCommentRequest) error {
+	return a.do(ctx, http.MethodPut, "/comments/"+strconv.FormatInt(request.ID, 10), nil, request, nil)
+}
+
+func (a *httpAPI) DeleteComment(ctx context.Context, id int64) error {
+	return a.do(ctx, http.MethodDelete, "/comments/"+strconv.FormatInt(id, 10), nil, nil, nil)
+}
+
+// do sends the request to the path and populates the response, if any, from the body. Failures replied by kiosk and
+// timeouts are returned as *errors.Type, as the nats client does.
+func (a *httpAPI) do(ctx context.Context, method, path string, query url.Values, request,
+	response interface{}) error {
+
+	var body []byte
+	if request != nil {
+		in, e := json.Marshal(request)
+		if e != nil {
+			return e
+		}
+
+		body = in
+	}
+
+	u := a.url + path
+	if query != nil {
+		u += "?" + query.Encode()
+	}
+
+	r, e := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if e != nil {
+		return e
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		r.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	resp, e := a.httpClient.Do(r)
+	if e != nil {
+		if e, ok := e.(interface{ Timeout() bool }); ok && e.Timeout() {
+			return errors.RequestTimeout("")
+		}
+
+		return e
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	out, e := ioutil.ReadAll(resp.Body)
+	if e != nil {
+		return e
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		et := &errors.Type{}
+		if json.Unmarshal(out, et) == nil && et.FingerPrint != "" {
+			return et
+		}
+
+		return fmt.Errorf("%v %v replied %v", method, u, resp.Status)
+	}
+
+	if response != nil {
+		return json.Unmarshal(out, response)
+	}
+
+	return nil
+}
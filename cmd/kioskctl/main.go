@@ -0,0 +1,193 @@
+// Command kioskctl is the command line tool of the operators of kiosk, talking to it over nats or HTTP.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jibitters/kiosk/client"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/messaging/nats"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+const defaultConfig = "./configs/kiosk.json"
+
+// command is a subcommand of kioskctl.
+type command struct {
+	name    string
+	summary string
+	run     func(arguments []string) error
+}
+
+var commands = []command{
+	{"create", "Creates a new ticket.", create},
+	{"show", "Shows a ticket along with its comments.", show},
+	{"update", "Changes the provided information of a ticket.", update},
+	{"delete", "Deletes a ticket along with its comments.", remove},
+	{"filter", "Filters tickets based on provided criteria values.", filter},
+	{"comment", "Creates a new comment for a ticket.", comment},
+	{"bulk-status", "Changes the status of the provided or filtered tickets.", bulkStatus},
+	{"migrations", "Shows the status of the database migration.", migrations},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage(os.Stdout)
+		return
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if e := c.run(os.Args[2:]); e != nil {
+				fail(e)
+			}
+
+			return
+		}
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "unknown command %v\n\n", os.Args[1])
+	usage(os.Stderr)
+	os.Exit(2)
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: kioskctl <command> [flags]")
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(w, "  %-12v %v\n", c.name, c.summary)
+	}
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Run kioskctl <command> --help for the flags of a command.")
+}
+
+// options holds the flags shared by the commands.
+type options struct {
+	config    string
+	transport string
+	nats      string
+	url       string
+	token     string
+	timeout   time.Duration
+	output    string
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	o := &options{}
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&o.config, "config", defaultConfig, "configuration file, ignored when the default one does not exist")
+	flags.StringVar(&o.transport, "transport", "nats", "transport of the requests, nats or http")
+	flags.StringVar(&o.nats, "nats", "", "comma separated nats addresses, defaults to nats.addresses of the configuration")
+	flags.StringVar(&o.url, "url", "", "base URL of the HTTP API, defaults to web.server.host and web.server.port of the "+
+		"configuration")
+	flags.StringVar(&o.token, "token", os.Getenv("KIOSK_TOKEN"), "bearer token of the HTTP API, defaults to $KIOSK_TOKEN")
+	flags.DurationVar(&o.timeout, "timeout", 5*time.Second, "timeout of every request")
+	flags.StringVar(&o.output, "output", outputTable, "output format, table, json or yaml")
+
+	return flags, o
+}
+
+// parse parses the arguments and checks the shared flags.
+func (o *options) parse(flags *flag.FlagSet, arguments []string) error {
+	_ = flags.Parse(arguments)
+
+	switch o.output {
+	case outputTable, outputJSON, outputYAML:
+		outputFormat = o.output
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %v, expected one of table, json or yaml", o.output)
+	}
+}
+
+// loadConfig loads the configuration file, or returns back an empty configuration when the default one does not exist.
+func (o *options) loadConfig() (*configuring.Config, error) {
+	config := configuring.New()
+
+	if _, e := os.Stat(o.config); os.IsNotExist(e) && o.config == defaultConfig {
+		return config, nil
+	}
+
+	if _, e := config.LoadJSON(o.config); e != nil {
+		return nil, e
+	}
+
+	return config, nil
+}
+
+// connect returns back the API over the transport, along with a function that releases its resources.
+func (o *options) connect() (client.API, func(), error) {
+	config, e := o.loadConfig()
+	if e != nil {
+		return nil, nil, e
+	}
+
+	switch o.transport {
+	case "nats":
+		addresses := o.nats
+		if addresses == "" {
+			addresses = strings.Join(config.Get("nats.addresses").
+				SliceOfStringOrElse([]string{"nats://localhost:4222"}), ",")
+		}
+
+		options, e := nats.Options(zap.NewNop().Sugar(), config)
+		if e != nil {
+			return nil, nil, e
+		}
+
+		natsClient, e := nc.Connect(addresses, append(options, nc.Name("kioskctl"))...)
+		if e != nil {
+			return nil, nil, e
+		}
+
+		return client.New(natsClient, o.timeout), natsClient.Close, nil
+	case "http":
+		url := o.url
+		if url == "" {
+			url = fmt.Sprintf("http://%v:%v", config.Get("web.server.host").StringOrElse("localhost"),
+				config.Get("web.server.port").UintOrElse(8080))
+		}
+
+		return newHTTPAPI(url, o.token, o.timeout), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown transport %v, expected nats or http", o.transport)
+	}
+}
+
+// print prints the value in the output format of the options.
+func (o *options) print(value interface{}, tables ...table) error {
+	return printOutput(os.Stdout, o.output, value, tables...)
+}
+
+// outputFormat is the output format of the failures, which are printed after the flags are parsed.
+var outputFormat = outputTable
+
+// fail prints the error, along with the code and fingerprint of the failures replied by kiosk, and exits.
+func fail(e error) {
+	et, ok := e.(*errors.Type)
+	if !ok {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", e.Error())
+		os.Exit(1)
+	}
+
+	t := table{headers: []string{"CODE", "MESSAGE", "FINGERPRINT"}}
+	for _, e := range et.Errors {
+		t.rows = append(t.rows, []string{e.Code, e.Message, et.FingerPrint})
+	}
+
+	if e := printOutput(os.Stderr, outputFormat, et, t); e != nil {
+		out, _ := json.Marshal(et)
+		_, _ = fmt.Fprintln(os.Stderr, string(out))
+	}
+
+	os.Exit(1)
+}
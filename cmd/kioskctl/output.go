@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Supported output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// table is the tabular representation of an output.
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+// printOutput prints the value in the format. The table format prints the tables instead, separated by empty lines.
+func printOutput(w io.Writer, format string, value interface{}, tables ...table) error {
+	switch format {
+	case outputJSON:
+		out, e := json.MarshalIndent(value, "", "  ")
+		if e != nil {
+			return e
+		}
+
+		_, e = fmt.Fprintln(w, string(out))
+		return e
+	case outputYAML:
+		out, e := toYAML(value)
+		if e != nil {
+			return e
+		}
+
+		_, e = w.Write(out)
+		return e
+	case outputTable:
+		for i, t := range tables {
+			if i > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, strings.Join(t.headers, "\t"))
+			for _, r := range t.rows {
+				_, _ = fmt.Fprintln(tw, strings.Join(r, "\t"))
+			}
+
+			if e := tw.Flush(); e != nil {
+				return e
+			}
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %v, expected one of table, json or yaml", format)
+	}
+}
+
+// field is a key value pair of a JSON object, as objects are decoded in order.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// toYAML encodes the value as YAML, following its JSON encoding and keeping the order of the fields.
+func toYAML(value interface{}) ([]byte, error) {
+	in, e := json.Marshal(value)
+	if e != nil {
+		return nil, e
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(in))
+	decoder.UseNumber()
+
+	node, e := decode(decoder)
+	if e != nil {
+		return nil, e
+	}
+
+	buffer := &bytes.Buffer{}
+	if isScalar(node) {
+		buffer.WriteString(scalar(node) + "\n")
+	} else {
+		writeYAML(buffer, node, 0)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// decode decodes the next JSON value, returning objects as []field and arrays as []interface{}.
+func decode(decoder *json.Decoder) (interface{}, error) {
+	token, e := decoder.Token()
+	if e != nil {
+		return nil, e
+	}
+
+	switch token {
+	case json.Delim('{'):
+		fields := make([]field, 0)
+		for decoder.More() {
+			key, e := decoder.Token()
+			if e != nil {
+				return nil, e
+			}
+
+			value, e := decode(decoder)
+			if e != nil {
+				return nil, e
+			}
+
+			fields = append(fields, field{key: key.(string), value: value})
+		}
+
+		_, e = decoder.Token()
+		return fields, e
+	case json.Delim('['):
+		items := make([]interface{}, 0)
+		for decoder.More() {
+			item, e := decode(decoder)
+			if e != nil {
+				return nil, e
+			}
+
+			items = append(items, item)
+		}
+
+		_, e = decoder.Token()
+		return items, e
+	default:
+		return token, nil
+	}
+}
+
+// writeYAML writes the object or array node as a YAML block, indenting every line with the indent.
+func writeYAML(buffer *bytes.Buffer, node interface{}, indent int) {
+	padding := strings.Repeat(" ", indent)
+
+	switch n := node.(type) {
+	case []field:
+		for _, f := range n {
+			buffer.WriteString(padding + scalar(f.key) + ":")
+			writeValue(buffer, f.value, indent+2)
+		}
+	case []interface{}:
+		for _, item := range n {
+			if isScalar(item) {
+				buffer.WriteString(padding + "- " + scalar(item) + "\n")
+				continue
+			}
+
+			// Write the item as a block and put the dash in place of the indentation of its first line.
+			block := &bytes.Buffer{}
+			writeYAML(block, item, indent+2)
+			buffer.WriteString(padding + "- " + strings.TrimPrefix(block.String(), padding+"  "))
+		}
+	}
+}
+
+// writeValue writes the value of a field, on the same line when it is a scalar and as an indented block otherwise.
+func writeValue(buffer *bytes.Buffer, value interface{}, indent int) {
+	if isScalar(value) {
+		buffer.WriteString(" " + scalar(value) + "\n")
+		return
+	}
+
+	buffer.WriteString("\n")
+	writeYAML(buffer, value, indent)
+}
+
+// isScalar returns back true when the node is written on a single line, including empty objects and arrays.
+func isScalar(node interface{}) bool {
+	switch n := node.(type) {
+	case []field:
+		return len(n) == 0
+	case []interface{}:
+		return len(n) == 0
+	default:
+		return true
+	}
+}
+
+var plain = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.@/+-]*$`)
+
+// scalar returns back the YAML representation of the scalar node. Strings that could be read as something else are
+// double quoted, which is the same as JSON quoting.
+func scalar(node interface{}) string {
+	switch n := node.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(n)
+	case json.Number:
+		return n.String()
+	case []field:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	case string:
+		switch strings.ToLower(n) {
+		case "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+			return strconv.Quote(n)
+		}
+
+		if plain.MatchString(n) {
+			return n
+		}
+
+		out, _ := json.Marshal(n)
+		return string(out)
+	default:
+		return fmt.Sprint(n)
+	}
+}
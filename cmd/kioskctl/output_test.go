@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Output", func() {
+	ticket := &data.TicketResponse{
+		ID:              1,
+		Issuer:          "Microservice-A",
+		Owner:           "user@example.com",
+		Subject:         "Technical Problem",
+		Content:         "Hello,\ni have some issues with REST API Docs!",
+		Metadata:        `{"ip":"192.168.1.1"}`,
+		ImportanceLevel: models.TicketImportanceLevelMedium,
+		Status:          models.TicketStatusNew,
+		Comments: []*data.CommentResponse{{ID: 2, TicketID: 1, Owner: "support@example.com",
+			Content: "Fixed the docs!", CreatedAt: "2020-01-01T00:00:00Z", ModifiedAt: "2020-01-01T00:00:00Z"}},
+		CreatedAt:  "2020-01-01T00:00:00Z",
+		ModifiedAt: "2020-01-01T00:00:00Z",
+	}
+
+	Context("When toYAML called", func() {
+		It("Should encode the fields in order and quote the ambiguous strings", func() {
+			out, e := toYAML(ticket)
+			Ω(e).Should(BeNil())
+			Ω(string(out)).Should(Equal(`ID: 1
+issuer: Microservice-A
+owner: user@example.com
+subject: "Technical Problem"
+content: "Hello,\ni have some issues with REST API Docs!"
+metadata: "{\"ip\":\"192.168.1.1\"}"
+importanceLevel: MEDIUM
+status: NEW
+comments:
+  - ID: 2
+    ticketID: 1
+    owner: support@example.com
+    content: "Fixed the docs!"
+    createdAt: "2020-01-01T00:00:00Z"
+    modifiedAt: "2020-01-01T00:00:00Z"
+createdAt: "2020-01-01T00:00:00Z"
+modifiedAt: "2020-01-01T00:00:00Z"
+`))
+		})
+
+		It("Should encode the empty collections and the scalars", func() {
+			out, _ := toYAML(map[string]interface{}{"tickets": []string{}, "no": "no", "none": nil})
+			Ω(string(out)).Should(Equal("\"no\": \"no\"\nnone: null\ntickets: []\n"))
+
+			out, _ = toYAML([][]int{{1, 2}})
+			Ω(string(out)).Should(Equal("- - 1\n  - 2\n"))
+
+			out, _ = toYAML(true)
+			Ω(string(out)).Should(Equal("true\n"))
+		})
+	})
+
+	Context("When printOutput called", func() {
+		It("Should print the tables aligned", func() {
+			buffer := &bytes.Buffer{}
+			Ω(printOutput(buffer, outputTable, ticket, commentsTable(ticket.Comments))).Should(Succeed())
+			Ω(buffer.String()).Should(Equal("COMMENT ID  OWNER                CONTENT          CREATED AT\n" +
+				"2           support@example.com  Fixed the docs!  2020-01-01T00:00:00Z\n"))
+		})
+
+		It("Should fail on the unknown formats", func() {
+			Ω(printOutput(&bytes.Buffer{}, "xml", ticket)).ShouldNot(Succeed())
+		})
+	})
+
+	Context("When truncate called", func() {
+		It("Should keep the text on a single line", func() {
+			Ω(truncate("Hello,\ni have  some issues", 40)).Should(Equal("Hello, i have some issues"))
+			Ω(truncate("Hello, i have some issues", 10)).Should(Equal("Hello, ..."))
+		})
+	})
+})
@@ -0,0 +1,176 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// placeholders matches the parameters of the messages, e.g: {max}.
+var placeholders = regexp.MustCompile(`{(\w+)}`)
+
+// Catalogue holds the messages of the error codes by language. Codes without a message fall back to the message of
+// their failure, i.e. the last part of the code, and then to the default language.
+type Catalogue struct {
+	fallback string
+	messages map[string]map[string]string
+}
+
+// NewCatalogue returns back a newly created Catalogue of the messages of the codes by language, falling back to the
+// messages of the fallback language.
+func NewCatalogue(fallback string, messages map[string]map[string]string) *Catalogue {
+	catalogue := &Catalogue{fallback: strings.ToLower(fallback), messages: make(map[string]map[string]string)}
+	for language, codes := range messages {
+		catalogue.messages[strings.ToLower(language)] = codes
+	}
+
+	return catalogue
+}
+
+// LoadCatalogue loads the messages of the JSON files of the directory, each of which is an object of the messages of
+// the codes named after its language, e.g: fa.json.
+func LoadCatalogue(directory, fallback string) (*Catalogue, error) {
+	files, e := ioutil.ReadDir(directory)
+	if e != nil {
+		return nil, e
+	}
+
+	messages := make(map[string]map[string]string)
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		in, e := ioutil.ReadFile(filepath.Join(directory, file.Name()))
+		if e != nil {
+			return nil, e
+		}
+
+		codes := make(map[string]string)
+		if e := json.Unmarshal(in, &codes); e != nil {
+			return nil, fmt.Errorf("invalid messages file %v: %v", file.Name(), e)
+		}
+
+		messages[strings.TrimSuffix(file.Name(), ".json")] = codes
+	}
+
+	catalogue := NewCatalogue(fallback, messages)
+	if _, ok := catalogue.messages[catalogue.fallback]; !ok {
+		return nil, fmt.Errorf("no messages of the default language %v in %v", fallback, directory)
+	}
+
+	return catalogue, nil
+}
+
+// Language returns back the language of the catalogue most preferred by the preferences, which are either a language
+// such as fa-IR or the value of an Accept-Language header, or the fallback language if none of them is supported.
+func (c *Catalogue) Language(preferences string) string {
+	type preference struct {
+		language string
+		quality  float64
+	}
+
+	ranges := make([]preference, 0)
+	for _, part := range strings.Split(preferences, ",") {
+		fields := strings.Split(part, ";")
+		p := preference{language: strings.ToLower(strings.TrimSpace(fields[0])), quality: 1}
+
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				p.quality, _ = strconv.ParseFloat(strings.TrimPrefix(field, "q="), 64)
+			}
+		}
+
+		if p.language != "" && p.quality > 0 {
+			ranges = append(ranges, p)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	for _, p := range ranges {
+		if _, ok := c.messages[p.language]; ok {
+			return p.language
+		}
+
+		if primary := strings.Split(p.language, "-")[0]; c.messages[primary] != nil {
+			return primary
+		}
+	}
+
+	return c.fallback
+}
+
+// Message returns back the message of the error in the language, or an empty one if the catalogue has none.
+func (c *Catalogue) Message(language string, e Error) string {
+	failure := e.Code[strings.LastIndex(e.Code, ".")+1:]
+
+	message, ok := "", false
+	for _, l := range []string{language, c.fallback} {
+		if message, ok = c.messages[l][e.Code]; ok {
+			break
+		}
+
+		if message, ok = c.messages[l][failure]; ok {
+			break
+		}
+	}
+
+	if !ok {
+		return ""
+	}
+
+	field := e.Field
+	if field == "" && failure != e.Code {
+		field = strings.TrimSuffix(e.Code, "."+failure)
+	}
+
+	return placeholders.ReplaceAllStringFunc(message, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		if name == "field" {
+			return field
+		}
+
+		value, ok := e.Params[name]
+		if !ok {
+			return placeholder
+		}
+
+		switch values := value.(type) {
+		case []string:
+			return strings.Join(values, ", ")
+		case []interface{}:
+			parts := make([]string, 0, len(values))
+			for _, v := range values {
+				parts = append(parts, fmt.Sprint(v))
+			}
+
+			return strings.Join(parts, ", ")
+		}
+
+		return fmt.Sprint(value)
+	})
+}
+
+// Localize populates the empty messages of the errors of t with the messages of the language most preferred by the
+// preferences. It is a no-op on a nil catalogue.
+func (c *Catalogue) Localize(t *Type, preferences string) *Type {
+	if c == nil || t == nil {
+		return t
+	}
+
+	language := c.Language(preferences)
+	for i := range t.Errors {
+		if t.Errors[i].Message == "" {
+			t.Errors[i].Message = c.Message(language, t.Errors[i])
+		}
+	}
+
+	return t
+}
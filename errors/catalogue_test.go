@@ -0,0 +1,139 @@
+package errors_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalogue", func() {
+	catalogue := errors.NewCatalogue("en", map[string]map[string]string{
+		"en": {
+			"is_required":      "{field} is required.",
+			"invalid_length":   "{field} must be at most {max} characters long.",
+			"not_found":        "{field} is not found.",
+			"status.not_valid": "status must be one of {allowed}.",
+			"unauthorized":     "The request is not authenticated.",
+		},
+		"fa": {
+			"is_required":       "وارد کردن {field} الزامی است.",
+			"too_many_requests": "لطفا پس از {retryAfter} ثانیه دوباره تلاش کنید.",
+		},
+	})
+
+	Context("When Language called", func() {
+		It("Should select the most preferred language of the catalogue", func() {
+			Ω(catalogue.Language("fa")).Should(Equal("fa"))
+			Ω(catalogue.Language("FA-ir")).Should(Equal("fa"))
+			Ω(catalogue.Language("de-DE, fa;q=0.5, en;q=0.8")).Should(Equal("en"))
+			Ω(catalogue.Language("en;q=0, fa")).Should(Equal("fa"))
+		})
+
+		It("Should fall back to the default language", func() {
+			Ω(catalogue.Language("")).Should(Equal("en"))
+			Ω(catalogue.Language("de, *")).Should(Equal("en"))
+		})
+	})
+
+	Context("When Localize called", func() {
+		It("Should populate the messages along with their parameters", func() {
+			et := errors.InvalidArguments([]errors.Error{
+				{Code: "subject.invalid_length", Field: "subject", Params: map[string]interface{}{"max": 255}},
+				{Code: "status.not_valid", Field: "status", Params: map[string]interface{}{
+					"allowed": []string{"NEW", "CLOSED"}}},
+				{Code: "comments.owner.is_required", Field: "comments[1].owner"},
+			})
+
+			catalogue.Localize(et, "en-US")
+			Ω(et.Errors[0].Message).Should(Equal("subject must be at most 255 characters long."))
+			Ω(et.Errors[1].Message).Should(Equal("status must be one of NEW, CLOSED."))
+			Ω(et.Errors[2].Message).Should(Equal("comments[1].owner is required."))
+		})
+
+		It("Should fall back to the failure of the code and to the default language", func() {
+			et := catalogue.Localize(errors.NotFound("ticket.not_found", ""), "fa")
+			Ω(et.Errors[0].Message).Should(Equal("ticket is not found."))
+
+			et = catalogue.Localize(errors.InvalidArgument("owner.is_required", ""), "fa")
+			Ω(et.Errors[0].Message).Should(Equal("وارد کردن owner الزامی است."))
+
+			et = catalogue.Localize(errors.TooManyRequests(1500*time.Millisecond), "fa")
+			Ω(et.Errors[0].Message).Should(Equal("لطفا پس از 2 ثانیه دوباره تلاش کنید."))
+		})
+
+		It("Should keep the messages already populated and the codes without a message", func() {
+			et := catalogue.Localize(errors.Unauthorized("token is expired"), "en")
+			Ω(et.Errors[0].Message).Should(Equal("token is expired"))
+
+			et = catalogue.Localize(errors.PreconditionFailed("export.not_completed", ""), "en")
+			Ω(et.Errors[0].Message).Should(BeEmpty())
+		})
+
+		It("Should do nothing without a catalogue", func() {
+			var catalogue *errors.Catalogue
+			et := catalogue.Localize(errors.Unauthorized(""), "en")
+			Ω(et.Errors[0].Message).Should(BeEmpty())
+		})
+	})
+
+	Context("When LoadCatalogue called", func() {
+		var directory string
+
+		BeforeEach(func() {
+			directory, _ = ioutil.TempDir("", "messages")
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(directory)
+		})
+
+		It("Should load the messages of each language", func() {
+			Ω(ioutil.WriteFile(filepath.Join(directory, "en.json"), []byte(`{"unknown": "Something went wrong."}`),
+				0600)).Should(Succeed())
+			Ω(ioutil.WriteFile(filepath.Join(directory, "fa.json"), []byte(`{"unknown": "خطایی رخ داد."}`), 0600)).
+				Should(Succeed())
+			Ω(ioutil.WriteFile(filepath.Join(directory, "README.md"), []byte("#"), 0600)).Should(Succeed())
+
+			catalogue, e := errors.LoadCatalogue(directory, "en")
+			Ω(e).Should(BeNil())
+			Ω(catalogue.Message("fa", errors.Error{Code: "unknown"})).Should(Equal("خطایی رخ داد."))
+			Ω(catalogue.Message("de", errors.Error{Code: "unknown"})).Should(Equal("Something went wrong."))
+		})
+
+		It("Should fail without the messages of the default language or with invalid files", func() {
+			Ω(ioutil.WriteFile(filepath.Join(directory, "fa.json"), []byte(`{}`), 0600)).Should(Succeed())
+			_, e := errors.LoadCatalogue(directory, "en")
+			Ω(e).ShouldNot(BeNil())
+
+			Ω(ioutil.WriteFile(filepath.Join(directory, "en.json"), []byte(`[]`), 0600)).Should(Succeed())
+			_, e = errors.LoadCatalogue(directory, "en")
+			Ω(e).ShouldNot(BeNil())
+		})
+
+		It("Should load the messages shipped with kiosk", func() {
+			catalogue, e := errors.LoadCatalogue("../messages", "en")
+			Ω(e).Should(BeNil())
+			Ω(catalogue.Language("fa-IR")).Should(Equal("fa"))
+		})
+
+		It("Should have a message for the codes replied by kiosk in every language", func() {
+			codes := []string{"ID.invalid", "invalid.json.format", "method.not_allowed", "route.not_found",
+				"ticket.not_found", "import.not_found", "import.file_not_found", "import.not_running",
+				"export.not_found", "export.file_not_found", "macro.issuer_mismatch", "canned_response.issuer_mismatch"}
+
+			for _, language := range []string{"en", "fa"} {
+				catalogue, e := errors.LoadCatalogue("../messages", language)
+				Ω(e).Should(BeNil())
+
+				for _, code := range codes {
+					Ω(catalogue.Message(language, errors.Error{Code: code})).ShouldNot(BeEmpty(), language+": "+code)
+				}
+			}
+		})
+	})
+})
@@ -2,7 +2,9 @@ package errors
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,12 +14,18 @@ type Type struct {
 	FingerPrint    string  `json:"fingerprint"`
 	Errors         []Error `json:"errors"`
 	HTTPStatusCode int     `json:"status"`
+
+	// RetryAfter is the number of seconds to wait before retrying the request, if known.
+	RetryAfter int `json:"retryAfter,omitempty"`
 }
 
-// Error encapsulates an specific error. An error type may include two or more errors.
+// Error encapsulates an specific error. An error type may include two or more errors, e.g: one for each invalid field
+// of a request, along with the path of the field and the parameters of the error such as the max length of the field.
 type Error struct {
-	Code    string `json:"code"`
-	Message string `json:"message,omitempty"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message,omitempty"`
+	Field   string                 `json:"field,omitempty"`
+	Params  map[string]interface{} `json:"params,omitempty"`
 }
 
 // String representation of Type.
@@ -32,60 +40,78 @@ func (t *Type) Error() string {
 
 // InvalidRequestBody is a helper method that indicates the request body is not valid.
 func InvalidRequestBody() *Type {
-	return &Type{uuid.New().String(), []Error{{"invalid.json.format", ""}},
-		http.StatusBadRequest}
+	return newType("invalid.json.format", "", http.StatusBadRequest)
 }
 
 // InvalidArgument is a helper method that indicates the provided argument is not valid.
 func InvalidArgument(code, message string) *Type {
-	return &Type{uuid.New().String(), []Error{{code, message}},
-		http.StatusBadRequest}
+	return newType(code, message, http.StatusBadRequest)
+}
+
+// InvalidArguments is a helper method that indicates the provided arguments are not valid, one error for each.
+func InvalidArguments(errors []Error) *Type {
+	return &Type{FingerPrint: uuid.New().String(), Errors: errors, HTTPStatusCode: http.StatusBadRequest}
 }
 
 // Unauthorized is a helper method that indicates the request is not authenticated.
 func Unauthorized(message string) *Type {
-	return &Type{uuid.New().String(), []Error{{"unauthorized", message}},
-		http.StatusUnauthorized}
+	return newType("unauthorized", message, http.StatusUnauthorized)
+}
+
+// Forbidden is a helper method that indicates the authenticated caller is not allowed to do the request.
+func Forbidden(message string) *Type {
+	return newType("forbidden", message, http.StatusForbidden)
 }
 
 // NotFound is a helper method that indicates the resource not found.
 func NotFound(code, message string) *Type {
-	return &Type{uuid.New().String(), []Error{{code, message}},
-		http.StatusNotFound}
+	return newType(code, message, http.StatusNotFound)
+}
+
+// MethodNotAllowed is a helper method that indicates the resource does not support the request method.
+func MethodNotAllowed(message string) *Type {
+	return newType("method.not_allowed", message, http.StatusMethodNotAllowed)
 }
 
 // AlreadyExists is a helper method that indicates the resource already exists.
 func AlreadyExists(code, message string) *Type {
-	return &Type{uuid.New().String(), []Error{{code, message}},
-		http.StatusPreconditionFailed}
+	return newType(code, message, http.StatusPreconditionFailed)
 }
 
 // PreconditionFailed is a helper method that indicates some precondition failure.
 func PreconditionFailed(code, message string) *Type {
-	return &Type{uuid.New().String(), []Error{{code, message}},
-		http.StatusPreconditionFailed}
+	return newType(code, message, http.StatusPreconditionFailed)
 }
 
 // RequestTimeout is a helper method that indicates request timeout occurred.
 func RequestTimeout(message string) *Type {
-	return &Type{uuid.New().String(), []Error{{"request.timeout", message}},
-		http.StatusRequestTimeout}
+	return newType("request.timeout", message, http.StatusRequestTimeout)
 }
 
 // ServiceUnavailable is a helper method that indicates the server is not available for now.
 func ServiceUnavailable(message string) *Type {
-	return &Type{uuid.New().String(), []Error{{"service.not_available", message}},
-		http.StatusServiceUnavailable}
+	return newType("service.not_available", message, http.StatusServiceUnavailable)
 }
 
 // InternalServerError is a helper method that indicates an internal server error occurred.
 func InternalServerError(code, message string) *Type {
-	return &Type{uuid.New().String(), []Error{{code, message}},
-		http.StatusInternalServerError}
+	return newType(code, message, http.StatusInternalServerError)
+}
+
+// TooManyRequests is a helper method that indicates the caller exceeded a rate limit and may retry after the duration.
+func TooManyRequests(retryAfter time.Duration) *Type {
+	t := newType("too_many_requests", "", http.StatusTooManyRequests)
+	t.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+	t.Errors[0].Params = map[string]interface{}{"retryAfter": t.RetryAfter}
+	return t
 }
 
 // NotImplemented is a helper method that indicates the service is not implemented yet.
 func NotImplemented() *Type {
-	return &Type{uuid.New().String(), []Error{{"service.not_implemented", ""}},
-		http.StatusNotImplemented}
+	return newType("service.not_implemented", "", http.StatusNotImplemented)
+}
+
+func newType(code, message string, httpStatusCode int) *Type {
+	return &Type{FingerPrint: uuid.New().String(), Errors: []Error{{Code: code, Message: message}},
+		HTTPStatusCode: httpStatusCode}
 }
@@ -9,7 +9,7 @@ require (
 	github.com/gorilla/mux v1.8.0
 	github.com/jackc/pgx/v4 v4.8.1
 	github.com/lireza/lib v0.0.13
-	github.com/nats-io/nats-server/v2 v2.1.8 // indirect
+	github.com/nats-io/nats-server/v2 v2.1.8
 	github.com/nats-io/nats.go v1.10.0
 	github.com/onsi/ginkgo v1.14.1
 	github.com/onsi/gomega v1.10.2
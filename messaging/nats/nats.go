@@ -0,0 +1,178 @@
+package nats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// Connect tries to connect to the nats cluster with the information provided in config instance. The connection is
+// named after name and its lifecycle events are logged by logger.
+func Connect(logger *zap.SugaredLogger, config *configuring.Config, name string) (*nc.Conn, error) {
+	addresses := config.Get("nats.addresses").SliceOfStringOrElse([]string{"nats://localhost:4222"})
+	logger.Info("nats.addresses -> ", addresses)
+
+	options, e := Options(logger, config)
+	if e != nil {
+		return nil, e
+	}
+
+	return nc.Connect(strings.Join(addresses, ","), append(options, nc.Name(name))...)
+}
+
+// Options returns back the connection options of the nats section of config.
+func Options(logger *zap.SugaredLogger, config *configuring.Config) ([]nc.Option, error) {
+	user := config.Get("nats.user").StringOrElse("")
+	password := config.Get("nats.password").StringOrElse("")
+	token := config.Get("nats.token").StringOrElse("")
+	nkeySeedFile := config.Get("nats.nkey_seed_file").StringOrElse("")
+	credentialsFile := config.Get("nats.credentials_file").StringOrElse("")
+	tlsEnabled := config.Get("nats.tls.enabled").StringOrElse("false")
+	caFile := config.Get("nats.tls.ca_file").StringOrElse("")
+	certFile := config.Get("nats.tls.cert_file").StringOrElse("")
+	keyFile := config.Get("nats.tls.key_file").StringOrElse("")
+	serverName := config.Get("nats.tls.server_name").StringOrElse("")
+	connectTimeout := config.Get("nats.connect_timeout").DurationOrElse(nc.DefaultTimeout)
+	reconnectWait := config.Get("nats.reconnect_wait").DurationOrElse(nc.DefaultReconnectWait)
+	maxReconnects := config.Get("nats.max_reconnects").IntOrElse(nc.DefaultMaxReconnect)
+	pingInterval := config.Get("nats.ping_interval").DurationOrElse(nc.DefaultPingInterval)
+
+	logger.Info("nats.user -> ", user)
+	logger.Info("nats.nkey_seed_file -> ", nkeySeedFile)
+	logger.Info("nats.credentials_file -> ", credentialsFile)
+	logger.Info("nats.tls.enabled -> ", tlsEnabled)
+	logger.Info("nats.tls.ca_file -> ", caFile)
+	logger.Info("nats.tls.cert_file -> ", certFile)
+	logger.Info("nats.tls.key_file -> ", keyFile)
+	logger.Info("nats.tls.server_name -> ", serverName)
+	logger.Info("nats.connect_timeout -> ", connectTimeout)
+	logger.Info("nats.reconnect_wait -> ", reconnectWait)
+	logger.Info("nats.max_reconnects -> ", maxReconnects)
+	logger.Info("nats.ping_interval -> ", pingInterval)
+
+	methods := 0
+	for _, configured := range []bool{user != "" || password != "", token != "", nkeySeedFile != "",
+		credentialsFile != ""} {
+
+		if configured {
+			methods++
+		}
+	}
+
+	if methods > 1 {
+		return nil, fmt.Errorf("nats accepts one of user and password, token, nkey_seed_file or credentials_file")
+	}
+
+	options := []nc.Option{
+		nc.Timeout(connectTimeout),
+		nc.ReconnectWait(reconnectWait),
+		nc.MaxReconnects(maxReconnects),
+		nc.PingInterval(pingInterval),
+	}
+
+	switch {
+	case user != "" || password != "":
+		options = append(options, nc.UserInfo(user, password))
+	case token != "":
+		options = append(options, nc.Token(token))
+	case nkeySeedFile != "":
+		option, e := nc.NkeyOptionFromSeed(nkeySeedFile)
+		if e != nil {
+			return nil, e
+		}
+
+		options = append(options, option)
+	case credentialsFile != "":
+		if _, e := ioutil.ReadFile(credentialsFile); e != nil {
+			return nil, e
+		}
+
+		options = append(options, nc.UserCredentials(credentialsFile))
+	}
+
+	if tlsEnabled == "true" || caFile != "" || certFile != "" || keyFile != "" {
+		tlsConfig, e := newTLSConfig(caFile, certFile, keyFile, serverName)
+		if e != nil {
+			return nil, e
+		}
+
+		options = append(options, nc.Secure(tlsConfig))
+	}
+
+	return append(options, lifecycleHandlers(logger)...), nil
+}
+
+// newTLSConfig returns back the TLS configuration trusting the CAs of the PEM caFile, or the system ones when it is
+// empty, and presenting the client certificate of certFile and keyFile, if any.
+func newTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+
+	if caFile != "" {
+		in, e := ioutil.ReadFile(caFile)
+		if e != nil {
+			return nil, e
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(in) {
+			return nil, fmt.Errorf("no certificate in %v", caFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("nats.tls.cert_file and nats.tls.key_file must be set together")
+	}
+
+	if certFile != "" {
+		certificate, e := tls.LoadX509KeyPair(certFile, keyFile)
+		if e != nil {
+			return nil, e
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// lifecycleHandlers returns back the options logging the disconnections, reconnections, closure and asynchronous
+// errors of the connection.
+func lifecycleHandlers(logger *zap.SugaredLogger) []nc.Option {
+	return []nc.Option{
+		nc.DisconnectErrHandler(func(_ *nc.Conn, e error) {
+			if e != nil {
+				logger.Warn("Disconnected from nats: ", e.Error())
+				return
+			}
+
+			logger.Info("Disconnected from nats")
+		}),
+		nc.ReconnectHandler(func(c *nc.Conn) {
+			logger.Info("Reconnected to nats at ", c.ConnectedUrl())
+		}),
+		nc.ClosedHandler(func(c *nc.Conn) {
+			if e := c.LastError(); e != nil {
+				logger.Warn("Nats connection closed: ", e.Error())
+				return
+			}
+
+			logger.Info("Nats connection closed")
+		}),
+		nc.ErrorHandler(func(_ *nc.Conn, s *nc.Subscription, e error) {
+			if s != nil {
+				logger.Error("Nats error on ", s.Subject, ": ", e.Error())
+				return
+			}
+
+			logger.Error("Nats error: ", e.Error())
+		}),
+	}
+}
@@ -0,0 +1,116 @@
+package nats_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jibitters/kiosk/messaging/nats"
+	"github.com/lireza/lib/configuring"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Nats", func() {
+	var directory string
+
+	BeforeEach(func() {
+		directory, _ = ioutil.TempDir("", "nats")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(directory)
+	})
+
+	write := func(name, content string) string {
+		file := filepath.Join(directory, name)
+		Ω(ioutil.WriteFile(file, []byte(content), 0600)).Should(Succeed())
+		return file
+	}
+
+	load := func(section string) *configuring.Config {
+		config := configuring.New()
+		_, e := config.LoadJSON(write("kiosk.json", `{"nats": `+section+`}`))
+		Ω(e).Should(BeNil())
+		return config
+	}
+
+	// certificate writes a self signed certificate along with its key and returns back their files.
+	certificate := func() (string, string) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "kiosk"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+		}
+
+		der, e := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Ω(e).Should(BeNil())
+		keyDER, e := x509.MarshalECPrivateKey(key)
+		Ω(e).Should(BeNil())
+
+		return write("cert.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))),
+			write("key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	}
+
+	Context("When Options called", func() {
+		It("Should return back the options of the defaults", func() {
+			options, e := nats.Options(zap.NewNop().Sugar(), load(`{}`))
+			Ω(e).Should(BeNil())
+			Ω(options).ShouldNot(BeEmpty())
+		})
+
+		It("Should accept one authentication method only", func() {
+			_, e := nats.Options(zap.NewNop().Sugar(), load(`{"user": "kiosk", "password": "secret", "token": "t"}`))
+			Ω(e).ShouldNot(BeNil())
+
+			_, e = nats.Options(zap.NewNop().Sugar(), load(`{"user": "kiosk", "password": "secret"}`))
+			Ω(e).Should(BeNil())
+
+			_, e = nats.Options(zap.NewNop().Sugar(), load(`{"token": "t"}`))
+			Ω(e).Should(BeNil())
+		})
+
+		It("Should fail for the missing seed and credentials files", func() {
+			_, e := nats.Options(zap.NewNop().Sugar(), load(`{"nkey_seed_file": "/not/exists.nk"}`))
+			Ω(e).ShouldNot(BeNil())
+
+			_, e = nats.Options(zap.NewNop().Sugar(), load(`{"credentials_file": "/not/exists.creds"}`))
+			Ω(e).ShouldNot(BeNil())
+		})
+
+		It("Should load the CA and the client certificate", func() {
+			certFile, keyFile := certificate()
+
+			_, e := nats.Options(zap.NewNop().Sugar(), load(`{"tls": {"ca_file": "`+certFile+`", "cert_file": "`+
+				certFile+`", "key_file": "`+keyFile+`"}}`))
+			Ω(e).Should(BeNil())
+		})
+
+		It("Should fail for the invalid TLS files", func() {
+			certFile, keyFile := certificate()
+
+			_, e := nats.Options(zap.NewNop().Sugar(), load(`{"tls": {"cert_file": "`+certFile+`"}}`))
+			Ω(e).ShouldNot(BeNil())
+
+			_, e = nats.Options(zap.NewNop().Sugar(), load(`{"tls": {"ca_file": "`+keyFile+`"}}`))
+			Ω(e).ShouldNot(BeNil())
+
+			_, e = nats.Options(zap.NewNop().Sugar(), load(`{"tls": {"cert_file": "`+keyFile+`", "key_file": "`+
+				keyFile+`"}}`))
+			Ω(e).ShouldNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,26 @@
+-- Notifications table definition. It is the outbox of the outbound emails, every notification is rendered once and
+-- delivered with retries until it is sent or runs out of attempts.
+CREATE TABLE notifications
+(
+    id              BIGSERIAL    NOT NULL,
+    key             VARCHAR(255) NOT NULL,
+    ticket_id       BIGINT       NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    event           VARCHAR(50)  NOT NULL,
+    recipient       VARCHAR(255) NOT NULL,
+    locale          VARCHAR(10)  NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    text_body       TEXT         NOT NULL,
+    html_body       TEXT         NOT NULL,
+    status          VARCHAR(25)  NOT NULL,
+    attempts        INT          NOT NULL,
+    last_error      TEXT         NOT NULL,
+    next_attempt_at TIMESTAMP    NOT NULL,
+    sent_at         TIMESTAMP,
+    created_at      TIMESTAMP    NOT NULL,
+    modified_at     TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (key)
+);
+
+CREATE INDEX notifications_status_next_attempt_at ON notifications (status, next_attempt_at);
+CREATE INDEX notifications_ticket_id ON notifications (ticket_id);
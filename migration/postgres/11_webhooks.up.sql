@@ -0,0 +1,41 @@
+-- Webhooks table definition. Events is a JSON array of event types and an empty issuer subscribes to the tickets of
+-- all issuers.
+CREATE TABLE webhooks
+(
+    id                   BIGSERIAL     NOT NULL,
+    url                  VARCHAR(2048) NOT NULL,
+    issuer               VARCHAR(50)   NOT NULL,
+    events               TEXT          NOT NULL,
+    secret               VARCHAR(255)  NOT NULL,
+    enabled              BOOLEAN       NOT NULL,
+    consecutive_failures INT           NOT NULL,
+    created_at           TIMESTAMP     NOT NULL,
+    modified_at          TIMESTAMP     NOT NULL,
+    PRIMARY KEY (id)
+);
+
+-- Webhook deliveries table definition. The payload is kept as it was when the event happened, so redeliveries send
+-- exactly the same content, and the id of the outbox event is kept, so an event relayed more than once is delivered
+-- once per webhook.
+CREATE TABLE webhook_deliveries
+(
+    id              BIGSERIAL   NOT NULL,
+    webhook_id      BIGINT      NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+    event_id        VARCHAR(36) NOT NULL,
+    event           VARCHAR(50) NOT NULL,
+    ticket_id       BIGINT      NOT NULL,
+    payload         TEXT        NOT NULL,
+    status          VARCHAR(25) NOT NULL,
+    attempts        INT         NOT NULL,
+    response_status INT         NOT NULL,
+    last_error      TEXT        NOT NULL,
+    next_attempt_at TIMESTAMP   NOT NULL,
+    delivered_at    TIMESTAMP,
+    created_at      TIMESTAMP   NOT NULL,
+    modified_at     TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX webhook_deliveries_status_next_attempt_at ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE UNIQUE INDEX webhook_deliveries_webhook_id_event_id ON webhook_deliveries (webhook_id, event_id);
@@ -0,0 +1,20 @@
+-- Outbox table definition. Domain events are written in the same transaction as the changes they announce and are
+-- relayed to nats in the order of their ids. Ticket and comment are JSON snapshots of the resources at the time.
+CREATE TABLE outbox
+(
+    id              BIGSERIAL    NOT NULL,
+    event_id        VARCHAR(36)  NOT NULL,
+    type            VARCHAR(50)  NOT NULL,
+    ticket_id       BIGINT       NOT NULL,
+    actor_type      VARCHAR(25)  NOT NULL,
+    actor_id        VARCHAR(255) NOT NULL,
+    previous_status VARCHAR(25)  NOT NULL,
+    ticket          TEXT,
+    comment         TEXT,
+    created_at      TIMESTAMP    NOT NULL,
+    published_at    TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX outbox_pending ON outbox (id) WHERE published_at IS NULL;
+CREATE INDEX outbox_published_at ON outbox (published_at);
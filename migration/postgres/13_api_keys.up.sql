@@ -0,0 +1,20 @@
+-- API keys table definition. Only the SHA-256 hash of the keys is stored, they are looked up by their public prefix.
+-- Operations is a JSON array of the subjects the key is allowed to request and a NULL expires_at never expires.
+CREATE TABLE api_keys
+(
+    id           BIGSERIAL    NOT NULL,
+    issuer       VARCHAR(50)  NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL,
+    hash         VARCHAR(64)  NOT NULL,
+    operations   TEXT         NOT NULL,
+    expires_at   TIMESTAMP,
+    revoked_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at   TIMESTAMP    NOT NULL,
+    modified_at  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX api_keys_prefix ON api_keys (prefix);
+CREATE INDEX api_keys_issuer_name ON api_keys (issuer, name);
@@ -0,0 +1,12 @@
+-- Rate limit buckets table definition. Each row is the token bucket of a rate limit for a caller, shared by the kiosk
+-- nodes, holding the tokens left at updated_at and whether the last request took one.
+CREATE TABLE rate_limit_buckets
+(
+    key        TEXT             NOT NULL,
+    tokens     DOUBLE PRECISION NOT NULL,
+    allowed    BOOLEAN          NOT NULL,
+    updated_at TIMESTAMP        NOT NULL,
+    PRIMARY KEY (key)
+);
+
+CREATE INDEX rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
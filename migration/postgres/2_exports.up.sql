@@ -0,0 +1,26 @@
+-- Exports table definition. Exports keep the token of their last claim, so a node that lost the lease of a job can no
+-- longer change it.
+CREATE TABLE exports
+(
+    id               BIGSERIAL    NOT NULL,
+    format           VARCHAR(25)  NOT NULL,
+    issuer           VARCHAR(50)  NOT NULL,
+    owner            VARCHAR(50)  NOT NULL,
+    importance_level VARCHAR(25)  NOT NULL,
+    status           VARCHAR(25)  NOT NULL,
+    from_date        TIMESTAMP    NOT NULL,
+    to_date          TIMESTAMP    NOT NULL,
+    job_status       VARCHAR(25)  NOT NULL,
+    file_name        VARCHAR(255) NOT NULL,
+    exported_tickets BIGINT       NOT NULL,
+    last_ticket_id   BIGINT       NOT NULL,
+    file_offset      BIGINT       NOT NULL,
+    failure_reason   TEXT,
+    locked_until     TIMESTAMP,
+    locked_by        VARCHAR(36),
+    created_at       TIMESTAMP    NOT NULL,
+    modified_at      TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX exports_job_status_locked_until ON exports (job_status, locked_until);
@@ -0,0 +1,30 @@
+-- Imports table definition. Imports are claimed by kiosk nodes like exports are, so imports of a stopped node are
+-- resumed by the others.
+CREATE TABLE imports
+(
+    id            BIGSERIAL    NOT NULL,
+    source        VARCHAR(50)  NOT NULL,
+    format        VARCHAR(25)  NOT NULL,
+    file_name     VARCHAR(255) NOT NULL,
+    status        VARCHAR(25)  NOT NULL,
+    total_rows    BIGINT       NOT NULL,
+    imported_rows BIGINT       NOT NULL,
+    skipped_rows  BIGINT       NOT NULL,
+    rejected_rows BIGINT       NOT NULL,
+    rejections    TEXT,
+    locked_until  TIMESTAMP,
+    locked_by     VARCHAR(36),
+    created_at    TIMESTAMP    NOT NULL,
+    modified_at   TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+-- Import mappings table definition, maps identifiers of external systems to ticket identifiers.
+CREATE TABLE import_mappings
+(
+    source      VARCHAR(50)  NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    ticket_id   BIGINT       NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    created_at  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (source, external_id)
+);
@@ -0,0 +1,12 @@
+-- Canned responses table definition. Canned responses with empty issuer are global.
+CREATE TABLE canned_responses
+(
+    id          BIGSERIAL    NOT NULL,
+    issuer      VARCHAR(50)  NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    body        TEXT         NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (issuer, name)
+);
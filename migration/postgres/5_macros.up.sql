@@ -0,0 +1,12 @@
+-- Macros table definition. Macros with empty issuer are global.
+CREATE TABLE macros
+(
+    id          BIGSERIAL    NOT NULL,
+    issuer      VARCHAR(50)  NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    actions     TEXT         NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (issuer, name)
+);
@@ -0,0 +1,32 @@
+-- Rules table definition.
+CREATE TABLE rules
+(
+    id          BIGSERIAL    NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    event       VARCHAR(25)  NOT NULL,
+    conditions  TEXT         NOT NULL,
+    actions     TEXT         NOT NULL,
+    enabled     BOOLEAN      NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (name)
+);
+
+CREATE INDEX rules_event_enabled ON rules (event, enabled);
+
+-- Rule executions table definition.
+CREATE TABLE rule_executions
+(
+    id          BIGSERIAL   NOT NULL,
+    rule_id     BIGINT      NOT NULL REFERENCES rules ON DELETE CASCADE,
+    ticket_id   BIGINT      NOT NULL,
+    event       VARCHAR(25) NOT NULL,
+    status      VARCHAR(25) NOT NULL,
+    details     TEXT        NOT NULL,
+    created_at  TIMESTAMP   NOT NULL,
+    modified_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX rule_executions_rule_id_id ON rule_executions (rule_id, id);
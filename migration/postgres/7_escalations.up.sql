@@ -0,0 +1,31 @@
+-- Escalation policies table definition. Policies with empty issuer apply to tickets of all issuers.
+CREATE TABLE escalation_policies
+(
+    id          BIGSERIAL    NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    issuer      VARCHAR(50)  NOT NULL,
+    statuses    TEXT         NOT NULL,
+    steps       TEXT         NOT NULL,
+    enabled     BOOLEAN      NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (name)
+);
+
+-- Escalations table definition. Each step of a policy is taken at most once per idle period of a ticket, which starts
+-- at the last modification of the ticket.
+CREATE TABLE escalations
+(
+    id          BIGSERIAL   NOT NULL,
+    ticket_id   BIGINT      NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    policy_id   BIGINT      NOT NULL REFERENCES escalation_policies ON DELETE CASCADE,
+    step        INT         NOT NULL,
+    action      VARCHAR(25) NOT NULL,
+    details     TEXT        NOT NULL,
+    idle_since  TIMESTAMP   NOT NULL,
+    created_at  TIMESTAMP   NOT NULL,
+    modified_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (ticket_id, policy_id, step, idle_since)
+);
@@ -0,0 +1,17 @@
+-- Satisfactions table definition. Issuer and assignee are kept as they were when the ticket was rated, for reports.
+CREATE TABLE satisfactions
+(
+    id          BIGSERIAL   NOT NULL,
+    ticket_id   BIGINT      NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    issuer      VARCHAR(50) NOT NULL,
+    assignee    VARCHAR(50) NOT NULL,
+    owner       VARCHAR(50) NOT NULL,
+    rating      SMALLINT    NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment     TEXT        NOT NULL,
+    created_at  TIMESTAMP   NOT NULL,
+    modified_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (ticket_id)
+);
+
+CREATE INDEX satisfactions_issuer_created_at ON satisfactions (issuer, created_at);
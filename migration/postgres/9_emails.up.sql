@@ -0,0 +1,16 @@
+-- Emails table definition. Keeps the message identifiers of inbound emails to thread replies into their tickets and to
+-- ingest every email only once.
+CREATE TABLE emails
+(
+    id          BIGSERIAL    NOT NULL,
+    message_id  VARCHAR(255) NOT NULL,
+    ticket_id   BIGINT       NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    comment_id  BIGINT REFERENCES comments ON DELETE CASCADE,
+    sender      VARCHAR(50)  NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (message_id)
+);
+
+CREATE INDEX emails_ticket_id ON emails (ticket_id);
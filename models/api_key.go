@@ -0,0 +1,356 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// APIKeyPrefix is the prefix of the API keys, which tells them apart from the bearer JWTs.
+const APIKeyPrefix = "kiosk_"
+
+// MaxActiveAPIKeys is the number of the active keys of a name of an issuer, the old and the new one while a key is
+// rotated.
+const MaxActiveAPIKeys = 2
+
+// APIKey is the entity model of api_keys table. Only the hash of the key is stored, so it is shown once when created.
+type APIKey struct {
+	Model
+
+	Issuer     string
+	Name       string
+	Prefix     string
+	Hash       string
+	Operations []string
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// Allows reports whether the key is allowed to request the subject.
+func (k *APIKey) Allows(subject string) bool {
+	for _, o := range k.Operations {
+		if o == subject {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HashAPIKey returns back the hex encoded SHA-256 hash of the key. Keys have enough entropy, so they need no salt.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// newAPIKey returns back a new random key along with its prefix.
+func newAPIKey() (string, string, error) {
+	prefix := make([]byte, 8)
+	if _, e := rand.Read(prefix); e != nil {
+		return "", "", e
+	}
+
+	secret := make([]byte, 32)
+	if _, e := rand.Read(secret); e != nil {
+		return "", "", e
+	}
+
+	p := hex.EncodeToString(prefix)
+	return APIKeyPrefix + p + "_" + base64.RawURLEncoding.EncodeToString(secret), p, nil
+}
+
+// APIKeyRepository is the repository implementation of APIKey model.
+type APIKeyRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewAPIKeyRepository returns back a newly created and ready to use APIKeyRepository.
+func NewAPIKeyRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a new key into api_keys table and returns back the inserted record along with the key.
+func (r *APIKeyRepository) Insert(ctx context.Context, apiKey APIKey) (*APIKey, string, *errors.Type) {
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, "", et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	active, et := r.lockActive(ctx, tx, apiKey.Issuer, apiKey.Name)
+	if et != nil {
+		return nil, "", et
+	}
+
+	if active > 0 {
+		return nil, "", errors.AlreadyExists("api_key.already_exists", "")
+	}
+
+	return r.insert(ctx, tx, apiKey)
+}
+
+// Rotate tries to replace an active key with a new one and returns back the new record along with the key. The
+// replaced key keeps working for grace, so the issuer can roll the new one out.
+func (r *APIKeyRepository) Rotate(ctx context.Context, id int64, expiresAt time.Time, grace time.Duration) (*APIKey,
+	string, *errors.Type) {
+
+	expireQ := `UPDATE api_keys SET expires_at = LEAST(expires_at, NOW() + $1 * INTERVAL '1 millisecond'),
+					modified_at = NOW() WHERE id = $2;`
+
+	old, et := r.LoadByID(ctx, id)
+	if et != nil {
+		return nil, "", et
+	}
+
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, "", et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	active, et := r.lockActive(ctx, tx, old.Issuer, old.Name)
+	if et != nil {
+		return nil, "", et
+	}
+
+	// The record is loaded again after the lock, so concurrent rotations and revocations are taken into account.
+	old, e = r.scan(tx.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1 AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > NOW());`, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, "", errors.PreconditionFailed("api_key.not_active", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, "", et
+	}
+
+	if active >= MaxActiveAPIKeys {
+		return nil, "", errors.PreconditionFailed("api_key.rotation_in_progress", "")
+	}
+
+	if _, e := tx.Exec(ctx, expireQ, grace.Milliseconds(), id); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, "", et
+	}
+
+	old.ExpiresAt = expiresAt
+	return r.insert(ctx, tx, *old)
+}
+
+// Revoke tries to revoke a key, which is rejected from then on.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64) *errors.Type {
+	q := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()), modified_at = NOW() WHERE id = $1;`
+
+	command, e := r.db.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("api_key.not_found", "")
+	}
+
+	return nil
+}
+
+// LoadByID tries to load a key from api_keys table.
+func (r *APIKeyRepository) LoadByID(ctx context.Context, id int64) (*APIKey, *errors.Type) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1;`
+
+	apiKey, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("api_key.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return apiKey, nil
+}
+
+// Filter tries to load the keys of the issuer, or of all issuers when it is empty, in the order of their creation. If
+// there is another page of result, the second returned value will be true, otherwise false.
+func (r *APIKeyRepository) Filter(ctx context.Context, issuer string, pageNumber, pageSize int) ([]*APIKey, bool,
+	*errors.Type) {
+
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE $1 = '' OR issuer = $1 ORDER BY id OFFSET $2 LIMIT $3;`
+
+	rows, e := r.db.Query(ctx, q, issuer, (pageNumber-1)*pageSize, pageSize+1)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, false, et
+	}
+	defer rows.Close()
+
+	apiKeys := make([]*APIKey, 0)
+	for rows.Next() {
+		apiKey, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, false, et
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	hasNextPage := len(apiKeys) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		apiKeys = apiKeys[:len(apiKeys)-1]
+	}
+
+	return apiKeys, hasNextPage, nil
+}
+
+// Authenticate tries to find the active key matching the provided one and records its use. Keys that are unknown,
+// revoked or expired are unauthorized. Uses are recorded at most once a minute, to spare the writes of busy keys.
+func (r *APIKeyRepository) Authenticate(ctx context.Context, key string) (*APIKey, *errors.Type) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW());`
+
+	usedQ := `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1
+				AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');`
+
+	parts := strings.SplitN(strings.TrimPrefix(key, APIKeyPrefix), "_", 2)
+	if !strings.HasPrefix(key, APIKeyPrefix) || len(parts) != 2 {
+		return nil, errors.Unauthorized("")
+	}
+
+	apiKey, e := r.scan(r.db.QueryRow(ctx, q, parts[0]))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.Unauthorized("")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(apiKey.Hash)) != 1 {
+		return nil, errors.Unauthorized("")
+	}
+
+	if _, e := r.db.Exec(ctx, usedQ, apiKey.ID); e != nil {
+		r.logger.Warn("Could not record the use of api key ", apiKey.ID, ": ", e.Error())
+	}
+
+	return apiKey, nil
+}
+
+const apiKeyColumns = `id, issuer, name, prefix, hash, operations, expires_at, revoked_at, last_used_at, created_at,
+	modified_at`
+
+// lockActive serializes the changes of the keys of a name of an issuer until the end of the transaction and returns
+// back the number of its active keys.
+func (r *APIKeyRepository) lockActive(ctx context.Context, tx pgx.Tx, issuer, name string) (int, *errors.Type) {
+	lockQ := `SELECT pg_advisory_xact_lock(hashtext('api_keys:' || $1 || ':' || $2));`
+
+	countQ := `SELECT COUNT(*) FROM api_keys WHERE issuer = $1 AND name = $2 AND revoked_at IS NULL
+				AND (expires_at IS NULL OR expires_at > NOW());`
+
+	if _, e := tx.Exec(ctx, lockQ, issuer, name); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	var active int
+	if e := tx.QueryRow(ctx, countQ, issuer, name).Scan(&active); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return active, nil
+}
+
+// insert inserts a new key of the issuer, name, operations and expiry of the provided one and commits the transaction.
+func (r *APIKeyRepository) insert(ctx context.Context, tx pgx.Tx, apiKey APIKey) (*APIKey, string, *errors.Type) {
+	q := `INSERT INTO api_keys (issuer, name, prefix, hash, operations, expires_at, created_at, modified_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW()) RETURNING ` + apiKeyColumns + `;`
+
+	key, prefix, e := newAPIKey()
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, "", et
+	}
+
+	expiresAt := sql.NullTime{Time: apiKey.ExpiresAt.UTC(), Valid: !apiKey.ExpiresAt.IsZero()}
+	operations, _ := json.Marshal(apiKey.Operations)
+
+	inserted, e := r.scan(tx.QueryRow(ctx, q, apiKey.Issuer, apiKey.Name, prefix, HashAPIKey(key), string(operations),
+		expiresAt))
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, "", et
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, "", et
+	}
+
+	return inserted, key, nil
+}
+
+func (r *APIKeyRepository) scan(row pgx.Row) (*APIKey, error) {
+	apiKey := &APIKey{}
+	var operations string
+	var expiresAt, revokedAt, lastUsedAt sql.NullTime
+
+	e := row.Scan(&apiKey.ID, &apiKey.Issuer, &apiKey.Name, &apiKey.Prefix, &apiKey.Hash, &operations, &expiresAt,
+		&revokedAt, &lastUsedAt, &apiKey.CreatedAt, &apiKey.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if e := json.Unmarshal([]byte(operations), &apiKey.Operations); e != nil {
+		return nil, e
+	}
+
+	if expiresAt.Valid {
+		apiKey.ExpiresAt = expiresAt.Time
+	}
+
+	if revokedAt.Valid {
+		apiKey.RevokedAt = revokedAt.Time
+	}
+
+	if lastUsedAt.Valid {
+		apiKey.LastUsedAt = lastUsedAt.Time
+	}
+
+	return apiKey, nil
+}
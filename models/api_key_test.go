@@ -0,0 +1,200 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("APIKey", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.APIKeyRepository
+
+	apiKey := models.APIKey{
+		Issuer:     "Microservice-A",
+		Name:       "orders",
+		Operations: []string{"kiosk.tickets.create", "kiosk.tickets.load"},
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewAPIKeyRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Context("When Insert called", func() {
+		It("Should store the hash of the key only", func() {
+			k, key, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+			Ω(key).Should(HavePrefix(models.APIKeyPrefix + k.Prefix + "_"))
+			Ω(k.Hash).Should(Equal(models.HashAPIKey(key)))
+			Ω(k.Operations).Should(Equal(apiKey.Operations))
+			Ω(k.ExpiresAt.IsZero()).Should(BeTrue())
+		})
+
+		It("Should not insert another active key of the name", func() {
+			_, _, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+
+			_, _, e = repository.Insert(context.Background(), apiKey)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("api_key.already_exists"))
+		})
+	})
+
+	Context("When Authenticate called", func() {
+		It("Should authenticate the key and record its use", func() {
+			k, key, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+
+			authenticated, e := repository.Authenticate(context.Background(), key)
+			Ω(e).Should(BeNil())
+			Ω(authenticated.ID).Should(Equal(k.ID))
+			Ω(authenticated.Issuer).Should(Equal(apiKey.Issuer))
+
+			loaded, e := repository.LoadByID(context.Background(), k.ID)
+			Ω(e).Should(BeNil())
+			Ω(loaded.LastUsedAt.IsZero()).Should(BeFalse())
+		})
+
+		It("Should reject the unknown, tampered, revoked and expired keys", func() {
+			k, key, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+
+			for _, invalid := range []string{"", "kiosk_", "kiosk_0123456789abcdef_secret", key + "x",
+				strings.TrimPrefix(key, models.APIKeyPrefix)} {
+
+				_, e := repository.Authenticate(context.Background(), invalid)
+				Ω(e).ShouldNot(BeNil(), invalid)
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusUnauthorized))
+			}
+
+			Ω(repository.Revoke(context.Background(), k.ID)).Should(BeNil())
+			_, e = repository.Authenticate(context.Background(), key)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusUnauthorized))
+
+			expiring := apiKey
+			expiring.Name = "expiring"
+			expiring.ExpiresAt = time.Now().Add(time.Hour)
+			k, key, e = repository.Insert(context.Background(), expiring)
+			Ω(e).Should(BeNil())
+
+			_, err := db.Exec(context.Background(), `UPDATE api_keys SET expires_at = NOW() - INTERVAL '1 second'
+				WHERE id = $1;`, k.ID)
+			Ω(err).Should(BeNil())
+
+			_, e = repository.Authenticate(context.Background(), key)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("When Rotate called", func() {
+		It("Should keep both keys active during the grace", func() {
+			k, oldKey, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+
+			rotated, newKey, e := repository.Rotate(context.Background(), k.ID, time.Time{}, time.Hour)
+			Ω(e).Should(BeNil())
+			Ω(rotated.ID).ShouldNot(Equal(k.ID))
+			Ω(rotated.Name).Should(Equal(apiKey.Name))
+			Ω(rotated.Operations).Should(Equal(apiKey.Operations))
+			Ω(newKey).ShouldNot(Equal(oldKey))
+
+			_, e = repository.Authenticate(context.Background(), oldKey)
+			Ω(e).Should(BeNil())
+			_, e = repository.Authenticate(context.Background(), newKey)
+			Ω(e).Should(BeNil())
+
+			old, e := repository.LoadByID(context.Background(), k.ID)
+			Ω(e).Should(BeNil())
+			Ω(old.ExpiresAt.IsZero()).Should(BeFalse())
+		})
+
+		It("Should not rotate while a previous rotation is in grace", func() {
+			k, _, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+
+			rotated, _, e := repository.Rotate(context.Background(), k.ID, time.Time{}, time.Hour)
+			Ω(e).Should(BeNil())
+
+			_, _, e = repository.Rotate(context.Background(), rotated.ID, time.Time{}, time.Hour)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("api_key.rotation_in_progress"))
+
+			_, _, e = repository.Rotate(context.Background(), k.ID, time.Time{}, time.Hour)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("api_key.rotation_in_progress"))
+		})
+
+		It("Should not rotate the revoked keys", func() {
+			k, _, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+			Ω(repository.Revoke(context.Background(), k.ID)).Should(BeNil())
+
+			_, _, e = repository.Rotate(context.Background(), k.ID, time.Time{}, time.Hour)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("api_key.not_active"))
+		})
+	})
+
+	Context("When Filter called", func() {
+		It("Should load the keys of the issuer", func() {
+			_, _, e := repository.Insert(context.Background(), apiKey)
+			Ω(e).Should(BeNil())
+
+			another := apiKey
+			another.Issuer = "Microservice-B"
+			_, _, e = repository.Insert(context.Background(), another)
+			Ω(e).Should(BeNil())
+
+			apiKeys, hasNextPage, e := repository.Filter(context.Background(), "Microservice-B", 1, 10)
+			Ω(e).Should(BeNil())
+			Ω(hasNextPage).Should(BeFalse())
+			Ω(apiKeys).Should(HaveLen(1))
+			Ω(apiKeys[0].Issuer).Should(Equal("Microservice-B"))
+
+			apiKeys, hasNextPage, e = repository.Filter(context.Background(), "", 1, 1)
+			Ω(e).Should(BeNil())
+			Ω(hasNextPage).Should(BeTrue())
+			Ω(apiKeys).Should(HaveLen(1))
+		})
+	})
+
+	Context("When Revoke called with an unknown id", func() {
+		It("Should return not found", func() {
+			e := repository.Revoke(context.Background(), 1)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("api_key.not_found"))
+		})
+	})
+})
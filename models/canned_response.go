@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// CannedResponse is the entity model of canned_responses table. The body is a text/template template rendered against
+// a ticket, and a canned response without issuer applies to the tickets of all issuers.
+type CannedResponse struct {
+	Model
+
+	Issuer string
+	Name   string
+	Body   string
+}
+
+// CannedResponseRepository is the repository implementation of CannedResponse model.
+type CannedResponseRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewCannedResponseRepository returns back a newly created and ready to use CannedResponseRepository.
+func NewCannedResponseRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *CannedResponseRepository {
+	return &CannedResponseRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a canned response into canned_responses table.
+func (r *CannedResponseRepository) Insert(ctx context.Context, cannedResponse CannedResponse) *errors.Type {
+	q := `INSERT INTO canned_responses (issuer, name, body, created_at, modified_at) VALUES ($1, $2, $3, NOW(), NOW());`
+
+	_, e := r.db.Exec(ctx, q, cannedResponse.Issuer, cannedResponse.Name, cannedResponse.Body)
+	if e != nil {
+		if strings.Contains(e.Error(), "canned_responses_issuer_name_key") {
+			return errors.AlreadyExists("canned_response.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// LoadByID tries to load a canned response from canned_responses table.
+func (r *CannedResponseRepository) LoadByID(ctx context.Context, id int64) (*CannedResponse, *errors.Type) {
+	q := `SELECT id, issuer, name, body, created_at, modified_at FROM canned_responses WHERE id = $1;`
+
+	cannedResponse := &CannedResponse{}
+
+	row := r.db.QueryRow(ctx, q, id)
+	e := row.Scan(&cannedResponse.ID, &cannedResponse.Issuer, &cannedResponse.Name, &cannedResponse.Body,
+		&cannedResponse.CreatedAt, &cannedResponse.ModifiedAt)
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("canned_response.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return cannedResponse, nil
+}
+
+// Update tries to update a canned response record.
+func (r *CannedResponseRepository) Update(ctx context.Context, cannedResponse *CannedResponse) *errors.Type {
+	q := `UPDATE canned_responses SET name = $1, body = $2, modified_at = NOW() WHERE id = $3;`
+
+	command, e := r.db.Exec(ctx, q, cannedResponse.Name, cannedResponse.Body, cannedResponse.ID)
+	if e != nil {
+		if strings.Contains(e.Error(), "canned_responses_issuer_name_key") {
+			return errors.AlreadyExists("canned_response.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("canned_response.not_found", "")
+	}
+
+	return nil
+}
+
+// DeleteByID tries to delete a canned response from canned_responses table.
+func (r *CannedResponseRepository) DeleteByID(ctx context.Context, id int64) *errors.Type {
+	q := `DELETE FROM canned_responses WHERE id = $1;`
+
+	_, e := r.db.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// Filter tries to load canned responses of an issuer, including the global ones. If there is another page of result,
+// the second returned value will be true, otherwise false.
+func (r *CannedResponseRepository) Filter(ctx context.Context, issuer string, pageNumber,
+	pageSize int) ([]*CannedResponse, bool, *errors.Type) {
+
+	q := `SELECT id, issuer, name, body, created_at, modified_at FROM canned_responses WHERE issuer = '' OR issuer = $1
+			ORDER BY name, issuer OFFSET $2 LIMIT $3;`
+
+	rows, e := r.db.Query(ctx, q, issuer, (pageNumber-1)*pageSize, pageSize+1)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, false, et
+	}
+	defer rows.Close()
+
+	cannedResponses := make([]*CannedResponse, 0)
+	for rows.Next() {
+		cannedResponse := &CannedResponse{}
+
+		e := rows.Scan(&cannedResponse.ID, &cannedResponse.Issuer, &cannedResponse.Name, &cannedResponse.Body,
+			&cannedResponse.CreatedAt, &cannedResponse.ModifiedAt)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, false, et
+		}
+
+		cannedResponses = append(cannedResponses, cannedResponse)
+	}
+
+	hasNextPage := len(cannedResponses) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		cannedResponses = cannedResponses[:len(cannedResponses)-1]
+	}
+
+	return cannedResponses, hasNextPage, nil
+}
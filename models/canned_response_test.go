@@ -0,0 +1,140 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("CannedResponse", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.CannedResponseRepository
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewCannedResponseRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Describe("CannedResponseRepository", func() {
+		Context("When Insert called", func() {
+			It("Should insert a canned response record in canned_responses table successfully", func() {
+				cannedResponse := models.CannedResponse{
+					Issuer: "Microservice-A",
+					Name:   "greeting",
+					Body:   "Dear {{.Owner}}, we received your ticket about {{.Subject}}.",
+				}
+
+				e := repository.Insert(context.Background(), cannedResponse)
+				Ω(e).Should(BeNil())
+
+				c, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(c.Issuer).Should(Equal(cannedResponse.Issuer))
+				Ω(c.Name).Should(Equal(cannedResponse.Name))
+				Ω(c.Body).Should(Equal(cannedResponse.Body))
+			})
+
+			It("Should return error when the name is already used by the issuer", func() {
+				cannedResponse := models.CannedResponse{Issuer: "Microservice-A", Name: "greeting", Body: "Hello!"}
+
+				e := repository.Insert(context.Background(), cannedResponse)
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), cannedResponse)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+				Ω(e.Errors[0].Code).Should(Equal("canned_response.already_exists"))
+			})
+		})
+
+		Context("When Update called", func() {
+			It("Should update the name and body of a canned response", func() {
+				e := repository.Insert(context.Background(), models.CannedResponse{Name: "greeting", Body: "Hello!"})
+				Ω(e).Should(BeNil())
+
+				e = repository.Update(context.Background(), &models.CannedResponse{Model: models.Model{ID: 1},
+					Name: "welcome", Body: "Welcome {{.Owner}}!"})
+				Ω(e).Should(BeNil())
+
+				c, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(c.Name).Should(Equal("welcome"))
+				Ω(c.Body).Should(Equal("Welcome {{.Owner}}!"))
+			})
+
+			It("Should return not found error when canned response does not exist", func() {
+				e := repository.Update(context.Background(), &models.CannedResponse{Model: models.Model{ID: 1},
+					Name: "welcome", Body: "Welcome!"})
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When DeleteByID called", func() {
+			It("Should delete a canned response", func() {
+				e := repository.Insert(context.Background(), models.CannedResponse{Name: "greeting", Body: "Hello!"})
+				Ω(e).Should(BeNil())
+
+				e = repository.DeleteByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+
+				_, e = repository.LoadByID(context.Background(), 1)
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When Filter called", func() {
+			It("Should load global canned responses along with the ones of the issuer", func() {
+				e := repository.Insert(context.Background(), models.CannedResponse{Name: "greeting", Body: "Hello!"})
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), models.CannedResponse{Issuer: "Microservice-A",
+					Name: "refund", Body: "Your refund is on the way."})
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), models.CannedResponse{Issuer: "Microservice-B",
+					Name: "shipping", Body: "Your order is shipped."})
+				Ω(e).Should(BeNil())
+
+				cs, hasNextPage, e := repository.Filter(context.Background(), "Microservice-A", 1, 10)
+				Ω(e).Should(BeNil())
+				Ω(hasNextPage).Should(BeFalse())
+				Ω(len(cs)).Should(Equal(2))
+				Ω(cs[0].Name).Should(Equal("greeting"))
+				Ω(cs[1].Name).Should(Equal("refund"))
+
+				cs, hasNextPage, e = repository.Filter(context.Background(), "", 1, 10)
+				Ω(e).Should(BeNil())
+				Ω(hasNextPage).Should(BeFalse())
+				Ω(len(cs)).Should(Equal(1))
+			})
+		})
+	})
+})
@@ -32,23 +32,43 @@ func NewCommentRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *CommentR
 	return &CommentRepository{logger: logger, db: db}
 }
 
-// Insert tries to insert a comment into comments table.
-func (r *CommentRepository) Insert(ctx context.Context, comment Comment) *errors.Type {
+// Insert tries to insert a comment into comments table and returns back the id of the inserted record. The creation
+// is announced through the outbox by the owner.
+func (r *CommentRepository) Insert(ctx context.Context, comment Comment) (int64, *errors.Type) {
 	q := `INSERT INTO comments (ticket_id, owner, content, metadata, created_at, modified_at) VALUES
-			($1, $2, $3, $4, NOW(), NOW());`
+			($1, $2, $3, $4, NOW(), NOW()) RETURNING id, created_at, modified_at;`
 
-	_, e := r.db.Exec(ctx, q, comment.TicketID, comment.Owner, comment.Content, comment.Metadata)
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	e = tx.QueryRow(ctx, q, comment.TicketID, comment.Owner, comment.Content, comment.Metadata).Scan(&comment.ID,
+		&comment.CreatedAt, &comment.ModifiedAt)
 	if e != nil {
 		if strings.Contains(e.Error(), "comments_ticket_id_fkey") {
-			return errors.PreconditionFailed("ticket.not_exists", "")
+			return 0, errors.PreconditionFailed("ticket.not_exists", "")
 		}
 
 		et := errors.InternalServerError("unknown", "")
 		r.logger.Error(et.FingerPrint, ": ", e.Error())
-		return et
+		return 0, et
 	}
 
-	return nil
+	if e := r.announce(ctx, tx, OutboxMessageTypeCommentCreated, &comment, UserActor(comment.Owner)); e != nil {
+		return 0, e
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return comment.ID, nil
 }
 
 // LoadByID tries to load a comment from comments table.
@@ -78,11 +98,19 @@ func (r *CommentRepository) LoadByID(ctx context.Context, id int64) (*Comment, *
 	return comment, nil
 }
 
-// Update tries to update a comment record.
-func (r *CommentRepository) Update(ctx context.Context, comment *Comment) *errors.Type {
+// Update tries to update a comment record. The update is announced through the outbox by the actor.
+func (r *CommentRepository) Update(ctx context.Context, comment *Comment, actor Actor) *errors.Type {
 	q := `UPDATE comments SET metadata = $1, modified_at = NOW() WHERE id = $2;`
 
-	command, e := r.db.Exec(ctx, q, comment.Metadata, comment.ID)
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	command, e := tx.Exec(ctx, q, comment.Metadata, comment.ID)
 	if e != nil {
 		et := errors.InternalServerError("unknown", "")
 		r.logger.Error(et.FingerPrint, ": ", e.Error())
@@ -94,14 +122,77 @@ func (r *CommentRepository) Update(ctx context.Context, comment *Comment) *error
 		return et
 	}
 
+	snapshot, e := loadCommentSnapshot(ctx, tx, comment.ID)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if e := r.announce(ctx, tx, OutboxMessageTypeCommentUpdated, snapshot, actor); e != nil {
+		return e
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
 	return nil
 }
 
-// DeleteByID tries to delete a comment from comments table.
-func (r *CommentRepository) DeleteByID(ctx context.Context, id int64) *errors.Type {
+// DeleteByID tries to delete a comment from comments table. The deletion is announced through the outbox by the actor,
+// with the comment as it was right before it.
+func (r *CommentRepository) DeleteByID(ctx context.Context, id int64, actor Actor) *errors.Type {
 	q := `DELETE FROM comments WHERE id=$1;`
 
-	_, e := r.db.Exec(ctx, q, id)
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	snapshot, e := loadCommentSnapshot(ctx, tx, id)
+	if e != nil && e != pgx.ErrNoRows {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	command, e := tx.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() > 0 && snapshot != nil {
+		if e := r.announce(ctx, tx, OutboxMessageTypeCommentDeleted, snapshot, actor); e != nil {
+			return e
+		}
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// announce writes the message of the comment change into the outbox, along with the ticket of the comment.
+func (r *CommentRepository) announce(ctx context.Context, tx pgx.Tx, messageType OutboxMessageType,
+	comment *Comment, actor Actor) *errors.Type {
+
+	ticket, e := loadTicketSnapshot(ctx, tx, comment.TicketID)
+	if e == nil {
+		e = writeOutbox(ctx, tx, &OutboxMessage{Type: messageType, TicketID: comment.TicketID, Actor: actor,
+			Ticket: ticket, Comment: comment})
+	}
 	if e != nil {
 		et := errors.InternalServerError("unknown", "")
 		r.logger.Error(et.FingerPrint, ": ", e.Error())
@@ -56,7 +56,7 @@ var _ = Describe("Comment", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := ticketRepository.Insert(context.Background(), ticket)
+				_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				comment := models.Comment{
@@ -66,7 +66,7 @@ var _ = Describe("Comment", func() {
 					Metadata: `{"ip":"192.168.1.1"}`,
 				}
 
-				e = repository.Insert(context.Background(), comment)
+				_, e = repository.Insert(context.Background(), comment)
 				Ω(e).Should(BeNil())
 			})
 
@@ -78,7 +78,7 @@ var _ = Describe("Comment", func() {
 					Metadata: `{"ip":"192.168.1.1"}`,
 				}
 
-				e := repository.Insert(context.Background(), comment)
+				_, e := repository.Insert(context.Background(), comment)
 				Ω(e).ShouldNot(BeNil())
 				Ω(e.FingerPrint).ShouldNot(BeEmpty())
 				Ω(e.Errors[0].Code).Should(Equal("ticket.not_exists"))
@@ -98,7 +98,7 @@ var _ = Describe("Comment", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := ticketRepository.Insert(context.Background(), ticket)
+				_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				comment := models.Comment{
@@ -108,7 +108,7 @@ var _ = Describe("Comment", func() {
 					Metadata: `{"ip":"192.168.1.11"}`,
 				}
 
-				e = repository.Insert(context.Background(), comment)
+				_, e = repository.Insert(context.Background(), comment)
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
@@ -143,7 +143,7 @@ var _ = Describe("Comment", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := ticketRepository.Insert(context.Background(), ticket)
+				_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				comment := models.Comment{
@@ -153,7 +153,7 @@ var _ = Describe("Comment", func() {
 					Metadata: `{"ip":"192.168.1.1"}`,
 				}
 
-				e = repository.Insert(context.Background(), comment)
+				_, e = repository.Insert(context.Background(), comment)
 				Ω(e).Should(BeNil())
 
 				c, e := repository.LoadByID(context.Background(), 1)
@@ -161,7 +161,7 @@ var _ = Describe("Comment", func() {
 
 				c.Metadata = `{"ip":"192.168.1.10"}`
 
-				e = repository.Update(context.Background(), c)
+				e = repository.Update(context.Background(), c, models.UserActor("admin@example.com"))
 				Ω(e).Should(BeNil())
 				Ω(c.Metadata).Should(Equal(`{"ip":"192.168.1.10"}`))
 			})
@@ -172,7 +172,7 @@ var _ = Describe("Comment", func() {
 					Metadata: `{"ip":"192.168.1.1"}`,
 				}
 
-				e := repository.Update(context.Background(), &comment)
+				e := repository.Update(context.Background(), &comment, models.UserActor(""))
 				Ω(e).ShouldNot(BeNil())
 				Ω(e.FingerPrint).ShouldNot(BeEmpty())
 				Ω(e.Errors[0].Code).Should(Equal("comment.not_found"))
@@ -192,7 +192,7 @@ var _ = Describe("Comment", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := ticketRepository.Insert(context.Background(), ticket)
+				_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				comment := models.Comment{
@@ -202,10 +202,10 @@ var _ = Describe("Comment", func() {
 					Metadata: `{"ip":"192.168.1.11"}`,
 				}
 
-				e = repository.Insert(context.Background(), comment)
+				_, e = repository.Insert(context.Background(), comment)
 				Ω(e).Should(BeNil())
 
-				e = repository.DeleteByID(context.Background(), 1)
+				e = repository.DeleteByID(context.Background(), 1, models.UserActor("admin@example.com"))
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Email is the entity model of emails table. An email either created a ticket, when CommentID is zero, or a comment on
+// the ticket.
+type Email struct {
+	Model
+
+	MessageID string
+	TicketID  int64
+	CommentID int64
+	Sender    string
+}
+
+// EmailRepository is the repository implementation of Email model.
+type EmailRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewEmailRepository returns back a newly created and ready to use EmailRepository.
+func NewEmailRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *EmailRepository {
+	return &EmailRepository{logger: logger, db: db}
+}
+
+// LoadTicketIDByMessageIDs tries to find the ticket that the most recent email with one of the message identifiers
+// belongs to.
+func (r *EmailRepository) LoadTicketIDByMessageIDs(ctx context.Context, messageIDs []string) (int64, *errors.Type) {
+	q := `SELECT ticket_id FROM emails WHERE message_id = ANY($1) ORDER BY id DESC LIMIT 1;`
+
+	var ticketID int64
+	if e := r.db.QueryRow(ctx, q, messageIDs).Scan(&ticketID); e != nil {
+		if e == pgx.ErrNoRows {
+			return 0, errors.NotFound("email.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return ticketID, nil
+}
+
+// Ingest tries to record an email along with the ticket it creates, or the comment it adds, in a single transaction.
+// An email already recorded by its message identifier is not ingested again and false is returned.
+func (r *EmailRepository) Ingest(ctx context.Context, email *Email, ticket *Ticket, comment *Comment) (bool,
+	*errors.Type) {
+
+	ticketQ := `INSERT INTO tickets (issuer, owner, subject, content, metadata, importance_level, status, created_at,
+					modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW()) RETURNING id;`
+
+	commentQ := `INSERT INTO comments (ticket_id, owner, content, metadata, created_at, modified_at) VALUES
+					($1, $2, $3, $4, NOW(), NOW()) RETURNING id;`
+
+	emailQ := `INSERT INTO emails (message_id, ticket_id, comment_id, sender, created_at, modified_at) VALUES
+				($1, $2, NULLIF($3::BIGINT, 0), $4, NOW(), NOW()) ON CONFLICT DO NOTHING RETURNING id;`
+
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if comment == nil {
+		e = tx.QueryRow(ctx, ticketQ, ticket.Issuer, ticket.Owner, ticket.Subject, ticket.Content, ticket.Metadata,
+			ticket.ImportanceLevel, TicketStatusNew).Scan(&ticket.ID)
+		email.TicketID = ticket.ID
+	} else {
+		comment.TicketID = email.TicketID
+		e = tx.QueryRow(ctx, commentQ, comment.TicketID, comment.Owner, comment.Content, comment.Metadata).
+			Scan(&comment.ID)
+		email.CommentID = comment.ID
+	}
+	if e != nil {
+		if strings.Contains(e.Error(), "comments_ticket_id_fkey") {
+			return false, errors.PreconditionFailed("ticket.not_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	e = tx.QueryRow(ctx, emailQ, email.MessageID, email.TicketID, email.CommentID, email.Sender).Scan(&email.ID)
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return false, nil
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	if e := r.announce(ctx, tx, email, comment); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	return true, nil
+}
+
+// announce writes the creation of the ticket, or of the comment when it is not nil, into the outbox by the sender.
+func (r *EmailRepository) announce(ctx context.Context, tx pgx.Tx, email *Email, comment *Comment) error {
+	ticket, e := loadTicketSnapshot(ctx, tx, email.TicketID)
+	if e != nil {
+		return e
+	}
+
+	message := &OutboxMessage{Type: OutboxMessageTypeTicketCreated, TicketID: ticket.ID,
+		Actor: UserActor(email.Sender), Ticket: ticket}
+	if comment != nil {
+		if message.Comment, e = loadCommentSnapshot(ctx, tx, comment.ID); e != nil {
+			return e
+		}
+		message.Type = OutboxMessageTypeCommentCreated
+	}
+
+	return writeOutbox(ctx, tx, message)
+}
@@ -0,0 +1,120 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Email", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.EmailRepository
+	var ticketRepository *models.TicketRepository
+
+	ticket := func() *models.Ticket {
+		return &models.Ticket{
+			Issuer:          "email",
+			Owner:           "user@example.com",
+			Subject:         "Technical Problem",
+			Content:         "Hello, i have some issues with REST API Docs!",
+			Metadata:        `{"sender":"user@example.com","messageID":"<1@example.com>"}`,
+			ImportanceLevel: models.TicketImportanceLevelMedium,
+		}
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewEmailRepository(zap.S(), db)
+			ticketRepository = models.NewTicketRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Context("When Ingest called", func() {
+		It("Should create a ticket and thread the replies into it", func() {
+			email := &models.Email{MessageID: "<1@example.com>", Sender: "user@example.com"}
+			ingested, e := repository.Ingest(context.Background(), email, ticket(), nil)
+			Ω(e).Should(BeNil())
+			Ω(ingested).Should(BeTrue())
+			Ω(email.TicketID).Should(Equal(int64(1)))
+
+			ticketID, e := repository.LoadTicketIDByMessageIDs(context.Background(),
+				[]string{"<0@example.com>", "<1@example.com>"})
+			Ω(e).Should(BeNil())
+			Ω(ticketID).Should(Equal(int64(1)))
+
+			reply := &models.Email{MessageID: "<2@example.com>", TicketID: ticketID, Sender: "user@example.com"}
+			comment := &models.Comment{Owner: "user@example.com", Content: "Any news?"}
+			ingested, e = repository.Ingest(context.Background(), reply, nil, comment)
+			Ω(e).Should(BeNil())
+			Ω(ingested).Should(BeTrue())
+			Ω(reply.CommentID).Should(Equal(comment.ID))
+
+			ticketID, e = repository.LoadTicketIDByMessageIDs(context.Background(), []string{"<2@example.com>"})
+			Ω(e).Should(BeNil())
+			Ω(ticketID).Should(Equal(int64(1)))
+
+			t, e := ticketRepository.LoadByID(context.Background(), 1)
+			Ω(e).Should(BeNil())
+			Ω(len(t.Comments)).Should(Equal(1))
+			Ω(t.Comments[0].Content).Should(Equal("Any news?"))
+		})
+
+		It("Should ingest every message only once", func() {
+			ingested, e := repository.Ingest(context.Background(),
+				&models.Email{MessageID: "<1@example.com>", Sender: "user@example.com"}, ticket(), nil)
+			Ω(e).Should(BeNil())
+			Ω(ingested).Should(BeTrue())
+
+			ingested, e = repository.Ingest(context.Background(),
+				&models.Email{MessageID: "<1@example.com>", Sender: "user@example.com"}, ticket(), nil)
+			Ω(e).Should(BeNil())
+			Ω(ingested).Should(BeFalse())
+
+			_, e = ticketRepository.LoadByID(context.Background(), 2)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+		})
+
+		It("Should return error when the replied ticket does not exist", func() {
+			reply := &models.Email{MessageID: "<2@example.com>", TicketID: 1, Sender: "user@example.com"}
+			_, e := repository.Ingest(context.Background(), reply, nil,
+				&models.Comment{Owner: "user@example.com", Content: "Any news?"})
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("ticket.not_exists"))
+		})
+	})
+
+	Context("When LoadTicketIDByMessageIDs called", func() {
+		It("Should return not found error for unknown messages", func() {
+			_, e := repository.LoadTicketIDByMessageIDs(context.Background(), []string{"<0@example.com>"})
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+		})
+	})
+})
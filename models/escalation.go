@@ -0,0 +1,433 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// EscalationPolicy is the entity model of escalation_policies table. A policy applies to the tickets of its issuer
+// that are in one of its statuses and escalates them step by step, as they stay idle for the idle time of each step.
+type EscalationPolicy struct {
+	Model
+
+	Name     string
+	Issuer   string
+	Statuses []TicketStatus
+	Steps    []EscalationStep
+	Enabled  bool
+}
+
+// EscalationStep is a single step of an escalation policy. Idle time is a duration like 4h or 30m, measured from the
+// last modification of the ticket. Only the fields related to the action of the step are used.
+type EscalationStep struct {
+	IdleTime   string           `json:"idleTime"`
+	Action     EscalationAction `json:"action"`
+	Assignee   string           `json:"assignee,omitempty"`
+	Topic      string           `json:"topic,omitempty"`
+	Supervisor string           `json:"supervisor,omitempty"`
+}
+
+// Idle returns back the parsed idle time of the step.
+func (s EscalationStep) Idle() time.Duration {
+	d, _ := time.ParseDuration(s.IdleTime)
+	return d
+}
+
+// Escalation is the entity model of escalations table, the record of a step taken for a ticket.
+type Escalation struct {
+	Model
+
+	TicketID  int64
+	PolicyID  int64
+	Step      int
+	Action    EscalationAction
+	Details   string
+	IdleSince time.Time
+}
+
+// EscalationPolicyRepository is the repository implementation of EscalationPolicy model.
+type EscalationPolicyRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewEscalationPolicyRepository returns back a newly created and ready to use EscalationPolicyRepository.
+func NewEscalationPolicyRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *EscalationPolicyRepository {
+	return &EscalationPolicyRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert an escalation policy into escalation_policies table.
+func (r *EscalationPolicyRepository) Insert(ctx context.Context, policy EscalationPolicy) *errors.Type {
+	q := `INSERT INTO escalation_policies (name, issuer, statuses, steps, enabled, created_at, modified_at) VALUES
+			($1, $2, $3, $4, $5, NOW(), NOW());`
+
+	statuses, _ := json.Marshal(policy.Statuses)
+	steps, _ := json.Marshal(policy.Steps)
+	_, e := r.db.Exec(ctx, q, policy.Name, policy.Issuer, string(statuses), string(steps), policy.Enabled)
+	if e != nil {
+		if strings.Contains(e.Error(), "escalation_policies_name_key") {
+			return errors.AlreadyExists("escalation_policy.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// LoadByID tries to load an escalation policy from escalation_policies table.
+func (r *EscalationPolicyRepository) LoadByID(ctx context.Context, id int64) (*EscalationPolicy, *errors.Type) {
+	q := `SELECT id, name, issuer, statuses, steps, enabled, created_at, modified_at FROM escalation_policies
+			WHERE id = $1;`
+
+	policy, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("escalation_policy.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return policy, nil
+}
+
+// Update tries to update an escalation policy record.
+func (r *EscalationPolicyRepository) Update(ctx context.Context, policy *EscalationPolicy) *errors.Type {
+	q := `UPDATE escalation_policies SET name = $1, issuer = $2, statuses = $3, steps = $4, enabled = $5,
+			modified_at = NOW() WHERE id = $6;`
+
+	statuses, _ := json.Marshal(policy.Statuses)
+	steps, _ := json.Marshal(policy.Steps)
+	command, e := r.db.Exec(ctx, q, policy.Name, policy.Issuer, string(statuses), string(steps), policy.Enabled,
+		policy.ID)
+	if e != nil {
+		if strings.Contains(e.Error(), "escalation_policies_name_key") {
+			return errors.AlreadyExists("escalation_policy.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("escalation_policy.not_found", "")
+	}
+
+	return nil
+}
+
+// DeleteByID tries to delete an escalation policy and the records of its escalations.
+func (r *EscalationPolicyRepository) DeleteByID(ctx context.Context, id int64) *errors.Type {
+	q := `DELETE FROM escalation_policies WHERE id = $1;`
+
+	_, e := r.db.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// Filter tries to load escalation policies of the issuer, or of every issuer when it is empty, ordered by their names.
+// If there is another page of result, the second returned value will be true, otherwise false.
+func (r *EscalationPolicyRepository) Filter(ctx context.Context, issuer string, pageNumber,
+	pageSize int) ([]*EscalationPolicy, bool, *errors.Type) {
+
+	q := `SELECT id, name, issuer, statuses, steps, enabled, created_at, modified_at FROM escalation_policies
+			WHERE $1 = '' OR issuer = $1 ORDER BY name OFFSET $2 LIMIT $3;`
+
+	policies, e := r.query(ctx, q, issuer, (pageNumber-1)*pageSize, pageSize+1)
+	if e != nil {
+		return nil, false, e
+	}
+
+	hasNextPage := len(policies) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		policies = policies[:len(policies)-1]
+	}
+
+	return policies, hasNextPage, nil
+}
+
+// LoadEnabled tries to load all enabled escalation policies.
+func (r *EscalationPolicyRepository) LoadEnabled(ctx context.Context) ([]*EscalationPolicy, *errors.Type) {
+	q := `SELECT id, name, issuer, statuses, steps, enabled, created_at, modified_at FROM escalation_policies
+			WHERE enabled ORDER BY id;`
+
+	return r.query(ctx, q)
+}
+
+func (r *EscalationPolicyRepository) query(ctx context.Context, q string, args ...interface{}) ([]*EscalationPolicy,
+	*errors.Type) {
+
+	rows, e := r.db.Query(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	policies := make([]*EscalationPolicy, 0)
+	for rows.Next() {
+		policy, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+func (r *EscalationPolicyRepository) scan(row pgx.Row) (*EscalationPolicy, error) {
+	policy := &EscalationPolicy{}
+	var statuses, steps string
+
+	e := row.Scan(&policy.ID, &policy.Name, &policy.Issuer, &statuses, &steps, &policy.Enabled, &policy.CreatedAt,
+		&policy.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if e := json.Unmarshal([]byte(statuses), &policy.Statuses); e != nil {
+		return nil, e
+	}
+
+	if e := json.Unmarshal([]byte(steps), &policy.Steps); e != nil {
+		return nil, e
+	}
+
+	return policy, nil
+}
+
+// EscalationRepository is the repository implementation of Escalation model.
+type EscalationRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewEscalationRepository returns back a newly created and ready to use EscalationRepository.
+func NewEscalationRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *EscalationRepository {
+	return &EscalationRepository{logger: logger, db: db}
+}
+
+// LoadCandidates tries to load the tickets, without comments, due for the step of the policy in their idle period.
+func (r *EscalationRepository) LoadCandidates(ctx context.Context, policy *EscalationPolicy, step int,
+	limit int) ([]*Ticket, *errors.Type) {
+
+	q := `SELECT t.id, t.issuer, t.owner, t.subject, t.content, t.metadata, t.importance_level, t.status, t.created_at,
+			t.modified_at FROM tickets t WHERE t.status = ANY($1) AND ($2 = '' OR t.issuer = $2) AND
+			t.modified_at <= NOW() - make_interval(secs => $3) AND
+			NOT EXISTS (SELECT 1 FROM escalations e WHERE e.ticket_id = t.id AND e.policy_id = $4 AND e.step = $5 AND
+				e.idle_since = t.modified_at) AND
+			($5 = 0 OR EXISTS (SELECT 1 FROM escalations e WHERE e.ticket_id = t.id AND e.policy_id = $4 AND
+				e.step = $5 - 1 AND e.idle_since = t.modified_at))
+			ORDER BY t.modified_at LIMIT $6;`
+
+	statuses := make([]string, 0, len(policy.Statuses))
+	for _, s := range policy.Statuses {
+		statuses = append(statuses, string(s))
+	}
+
+	idle := policy.Steps[step].Idle().Seconds()
+	rows, e := r.db.Query(ctx, q, statuses, policy.Issuer, idle, policy.ID, step, limit)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	tickets := make([]*Ticket, 0)
+	for rows.Next() {
+		ticket := &Ticket{}
+		var metadata sql.NullString
+
+		e := rows.Scan(&ticket.ID, &ticket.Issuer, &ticket.Owner, &ticket.Subject, &ticket.Content, &metadata,
+			&ticket.ImportanceLevel, &ticket.Status, &ticket.CreatedAt, &ticket.ModifiedAt)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		if metadata.Valid {
+			ticket.Metadata = metadata.String
+		}
+
+		tickets = append(tickets, ticket)
+	}
+
+	return tickets, nil
+}
+
+// Record tries to record the escalation and apply its effects to the ticket in a single transaction. If the ticket has
+// been modified since, or the step is already taken on another node, nothing is changed and false is returned.
+func (r *EscalationRepository) Record(ctx context.Context, escalation Escalation, ticket *Ticket,
+	comment *Comment) (bool, *errors.Type) {
+
+	lockQ := `SELECT modified_at FROM tickets WHERE id = $1 FOR UPDATE;`
+
+	insertQ := `INSERT INTO escalations (ticket_id, policy_id, step, action, details, idle_since, created_at,
+					modified_at) VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW()) ON CONFLICT DO NOTHING;`
+
+	updateQ := `UPDATE tickets SET importance_level = $1, metadata = $2 WHERE id = $3;`
+
+	commentQ := `INSERT INTO comments (ticket_id, owner, content, metadata, created_at, modified_at) VALUES
+					($1, $2, $3, $4, NOW(), NOW()) RETURNING id;`
+
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var modifiedAt time.Time
+	if e := tx.QueryRow(ctx, lockQ, escalation.TicketID).Scan(&modifiedAt); e != nil {
+		if e == pgx.ErrNoRows {
+			return false, nil
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	if !modifiedAt.Equal(escalation.IdleSince) {
+		return false, nil
+	}
+
+	command, e := tx.Exec(ctx, insertQ, escalation.TicketID, escalation.PolicyID, escalation.Step, escalation.Action,
+		escalation.Details, escalation.IdleSince)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	if command.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, e := tx.Exec(ctx, updateQ, ticket.ImportanceLevel, ticket.Metadata, escalation.TicketID); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	var commentID int64
+	if comment != nil {
+		e := tx.QueryRow(ctx, commentQ, escalation.TicketID, comment.Owner, comment.Content, comment.Metadata).
+			Scan(&commentID)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return false, et
+		}
+	}
+
+	if e := r.announce(ctx, tx, escalation, commentID); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	return true, nil
+}
+
+// announce writes the update of the ticket, and the creation of the comment when there is one, into the outbox by the
+// escalation policy.
+func (r *EscalationRepository) announce(ctx context.Context, tx pgx.Tx, escalation Escalation,
+	commentID int64) error {
+
+	ticket, e := loadTicketSnapshot(ctx, tx, escalation.TicketID)
+	if e != nil {
+		return e
+	}
+
+	actor := SystemActor(ActorTypeEscalation, escalation.PolicyID)
+	messages := ticketUpdatedMessages(ticket, ticket.Status, actor)
+
+	if commentID > 0 {
+		comment, e := loadCommentSnapshot(ctx, tx, commentID)
+		if e != nil {
+			return e
+		}
+
+		messages = append(messages, &OutboxMessage{Type: OutboxMessageTypeCommentCreated, TicketID: ticket.ID,
+			Actor: actor, Ticket: ticket, Comment: comment})
+	}
+
+	return writeOutbox(ctx, tx, messages...)
+}
+
+// LoadByTicketID tries to load all escalations of a ticket, from the newest one.
+func (r *EscalationRepository) LoadByTicketID(ctx context.Context, ticketID int64) ([]*Escalation, *errors.Type) {
+	q := `SELECT id, ticket_id, policy_id, step, action, details, idle_since, created_at, modified_at FROM escalations
+			WHERE ticket_id = $1 ORDER BY id DESC;`
+
+	rows, e := r.db.Query(ctx, q, ticketID)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	escalations := make([]*Escalation, 0)
+	for rows.Next() {
+		escalation := &Escalation{}
+
+		e := rows.Scan(&escalation.ID, &escalation.TicketID, &escalation.PolicyID, &escalation.Step,
+			&escalation.Action, &escalation.Details, &escalation.IdleSince, &escalation.CreatedAt,
+			&escalation.ModifiedAt)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		escalations = append(escalations, escalation)
+	}
+
+	return escalations, nil
+}
+
+// EscalationAction model.
+type EscalationAction string
+
+// Different escalation action instances.
+const (
+	EscalationActionRaiseImportanceLevel EscalationAction = "RAISE_IMPORTANCE_LEVEL"
+	EscalationActionReassign             EscalationAction = "REASSIGN"
+	EscalationActionNotify               EscalationAction = "NOTIFY"
+)
@@ -0,0 +1,198 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Escalation", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var policyRepository *models.EscalationPolicyRepository
+	var repository *models.EscalationRepository
+	var ticketRepository *models.TicketRepository
+
+	policy := models.EscalationPolicy{
+		Name:     "stale-tickets",
+		Statuses: []models.TicketStatus{models.TicketStatusNew, models.TicketStatusReplied},
+		Steps: []models.EscalationStep{
+			{IdleTime: "1h", Action: models.EscalationActionRaiseImportanceLevel},
+			{IdleTime: "3h", Action: models.EscalationActionReassign, Assignee: "tier-2"},
+		},
+		Enabled: true,
+	}
+
+	ticket := models.Ticket{
+		Issuer:          "Microservice-A",
+		Owner:           "user@example.com",
+		Subject:         "Technical Problem",
+		Content:         "Hello, i have some issues with REST API Docs!",
+		Metadata:        `{"ip":"192.168.1.1"}`,
+		ImportanceLevel: models.TicketImportanceLevelMedium,
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			policyRepository = models.NewEscalationPolicyRepository(zap.S(), db)
+			repository = models.NewEscalationRepository(zap.S(), db)
+			ticketRepository = models.NewTicketRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Describe("EscalationPolicyRepository", func() {
+		Context("When Insert called", func() {
+			It("Should insert an escalation policy record successfully", func() {
+				e := policyRepository.Insert(context.Background(), policy)
+				Ω(e).Should(BeNil())
+
+				p, e := policyRepository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(p.Name).Should(Equal(policy.Name))
+				Ω(p.Statuses).Should(Equal(policy.Statuses))
+				Ω(p.Steps).Should(Equal(policy.Steps))
+				Ω(p.Enabled).Should(BeTrue())
+			})
+
+			It("Should return error when the name is already used", func() {
+				e := policyRepository.Insert(context.Background(), policy)
+				Ω(e).Should(BeNil())
+
+				e = policyRepository.Insert(context.Background(), policy)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+				Ω(e.Errors[0].Code).Should(Equal("escalation_policy.already_exists"))
+			})
+		})
+
+		Context("When LoadEnabled called", func() {
+			It("Should only load the enabled policies", func() {
+				e := policyRepository.Insert(context.Background(), policy)
+				Ω(e).Should(BeNil())
+
+				disabled := policy
+				disabled.Name = "disabled"
+				disabled.Enabled = false
+				e = policyRepository.Insert(context.Background(), disabled)
+				Ω(e).Should(BeNil())
+
+				ps, e := policyRepository.LoadEnabled(context.Background())
+				Ω(e).Should(BeNil())
+				Ω(len(ps)).Should(Equal(1))
+				Ω(ps[0].Name).Should(Equal(policy.Name))
+			})
+		})
+	})
+
+	Describe("EscalationRepository", func() {
+		Context("When LoadCandidates and Record called", func() {
+			It("Should escalate idle tickets step by step and only once", func() {
+				e := policyRepository.Insert(context.Background(), policy)
+				Ω(e).Should(BeNil())
+
+				_, e = ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+
+				p, e := policyRepository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+
+				ts, e := repository.LoadCandidates(context.Background(), p, 0, 10)
+				Ω(e).Should(BeNil())
+				Ω(ts).Should(BeEmpty())
+
+				_, err := db.Exec(context.Background(), `UPDATE tickets SET modified_at = NOW() - INTERVAL '2 hours';`)
+				Ω(err).Should(BeNil())
+
+				ts, e = repository.LoadCandidates(context.Background(), p, 1, 10)
+				Ω(e).Should(BeNil())
+				Ω(ts).Should(BeEmpty())
+
+				ts, e = repository.LoadCandidates(context.Background(), p, 0, 10)
+				Ω(e).Should(BeNil())
+				Ω(len(ts)).Should(Equal(1))
+
+				escalation := models.Escalation{TicketID: ts[0].ID, PolicyID: p.ID, Step: 0,
+					Action: models.EscalationActionRaiseImportanceLevel, Details: "raised", IdleSince: ts[0].ModifiedAt}
+				ts[0].ImportanceLevel = models.TicketImportanceLevelHigh
+				comment := &models.Comment{Owner: "kiosk", Content: "Escalated."}
+
+				recorded, e := repository.Record(context.Background(), escalation, ts[0], comment)
+				Ω(e).Should(BeNil())
+				Ω(recorded).Should(BeTrue())
+
+				recorded, e = repository.Record(context.Background(), escalation, ts[0], comment)
+				Ω(e).Should(BeNil())
+				Ω(recorded).Should(BeFalse())
+
+				t, e := ticketRepository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(t.ImportanceLevel).Should(Equal(models.TicketImportanceLevelHigh))
+				Ω(t.ModifiedAt).Should(BeTemporally("==", escalation.IdleSince))
+				Ω(len(t.Comments)).Should(Equal(1))
+
+				ts, e = repository.LoadCandidates(context.Background(), p, 0, 10)
+				Ω(e).Should(BeNil())
+				Ω(ts).Should(BeEmpty())
+
+				es, e := repository.LoadByTicketID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(len(es)).Should(Equal(1))
+				Ω(es[0].Details).Should(Equal("raised"))
+			})
+
+			It("Should not escalate a ticket that is modified meanwhile", func() {
+				e := policyRepository.Insert(context.Background(), policy)
+				Ω(e).Should(BeNil())
+
+				_, e = ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+
+				_, err := db.Exec(context.Background(), `UPDATE tickets SET modified_at = NOW() - INTERVAL '2 hours';`)
+				Ω(err).Should(BeNil())
+
+				p, e := policyRepository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+
+				ts, e := repository.LoadCandidates(context.Background(), p, 0, 10)
+				Ω(e).Should(BeNil())
+				Ω(len(ts)).Should(Equal(1))
+
+				t, e := ticketRepository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				_, e = ticketRepository.Apply(context.Background(), t, nil, models.UserActor(""), nil)
+				Ω(e).Should(BeNil())
+
+				escalation := models.Escalation{TicketID: ts[0].ID, PolicyID: p.ID, Step: 0,
+					Action: models.EscalationActionRaiseImportanceLevel, IdleSince: ts[0].ModifiedAt}
+				recorded, e := repository.Record(context.Background(), escalation, ts[0], nil)
+				Ω(e).Should(BeNil())
+				Ω(recorded).Should(BeFalse())
+			})
+		})
+	})
+})
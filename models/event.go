@@ -0,0 +1,48 @@
+package models
+
+import "strconv"
+
+// EventType model.
+type EventType string
+
+// Different event type instances.
+const (
+	EventTypeTicketCreated  EventType = "TICKET_CREATED"
+	EventTypeTicketUpdated  EventType = "TICKET_UPDATED"
+	EventTypeTicketDeleted  EventType = "TICKET_DELETED"
+	EventTypeCommentCreated EventType = "COMMENT_CREATED"
+	EventTypeCommentUpdated EventType = "COMMENT_UPDATED"
+	EventTypeCommentDeleted EventType = "COMMENT_DELETED"
+)
+
+// ActorType model.
+type ActorType string
+
+// Different actor type instances. Changes requested through nats without telling who asked for them are made by a
+// client.
+const (
+	ActorTypeUser       ActorType = "USER"
+	ActorTypeClient     ActorType = "CLIENT"
+	ActorTypeRule       ActorType = "RULE"
+	ActorTypeEscalation ActorType = "ESCALATION"
+)
+
+// Actor is the one who made a change, e.g: a user by its name or a rule by its id.
+type Actor struct {
+	Type ActorType
+	ID   string
+}
+
+// UserActor returns back the user as an actor, or a client when the user is unknown.
+func UserActor(user string) Actor {
+	if user == "" {
+		return Actor{Type: ActorTypeClient}
+	}
+
+	return Actor{Type: ActorTypeUser, ID: user}
+}
+
+// SystemActor returns back the automation of the type, i.e. a rule or an escalation policy, with the id as an actor.
+func SystemActor(actorType ActorType, id int64) Actor {
+	return Actor{Type: actorType, ID: strconv.FormatInt(id, 10)}
+}
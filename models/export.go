@@ -0,0 +1,201 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Export is the entity model of exports table. An export is a background job that dumps all tickets matching its
+// filter criteria into a file. LockedBy is the token of the last claim of the job, so only its holder can change it.
+type Export struct {
+	Model
+
+	Format          ExportFormat
+	Issuer          string
+	Owner           string
+	ImportanceLevel TicketImportanceLevel
+	Status          TicketStatus
+	FromDate        time.Time
+	ToDate          time.Time
+	JobStatus       ExportJobStatus
+	FileName        string
+	ExportedTickets int64
+	LastTicketID    int64
+	FileOffset      int64
+	FailureReason   string
+	LockedBy        string
+}
+
+// ExportRepository is the repository implementation of Export model.
+type ExportRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewExportRepository returns back a newly created and ready to use ExportRepository.
+func NewExportRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *ExportRepository {
+	return &ExportRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert an export job into exports table and returns back the generated identifier.
+func (r *ExportRepository) Insert(ctx context.Context, export Export) (int64, *errors.Type) {
+	q := `INSERT INTO exports (format, issuer, owner, importance_level, status, from_date, to_date, job_status,
+			file_name, exported_tickets, last_ticket_id, file_offset, created_at, modified_at) VALUES ($1, $2, $3, $4,
+			$5, $6, $7, $8, $9, 0, 0, 0, NOW(), NOW()) RETURNING id;`
+
+	var id int64
+	e := r.db.QueryRow(ctx, q, export.Format, export.Issuer, export.Owner, export.ImportanceLevel, export.Status,
+		export.FromDate, export.ToDate, ExportJobStatusPending, export.FileName).Scan(&id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return id, nil
+}
+
+// LoadByID tries to load an export job from exports table.
+func (r *ExportRepository) LoadByID(ctx context.Context, id int64) (*Export, *errors.Type) {
+	q := `SELECT id, format, issuer, owner, importance_level, status, from_date, to_date, job_status, file_name,
+			exported_tickets, last_ticket_id, file_offset, failure_reason, locked_by, created_at, modified_at
+			FROM exports WHERE id = $1;`
+
+	export, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("export.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return export, nil
+}
+
+// Claim tries to lock the oldest pending export, or a running one whose lease has expired, with a new token, so the
+// node that lost the lease can no longer change it. When there is nothing to claim, both returned values are nil.
+func (r *ExportRepository) Claim(ctx context.Context, lease time.Duration) (*Export, *errors.Type) {
+	q := `UPDATE exports SET job_status = $1, locked_until = NOW() + $2 * INTERVAL '1 millisecond', locked_by = $3,
+			modified_at = NOW() WHERE id = (SELECT id FROM exports WHERE job_status = $4 OR (job_status = $1 AND
+			locked_until < NOW()) ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, format, issuer, owner,
+			importance_level, status, from_date, to_date, job_status, file_name, exported_tickets, last_ticket_id,
+			file_offset, failure_reason, locked_by, created_at, modified_at;`
+
+	export, e := r.scan(r.db.QueryRow(ctx, q, ExportJobStatusRunning, lease.Milliseconds(), uuid.New().String(),
+		ExportJobStatusPending))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, nil
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return export, nil
+}
+
+// Checkpoint persists the progress of a running export job and renews its lease, as long as the claim of the job is
+// still the one of the export. Otherwise, the returned error is precondition failed.
+func (r *ExportRepository) Checkpoint(ctx context.Context, export *Export, lease time.Duration) *errors.Type {
+	q := `UPDATE exports SET exported_tickets = $1, last_ticket_id = $2, file_offset = $3,
+			locked_until = NOW() + $4 * INTERVAL '1 millisecond', modified_at = NOW() WHERE id = $5 AND job_status = $6
+			AND locked_by = $7;`
+
+	command, e := r.db.Exec(ctx, q, export.ExportedTickets, export.LastTicketID, export.FileOffset,
+		lease.Milliseconds(), export.ID, ExportJobStatusRunning, export.LockedBy)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.PreconditionFailed("export.not_running", "")
+	}
+
+	return nil
+}
+
+// Complete marks a running export job as completed, like Checkpoint does.
+func (r *ExportRepository) Complete(ctx context.Context, export *Export) *errors.Type {
+	return r.finish(ctx, export, ExportJobStatusCompleted, "")
+}
+
+// Fail marks a running export job as failed with the provided reason, like Checkpoint does.
+func (r *ExportRepository) Fail(ctx context.Context, export *Export, reason string) *errors.Type {
+	return r.finish(ctx, export, ExportJobStatusFailed, reason)
+}
+
+func (r *ExportRepository) finish(ctx context.Context, export *Export, jobStatus ExportJobStatus,
+	reason string) *errors.Type {
+
+	q := `UPDATE exports SET job_status = $1, failure_reason = $2, locked_until = NULL, modified_at = NOW()
+			WHERE id = $3 AND job_status = $4 AND locked_by = $5;`
+
+	command, e := r.db.Exec(ctx, q, jobStatus, reason, export.ID, ExportJobStatusRunning, export.LockedBy)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.PreconditionFailed("export.not_running", "")
+	}
+
+	return nil
+}
+
+func (r *ExportRepository) scan(row pgx.Row) (*Export, error) {
+	export := &Export{}
+	var failureReason, lockedBy sql.NullString
+
+	e := row.Scan(&export.ID, &export.Format, &export.Issuer, &export.Owner, &export.ImportanceLevel, &export.Status,
+		&export.FromDate, &export.ToDate, &export.JobStatus, &export.FileName, &export.ExportedTickets,
+		&export.LastTicketID, &export.FileOffset, &failureReason, &lockedBy, &export.CreatedAt, &export.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if failureReason.Valid {
+		export.FailureReason = failureReason.String
+	}
+
+	if lockedBy.Valid {
+		export.LockedBy = lockedBy.String
+	}
+
+	return export, nil
+}
+
+// ExportFormat model.
+type ExportFormat string
+
+// Different export format instances.
+const (
+	ExportFormatCSV    ExportFormat = "CSV"
+	ExportFormatNDJSON ExportFormat = "NDJSON"
+)
+
+// ExportJobStatus model.
+type ExportJobStatus string
+
+// Different export job status instances.
+const (
+	ExportJobStatusPending   ExportJobStatus = "PENDING"
+	ExportJobStatusRunning   ExportJobStatus = "RUNNING"
+	ExportJobStatusCompleted ExportJobStatus = "COMPLETED"
+	ExportJobStatusFailed    ExportJobStatus = "FAILED"
+)
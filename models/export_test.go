@@ -0,0 +1,182 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Export", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.ExportRepository
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewExportRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	newExport := func() models.Export {
+		return models.Export{
+			Format:          models.ExportFormatCSV,
+			Issuer:          "Microservice-A",
+			ImportanceLevel: models.TicketImportanceLevelHigh,
+			FromDate:        time.Now().UTC().Add(-time.Hour),
+			ToDate:          time.Now().UTC().Add(time.Hour),
+			FileName:        "export-1.csv",
+		}
+	}
+
+	Describe("ExportRepository", func() {
+		Context("When Insert called", func() {
+			It("Should insert a pending export record in exports table successfully", func() {
+				id, e := repository.Insert(context.Background(), newExport())
+				Ω(e).Should(BeNil())
+				Ω(id).Should(Equal(int64(1)))
+
+				export, e := repository.LoadByID(context.Background(), id)
+				Ω(e).Should(BeNil())
+				Ω(export.Format).Should(Equal(models.ExportFormatCSV))
+				Ω(export.Issuer).Should(Equal("Microservice-A"))
+				Ω(export.ImportanceLevel).Should(Equal(models.TicketImportanceLevelHigh))
+				Ω(export.JobStatus).Should(Equal(models.ExportJobStatusPending))
+				Ω(export.FileName).Should(Equal("export-1.csv"))
+				Ω(export.ExportedTickets).Should(Equal(int64(0)))
+			})
+		})
+
+		Context("When LoadByID called", func() {
+			It("Should return not found error when export does not exist", func() {
+				export, e := repository.LoadByID(context.Background(), 1)
+				Ω(export).Should(BeNil())
+				Ω(e.Errors[0].Code).Should(Equal("export.not_found"))
+			})
+		})
+
+		Context("When Claim called", func() {
+			It("Should claim a pending export only once while its lease is valid", func() {
+				_, e := repository.Insert(context.Background(), newExport())
+				Ω(e).Should(BeNil())
+
+				export, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+				Ω(export.ID).Should(Equal(int64(1)))
+				Ω(export.JobStatus).Should(Equal(models.ExportJobStatusRunning))
+
+				export, e = repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+				Ω(export).Should(BeNil())
+			})
+
+			It("Should claim a running export again after its lease is expired", func() {
+				_, e := repository.Insert(context.Background(), newExport())
+				Ω(e).Should(BeNil())
+
+				export, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+
+				export.LastTicketID = 10
+				export.ExportedTickets = 10
+				export.FileOffset = 1024
+				e = repository.Checkpoint(context.Background(), export, 0)
+				Ω(e).Should(BeNil())
+
+				reclaimed, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+				Ω(reclaimed.LastTicketID).Should(Equal(int64(10)))
+				Ω(reclaimed.ExportedTickets).Should(Equal(int64(10)))
+				Ω(reclaimed.FileOffset).Should(Equal(int64(1024)))
+				Ω(reclaimed.LockedBy).ShouldNot(Equal(export.LockedBy))
+			})
+
+			It("Should reject the changes of the node that lost the claim", func() {
+				_, e := repository.Insert(context.Background(), newExport())
+				Ω(e).Should(BeNil())
+
+				export, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+
+				e = repository.Checkpoint(context.Background(), export, 0)
+				Ω(e).Should(BeNil())
+
+				reclaimed, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+
+				export.LastTicketID = 10
+				e = repository.Checkpoint(context.Background(), export, time.Minute)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+
+				e = repository.Complete(context.Background(), export)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+
+				e = repository.Checkpoint(context.Background(), reclaimed, time.Minute)
+				Ω(e).Should(BeNil())
+
+				loaded, e := repository.LoadByID(context.Background(), export.ID)
+				Ω(e).Should(BeNil())
+				Ω(loaded.JobStatus).Should(Equal(models.ExportJobStatusRunning))
+				Ω(loaded.LastTicketID).Should(Equal(int64(0)))
+			})
+		})
+
+		Context("When Complete or Fail called", func() {
+			It("Should finish the claimed export job", func() {
+				_, e := repository.Insert(context.Background(), newExport())
+				Ω(e).Should(BeNil())
+
+				export, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+
+				e = repository.Complete(context.Background(), export)
+				Ω(e).Should(BeNil())
+
+				loaded, e := repository.LoadByID(context.Background(), export.ID)
+				Ω(e).Should(BeNil())
+				Ω(loaded.JobStatus).Should(Equal(models.ExportJobStatusCompleted))
+
+				_, e = repository.Insert(context.Background(), newExport())
+				Ω(e).Should(BeNil())
+
+				export, e = repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+
+				e = repository.Fail(context.Background(), export, "disk is full")
+				Ω(e).Should(BeNil())
+
+				loaded, e = repository.LoadByID(context.Background(), export.ID)
+				Ω(e).Should(BeNil())
+				Ω(loaded.JobStatus).Should(Equal(models.ExportJobStatusFailed))
+				Ω(loaded.FailureReason).Should(Equal("disk is full"))
+			})
+		})
+	})
+})
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Import is the entity model of imports table, loading the tickets of an external system, the source, into kiosk.
+type Import struct {
+	Model
+
+	Source       string
+	Format       ImportFormat
+	FileName     string
+	Status       ImportStatus
+	TotalRows    int64
+	ImportedRows int64
+	SkippedRows  int64
+	RejectedRows int64
+	Rejections   []ImportRejection
+	LockedBy     string
+}
+
+// ImportRejection describes a row of an import file that could not be imported.
+type ImportRejection struct {
+	Row        int64    `json:"row"`
+	ExternalID string   `json:"externalID,omitempty"`
+	Codes      []string `json:"codes"`
+}
+
+// ImportRepository is the repository implementation of Import model.
+type ImportRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewImportRepository returns back a newly created and ready to use ImportRepository.
+func NewImportRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *ImportRepository {
+	return &ImportRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert an import into imports table and returns back the generated identifier.
+func (r *ImportRepository) Insert(ctx context.Context, i Import) (int64, *errors.Type) {
+	q := `INSERT INTO imports (source, format, file_name, status, total_rows, imported_rows, skipped_rows,
+			rejected_rows, created_at, modified_at) VALUES ($1, $2, $3, $4, 0, 0, 0, 0, NOW(), NOW()) RETURNING id;`
+
+	var id int64
+	e := r.db.QueryRow(ctx, q, i.Source, i.Format, i.FileName, i.Status).Scan(&id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return id, nil
+}
+
+// LoadByID tries to load an import from imports table.
+func (r *ImportRepository) LoadByID(ctx context.Context, id int64) (*Import, *errors.Type) {
+	q := `SELECT id, source, format, file_name, status, total_rows, imported_rows, skipped_rows, rejected_rows,
+			rejections, locked_by, created_at, modified_at FROM imports WHERE id = $1;`
+
+	i, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("import.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return i, nil
+}
+
+// Claim tries to lock the oldest pending import, or a running one whose lease has expired, with a new token. When
+// there is nothing to claim, both returned values are nil.
+func (r *ImportRepository) Claim(ctx context.Context, lease time.Duration) (*Import, *errors.Type) {
+	q := `UPDATE imports SET status = $1, locked_until = NOW() + $2 * INTERVAL '1 millisecond', locked_by = $3,
+			modified_at = NOW() WHERE id = (SELECT id FROM imports WHERE status = $4 OR (status = $1 AND
+			locked_until < NOW()) ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, source, format, file_name,
+			status, total_rows, imported_rows, skipped_rows, rejected_rows, rejections, locked_by, created_at,
+			modified_at;`
+
+	i, e := r.scan(r.db.QueryRow(ctx, q, ImportStatusRunning, lease.Milliseconds(), uuid.New().String(),
+		ImportStatusPending))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, nil
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return i, nil
+}
+
+// Checkpoint persists the counters and rejections of a running import and renews its lease, as long as the claim of
+// the import is still the one of the provided import. Otherwise, the returned error is precondition failed.
+func (r *ImportRepository) Checkpoint(ctx context.Context, i *Import, lease time.Duration) *errors.Type {
+	q := `UPDATE imports SET total_rows = $1, imported_rows = $2, skipped_rows = $3, rejected_rows = $4,
+			rejections = $5, locked_until = NOW() + $6 * INTERVAL '1 millisecond', modified_at = NOW() WHERE id = $7
+			AND status = $8 AND locked_by = $9;`
+
+	rejections, _ := json.Marshal(i.Rejections)
+	command, e := r.db.Exec(ctx, q, i.TotalRows, i.ImportedRows, i.SkippedRows, i.RejectedRows, string(rejections),
+		lease.Milliseconds(), i.ID, ImportStatusRunning, i.LockedBy)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.PreconditionFailed("import.not_running", "")
+	}
+
+	return nil
+}
+
+// Complete marks a running import as completed with its final counters, like Checkpoint does.
+func (r *ImportRepository) Complete(ctx context.Context, i *Import) *errors.Type {
+	return r.finish(ctx, i, ImportStatusCompleted)
+}
+
+// Fail marks a running import as failed with its final counters, like Checkpoint does.
+func (r *ImportRepository) Fail(ctx context.Context, i *Import) *errors.Type {
+	return r.finish(ctx, i, ImportStatusFailed)
+}
+
+func (r *ImportRepository) finish(ctx context.Context, i *Import, status ImportStatus) *errors.Type {
+	q := `UPDATE imports SET status = $1, total_rows = $2, imported_rows = $3, skipped_rows = $4, rejected_rows = $5,
+			rejections = $6, locked_until = NULL, modified_at = NOW() WHERE id = $7 AND status = $8 AND locked_by = $9;`
+
+	rejections, _ := json.Marshal(i.Rejections)
+	command, e := r.db.Exec(ctx, q, status, i.TotalRows, i.ImportedRows, i.SkippedRows, i.RejectedRows,
+		string(rejections), i.ID, ImportStatusRunning, i.LockedBy)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.PreconditionFailed("import.not_running", "")
+	}
+
+	i.Status = status
+	return nil
+}
+
+// Update tries to update the status, counters and rejections of an import record that is not claimed, i.e. the
+// imports of the import command.
+func (r *ImportRepository) Update(ctx context.Context, i *Import) *errors.Type {
+	q := `UPDATE imports SET status = $1, total_rows = $2, imported_rows = $3, skipped_rows = $4, rejected_rows = $5,
+			rejections = $6, modified_at = NOW() WHERE id = $7;`
+
+	rejections, _ := json.Marshal(i.Rejections)
+	command, e := r.db.Exec(ctx, q, i.Status, i.TotalRows, i.ImportedRows, i.SkippedRows, i.RejectedRows,
+		string(rejections), i.ID)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("import.not_found", "")
+	}
+
+	return nil
+}
+
+// ImportTicket tries to insert a ticket and its comments as they are and map the external identifier to it, in a
+// single transaction. If the external identifier is already mapped, nothing is inserted and false is returned.
+func (r *ImportRepository) ImportTicket(ctx context.Context, source, externalID string, ticket *Ticket) (bool,
+	*errors.Type) {
+
+	existsQ := `SELECT EXISTS (SELECT 1 FROM import_mappings WHERE source = $1 AND external_id = $2);`
+
+	mappingQ := `INSERT INTO import_mappings (source, external_id, ticket_id, created_at) VALUES ($1, $2, $3, NOW())
+					ON CONFLICT (source, external_id) DO NOTHING;`
+
+	ticketQ := `INSERT INTO tickets (issuer, owner, subject, content, metadata, importance_level, status, created_at,
+					modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;`
+
+	commentQ := `INSERT INTO comments (ticket_id, owner, content, metadata, created_at, modified_at) VALUES ($1, $2, $3,
+					$4, $5, $6);`
+
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var exists bool
+	if e := tx.QueryRow(ctx, existsQ, source, externalID).Scan(&exists); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	if exists {
+		return false, nil
+	}
+
+	e = tx.QueryRow(ctx, ticketQ, ticket.Issuer, ticket.Owner, ticket.Subject, ticket.Content, ticket.Metadata,
+		ticket.ImportanceLevel, ticket.Status, ticket.CreatedAt, ticket.ModifiedAt).Scan(&ticket.ID)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	command, e := tx.Exec(ctx, mappingQ, source, externalID, ticket.ID)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	if command.RowsAffected() == 0 {
+		// Imported concurrently by another run, the deferred rollback drops the inserted ticket.
+		return false, nil
+	}
+
+	for _, c := range ticket.Comments {
+		c.TicketID = ticket.ID
+		if _, e := tx.Exec(ctx, commentQ, c.TicketID, c.Owner, c.Content, c.Metadata, c.CreatedAt,
+			c.ModifiedAt); e != nil {
+
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return false, et
+		}
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	return true, nil
+}
+
+// LoadTicketID tries to find the ticket identifier that an external identifier of the source is mapped to.
+func (r *ImportRepository) LoadTicketID(ctx context.Context, source, externalID string) (int64, *errors.Type) {
+	q := `SELECT ticket_id FROM import_mappings WHERE source = $1 AND external_id = $2;`
+
+	var id int64
+	if e := r.db.QueryRow(ctx, q, source, externalID).Scan(&id); e != nil {
+		if e == pgx.ErrNoRows {
+			return 0, errors.NotFound("import_mapping.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return id, nil
+}
+
+func (r *ImportRepository) scan(row pgx.Row) (*Import, error) {
+	i := &Import{}
+	var rejections, lockedBy sql.NullString
+
+	e := row.Scan(&i.ID, &i.Source, &i.Format, &i.FileName, &i.Status, &i.TotalRows, &i.ImportedRows, &i.SkippedRows,
+		&i.RejectedRows, &rejections, &lockedBy, &i.CreatedAt, &i.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if rejections.Valid {
+		_ = json.Unmarshal([]byte(rejections.String), &i.Rejections)
+	}
+
+	if lockedBy.Valid {
+		i.LockedBy = lockedBy.String
+	}
+
+	return i, nil
+}
+
+// ImportFormat model.
+type ImportFormat string
+
+// Different import format instances.
+const (
+	ImportFormatCSV    ImportFormat = "CSV"
+	ImportFormatNDJSON ImportFormat = "NDJSON"
+)
+
+// ImportStatus model.
+type ImportStatus string
+
+// Different import status instances.
+const (
+	ImportStatusPending   ImportStatus = "PENDING"
+	ImportStatusRunning   ImportStatus = "RUNNING"
+	ImportStatusCompleted ImportStatus = "COMPLETED"
+	ImportStatusFailed    ImportStatus = "FAILED"
+)
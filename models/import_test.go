@@ -0,0 +1,184 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Import", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var ticketRepository *models.TicketRepository
+	var repository *models.ImportRepository
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			ticketRepository = models.NewTicketRepository(zap.S(), db)
+			repository = models.NewImportRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	newTicket := func() *models.Ticket {
+		createdAt := time.Date(2015, 3, 10, 8, 30, 0, 0, time.UTC)
+		modifiedAt := time.Date(2015, 3, 12, 17, 0, 0, 0, time.UTC)
+
+		return &models.Ticket{
+			Model:           models.Model{CreatedAt: createdAt, ModifiedAt: modifiedAt},
+			Issuer:          "Microservice-A",
+			Owner:           "user@example.com",
+			Subject:         "Technical Problem",
+			Content:         "Hello, i have some issues with REST API Docs!",
+			ImportanceLevel: models.TicketImportanceLevelMedium,
+			Status:          models.TicketStatusClosed,
+			Comments: []*models.Comment{{
+				Model:   models.Model{CreatedAt: createdAt.Add(time.Hour), ModifiedAt: createdAt.Add(time.Hour)},
+				Owner:   "admin@example.com",
+				Content: "Hello, we are working on these.",
+			}},
+		}
+	}
+
+	Describe("ImportRepository", func() {
+		Context("When ImportTicket called", func() {
+			It("Should import a ticket with its comments preserving timestamps and status", func() {
+				imported, e := repository.ImportTicket(context.Background(), "legacy", "T-1", newTicket())
+				Ω(e).Should(BeNil())
+				Ω(imported).Should(BeTrue())
+
+				id, e := repository.LoadTicketID(context.Background(), "legacy", "T-1")
+				Ω(e).Should(BeNil())
+
+				t, e := ticketRepository.LoadByID(context.Background(), id)
+				Ω(e).Should(BeNil())
+				Ω(t.Status).Should(Equal(models.TicketStatusClosed))
+				Ω(t.CreatedAt.Equal(time.Date(2015, 3, 10, 8, 30, 0, 0, time.UTC))).Should(BeTrue())
+				Ω(t.ModifiedAt.Equal(time.Date(2015, 3, 12, 17, 0, 0, 0, time.UTC))).Should(BeTrue())
+				Ω(len(t.Comments)).Should(Equal(1))
+				Ω(t.Comments[0].Owner).Should(Equal("admin@example.com"))
+			})
+
+			It("Should skip a ticket that is already imported from the same source", func() {
+				imported, e := repository.ImportTicket(context.Background(), "legacy", "T-1", newTicket())
+				Ω(e).Should(BeNil())
+				Ω(imported).Should(BeTrue())
+
+				imported, e = repository.ImportTicket(context.Background(), "legacy", "T-1", newTicket())
+				Ω(e).Should(BeNil())
+				Ω(imported).Should(BeFalse())
+
+				imported, e = repository.ImportTicket(context.Background(), "another", "T-1", newTicket())
+				Ω(e).Should(BeNil())
+				Ω(imported).Should(BeTrue())
+			})
+		})
+
+		Context("When Update called", func() {
+			It("Should persist the counters and rejections of an import", func() {
+				i := models.Import{Source: "legacy", Format: models.ImportFormatNDJSON, FileName: "tickets.ndjson"}
+				id, e := repository.Insert(context.Background(), i)
+				Ω(e).Should(BeNil())
+
+				i.ID = id
+				i.Status = models.ImportStatusCompleted
+				i.TotalRows = 3
+				i.ImportedRows = 1
+				i.SkippedRows = 1
+				i.RejectedRows = 1
+				i.Rejections = []models.ImportRejection{{Row: 3, ExternalID: "T-3", Codes: []string{"status.not_valid"}}}
+
+				e = repository.Update(context.Background(), &i)
+				Ω(e).Should(BeNil())
+
+				loaded, e := repository.LoadByID(context.Background(), id)
+				Ω(e).Should(BeNil())
+				Ω(loaded.Status).Should(Equal(models.ImportStatusCompleted))
+				Ω(loaded.TotalRows).Should(Equal(int64(3)))
+				Ω(loaded.RejectedRows).Should(Equal(int64(1)))
+				Ω(loaded.Rejections).Should(Equal(i.Rejections))
+			})
+		})
+
+		Context("When Claim called", func() {
+			newImport := func() models.Import {
+				return models.Import{Source: "legacy", Format: models.ImportFormatNDJSON, FileName: "tickets.ndjson",
+					Status: models.ImportStatusPending}
+			}
+
+			It("Should not claim the imports of the import command", func() {
+				i := newImport()
+				i.Status = models.ImportStatusRunning
+				_, e := repository.Insert(context.Background(), i)
+				Ω(e).Should(BeNil())
+
+				claimed, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+				Ω(claimed).Should(BeNil())
+			})
+
+			It("Should resume a running import from its checkpoint after its lease is expired", func() {
+				_, e := repository.Insert(context.Background(), newImport())
+				Ω(e).Should(BeNil())
+
+				i, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+				Ω(i.Status).Should(Equal(models.ImportStatusRunning))
+
+				claimed, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+				Ω(claimed).Should(BeNil())
+
+				i.TotalRows = 100
+				i.ImportedRows = 99
+				i.RejectedRows = 1
+				i.Rejections = []models.ImportRejection{{Row: 7, ExternalID: "T-7", Codes: []string{"status.not_valid"}}}
+				e = repository.Checkpoint(context.Background(), i, 0)
+				Ω(e).Should(BeNil())
+
+				reclaimed, e := repository.Claim(context.Background(), time.Minute)
+				Ω(e).Should(BeNil())
+				Ω(reclaimed.TotalRows).Should(Equal(int64(100)))
+				Ω(reclaimed.ImportedRows).Should(Equal(int64(99)))
+				Ω(reclaimed.Rejections).Should(Equal(i.Rejections))
+				Ω(reclaimed.LockedBy).ShouldNot(Equal(i.LockedBy))
+
+				e = repository.Complete(context.Background(), i)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+
+				e = repository.Complete(context.Background(), reclaimed)
+				Ω(e).Should(BeNil())
+
+				loaded, e := repository.LoadByID(context.Background(), i.ID)
+				Ω(e).Should(BeNil())
+				Ω(loaded.Status).Should(Equal(models.ImportStatusCompleted))
+			})
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Macro is the entity model of macros table. A macro is a named and ordered list of actions that are applied to a
+// ticket at once. A macro without issuer is global and applies to tickets of all issuers.
+type Macro struct {
+	Model
+
+	Issuer  string
+	Name    string
+	Actions []MacroAction
+}
+
+// MacroAction is a single step of a macro. Only the fields related to the type of the action are used.
+type MacroAction struct {
+	Type             MacroActionType       `json:"type"`
+	Status           TicketStatus          `json:"status,omitempty"`
+	ImportanceLevel  TicketImportanceLevel `json:"importanceLevel,omitempty"`
+	Subject          string                `json:"subject,omitempty"`
+	Tag              string                `json:"tag,omitempty"`
+	Key              string                `json:"key,omitempty"`
+	Value            string                `json:"value,omitempty"`
+	Owner            string                `json:"owner,omitempty"`
+	Content          string                `json:"content,omitempty"`
+	CannedResponseID int64                 `json:"cannedResponseID,omitempty"`
+}
+
+// MacroRepository is the repository implementation of Macro model.
+type MacroRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewMacroRepository returns back a newly created and ready to use MacroRepository.
+func NewMacroRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *MacroRepository {
+	return &MacroRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a macro into macros table.
+func (r *MacroRepository) Insert(ctx context.Context, macro Macro) *errors.Type {
+	q := `INSERT INTO macros (issuer, name, actions, created_at, modified_at) VALUES ($1, $2, $3, NOW(), NOW());`
+
+	actions, _ := json.Marshal(macro.Actions)
+	_, e := r.db.Exec(ctx, q, macro.Issuer, macro.Name, string(actions))
+	if e != nil {
+		if strings.Contains(e.Error(), "macros_issuer_name_key") {
+			return errors.AlreadyExists("macro.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// LoadByID tries to load a macro from macros table.
+func (r *MacroRepository) LoadByID(ctx context.Context, id int64) (*Macro, *errors.Type) {
+	q := `SELECT id, issuer, name, actions, created_at, modified_at FROM macros WHERE id = $1;`
+
+	macro, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("macro.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return macro, nil
+}
+
+// Update tries to update a macro record.
+func (r *MacroRepository) Update(ctx context.Context, macro *Macro) *errors.Type {
+	q := `UPDATE macros SET name = $1, actions = $2, modified_at = NOW() WHERE id = $3;`
+
+	actions, _ := json.Marshal(macro.Actions)
+	command, e := r.db.Exec(ctx, q, macro.Name, string(actions), macro.ID)
+	if e != nil {
+		if strings.Contains(e.Error(), "macros_issuer_name_key") {
+			return errors.AlreadyExists("macro.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("macro.not_found", "")
+	}
+
+	return nil
+}
+
+// DeleteByID tries to delete a macro from macros table.
+func (r *MacroRepository) DeleteByID(ctx context.Context, id int64) *errors.Type {
+	q := `DELETE FROM macros WHERE id = $1;`
+
+	_, e := r.db.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// Filter tries to load macros of an issuer, including the global ones, ordered by their names. An empty issuer only
+// loads the global ones. If there is another page of result, the second returned value will be true, otherwise false.
+func (r *MacroRepository) Filter(ctx context.Context, issuer string, pageNumber, pageSize int) ([]*Macro, bool,
+	*errors.Type) {
+
+	q := `SELECT id, issuer, name, actions, created_at, modified_at FROM macros WHERE issuer = '' OR issuer = $1
+			ORDER BY name, issuer OFFSET $2 LIMIT $3;`
+
+	rows, e := r.db.Query(ctx, q, issuer, (pageNumber-1)*pageSize, pageSize+1)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, false, et
+	}
+	defer rows.Close()
+
+	macros := make([]*Macro, 0)
+	for rows.Next() {
+		macro, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, false, et
+		}
+
+		macros = append(macros, macro)
+	}
+
+	hasNextPage := len(macros) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		macros = macros[:len(macros)-1]
+	}
+
+	return macros, hasNextPage, nil
+}
+
+func (r *MacroRepository) scan(row pgx.Row) (*Macro, error) {
+	macro := &Macro{}
+	var actions string
+
+	e := row.Scan(&macro.ID, &macro.Issuer, &macro.Name, &actions, &macro.CreatedAt, &macro.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if e := json.Unmarshal([]byte(actions), &macro.Actions); e != nil {
+		return nil, e
+	}
+
+	return macro, nil
+}
+
+// MacroActionType model.
+type MacroActionType string
+
+// Different macro action type instances.
+const (
+	MacroActionTypeSetStatus          MacroActionType = "SET_STATUS"
+	MacroActionTypeSetImportanceLevel MacroActionType = "SET_IMPORTANCE_LEVEL"
+	MacroActionTypeSetSubject         MacroActionType = "SET_SUBJECT"
+	MacroActionTypeAddTag             MacroActionType = "ADD_TAG"
+	MacroActionTypeRemoveTag          MacroActionType = "REMOVE_TAG"
+	MacroActionTypeSetMetadata        MacroActionType = "SET_METADATA"
+	MacroActionTypeAddComment         MacroActionType = "ADD_COMMENT"
+)
@@ -0,0 +1,147 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Macro", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.MacroRepository
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewMacroRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Describe("MacroRepository", func() {
+		Context("When Insert called", func() {
+			It("Should insert a macro record in macros table successfully", func() {
+				macro := models.Macro{
+					Issuer: "Microservice-A",
+					Name:   "resolve",
+					Actions: []models.MacroAction{
+						{Type: models.MacroActionTypeAddTag, Tag: "refund"},
+						{Type: models.MacroActionTypeAddComment, Content: "Your refund is on the way."},
+						{Type: models.MacroActionTypeSetStatus, Status: models.TicketStatusResolved},
+					},
+				}
+
+				e := repository.Insert(context.Background(), macro)
+				Ω(e).Should(BeNil())
+
+				m, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(m.Issuer).Should(Equal(macro.Issuer))
+				Ω(m.Name).Should(Equal(macro.Name))
+				Ω(m.Actions).Should(Equal(macro.Actions))
+			})
+
+			It("Should return error when the name is already used by the issuer", func() {
+				macro := models.Macro{Issuer: "Microservice-A", Name: "resolve", Actions: []models.MacroAction{
+					{Type: models.MacroActionTypeSetStatus, Status: models.TicketStatusResolved},
+				}}
+
+				e := repository.Insert(context.Background(), macro)
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), macro)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+				Ω(e.Errors[0].Code).Should(Equal("macro.already_exists"))
+			})
+		})
+
+		Context("When Update called", func() {
+			It("Should update the name and actions of a macro", func() {
+				e := repository.Insert(context.Background(), models.Macro{Name: "resolve", Actions: []models.MacroAction{
+					{Type: models.MacroActionTypeSetStatus, Status: models.TicketStatusResolved},
+				}})
+				Ω(e).Should(BeNil())
+
+				actions := []models.MacroAction{{Type: models.MacroActionTypeSetStatus, Status: models.TicketStatusClosed}}
+				e = repository.Update(context.Background(), &models.Macro{Model: models.Model{ID: 1}, Name: "close",
+					Actions: actions})
+				Ω(e).Should(BeNil())
+
+				m, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(m.Name).Should(Equal("close"))
+				Ω(m.Actions).Should(Equal(actions))
+			})
+
+			It("Should return not found error when macro does not exist", func() {
+				e := repository.Update(context.Background(), &models.Macro{Model: models.Model{ID: 1}, Name: "close"})
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When DeleteByID called", func() {
+			It("Should delete a macro", func() {
+				e := repository.Insert(context.Background(), models.Macro{Name: "resolve", Actions: []models.MacroAction{
+					{Type: models.MacroActionTypeSetStatus, Status: models.TicketStatusResolved},
+				}})
+				Ω(e).Should(BeNil())
+
+				e = repository.DeleteByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+
+				_, e = repository.LoadByID(context.Background(), 1)
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When Filter called", func() {
+			It("Should load global macros along with the ones of the issuer", func() {
+				actions := []models.MacroAction{{Type: models.MacroActionTypeSetStatus, Status: models.TicketStatusClosed}}
+
+				e := repository.Insert(context.Background(), models.Macro{Name: "close", Actions: actions})
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), models.Macro{Issuer: "Microservice-A", Name: "refund",
+					Actions: actions})
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), models.Macro{Issuer: "Microservice-B", Name: "shipping",
+					Actions: actions})
+				Ω(e).Should(BeNil())
+
+				ms, hasNextPage, e := repository.Filter(context.Background(), "Microservice-A", 1, 10)
+				Ω(e).Should(BeNil())
+				Ω(hasNextPage).Should(BeFalse())
+				Ω(len(ms)).Should(Equal(2))
+				Ω(ms[0].Name).Should(Equal("close"))
+				Ω(ms[1].Name).Should(Equal("refund"))
+			})
+		})
+	})
+})
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Notification is the entity model of notifications table. A notification is a rendered email waiting to be, or
+// already, delivered. Key identifies the change that caused the notification, so every change is notified once.
+type Notification struct {
+	Model
+
+	Key           string
+	TicketID      int64
+	Event         NotificationEvent
+	Recipient     string
+	Locale        string
+	Subject       string
+	TextBody      string
+	HTMLBody      string
+	Status        NotificationStatus
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	SentAt        time.Time
+}
+
+// NotificationRepository is the repository implementation of Notification model.
+type NotificationRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewNotificationRepository returns back a newly created and ready to use NotificationRepository.
+func NewNotificationRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a pending notification into notifications table, to be delivered as soon as possible. If a
+// notification with the same key already exists nothing is changed and false is returned.
+func (r *NotificationRepository) Insert(ctx context.Context, notification Notification) (bool, *errors.Type) {
+	q := `INSERT INTO notifications (key, ticket_id, event, recipient, locale, subject, text_body, html_body, status,
+			attempts, last_error, next_attempt_at, created_at, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,
+			0, '', NOW(), NOW(), NOW()) ON CONFLICT DO NOTHING;`
+
+	command, e := r.db.Exec(ctx, q, notification.Key, notification.TicketID, notification.Event,
+		notification.Recipient, notification.Locale, notification.Subject, notification.TextBody,
+		notification.HTMLBody, NotificationStatusPending)
+	if e != nil {
+		if strings.Contains(e.Error(), "notifications_ticket_id_fkey") {
+			return false, errors.PreconditionFailed("ticket.not_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	return command.RowsAffected() == 1, nil
+}
+
+// Claim tries to lock at most limit pending notifications that are due, counting a delivery attempt for each. The
+// lock is held until the lease duration elapses, so the notifications of a crashed node are retried eventually.
+func (r *NotificationRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Notification,
+	*errors.Type) {
+
+	q := `UPDATE notifications SET attempts = attempts + 1,
+			next_attempt_at = NOW() + $1 * INTERVAL '1 millisecond', modified_at = NOW()
+			WHERE id IN (SELECT id FROM notifications WHERE status = $2 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED) RETURNING id, key, ticket_id, event, recipient,
+			locale, subject, text_body, html_body, status, attempts, last_error, next_attempt_at, sent_at, created_at,
+			modified_at;`
+
+	return r.query(ctx, q, lease.Milliseconds(), NotificationStatusPending, limit)
+}
+
+// MarkSent marks a notification as delivered.
+func (r *NotificationRepository) MarkSent(ctx context.Context, id int64) *errors.Type {
+	q := `UPDATE notifications SET status = $1, last_error = '', sent_at = NOW(), modified_at = NOW() WHERE id = $2;`
+
+	return r.exec(ctx, q, NotificationStatusSent, id)
+}
+
+// Retry records a failed delivery attempt of a notification and schedules the next one after the backoff.
+func (r *NotificationRepository) Retry(ctx context.Context, id int64, lastError string,
+	backoff time.Duration) *errors.Type {
+
+	q := `UPDATE notifications SET last_error = $1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond',
+			modified_at = NOW() WHERE id = $3;`
+
+	return r.exec(ctx, q, lastError, backoff.Milliseconds(), id)
+}
+
+// MarkFailed marks a notification as failed for good, after its last delivery attempt.
+func (r *NotificationRepository) MarkFailed(ctx context.Context, id int64, lastError string) *errors.Type {
+	q := `UPDATE notifications SET status = $1, last_error = $2, modified_at = NOW() WHERE id = $3;`
+
+	return r.exec(ctx, q, NotificationStatusFailed, lastError, id)
+}
+
+// Filter tries to filter the notifications of a ticket, from the newest one. Empty status means all of the statuses.
+// If there is another page of result, the second returned value will be true, otherwise false.
+func (r *NotificationRepository) Filter(ctx context.Context, ticketID int64, status NotificationStatus, pageNumber,
+	pageSize int) ([]*Notification, bool, *errors.Type) {
+
+	q := strings.Builder{}
+	args := make([]interface{}, 0)
+
+	q.WriteString(`SELECT id, key, ticket_id, event, recipient, locale, subject, text_body, html_body, status,
+						attempts, last_error, next_attempt_at, sent_at, created_at, modified_at FROM notifications
+						WHERE ticket_id = $1`)
+	args = append(args, ticketID)
+
+	if status != "" {
+		args = append(args, status)
+		q.WriteString(` AND status = $` + strconv.Itoa(len(args)))
+	}
+
+	args = append(args, (pageNumber-1)*pageSize)
+	q.WriteString(` ORDER BY id DESC OFFSET $` + strconv.Itoa(len(args)))
+
+	args = append(args, pageSize+1)
+	q.WriteString(` LIMIT $` + strconv.Itoa(len(args)) + `;`)
+
+	notifications, e := r.query(ctx, q.String(), args...)
+	if e != nil {
+		return nil, false, e
+	}
+
+	hasNextPage := len(notifications) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		notifications = notifications[:len(notifications)-1]
+	}
+
+	return notifications, hasNextPage, nil
+}
+
+func (r *NotificationRepository) exec(ctx context.Context, q string, args ...interface{}) *errors.Type {
+	command, e := r.db.Exec(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("notification.not_found", "")
+	}
+
+	return nil
+}
+
+func (r *NotificationRepository) query(ctx context.Context, q string, args ...interface{}) ([]*Notification,
+	*errors.Type) {
+
+	rows, e := r.db.Query(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	notifications := make([]*Notification, 0)
+	for rows.Next() {
+		notification, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	if e := rows.Err(); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return notifications, nil
+}
+
+func (r *NotificationRepository) scan(row pgx.Row) (*Notification, error) {
+	notification := &Notification{}
+	var sentAt sql.NullTime
+
+	e := row.Scan(&notification.ID, &notification.Key, &notification.TicketID, &notification.Event,
+		&notification.Recipient, &notification.Locale, &notification.Subject, &notification.TextBody,
+		&notification.HTMLBody, &notification.Status, &notification.Attempts, &notification.LastError,
+		&notification.NextAttemptAt, &sentAt, &notification.CreatedAt, &notification.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if sentAt.Valid {
+		notification.SentAt = sentAt.Time
+	}
+
+	return notification, nil
+}
+
+// NotificationEvent model.
+type NotificationEvent string
+
+// Different notification event instances.
+const (
+	NotificationEventTicketCreated NotificationEvent = "TICKET_CREATED"
+	NotificationEventCommentAdded  NotificationEvent = "COMMENT_ADDED"
+	NotificationEventStatusChanged NotificationEvent = "STATUS_CHANGED"
+)
+
+// NotificationStatus model.
+type NotificationStatus string
+
+// Different notification status instances.
+const (
+	NotificationStatusPending NotificationStatus = "PENDING"
+	NotificationStatusSent    NotificationStatus = "SENT"
+	NotificationStatusFailed  NotificationStatus = "FAILED"
+)
@@ -0,0 +1,166 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Notification", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.NotificationRepository
+	var ticketRepository *models.TicketRepository
+
+	ticket := models.Ticket{
+		Issuer:          "Microservice-A",
+		Owner:           "user@example.com",
+		Subject:         "Technical Problem",
+		Content:         "Hello, i have some issues with REST API Docs!",
+		ImportanceLevel: models.TicketImportanceLevelMedium,
+	}
+
+	notification := models.Notification{
+		Key:       "TICKET_CREATED:1",
+		TicketID:  1,
+		Event:     models.NotificationEventTicketCreated,
+		Recipient: "user@example.com",
+		Locale:    "en",
+		Subject:   "[#1] Technical Problem",
+		TextBody:  "Hello",
+		HTMLBody:  "<p>Hello</p>",
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewNotificationRepository(zap.S(), db)
+			ticketRepository = models.NewTicketRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Context("When Insert called", func() {
+		It("Should insert every notification only once", func() {
+			_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+			Ω(e).Should(BeNil())
+
+			inserted, e := repository.Insert(context.Background(), notification)
+			Ω(e).Should(BeNil())
+			Ω(inserted).Should(BeTrue())
+
+			inserted, e = repository.Insert(context.Background(), notification)
+			Ω(e).Should(BeNil())
+			Ω(inserted).Should(BeFalse())
+
+			ns, hasNextPage, e := repository.Filter(context.Background(), 1, "", 1, 10)
+			Ω(e).Should(BeNil())
+			Ω(hasNextPage).Should(BeFalse())
+			Ω(len(ns)).Should(Equal(1))
+			Ω(ns[0].Status).Should(Equal(models.NotificationStatusPending))
+			Ω(ns[0].Attempts).Should(Equal(0))
+		})
+
+		It("Should return error when the ticket does not exist", func() {
+			_, e := repository.Insert(context.Background(), notification)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("ticket.not_exists"))
+		})
+	})
+
+	Context("When Claim called", func() {
+		It("Should claim due notifications only once until their lease expires", func() {
+			_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+			Ω(e).Should(BeNil())
+
+			_, e = repository.Insert(context.Background(), notification)
+			Ω(e).Should(BeNil())
+
+			ns, e := repository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(len(ns)).Should(Equal(1))
+			Ω(ns[0].Attempts).Should(Equal(1))
+
+			ns, e = repository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(ns).Should(BeEmpty())
+
+			e = repository.Retry(context.Background(), 1, "connection refused", 0)
+			Ω(e).Should(BeNil())
+
+			ns, e = repository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(len(ns)).Should(Equal(1))
+			Ω(ns[0].Attempts).Should(Equal(2))
+			Ω(ns[0].LastError).Should(Equal("connection refused"))
+
+			e = repository.MarkSent(context.Background(), 1)
+			Ω(e).Should(BeNil())
+
+			_, err := db.Exec(context.Background(), `UPDATE notifications SET next_attempt_at = NOW();`)
+			Ω(err).Should(BeNil())
+
+			ns, e = repository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(ns).Should(BeEmpty())
+
+			ns, _, e = repository.Filter(context.Background(), 1, models.NotificationStatusSent, 1, 10)
+			Ω(e).Should(BeNil())
+			Ω(len(ns)).Should(Equal(1))
+			Ω(ns[0].SentAt.IsZero()).Should(BeFalse())
+			Ω(ns[0].LastError).Should(BeEmpty())
+		})
+	})
+
+	Context("When MarkFailed called", func() {
+		It("Should stop retrying the notification", func() {
+			_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+			Ω(e).Should(BeNil())
+
+			_, e = repository.Insert(context.Background(), notification)
+			Ω(e).Should(BeNil())
+
+			e = repository.MarkFailed(context.Background(), 1, "mailbox unavailable")
+			Ω(e).Should(BeNil())
+
+			ns, e := repository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(ns).Should(BeEmpty())
+
+			ns, _, e = repository.Filter(context.Background(), 1, models.NotificationStatusFailed, 1, 10)
+			Ω(e).Should(BeNil())
+			Ω(len(ns)).Should(Equal(1))
+			Ω(ns[0].LastError).Should(Equal("mailbox unavailable"))
+		})
+
+		It("Should return not found error when the notification does not exist", func() {
+			e := repository.MarkFailed(context.Background(), 1, "mailbox unavailable")
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+		})
+	})
+})
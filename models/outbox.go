@@ -0,0 +1,343 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// OutboxMessage is the entity model of outbox table, a domain event written along with the change it announces.
+type OutboxMessage struct {
+	ID             int64
+	EventID        string
+	Type           OutboxMessageType
+	TicketID       int64
+	Actor          Actor
+	PreviousStatus TicketStatus
+	Ticket         *Ticket
+	Comment        *Comment
+	CreatedAt      time.Time
+	PublishedAt    time.Time
+}
+
+// OutboxRepository is the repository implementation of OutboxMessage model. Messages are written by the other
+// repositories and are relayed through this one.
+type OutboxRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewOutboxRepository returns back a newly created and ready to use OutboxRepository.
+func NewOutboxRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{logger: logger, db: db}
+}
+
+// Relay passes at most limit pending messages, in order, to publish and marks the leading ones it reports as published.
+// A single node relays at a time, so the returned value is zero when another node is relaying.
+func (r *OutboxRepository) Relay(ctx context.Context, limit int, publish func([]*OutboxMessage) int) (int,
+	*errors.Type) {
+
+	lockQ := `SELECT pg_try_advisory_xact_lock(hashtext('kiosk.outbox'), 0);`
+
+	q := `SELECT id, event_id, type, ticket_id, actor_type, actor_id, previous_status, ticket, comment, created_at,
+			published_at FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT $1;`
+
+	publishedQ := `UPDATE outbox SET published_at = NOW() WHERE id = ANY($1);`
+
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var locked bool
+	if e := tx.QueryRow(ctx, lockQ).Scan(&locked); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	if !locked {
+		return 0, nil
+	}
+
+	rows, e := tx.Query(ctx, q, limit)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	messages := make([]*OutboxMessage, 0)
+	for rows.Next() {
+		message, e := r.scan(rows)
+		if e != nil {
+			rows.Close()
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return 0, et
+		}
+
+		messages = append(messages, message)
+	}
+	rows.Close()
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	published := publish(messages)
+	if published == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, published)
+	for _, m := range messages[:published] {
+		ids = append(ids, m.ID)
+	}
+
+	if _, e := tx.Exec(ctx, publishedQ, ids); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return published, nil
+}
+
+// Replay loads at most limit messages relayed after the sequence, along with the pending ones, in the order they were
+// relayed. If there are more messages, the second returned value will be true, otherwise false.
+func (r *OutboxRepository) Replay(ctx context.Context, after int64, limit int) ([]*OutboxMessage, bool,
+	*errors.Type) {
+
+	existsQ := `SELECT EXISTS (SELECT 1 FROM outbox WHERE id = $1);`
+
+	q := `SELECT id, event_id, type, ticket_id, actor_type, actor_id, previous_status, ticket, comment, created_at,
+			published_at FROM outbox WHERE id > $1 OR published_at > (SELECT COALESCE(published_at, NOW()) FROM outbox
+			WHERE id = $1) ORDER BY published_at, id LIMIT $2;`
+
+	var exists bool
+	if e := r.db.QueryRow(ctx, existsQ, after).Scan(&exists); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, false, et
+	}
+
+	if !exists {
+		return nil, false, errors.PreconditionFailed("event.expired", "")
+	}
+
+	rows, e := r.db.Query(ctx, q, after, limit+1)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, false, et
+	}
+	defer rows.Close()
+
+	messages := make([]*OutboxMessage, 0)
+	for rows.Next() {
+		message, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, false, et
+		}
+
+		messages = append(messages, message)
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		// Drop the extra one.
+		messages = messages[:len(messages)-1]
+	}
+
+	return messages, hasMore, nil
+}
+
+// Lag returns back the number of pending messages and how long the oldest one of them has been waiting.
+func (r *OutboxRepository) Lag(ctx context.Context) (int64, time.Duration, *errors.Type) {
+	q := `SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)::FLOAT8 FROM outbox
+			WHERE published_at IS NULL;`
+
+	var pending int64
+	var seconds float64
+	if e := r.db.QueryRow(ctx, q).Scan(&pending, &seconds); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, 0, et
+	}
+
+	return pending, time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Purge deletes the messages published more than the retention ago.
+func (r *OutboxRepository) Purge(ctx context.Context, retention time.Duration) *errors.Type {
+	q := `DELETE FROM outbox WHERE published_at < NOW() - $1 * INTERVAL '1 millisecond';`
+
+	if _, e := r.db.Exec(ctx, q, retention.Milliseconds()); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) scan(row pgx.Row) (*OutboxMessage, error) {
+	message := &OutboxMessage{}
+	var ticket, comment sql.NullString
+	var publishedAt sql.NullTime
+
+	e := row.Scan(&message.ID, &message.EventID, &message.Type, &message.TicketID, &message.Actor.Type,
+		&message.Actor.ID, &message.PreviousStatus, &ticket, &comment, &message.CreatedAt, &publishedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if ticket.Valid {
+		message.Ticket = &Ticket{}
+		if e := json.Unmarshal([]byte(ticket.String), message.Ticket); e != nil {
+			return nil, e
+		}
+	}
+
+	if comment.Valid {
+		message.Comment = &Comment{}
+		if e := json.Unmarshal([]byte(comment.String), message.Comment); e != nil {
+			return nil, e
+		}
+	}
+
+	if publishedAt.Valid {
+		message.PublishedAt = publishedAt.Time
+	}
+
+	return message, nil
+}
+
+// writeOutbox writes the messages into outbox table as part of the transaction, locking the outbox of every ticket so
+// its messages are numbered in the order of their commits.
+func writeOutbox(ctx context.Context, tx pgx.Tx, messages ...*OutboxMessage) error {
+	lockQ := `SELECT pg_advisory_xact_lock($1);`
+
+	q := `INSERT INTO outbox (event_id, type, ticket_id, actor_type, actor_id, previous_status, ticket, comment,
+			created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW());`
+
+	locked := make(map[int64]bool)
+	for _, m := range messages {
+		if locked[m.TicketID] {
+			continue
+		}
+
+		if _, e := tx.Exec(ctx, lockQ, m.TicketID); e != nil {
+			return e
+		}
+		locked[m.TicketID] = true
+	}
+
+	for _, m := range messages {
+		var ticket, comment sql.NullString
+		if m.Ticket != nil {
+			snapshot, _ := json.Marshal(m.Ticket)
+			ticket = sql.NullString{String: string(snapshot), Valid: true}
+		}
+		if m.Comment != nil {
+			snapshot, _ := json.Marshal(m.Comment)
+			comment = sql.NullString{String: string(snapshot), Valid: true}
+		}
+
+		_, e := tx.Exec(ctx, q, uuid.New().String(), m.Type, m.TicketID, m.Actor.Type, m.Actor.ID, m.PreviousStatus,
+			ticket, comment)
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// ticketUpdatedMessages returns back the messages announcing the update of the ticket, which is also announced as a
+// status change when the status is not the previous one.
+func ticketUpdatedMessages(ticket *Ticket, previousStatus TicketStatus, actor Actor) []*OutboxMessage {
+	messages := []*OutboxMessage{{Type: OutboxMessageTypeTicketUpdated, TicketID: ticket.ID, Actor: actor,
+		PreviousStatus: previousStatus, Ticket: ticket}}
+
+	if ticket.Status != previousStatus {
+		messages = append(messages, &OutboxMessage{Type: OutboxMessageTypeTicketStatusChanged, TicketID: ticket.ID,
+			Actor: actor, PreviousStatus: previousStatus, Ticket: ticket})
+	}
+
+	return messages
+}
+
+// loadTicketSnapshot loads a ticket, without its comments, as seen by the transaction.
+func loadTicketSnapshot(ctx context.Context, tx pgx.Tx, id int64) (*Ticket, error) {
+	q := `SELECT id, issuer, owner, subject, content, metadata, importance_level, status, created_at, modified_at
+			FROM tickets WHERE id = $1;`
+
+	ticket := &Ticket{}
+	var metadata sql.NullString
+
+	e := tx.QueryRow(ctx, q, id).Scan(&ticket.ID, &ticket.Issuer, &ticket.Owner, &ticket.Subject, &ticket.Content,
+		&metadata, &ticket.ImportanceLevel, &ticket.Status, &ticket.CreatedAt, &ticket.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if metadata.Valid {
+		ticket.Metadata = metadata.String
+	}
+
+	return ticket, nil
+}
+
+// loadCommentSnapshot loads a comment as seen by the transaction.
+func loadCommentSnapshot(ctx context.Context, tx pgx.Tx, id int64) (*Comment, error) {
+	q := `SELECT id, ticket_id, owner, content, metadata, created_at, modified_at FROM comments WHERE id = $1;`
+
+	comment := &Comment{}
+	var metadata sql.NullString
+
+	e := tx.QueryRow(ctx, q, id).Scan(&comment.ID, &comment.TicketID, &comment.Owner, &comment.Content, &metadata,
+		&comment.CreatedAt, &comment.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if metadata.Valid {
+		comment.Metadata = metadata.String
+	}
+
+	return comment, nil
+}
+
+// OutboxMessageType model. It is also the subject of the message without the prefix.
+type OutboxMessageType string
+
+// Different outbox message type instances.
+const (
+	OutboxMessageTypeTicketCreated       OutboxMessageType = "tickets.created"
+	OutboxMessageTypeTicketUpdated       OutboxMessageType = "tickets.updated"
+	OutboxMessageTypeTicketStatusChanged OutboxMessageType = "tickets.status_changed"
+	OutboxMessageTypeTicketDeleted       OutboxMessageType = "tickets.deleted"
+	OutboxMessageTypeCommentCreated      OutboxMessageType = "comments.created"
+	OutboxMessageTypeCommentUpdated      OutboxMessageType = "comments.updated"
+	OutboxMessageTypeCommentDeleted      OutboxMessageType = "comments.deleted"
+)
@@ -0,0 +1,205 @@
+package models_test
+
+import (
+	"context"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Outbox", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.OutboxRepository
+	var ticketRepository *models.TicketRepository
+	var commentRepository *models.CommentRepository
+
+	ticket := models.Ticket{
+		Issuer:          "Microservice-A",
+		Owner:           "user@example.com",
+		Subject:         "Technical Problem",
+		Content:         "Hello, i have some issues with REST API Docs!",
+		Metadata:        `{"ip":"192.168.1.1"}`,
+		ImportanceLevel: models.TicketImportanceLevelMedium,
+	}
+
+	relayAll := func() []*models.OutboxMessage {
+		var messages []*models.OutboxMessage
+		_, e := repository.Relay(context.Background(), 100, func(ms []*models.OutboxMessage) int {
+			messages = ms
+			return len(ms)
+		})
+		Ω(e).Should(BeNil())
+
+		return messages
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewOutboxRepository(zap.S(), db)
+			ticketRepository = models.NewTicketRepository(zap.S(), db)
+			commentRepository = models.NewCommentRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Context("When tickets and comments change", func() {
+		It("Should write the events along with the changes in order", func() {
+			id, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor("agent@example.com"))
+			Ω(e).Should(BeNil())
+
+			commentID, e := commentRepository.Insert(context.Background(), models.Comment{TicketID: id,
+				Owner: "support@example.com", Content: "Fixed the docs!", Metadata: "{}"})
+			Ω(e).Should(BeNil())
+
+			t, e := ticketRepository.LoadByID(context.Background(), id)
+			Ω(e).Should(BeNil())
+			t.Status = models.TicketStatusResolved
+			_, e = ticketRepository.Apply(context.Background(), t, nil, models.UserActor("agent@example.com"), nil)
+			Ω(e).Should(BeNil())
+
+			e = commentRepository.DeleteByID(context.Background(), commentID, models.UserActor("agent@example.com"))
+			Ω(e).Should(BeNil())
+
+			e = ticketRepository.DeleteByID(context.Background(), id, models.UserActor("agent@example.com"), nil)
+			Ω(e).Should(BeNil())
+
+			messages := relayAll()
+			Ω(len(messages)).Should(Equal(6))
+
+			Ω(messages[0].Type).Should(Equal(models.OutboxMessageTypeTicketCreated))
+			Ω(messages[0].EventID).ShouldNot(BeEmpty())
+			Ω(messages[0].Actor).Should(Equal(models.UserActor("agent@example.com")))
+			Ω(messages[0].Ticket.Subject).Should(Equal(ticket.Subject))
+			Ω(messages[0].Ticket.Status).Should(Equal(models.TicketStatusNew))
+
+			Ω(messages[1].Type).Should(Equal(models.OutboxMessageTypeCommentCreated))
+			Ω(messages[1].Actor).Should(Equal(models.UserActor("support@example.com")))
+			Ω(messages[1].Comment.ID).Should(Equal(commentID))
+			Ω(messages[1].Ticket.ID).Should(Equal(id))
+
+			Ω(messages[2].Type).Should(Equal(models.OutboxMessageTypeTicketUpdated))
+			Ω(messages[2].Actor).Should(Equal(models.UserActor("agent@example.com")))
+			Ω(messages[3].Type).Should(Equal(models.OutboxMessageTypeTicketStatusChanged))
+			Ω(messages[3].PreviousStatus).Should(Equal(models.TicketStatusNew))
+			Ω(messages[3].Ticket.Status).Should(Equal(models.TicketStatusResolved))
+
+			Ω(messages[4].Type).Should(Equal(models.OutboxMessageTypeCommentDeleted))
+			Ω(messages[4].Actor).Should(Equal(models.UserActor("agent@example.com")))
+			Ω(messages[4].Comment.Content).Should(Equal("Fixed the docs!"))
+
+			Ω(messages[5].Type).Should(Equal(models.OutboxMessageTypeTicketDeleted))
+			Ω(messages[5].Actor).Should(Equal(models.UserActor("agent@example.com")))
+			Ω(messages[5].Ticket.Status).Should(Equal(models.TicketStatusResolved))
+
+			for i := 1; i < len(messages); i++ {
+				Ω(messages[i].ID).Should(BeNumerically(">", messages[i-1].ID))
+			}
+		})
+
+		It("Should not write any event when the change fails", func() {
+			_, e := commentRepository.Insert(context.Background(), models.Comment{TicketID: 100,
+				Owner: "support@example.com", Content: "Fixed the docs!", Metadata: "{}"})
+			Ω(e).ShouldNot(BeNil())
+
+			_, e = ticketRepository.Apply(context.Background(), &models.Ticket{Model: models.Model{ID: 100},
+				Subject: "Technical Problem", ImportanceLevel: models.TicketImportanceLevelLow,
+				Status: models.TicketStatusClosed}, nil, models.UserActor(""), nil)
+			Ω(e).ShouldNot(BeNil())
+
+			Ω(relayAll()).Should(BeEmpty())
+		})
+	})
+
+	Context("When Relay called", func() {
+		It("Should only mark the published messages and relay the rest later in order", func() {
+			for i := 0; i < 3; i++ {
+				_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+			}
+
+			published, e := repository.Relay(context.Background(), 100, func(ms []*models.OutboxMessage) int {
+				return 1
+			})
+			Ω(e).Should(BeNil())
+			Ω(published).Should(Equal(1))
+
+			pending, _, e := repository.Lag(context.Background())
+			Ω(e).Should(BeNil())
+			Ω(pending).Should(Equal(int64(2)))
+
+			published, e = repository.Relay(context.Background(), 100, func(ms []*models.OutboxMessage) int {
+				return 0
+			})
+			Ω(e).Should(BeNil())
+			Ω(published).Should(Equal(0))
+
+			messages := relayAll()
+			Ω(len(messages)).Should(Equal(2))
+			Ω(messages[0].TicketID).Should(Equal(int64(2)))
+			Ω(messages[1].TicketID).Should(Equal(int64(3)))
+
+			pending, lag, e := repository.Lag(context.Background())
+			Ω(e).Should(BeNil())
+			Ω(pending).Should(Equal(int64(0)))
+			Ω(lag).Should(BeZero())
+
+			Ω(relayAll()).Should(BeEmpty())
+		})
+	})
+
+	Context("When Replay called", func() {
+		It("Should return back the messages after the sequence in the order they were relayed", func() {
+			for i := 0; i < 3; i++ {
+				_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+			}
+
+			first := relayAll()[0]
+
+			_, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+			Ω(e).Should(BeNil())
+
+			messages, hasMore, e := repository.Replay(context.Background(), first.ID, 10)
+			Ω(e).Should(BeNil())
+			Ω(hasMore).Should(BeFalse())
+			Ω(len(messages)).Should(Equal(3))
+			Ω(messages[0].TicketID).Should(Equal(int64(2)))
+			Ω(messages[2].TicketID).Should(Equal(int64(4)))
+			Ω(messages[2].PublishedAt).Should(BeZero())
+
+			messages, hasMore, e = repository.Replay(context.Background(), first.ID, 2)
+			Ω(e).Should(BeNil())
+			Ω(hasMore).Should(BeTrue())
+			Ω(len(messages)).Should(Equal(2))
+		})
+
+		It("Should return error when the sequence is not kept", func() {
+			_, _, e := repository.Replay(context.Background(), 100, 10)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("event.expired"))
+		})
+	})
+})
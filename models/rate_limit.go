@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// RateLimitRepository keeps the token buckets of the rate limits in the database, so the limits hold across the kiosk
+// nodes.
+type RateLimitRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewRateLimitRepository returns back a newly created and ready to use RateLimitRepository.
+func NewRateLimitRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{logger: logger, db: db}
+}
+
+// Take takes a token of the bucket of the key in a single statement, so the concurrent requests are serialized on it.
+// It returns back whether the token is taken and, if not, how long it takes to refill one.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration,
+	*errors.Type) {
+
+	// The tokens left after the refill since the last request.
+	refilled := `LEAST($2::DOUBLE PRECISION, rate_limit_buckets.tokens + $3::DOUBLE PRECISION *
+		GREATEST(0, EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at)::DOUBLE PRECISION))`
+
+	q := `INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+			VALUES ($1, $2::DOUBLE PRECISION - 1, TRUE, NOW()) ON CONFLICT (key) DO UPDATE SET
+			tokens = ` + refilled + ` - CASE WHEN ` + refilled + ` >= 1 THEN 1 ELSE 0 END,
+			allowed = ` + refilled + ` >= 1,
+			updated_at = NOW()
+			RETURNING tokens, allowed;`
+
+	var tokens float64
+	var allowed bool
+	if e := r.db.QueryRow(ctx, q, key, float64(burst), rate).Scan(&tokens, &allowed); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, 0, et
+	}
+
+	if allowed {
+		return true, 0, nil
+	}
+
+	return false, time.Duration((1 - tokens) / rate * float64(time.Second)), nil
+}
+
+// Purge deletes the buckets not used for more than the idle duration. Buckets idle long enough to be refilled are
+// the same as the missing ones.
+func (r *RateLimitRepository) Purge(ctx context.Context, idle time.Duration) *errors.Type {
+	q := `DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - $1 * INTERVAL '1 millisecond';`
+
+	if _, e := r.db.Exec(ctx, q, idle.Milliseconds()); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"context"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("RateLimit", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.RateLimitRepository
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewRateLimitRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Context("When Take called", func() {
+		It("Should take the burst and then wait for the refill", func() {
+			for i := 0; i < 3; i++ {
+				taken, _, e := repository.Take(context.Background(), "kiosk.tickets.create:ISSUER:A", 1, 3)
+				Ω(e).Should(BeNil())
+				Ω(taken).Should(BeTrue(), i)
+			}
+
+			taken, retryAfter, e := repository.Take(context.Background(), "kiosk.tickets.create:ISSUER:A", 1, 3)
+			Ω(e).Should(BeNil())
+			Ω(taken).Should(BeFalse())
+			Ω(retryAfter).Should(BeNumerically(">", 0))
+			Ω(retryAfter).Should(BeNumerically("<=", time.Second))
+
+			taken, _, e = repository.Take(context.Background(), "kiosk.tickets.create:ISSUER:B", 1, 3)
+			Ω(e).Should(BeNil())
+			Ω(taken).Should(BeTrue())
+
+			time.Sleep(retryAfter + 100*time.Millisecond)
+			taken, _, e = repository.Take(context.Background(), "kiosk.tickets.create:ISSUER:A", 1, 3)
+			Ω(e).Should(BeNil())
+			Ω(taken).Should(BeTrue())
+		})
+	})
+
+	Context("When Purge called", func() {
+		It("Should delete the idle buckets only", func() {
+			_, _, e := repository.Take(context.Background(), "kiosk.tickets.create:OWNER:idle", 1, 1)
+			Ω(e).Should(BeNil())
+
+			_, err := db.Exec(context.Background(),
+				`UPDATE rate_limit_buckets SET updated_at = NOW() - INTERVAL '1 hour';`)
+			Ω(err).Should(BeNil())
+
+			_, _, e = repository.Take(context.Background(), "kiosk.tickets.create:OWNER:active", 1, 1)
+			Ω(e).Should(BeNil())
+
+			Ω(repository.Purge(context.Background(), time.Minute)).Should(BeNil())
+
+			var keys []string
+			rows, err := db.Query(context.Background(), `SELECT key FROM rate_limit_buckets;`)
+			Ω(err).Should(BeNil())
+			for rows.Next() {
+				var key string
+				Ω(rows.Scan(&key)).Should(Succeed())
+				keys = append(keys, key)
+			}
+
+			Ω(keys).Should(Equal([]string{"kiosk.tickets.create:OWNER:active"}))
+		})
+	})
+})
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Rule is the entity model of rules table. A rule listens to one type of lifecycle events and when all of its
+// conditions match the ticket of the event, its actions are executed in order.
+type Rule struct {
+	Model
+
+	Name       string
+	Event      EventType
+	Conditions []RuleCondition
+	Actions    []RuleAction
+	Enabled    bool
+}
+
+// RuleCondition compares a field of a ticket, or of the comment of the event, with a value.
+type RuleCondition struct {
+	Field    string                `json:"field"`
+	Operator RuleConditionOperator `json:"operator"`
+	Value    string                `json:"value"`
+}
+
+// RuleAction is a single step of a rule. Only the fields related to the type of the action are used.
+type RuleAction struct {
+	Type             RuleActionType        `json:"type"`
+	Status           TicketStatus          `json:"status,omitempty"`
+	ImportanceLevel  TicketImportanceLevel `json:"importanceLevel,omitempty"`
+	Subject          string                `json:"subject,omitempty"`
+	Tag              string                `json:"tag,omitempty"`
+	Key              string                `json:"key,omitempty"`
+	Value            string                `json:"value,omitempty"`
+	Owner            string                `json:"owner,omitempty"`
+	Content          string                `json:"content,omitempty"`
+	CannedResponseID int64                 `json:"cannedResponseID,omitempty"`
+	Topic            string                `json:"topic,omitempty"`
+	URL              string                `json:"url,omitempty"`
+}
+
+// IsTicketAction reports whether the action changes the ticket itself, in contrast to notifying the outside world.
+func (a RuleAction) IsTicketAction() bool {
+	return a.Type != RuleActionTypePublishMessage && a.Type != RuleActionTypeCallWebhook
+}
+
+// AsMacroAction converts a ticket changing rule action into the equivalent macro action.
+func (a RuleAction) AsMacroAction() MacroAction {
+	return MacroAction{
+		Type:             MacroActionType(a.Type),
+		Status:           a.Status,
+		ImportanceLevel:  a.ImportanceLevel,
+		Subject:          a.Subject,
+		Tag:              a.Tag,
+		Key:              a.Key,
+		Value:            a.Value,
+		Owner:            a.Owner,
+		Content:          a.Content,
+		CannedResponseID: a.CannedResponseID,
+	}
+}
+
+// RuleRepository is the repository implementation of Rule model.
+type RuleRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewRuleRepository returns back a newly created and ready to use RuleRepository.
+func NewRuleRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *RuleRepository {
+	return &RuleRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a rule into rules table.
+func (r *RuleRepository) Insert(ctx context.Context, rule Rule) *errors.Type {
+	q := `INSERT INTO rules (name, event, conditions, actions, enabled, created_at, modified_at) VALUES
+			($1, $2, $3, $4, $5, NOW(), NOW());`
+
+	conditions, _ := json.Marshal(rule.Conditions)
+	actions, _ := json.Marshal(rule.Actions)
+	_, e := r.db.Exec(ctx, q, rule.Name, rule.Event, string(conditions), string(actions), rule.Enabled)
+	if e != nil {
+		if strings.Contains(e.Error(), "rules_name_key") {
+			return errors.AlreadyExists("rule.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// LoadByID tries to load a rule from rules table.
+func (r *RuleRepository) LoadByID(ctx context.Context, id int64) (*Rule, *errors.Type) {
+	q := `SELECT id, name, event, conditions, actions, enabled, created_at, modified_at FROM rules WHERE id = $1;`
+
+	rule, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("rule.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return rule, nil
+}
+
+// Update tries to update a rule record.
+func (r *RuleRepository) Update(ctx context.Context, rule *Rule) *errors.Type {
+	q := `UPDATE rules SET name = $1, event = $2, conditions = $3, actions = $4, enabled = $5, modified_at = NOW()
+			WHERE id = $6;`
+
+	conditions, _ := json.Marshal(rule.Conditions)
+	actions, _ := json.Marshal(rule.Actions)
+	command, e := r.db.Exec(ctx, q, rule.Name, rule.Event, string(conditions), string(actions), rule.Enabled,
+		rule.ID)
+	if e != nil {
+		if strings.Contains(e.Error(), "rules_name_key") {
+			return errors.AlreadyExists("rule.already_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("rule.not_found", "")
+	}
+
+	return nil
+}
+
+// DeleteByID tries to delete a rule and all of its execution logs.
+func (r *RuleRepository) DeleteByID(ctx context.Context, id int64) *errors.Type {
+	q := `DELETE FROM rules WHERE id = $1;`
+
+	_, e := r.db.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// Filter tries to load rules ordered by their names. If there is another page of result, the second returned value
+// will be true, otherwise false.
+func (r *RuleRepository) Filter(ctx context.Context, pageNumber, pageSize int) ([]*Rule, bool, *errors.Type) {
+	q := `SELECT id, name, event, conditions, actions, enabled, created_at, modified_at FROM rules ORDER BY name
+			OFFSET $1 LIMIT $2;`
+
+	rules, e := r.query(ctx, q, (pageNumber-1)*pageSize, pageSize+1)
+	if e != nil {
+		return nil, false, e
+	}
+
+	hasNextPage := len(rules) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		rules = rules[:len(rules)-1]
+	}
+
+	return rules, hasNextPage, nil
+}
+
+// LoadEnabledByEvent tries to load all enabled rules that listen to the event type, in the order of their creation.
+func (r *RuleRepository) LoadEnabledByEvent(ctx context.Context, event EventType) ([]*Rule, *errors.Type) {
+	q := `SELECT id, name, event, conditions, actions, enabled, created_at, modified_at FROM rules
+			WHERE event = $1 AND enabled ORDER BY id;`
+
+	return r.query(ctx, q, event)
+}
+
+func (r *RuleRepository) query(ctx context.Context, q string, args ...interface{}) ([]*Rule, *errors.Type) {
+	rows, e := r.db.Query(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	rules := make([]*Rule, 0)
+	for rows.Next() {
+		rule, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (r *RuleRepository) scan(row pgx.Row) (*Rule, error) {
+	rule := &Rule{}
+	var conditions, actions string
+
+	e := row.Scan(&rule.ID, &rule.Name, &rule.Event, &conditions, &actions, &rule.Enabled, &rule.CreatedAt,
+		&rule.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if e := json.Unmarshal([]byte(conditions), &rule.Conditions); e != nil {
+		return nil, e
+	}
+
+	if e := json.Unmarshal([]byte(actions), &rule.Actions); e != nil {
+		return nil, e
+	}
+
+	return rule, nil
+}
+
+// RuleConditionOperator model.
+type RuleConditionOperator string
+
+// Different rule condition operator instances.
+const (
+	RuleConditionOperatorEquals      RuleConditionOperator = "EQUALS"
+	RuleConditionOperatorNotEquals   RuleConditionOperator = "NOT_EQUALS"
+	RuleConditionOperatorContains    RuleConditionOperator = "CONTAINS"
+	RuleConditionOperatorMatches     RuleConditionOperator = "MATCHES"
+	RuleConditionOperatorGreaterThan RuleConditionOperator = "GREATER_THAN"
+	RuleConditionOperatorLessThan    RuleConditionOperator = "LESS_THAN"
+)
+
+// RuleActionType model.
+type RuleActionType string
+
+// Different rule action type instances. The ticket changing ones share their semantics with the macro actions.
+const (
+	RuleActionTypeSetStatus          RuleActionType = RuleActionType(MacroActionTypeSetStatus)
+	RuleActionTypeSetImportanceLevel RuleActionType = RuleActionType(MacroActionTypeSetImportanceLevel)
+	RuleActionTypeSetSubject         RuleActionType = RuleActionType(MacroActionTypeSetSubject)
+	RuleActionTypeAddTag             RuleActionType = RuleActionType(MacroActionTypeAddTag)
+	RuleActionTypeRemoveTag          RuleActionType = RuleActionType(MacroActionTypeRemoveTag)
+	RuleActionTypeSetMetadata        RuleActionType = RuleActionType(MacroActionTypeSetMetadata)
+	RuleActionTypeAddComment         RuleActionType = RuleActionType(MacroActionTypeAddComment)
+	RuleActionTypePublishMessage     RuleActionType = "PUBLISH_MESSAGE"
+	RuleActionTypeCallWebhook        RuleActionType = "CALL_WEBHOOK"
+)
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// RuleExecution is the entity model of rule_executions table. Every time a rule matches an event, or is skipped by the
+// loop protection, an execution log is recorded.
+type RuleExecution struct {
+	Model
+
+	RuleID   int64
+	TicketID int64
+	Event    EventType
+	Status   RuleExecutionStatus
+	Details  string
+}
+
+// RuleExecutionRepository is the repository implementation of RuleExecution model.
+type RuleExecutionRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewRuleExecutionRepository returns back a newly created and ready to use RuleExecutionRepository.
+func NewRuleExecutionRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *RuleExecutionRepository {
+	return &RuleExecutionRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a rule execution into rule_executions table.
+func (r *RuleExecutionRepository) Insert(ctx context.Context, execution RuleExecution) *errors.Type {
+	q := `INSERT INTO rule_executions (rule_id, ticket_id, event, status, details, created_at, modified_at) VALUES
+			($1, $2, $3, $4, $5, NOW(), NOW());`
+
+	_, e := r.db.Exec(ctx, q, execution.RuleID, execution.TicketID, execution.Event, execution.Status,
+		execution.Details)
+	if e != nil {
+		if strings.Contains(e.Error(), "rule_executions_rule_id_fkey") {
+			return errors.PreconditionFailed("rule.not_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// Filter tries to load the execution logs of a rule, from the newest one. If there is another page of result, the
+// second returned value will be true, otherwise false.
+func (r *RuleExecutionRepository) Filter(ctx context.Context, ruleID int64, pageNumber,
+	pageSize int) ([]*RuleExecution, bool, *errors.Type) {
+
+	q := `SELECT id, rule_id, ticket_id, event, status, details, created_at, modified_at FROM rule_executions
+			WHERE rule_id = $1 ORDER BY id DESC OFFSET $2 LIMIT $3;`
+
+	rows, e := r.db.Query(ctx, q, ruleID, (pageNumber-1)*pageSize, pageSize+1)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, false, et
+	}
+	defer rows.Close()
+
+	executions := make([]*RuleExecution, 0)
+	for rows.Next() {
+		execution := &RuleExecution{}
+
+		e := rows.Scan(&execution.ID, &execution.RuleID, &execution.TicketID, &execution.Event, &execution.Status,
+			&execution.Details, &execution.CreatedAt, &execution.ModifiedAt)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, false, et
+		}
+
+		executions = append(executions, execution)
+	}
+
+	hasNextPage := len(executions) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		executions = executions[:len(executions)-1]
+	}
+
+	return executions, hasNextPage, nil
+}
+
+// RuleExecutionStatus model.
+type RuleExecutionStatus string
+
+// Different rule execution status instances.
+const (
+	RuleExecutionStatusApplied RuleExecutionStatus = "APPLIED"
+	RuleExecutionStatusSkipped RuleExecutionStatus = "SKIPPED"
+	RuleExecutionStatusFailed  RuleExecutionStatus = "FAILED"
+)
@@ -0,0 +1,191 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Rule", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.RuleRepository
+	var executionRepository *models.RuleExecutionRepository
+
+	rule := func(name string, event models.EventType, enabled bool) models.Rule {
+		return models.Rule{
+			Name:  name,
+			Event: event,
+			Conditions: []models.RuleCondition{
+				{Field: "importance_level", Operator: models.RuleConditionOperatorEquals, Value: "CRITICAL"},
+			},
+			Actions: []models.RuleAction{
+				{Type: models.RuleActionTypeSetMetadata, Key: "team", Value: "escalations"},
+				{Type: models.RuleActionTypePublishMessage, Topic: "shop.critical_tickets"},
+			},
+			Enabled: enabled,
+		}
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewRuleRepository(zap.S(), db)
+			executionRepository = models.NewRuleExecutionRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Describe("RuleRepository", func() {
+		Context("When Insert called", func() {
+			It("Should insert a rule record in rules table successfully", func() {
+				r := rule("critical", models.EventTypeTicketCreated, true)
+
+				e := repository.Insert(context.Background(), r)
+				Ω(e).Should(BeNil())
+
+				loaded, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(loaded.Name).Should(Equal(r.Name))
+				Ω(loaded.Event).Should(Equal(r.Event))
+				Ω(loaded.Conditions).Should(Equal(r.Conditions))
+				Ω(loaded.Actions).Should(Equal(r.Actions))
+				Ω(loaded.Enabled).Should(BeTrue())
+			})
+
+			It("Should return error when the name is already used", func() {
+				e := repository.Insert(context.Background(), rule("critical", models.EventTypeTicketCreated, true))
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), rule("critical", models.EventTypeTicketUpdated, true))
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+				Ω(e.Errors[0].Code).Should(Equal("rule.already_exists"))
+			})
+		})
+
+		Context("When Update called", func() {
+			It("Should update a rule", func() {
+				e := repository.Insert(context.Background(), rule("critical", models.EventTypeTicketCreated, true))
+				Ω(e).Should(BeNil())
+
+				r := rule("critical-updates", models.EventTypeTicketUpdated, false)
+				r.ID = 1
+				e = repository.Update(context.Background(), &r)
+				Ω(e).Should(BeNil())
+
+				loaded, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(loaded.Name).Should(Equal("critical-updates"))
+				Ω(loaded.Event).Should(Equal(models.EventTypeTicketUpdated))
+				Ω(loaded.Enabled).Should(BeFalse())
+			})
+
+			It("Should return not found error when rule does not exist", func() {
+				r := rule("critical", models.EventTypeTicketCreated, true)
+				r.ID = 1
+				e := repository.Update(context.Background(), &r)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When LoadEnabledByEvent called", func() {
+			It("Should only load the enabled rules of the event", func() {
+				e := repository.Insert(context.Background(), rule("a", models.EventTypeTicketCreated, true))
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), rule("b", models.EventTypeTicketCreated, false))
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), rule("c", models.EventTypeTicketUpdated, true))
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), rule("d", models.EventTypeTicketCreated, true))
+				Ω(e).Should(BeNil())
+
+				rs, e := repository.LoadEnabledByEvent(context.Background(), models.EventTypeTicketCreated)
+				Ω(e).Should(BeNil())
+				Ω(len(rs)).Should(Equal(2))
+				Ω(rs[0].Name).Should(Equal("a"))
+				Ω(rs[1].Name).Should(Equal("d"))
+			})
+		})
+
+		Context("When DeleteByID called", func() {
+			It("Should delete a rule along with its execution logs", func() {
+				e := repository.Insert(context.Background(), rule("critical", models.EventTypeTicketCreated, true))
+				Ω(e).Should(BeNil())
+
+				e = executionRepository.Insert(context.Background(), models.RuleExecution{RuleID: 1, TicketID: 1,
+					Event: models.EventTypeTicketCreated, Status: models.RuleExecutionStatusApplied})
+				Ω(e).Should(BeNil())
+
+				e = repository.DeleteByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+
+				_, e = repository.LoadByID(context.Background(), 1)
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+
+				es, _, e := executionRepository.Filter(context.Background(), 1, 1, 10)
+				Ω(e).Should(BeNil())
+				Ω(es).Should(BeEmpty())
+			})
+		})
+	})
+
+	Describe("RuleExecutionRepository", func() {
+		Context("When Filter called", func() {
+			It("Should load the execution logs of a rule from the newest one", func() {
+				e := repository.Insert(context.Background(), rule("critical", models.EventTypeTicketCreated, true))
+				Ω(e).Should(BeNil())
+
+				e = executionRepository.Insert(context.Background(), models.RuleExecution{RuleID: 1, TicketID: 1,
+					Event: models.EventTypeTicketCreated, Status: models.RuleExecutionStatusApplied})
+				Ω(e).Should(BeNil())
+
+				e = executionRepository.Insert(context.Background(), models.RuleExecution{RuleID: 1, TicketID: 1,
+					Event: models.EventTypeTicketUpdated, Status: models.RuleExecutionStatusSkipped,
+					Details: "rule.loop_detected"})
+				Ω(e).Should(BeNil())
+
+				es, hasNextPage, e := executionRepository.Filter(context.Background(), 1, 1, 1)
+				Ω(e).Should(BeNil())
+				Ω(hasNextPage).Should(BeTrue())
+				Ω(len(es)).Should(Equal(1))
+				Ω(es[0].Status).Should(Equal(models.RuleExecutionStatusSkipped))
+				Ω(es[0].Details).Should(Equal("rule.loop_detected"))
+			})
+
+			It("Should return error when the rule does not exist", func() {
+				e := executionRepository.Insert(context.Background(), models.RuleExecution{RuleID: 1, TicketID: 1,
+					Event: models.EventTypeTicketCreated, Status: models.RuleExecutionStatusApplied})
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.Errors[0].Code).Should(Equal("rule.not_exists"))
+			})
+		})
+	})
+})
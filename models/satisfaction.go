@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Satisfaction is the entity model of satisfactions table. Issuer and assignee are the ones of the ticket when it was
+// rated.
+type Satisfaction struct {
+	Model
+
+	TicketID int64
+	Issuer   string
+	Assignee string
+	Owner    string
+	Rating   int
+	Comment  string
+}
+
+// SatisfactionSummary is the aggregated ratings of an issuer and assignee pair.
+type SatisfactionSummary struct {
+	Issuer   string
+	Assignee string
+	Count    int64
+	Sum      int64
+
+	// Ratings holds the number of ratings for each score, Ratings[0] is the number of 1 ratings and so on.
+	Ratings [5]int64
+}
+
+// SatisfactionRepository is the repository implementation of Satisfaction model.
+type SatisfactionRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewSatisfactionRepository returns back a newly created and ready to use SatisfactionRepository.
+func NewSatisfactionRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *SatisfactionRepository {
+	return &SatisfactionRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a satisfaction into satisfactions table. Each ticket can be rated only once.
+func (r *SatisfactionRepository) Insert(ctx context.Context, satisfaction Satisfaction) *errors.Type {
+	q := `INSERT INTO satisfactions (ticket_id, issuer, assignee, owner, rating, comment, created_at, modified_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW());`
+
+	_, e := r.db.Exec(ctx, q, satisfaction.TicketID, satisfaction.Issuer, satisfaction.Assignee, satisfaction.Owner,
+		satisfaction.Rating, satisfaction.Comment)
+	if e != nil {
+		if strings.Contains(e.Error(), "satisfactions_ticket_id_key") {
+			return errors.AlreadyExists("satisfaction.already_exists", "")
+		}
+
+		if strings.Contains(e.Error(), "satisfactions_ticket_id_fkey") {
+			return errors.PreconditionFailed("ticket.not_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// Report tries to aggregate the ratings created in [fromDate, toDate) by issuer and assignee. Empty issuer means all
+// of the issuers.
+func (r *SatisfactionRepository) Report(ctx context.Context, issuer string, fromDate,
+	toDate time.Time) ([]*SatisfactionSummary, *errors.Type) {
+
+	q := strings.Builder{}
+	args := make([]interface{}, 0)
+
+	q.WriteString(`SELECT issuer, assignee, COUNT(*), SUM(rating),
+						COUNT(*) FILTER (WHERE rating = 1), COUNT(*) FILTER (WHERE rating = 2),
+						COUNT(*) FILTER (WHERE rating = 3), COUNT(*) FILTER (WHERE rating = 4),
+						COUNT(*) FILTER (WHERE rating = 5)
+						FROM satisfactions WHERE created_at >= $1 AND created_at < $2`)
+	args = append(args, fromDate, toDate)
+
+	if issuer != "" {
+		q.WriteString(` AND issuer = $` + strconv.Itoa(len(args)+1))
+		args = append(args, issuer)
+	}
+
+	q.WriteString(` GROUP BY issuer, assignee ORDER BY issuer, assignee;`)
+
+	rows, e := r.db.Query(ctx, q.String(), args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	summaries := make([]*SatisfactionSummary, 0)
+	for rows.Next() {
+		s := &SatisfactionSummary{}
+
+		e := rows.Scan(&s.Issuer, &s.Assignee, &s.Count, &s.Sum, &s.Ratings[0], &s.Ratings[1], &s.Ratings[2],
+			&s.Ratings[3], &s.Ratings[4])
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		summaries = append(summaries, s)
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,135 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Satisfaction", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.SatisfactionRepository
+	var ticketRepository *models.TicketRepository
+
+	ticket := models.Ticket{
+		Issuer:          "Microservice-A",
+		Owner:           "user@example.com",
+		Subject:         "Technical Problem",
+		Content:         "Hello, i have some issues with REST API Docs!",
+		Metadata:        `{"assignee":"tier-1"}`,
+		ImportanceLevel: models.TicketImportanceLevelMedium,
+	}
+
+	satisfaction := func(ticketID int64, assignee string, rating int) models.Satisfaction {
+		return models.Satisfaction{TicketID: ticketID, Issuer: ticket.Issuer, Assignee: assignee, Owner: ticket.Owner,
+			Rating: rating, Comment: "Thanks!"}
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewSatisfactionRepository(zap.S(), db)
+			ticketRepository = models.NewTicketRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Context("When Insert called", func() {
+		It("Should insert a satisfaction and load it along with the ticket", func() {
+			id, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+			Ω(e).Should(BeNil())
+
+			e = repository.Insert(context.Background(), satisfaction(id, "tier-1", 5))
+			Ω(e).Should(BeNil())
+
+			t, e := ticketRepository.LoadByID(context.Background(), id)
+			Ω(e).Should(BeNil())
+			Ω(t.Satisfaction).ShouldNot(BeNil())
+			Ω(t.Satisfaction.Rating).Should(Equal(5))
+			Ω(t.Satisfaction.Comment).Should(Equal("Thanks!"))
+
+			ts, _, e := ticketRepository.Filter(context.Background(), "", "", "", "", "2000-01-01T00:00:00Z",
+				time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano), 1, 10)
+			Ω(e).Should(BeNil())
+			Ω(len(ts)).Should(Equal(1))
+			Ω(ts[0].Satisfaction).ShouldNot(BeNil())
+			Ω(ts[0].Satisfaction.Assignee).Should(Equal("tier-1"))
+		})
+
+		It("Should return error when the ticket is already rated", func() {
+			id, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+			Ω(e).Should(BeNil())
+
+			e = repository.Insert(context.Background(), satisfaction(id, "tier-1", 5))
+			Ω(e).Should(BeNil())
+
+			e = repository.Insert(context.Background(), satisfaction(id, "tier-1", 1))
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+			Ω(e.Errors[0].Code).Should(Equal("satisfaction.already_exists"))
+		})
+
+		It("Should return error when the ticket does not exist", func() {
+			e := repository.Insert(context.Background(), satisfaction(1, "tier-1", 5))
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("ticket.not_exists"))
+		})
+	})
+
+	Context("When Report called", func() {
+		It("Should aggregate the ratings by issuer and assignee", func() {
+			for _, r := range []struct {
+				assignee string
+				rating   int
+			}{{"tier-1", 5}, {"tier-1", 4}, {"tier-1", 1}, {"tier-2", 3}} {
+				id, e := ticketRepository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+
+				e = repository.Insert(context.Background(), satisfaction(id, r.assignee, r.rating))
+				Ω(e).Should(BeNil())
+			}
+
+			from := time.Now().UTC().Add(-time.Hour)
+			to := time.Now().UTC().Add(time.Hour)
+
+			ss, e := repository.Report(context.Background(), ticket.Issuer, from, to)
+			Ω(e).Should(BeNil())
+			Ω(len(ss)).Should(Equal(2))
+			Ω(ss[0].Assignee).Should(Equal("tier-1"))
+			Ω(ss[0].Count).Should(Equal(int64(3)))
+			Ω(ss[0].Sum).Should(Equal(int64(10)))
+			Ω(ss[0].Ratings).Should(Equal([5]int64{1, 0, 0, 1, 1}))
+			Ω(ss[1].Assignee).Should(Equal("tier-2"))
+			Ω(ss[1].Count).Should(Equal(int64(1)))
+
+			ss, e = repository.Report(context.Background(), "Microservice-B", from, to)
+			Ω(e).Should(BeNil())
+			Ω(ss).Should(BeEmpty())
+		})
+	})
+})
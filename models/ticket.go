@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -24,6 +25,7 @@ type Ticket struct {
 	ImportanceLevel TicketImportanceLevel
 	Status          TicketStatus
 	Comments        []*Comment
+	Satisfaction    *Satisfaction
 }
 
 // TicketRepository is the repository implementation of Ticket model.
@@ -37,23 +39,49 @@ func NewTicketRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *TicketRep
 	return &TicketRepository{logger: logger, db: db}
 }
 
-// Insert tries to insert a ticket into tickets table.
-func (r *TicketRepository) Insert(ctx context.Context, ticket Ticket) *errors.Type {
+// Insert tries to insert a ticket into tickets table and returns back the id of the inserted record. The creation is
+// announced through the outbox by the actor.
+func (r *TicketRepository) Insert(ctx context.Context, ticket Ticket, actor Actor) (int64, *errors.Type) {
 	q := `INSERT INTO tickets (issuer, owner, subject, content, metadata, importance_level, status, created_at,
-			modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW());`
+			modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW()) RETURNING id, status, created_at,
+			modified_at;`
 
-	_, e := r.db.Exec(ctx, q, ticket.Issuer, ticket.Owner, ticket.Subject, ticket.Content, ticket.Metadata,
-		ticket.ImportanceLevel, TicketStatusNew)
+	tx, e := r.db.Begin(ctx)
 	if e != nil {
 		et := errors.InternalServerError("unknown", "")
 		r.logger.Error(et.FingerPrint, ": ", e.Error())
-		return et
+		return 0, et
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	return nil
+	e = tx.QueryRow(ctx, q, ticket.Issuer, ticket.Owner, ticket.Subject, ticket.Content, ticket.Metadata,
+		ticket.ImportanceLevel, TicketStatusNew).Scan(&ticket.ID, &ticket.Status, &ticket.CreatedAt, &ticket.ModifiedAt)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	ticket.Comments = nil
+	ticket.Satisfaction = nil
+	e = writeOutbox(ctx, tx, &OutboxMessage{Type: OutboxMessageTypeTicketCreated, TicketID: ticket.ID,
+		Actor: actor, Ticket: &ticket})
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return 0, et
+	}
+
+	return ticket.ID, nil
 }
 
-// LoadByID tries to load a ticket and its comments from tickets table.
+// LoadByID tries to load a ticket, its comments and its satisfaction rating from tickets table.
 func (r *TicketRepository) LoadByID(ctx context.Context, id int64) (*Ticket, *errors.Type) {
 	q := `SELECT id, issuer, owner, subject, content, metadata, importance_level, status, created_at, modified_at
 			FROM tickets WHERE id = $1;`
@@ -61,9 +89,13 @@ func (r *TicketRepository) LoadByID(ctx context.Context, id int64) (*Ticket, *er
 	commentsQ := `SELECT id, ticket_id, owner, content, metadata, created_at, modified_at FROM comments WHERE
 					ticket_id = $1 ORDER BY created_at DESC;`
 
+	satisfactionQ := `SELECT id, ticket_id, issuer, assignee, owner, rating, comment, created_at, modified_at FROM
+						satisfactions WHERE ticket_id = $1;`
+
 	batch := &pgx.Batch{}
 	batch.Queue(q, id)
 	batch.Queue(commentsQ, id)
+	batch.Queue(satisfactionQ, id)
 
 	results := r.db.SendBatch(ctx, batch)
 	defer func() { _ = results.Close() }()
@@ -114,45 +146,170 @@ func (r *TicketRepository) LoadByID(ctx context.Context, id int64) (*Ticket, *er
 
 		ticket.Comments = append(ticket.Comments, comment)
 	}
+	rows.Close()
+
+	satisfaction := &Satisfaction{}
+	row = results.QueryRow()
+	e = row.Scan(&satisfaction.ID, &satisfaction.TicketID, &satisfaction.Issuer, &satisfaction.Assignee,
+		&satisfaction.Owner, &satisfaction.Rating, &satisfaction.Comment, &satisfaction.CreatedAt,
+		&satisfaction.ModifiedAt)
+	if e != nil && e != pgx.ErrNoRows {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	if e == nil {
+		ticket.Satisfaction = satisfaction
+	}
 
 	return ticket, nil
 }
 
-// Update tries to update a ticket record.
-func (r *TicketRepository) Update(ctx context.Context, ticket *Ticket) *errors.Type {
+// Apply tries to update a ticket record and add the comments to it in a single transaction, and returns back the status
+// of the ticket before the update. The check runs on the locked ticket, so it may complete the ticket from it.
+func (r *TicketRepository) Apply(ctx context.Context, ticket *Ticket, comments []*Comment, actor Actor,
+	check func(*Ticket) *errors.Type) (TicketStatus, *errors.Type) {
+
+	lockQ := `SELECT issuer, owner, subject, metadata, importance_level, status FROM tickets WHERE id = $1 FOR UPDATE;`
+
 	q := `UPDATE tickets SET subject = $1, metadata = $2, importance_level = $3, status = $4, modified_at = NOW()
 			WHERE id = $5;`
 
-	command, e := r.db.Exec(ctx, q, ticket.Subject, ticket.Metadata, ticket.ImportanceLevel, ticket.Status, ticket.ID)
+	commentQ := `INSERT INTO comments (ticket_id, owner, content, metadata, created_at, modified_at) VALUES
+					($1, $2, $3, $4, NOW(), NOW()) RETURNING id, created_at, modified_at;`
+
+	tx, e := r.db.Begin(ctx)
 	if e != nil {
 		et := errors.InternalServerError("unknown", "")
 		r.logger.Error(et.FingerPrint, ": ", e.Error())
-		return et
+		return "", et
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	if command.RowsAffected() == 0 {
-		et := errors.PreconditionFailed("ticket.not_found", "")
-		return et
+	locked := &Ticket{Model: Model{ID: ticket.ID}}
+	var metadata sql.NullString
+
+	e = tx.QueryRow(ctx, lockQ, ticket.ID).Scan(&locked.Issuer, &locked.Owner, &locked.Subject, &metadata,
+		&locked.ImportanceLevel, &locked.Status)
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return "", errors.PreconditionFailed("ticket.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return "", et
 	}
 
-	return nil
+	if metadata.Valid {
+		locked.Metadata = metadata.String
+	}
+
+	if check != nil {
+		if e := check(locked); e != nil {
+			return "", e
+		}
+	}
+
+	if _, e := tx.Exec(ctx, q, ticket.Subject, ticket.Metadata, ticket.ImportanceLevel, ticket.Status,
+		ticket.ID); e != nil {
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return "", et
+	}
+
+	snapshot, e := loadTicketSnapshot(ctx, tx, ticket.ID)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return "", et
+	}
+	messages := ticketUpdatedMessages(snapshot, locked.Status, actor)
+
+	for _, c := range comments {
+		comment := &Comment{TicketID: ticket.ID, Owner: c.Owner, Content: c.Content, Metadata: c.Metadata}
+		e := tx.QueryRow(ctx, commentQ, comment.TicketID, comment.Owner, comment.Content, comment.Metadata).
+			Scan(&comment.ID, &comment.CreatedAt, &comment.ModifiedAt)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return "", et
+		}
+
+		messages = append(messages, &OutboxMessage{Type: OutboxMessageTypeCommentCreated, TicketID: ticket.ID,
+			Actor: actor, Ticket: snapshot, Comment: comment})
+	}
+
+	if e := writeOutbox(ctx, tx, messages...); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return "", et
+	}
+
+	if e := tx.Commit(ctx); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return "", et
+	}
+
+	return locked.Status, nil
 }
 
-// DeleteByID tries to delete a ticket and all of its comments.
-func (r *TicketRepository) DeleteByID(ctx context.Context, id int64) *errors.Type {
-	begin := `BEGIN;`
+// DeleteByID tries to delete a ticket and all of its comments. The check, if any, is run on the ticket and its failure
+// aborts the deletion.
+func (r *TicketRepository) DeleteByID(ctx context.Context, id int64, actor Actor,
+	check func(*Ticket) *errors.Type) *errors.Type {
+
 	commentsQ := `DELETE FROM comments WHERE ticket_id=$1;`
 	q := `DELETE FROM tickets WHERE id=$1;`
-	commit := `COMMIT;`
 
-	batch := &pgx.Batch{}
-	batch.Queue(begin)
-	batch.Queue(commentsQ, id)
-	batch.Queue(q, id)
-	batch.Queue(commit)
+	tx, e := r.db.Begin(ctx)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	results := r.db.SendBatch(ctx, batch)
-	if e := results.Close(); e != nil {
+	snapshot, e := loadTicketSnapshot(ctx, tx, id)
+	if e != nil && e != pgx.ErrNoRows {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if check != nil && snapshot != nil {
+		if e := check(snapshot); e != nil {
+			return e
+		}
+	}
+
+	if _, e := tx.Exec(ctx, commentsQ, id); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	command, e := tx.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() > 0 && snapshot != nil {
+		e := writeOutbox(ctx, tx, &OutboxMessage{Type: OutboxMessageTypeTicketDeleted, TicketID: id,
+			Actor: actor, Ticket: snapshot})
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return et
+		}
+	}
+
+	if e := tx.Commit(ctx); e != nil {
 		et := errors.InternalServerError("unknown", "")
 		r.logger.Error(et.FingerPrint, ": ", e.Error())
 		return et
@@ -231,11 +388,89 @@ func (r *TicketRepository) Filter(ctx context.Context, issuer, owner string, imp
 
 			ticketsMap[comment.TicketID].Comments = append(ticketsMap[comment.TicketID].Comments, comment)
 		}
+
+		if e := r.loadSatisfactions(ctx, tickets, ticketsMap); e != nil {
+			return nil, false, e
+		}
 	}
 
 	return tickets, hasNextPage, nil
 }
 
+// FilterAfter tries to load at most limit tickets, with their comments, whose identifiers are greater than afterID in
+// ascending order of identifiers. Empty criteria values are ignored.
+func (r *TicketRepository) FilterAfter(ctx context.Context, issuer, owner string,
+	importanceLevel TicketImportanceLevel, status TicketStatus, fromDate, toDate time.Time, afterID int64,
+	limit int) ([]*Ticket, *errors.Type) {
+
+	q, args := r.buildFilterAfterQuery(issuer, owner, importanceLevel, status, fromDate, toDate, afterID, limit)
+	rows, e := r.db.Query(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	tickets := make([]*Ticket, 0)
+	ticketsMap := make(map[int64]*Ticket)
+	for rows.Next() {
+		ticket := &Ticket{}
+		var metadata sql.NullString
+
+		e := rows.Scan(&ticket.ID, &ticket.Issuer, &ticket.Owner, &ticket.Subject, &ticket.Content, &metadata,
+			&ticket.ImportanceLevel, &ticket.Status, &ticket.CreatedAt, &ticket.ModifiedAt)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		if metadata.Valid {
+			ticket.Metadata = metadata.String
+		}
+
+		tickets = append(tickets, ticket)
+		ticketsMap[ticket.ID] = ticket
+	}
+
+	if len(tickets) > 0 {
+		q, args = r.buildLoadCommentsQuery(tickets)
+		rows, e = r.db.Query(ctx, q, args...)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			comment := &Comment{}
+			var metadata sql.NullString
+
+			e := rows.Scan(&comment.ID, &comment.TicketID, &comment.Owner, &comment.Content, &metadata,
+				&comment.CreatedAt, &comment.ModifiedAt)
+			if e != nil {
+				et := errors.InternalServerError("unknown", "")
+				r.logger.Error(et.FingerPrint, ": ", e.Error())
+				return nil, et
+			}
+
+			if metadata.Valid {
+				comment.Metadata = metadata.String
+			}
+
+			ticketsMap[comment.TicketID].Comments = append(ticketsMap[comment.TicketID].Comments, comment)
+		}
+
+		if e := r.loadSatisfactions(ctx, tickets, ticketsMap); e != nil {
+			return nil, e
+		}
+	}
+
+	return tickets, nil
+}
+
 // TicketImportanceLevel model.
 type TicketImportanceLevel string
 
@@ -338,3 +573,108 @@ func (r *TicketRepository) buildLoadCommentsQuery(tickets []*Ticket) (string, []
 
 	return q.String(), args
 }
+
+func (r *TicketRepository) loadSatisfactions(ctx context.Context, tickets []*Ticket,
+	ticketsMap map[int64]*Ticket) *errors.Type {
+
+	q, args := r.buildLoadSatisfactionsQuery(tickets)
+	rows, e := r.db.Query(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		satisfaction := &Satisfaction{}
+
+		e := rows.Scan(&satisfaction.ID, &satisfaction.TicketID, &satisfaction.Issuer, &satisfaction.Assignee,
+			&satisfaction.Owner, &satisfaction.Rating, &satisfaction.Comment, &satisfaction.CreatedAt,
+			&satisfaction.ModifiedAt)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return et
+		}
+
+		ticketsMap[satisfaction.TicketID].Satisfaction = satisfaction
+	}
+
+	return nil
+}
+
+func (r *TicketRepository) buildLoadSatisfactionsQuery(tickets []*Ticket) (string, []interface{}) {
+	q := strings.Builder{}
+	args := make([]interface{}, 0)
+
+	q.WriteString(`SELECT id, ticket_id, issuer, assignee, owner, rating, comment, created_at, modified_at FROM
+						satisfactions WHERE ticket_id IN (`)
+
+	for i, t := range tickets {
+		if i > 0 {
+			q.WriteString(`, `)
+		}
+		q.WriteString(`$`)
+		q.WriteString(strconv.Itoa(i + 1))
+
+		args = append(args, t.ID)
+	}
+
+	q.WriteString(`);`)
+
+	return q.String(), args
+}
+
+func (r *TicketRepository) buildFilterAfterQuery(issuer, owner string, importanceLevel TicketImportanceLevel,
+	status TicketStatus, fromDate, toDate time.Time, afterID int64, limit int) (string, []interface{}) {
+
+	args := make([]interface{}, 0)
+	q := strings.Builder{}
+
+	q.WriteString(`SELECT id, issuer, owner, subject, content, metadata, importance_level, status, created_at,
+						modified_at FROM tickets WHERE`)
+
+	counter := 0
+	counter++
+	q.WriteString(` id > $` + strconv.Itoa(counter))
+	args = append(args, afterID)
+
+	counter++
+	q.WriteString(` AND modified_at >= $` + strconv.Itoa(counter))
+	args = append(args, fromDate)
+
+	counter++
+	q.WriteString(` AND modified_at < $` + strconv.Itoa(counter))
+	args = append(args, toDate)
+
+	if issuer != "" {
+		counter++
+		q.WriteString(` AND issuer = $` + strconv.Itoa(counter))
+		args = append(args, issuer)
+	}
+
+	if owner != "" {
+		counter++
+		q.WriteString(` AND owner = $` + strconv.Itoa(counter))
+		args = append(args, owner)
+	}
+
+	if importanceLevel != "" {
+		counter++
+		q.WriteString(` AND importance_level = $` + strconv.Itoa(counter))
+		args = append(args, importanceLevel)
+	}
+
+	if status != "" {
+		counter++
+		q.WriteString(` AND status = $` + strconv.Itoa(counter))
+		args = append(args, status)
+	}
+
+	counter++
+	q.WriteString(` ORDER BY id ASC LIMIT $` + strconv.Itoa(counter))
+	args = append(args, limit)
+
+	return q.String(), args
+}
@@ -8,6 +8,7 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
 	"github.com/jibitters/kiosk/models"
 	"github.com/jibitters/kiosk/test"
 	"github.com/jibitters/kiosk/test/containers"
@@ -57,7 +58,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket)
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 			})
 		})
@@ -73,7 +74,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket)
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
@@ -100,7 +101,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket)
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				comment := models.Comment{
@@ -110,7 +111,7 @@ var _ = Describe("Ticket", func() {
 					Metadata: `{"ip":"192.168.1.11"}`,
 				}
 
-				e = commentRepository.Insert(context.Background(), comment)
+				_, e = commentRepository.Insert(context.Background(), comment)
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
@@ -145,7 +146,7 @@ var _ = Describe("Ticket", func() {
 			})
 		})
 
-		Context("When Update called", func() {
+		Context("When Apply called without comments", func() {
 			It("Should update a ticket successfully", func() {
 				ticket := models.Ticket{
 					Issuer:          "Microservice-A",
@@ -156,7 +157,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket)
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
@@ -167,8 +168,9 @@ var _ = Describe("Ticket", func() {
 				t.ImportanceLevel = models.TicketImportanceLevelHigh
 				t.Status = models.TicketStatusClosed
 
-				e = repository.Update(context.Background(), t)
+				previousStatus, e := repository.Apply(context.Background(), t, nil, models.UserActor(""), nil)
 				Ω(e).Should(BeNil())
+				Ω(previousStatus).Should(Equal(models.TicketStatusNew))
 
 				t, e = repository.LoadByID(context.Background(), 1)
 				Ω(e).Should(BeNil())
@@ -188,14 +190,14 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket)
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
 				Ω(e).Should(BeNil())
 				t.ID = 100
 
-				e = repository.Update(context.Background(), t)
+				_, e = repository.Apply(context.Background(), t, nil, models.UserActor(""), nil)
 				Ω(e).ShouldNot(BeNil())
 				Ω(e.FingerPrint).ShouldNot(BeNil())
 				Ω(e.Errors[0].Code).Should(Equal("ticket.not_found"))
@@ -204,6 +206,137 @@ var _ = Describe("Ticket", func() {
 			})
 		})
 
+		Context("When Apply called", func() {
+			It("Should update a ticket and add the comments to it successfully", func() {
+				ticket := models.Ticket{
+					Issuer:          "Microservice-A",
+					Owner:           "user@example.com",
+					Subject:         "Technical Problem",
+					Content:         "Hello, i have some issues with REST API Docs!",
+					Metadata:        `{"ip":"192.168.1.1"}`,
+					ImportanceLevel: models.TicketImportanceLevelMedium,
+				}
+
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+
+				t, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+
+				t.Status = models.TicketStatusResolved
+				comments := []*models.Comment{
+					{Owner: "support@example.com", Content: "Fixed the docs!", Metadata: "{}"},
+					{Owner: "support@example.com", Content: "Please check again.", Metadata: "{}"},
+				}
+
+				_, e = repository.Apply(context.Background(), t, comments, models.UserActor("support@example.com"), nil)
+				Ω(e).Should(BeNil())
+
+				t, e = repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(t.Status).Should(Equal(models.TicketStatusResolved))
+				Ω(len(t.Comments)).Should(Equal(2))
+			})
+
+			It("Should not add any comment when provided id does not exists", func() {
+				ticket := models.Ticket{
+					Issuer:          "Microservice-A",
+					Owner:           "user@example.com",
+					Subject:         "Technical Problem",
+					Content:         "Hello, i have some issues with REST API Docs!",
+					Metadata:        `{"ip":"192.168.1.1"}`,
+					ImportanceLevel: models.TicketImportanceLevelMedium,
+				}
+
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+
+				t, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				t.ID = 100
+
+				_, e = repository.Apply(context.Background(), t, []*models.Comment{{Owner: "support@example.com",
+					Content: "Fixed the docs!", Metadata: "{}"}}, models.UserActor("support@example.com"), nil)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.Errors[0].Code).Should(Equal("ticket.not_found"))
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusPreconditionFailed))
+
+				t, e = repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(t.Comments).Should(BeEmpty())
+			})
+
+			It("Should not change the ticket when the check on the locked ticket fails", func() {
+				ticket := models.Ticket{
+					Issuer:          "Microservice-A",
+					Owner:           "user@example.com",
+					Subject:         "Technical Problem",
+					Content:         "Hello, i have some issues with REST API Docs!",
+					Metadata:        `{"ip":"192.168.1.1"}`,
+					ImportanceLevel: models.TicketImportanceLevelMedium,
+				}
+
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+
+				t, e := repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				t.Status = models.TicketStatusClosed
+
+				var checked *models.Ticket
+				check := func(locked *models.Ticket) *errors.Type {
+					checked = locked
+					return errors.Forbidden("")
+				}
+
+				_, e = repository.Apply(context.Background(), t, nil, models.UserActor(""), check)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusForbidden))
+				Ω(checked.Issuer).Should(Equal("Microservice-A"))
+				Ω(checked.Owner).Should(Equal("user@example.com"))
+				Ω(checked.Status).Should(Equal(models.TicketStatusNew))
+
+				e = repository.DeleteByID(context.Background(), 1, models.UserActor(""), check)
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.HTTPStatusCode).Should(Equal(http.StatusForbidden))
+
+				t, e = repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(t.Status).Should(Equal(models.TicketStatusNew))
+			})
+
+			It("Should let the check complete the ticket from the locked one", func() {
+				ticket := models.Ticket{
+					Issuer:          "Microservice-A",
+					Owner:           "user@example.com",
+					Subject:         "Technical Problem",
+					Content:         "Hello, i have some issues with REST API Docs!",
+					Metadata:        `{"ip":"192.168.1.1"}`,
+					ImportanceLevel: models.TicketImportanceLevelMedium,
+				}
+
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
+				Ω(e).Should(BeNil())
+
+				t := &models.Ticket{Model: models.Model{ID: 1}}
+				check := func(locked *models.Ticket) *errors.Type {
+					*t = *locked
+					t.Status = models.TicketStatusResolved
+					return nil
+				}
+
+				_, e = repository.Apply(context.Background(), t, nil, models.UserActor(""), check)
+				Ω(e).Should(BeNil())
+
+				t, e = repository.LoadByID(context.Background(), 1)
+				Ω(e).Should(BeNil())
+				Ω(t.Subject).Should(Equal("Technical Problem"))
+				Ω(t.Metadata).Should(Equal(`{"ip":"192.168.1.1"}`))
+				Ω(t.ImportanceLevel).Should(Equal(models.TicketImportanceLevelMedium))
+				Ω(t.Status).Should(Equal(models.TicketStatusResolved))
+			})
+		})
+
 		Context("When DeleteByID called", func() {
 			It("Should delete a ticket record from tickets table successfully", func() {
 				ticket := models.Ticket{
@@ -215,10 +348,10 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket)
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
-				e = repository.DeleteByID(context.Background(), 1)
+				e = repository.DeleteByID(context.Background(), 1, models.UserActor(""), nil)
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
@@ -240,7 +373,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket)
+				_, e := repository.Insert(context.Background(), ticket, models.UserActor(ticket.Owner))
 				Ω(e).Should(BeNil())
 
 				comment := models.Comment{
@@ -250,10 +383,10 @@ var _ = Describe("Ticket", func() {
 					Metadata: `{"ip":"192.168.1.11"}`,
 				}
 
-				e = commentRepository.Insert(context.Background(), comment)
+				_, e = commentRepository.Insert(context.Background(), comment)
 				Ω(e).Should(BeNil())
 
-				e = repository.DeleteByID(context.Background(), 1)
+				e = repository.DeleteByID(context.Background(), 1, models.UserActor(""), nil)
 				Ω(e).Should(BeNil())
 
 				t, e := repository.LoadByID(context.Background(), 1)
@@ -285,7 +418,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket1)
+				_, e := repository.Insert(context.Background(), ticket1, models.UserActor(ticket1.Owner))
 				Ω(e).Should(BeNil())
 
 				comment1 := models.Comment{
@@ -295,7 +428,7 @@ var _ = Describe("Ticket", func() {
 					Metadata: `{"ip":"192.168.1.11"}`,
 				}
 
-				e = commentRepository.Insert(context.Background(), comment1)
+				_, e = commentRepository.Insert(context.Background(), comment1)
 				Ω(e).Should(BeNil())
 
 				comment2 := models.Comment{
@@ -305,7 +438,7 @@ var _ = Describe("Ticket", func() {
 					Metadata: `{"ip":"192.168.1.1"}`,
 				}
 
-				e = commentRepository.Insert(context.Background(), comment2)
+				_, e = commentRepository.Insert(context.Background(), comment2)
 				Ω(e).Should(BeNil())
 
 				ticket2 := models.Ticket{
@@ -317,7 +450,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelLow,
 				}
 
-				e = repository.Insert(context.Background(), ticket2)
+				_, e = repository.Insert(context.Background(), ticket2, models.UserActor(ticket2.Owner))
 				Ω(e).Should(BeNil())
 
 				comment3 := models.Comment{
@@ -327,7 +460,7 @@ var _ = Describe("Ticket", func() {
 					Metadata: `{"ip":"192.168.1.11"}`,
 				}
 
-				e = commentRepository.Insert(context.Background(), comment3)
+				_, e = commentRepository.Insert(context.Background(), comment3)
 				Ω(e).Should(BeNil())
 
 				ts, hasNextPage, e := repository.Filter(context.Background(), "", "", "",
@@ -352,7 +485,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket1)
+				_, e := repository.Insert(context.Background(), ticket1, models.UserActor(ticket1.Owner))
 				Ω(e).Should(BeNil())
 
 				ticket2 := models.Ticket{
@@ -364,7 +497,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelLow,
 				}
 
-				e = repository.Insert(context.Background(), ticket2)
+				_, e = repository.Insert(context.Background(), ticket2, models.UserActor(ticket2.Owner))
 				Ω(e).Should(BeNil())
 
 				ts, hasNextPage, e := repository.Filter(context.Background(), "Microservice-A", "", "",
@@ -386,7 +519,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket1)
+				_, e := repository.Insert(context.Background(), ticket1, models.UserActor(ticket1.Owner))
 				Ω(e).Should(BeNil())
 
 				ticket2 := models.Ticket{
@@ -398,7 +531,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelLow,
 				}
 
-				e = repository.Insert(context.Background(), ticket2)
+				_, e = repository.Insert(context.Background(), ticket2, models.UserActor(ticket2.Owner))
 				Ω(e).Should(BeNil())
 
 				ts, hasNextPage, e := repository.Filter(context.Background(), "Microservice-A", "user1@example.com", "",
@@ -420,7 +553,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelMedium,
 				}
 
-				e := repository.Insert(context.Background(), ticket1)
+				_, e := repository.Insert(context.Background(), ticket1, models.UserActor(ticket1.Owner))
 				Ω(e).Should(BeNil())
 
 				ticket2 := models.Ticket{
@@ -432,7 +565,7 @@ var _ = Describe("Ticket", func() {
 					ImportanceLevel: models.TicketImportanceLevelLow,
 				}
 
-				e = repository.Insert(context.Background(), ticket2)
+				_, e = repository.Insert(context.Background(), ticket2, models.UserActor(ticket2.Owner))
 				Ω(e).Should(BeNil())
 
 				ts, hasNextPage, e := repository.Filter(context.Background(), "", "", "",
@@ -0,0 +1,226 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// Webhook is the entity model of webhooks table. A webhook subscribes an endpoint to some types of lifecycle events
+// of the tickets of an issuer, or of all issuers when Issuer is empty. Deliveries are signed with the secret.
+type Webhook struct {
+	Model
+
+	URL                 string
+	Issuer              string
+	Events              []EventType
+	Secret              string
+	Enabled             bool
+	ConsecutiveFailures int
+}
+
+// Subscribes reports whether the webhook wants to be notified about the event of a ticket of the issuer.
+func (w *Webhook) Subscribes(event EventType, issuer string) bool {
+	if !w.Enabled || (w.Issuer != "" && w.Issuer != issuer) {
+		return false
+	}
+
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookRepository is the repository implementation of Webhook model.
+type WebhookRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewWebhookRepository returns back a newly created and ready to use WebhookRepository.
+func NewWebhookRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a webhook into webhooks table.
+func (r *WebhookRepository) Insert(ctx context.Context, webhook Webhook) *errors.Type {
+	q := `INSERT INTO webhooks (url, issuer, events, secret, enabled, consecutive_failures, created_at, modified_at)
+			VALUES ($1, $2, $3, $4, $5, 0, NOW(), NOW());`
+
+	events, _ := json.Marshal(webhook.Events)
+	_, e := r.db.Exec(ctx, q, webhook.URL, webhook.Issuer, string(events), webhook.Secret, webhook.Enabled)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// LoadByID tries to load a webhook from webhooks table.
+func (r *WebhookRepository) LoadByID(ctx context.Context, id int64) (*Webhook, *errors.Type) {
+	q := `SELECT id, url, issuer, events, secret, enabled, consecutive_failures, created_at, modified_at FROM webhooks
+			WHERE id = $1;`
+
+	webhook, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("webhook.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return webhook, nil
+}
+
+// Update tries to update a webhook record. Failures are forgotten, so a disabled webhook can be enabled again once
+// its endpoint is fixed.
+func (r *WebhookRepository) Update(ctx context.Context, webhook *Webhook) *errors.Type {
+	q := `UPDATE webhooks SET url = $1, issuer = $2, events = $3, secret = $4, enabled = $5, consecutive_failures = 0,
+			modified_at = NOW() WHERE id = $6;`
+
+	events, _ := json.Marshal(webhook.Events)
+	command, e := r.db.Exec(ctx, q, webhook.URL, webhook.Issuer, string(events), webhook.Secret, webhook.Enabled,
+		webhook.ID)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("webhook.not_found", "")
+	}
+
+	return nil
+}
+
+// DeleteByID tries to delete a webhook and all of its deliveries.
+func (r *WebhookRepository) DeleteByID(ctx context.Context, id int64) *errors.Type {
+	q := `DELETE FROM webhooks WHERE id = $1;`
+
+	_, e := r.db.Exec(ctx, q, id)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// Filter tries to load webhooks of the issuer, or of every issuer when it is empty, in the order of their creation. If
+// there is another page of result, the second returned value will be true, otherwise false.
+func (r *WebhookRepository) Filter(ctx context.Context, issuer string, pageNumber, pageSize int) ([]*Webhook, bool,
+	*errors.Type) {
+
+	q := `SELECT id, url, issuer, events, secret, enabled, consecutive_failures, created_at, modified_at FROM webhooks
+			WHERE $1 = '' OR issuer = $1 ORDER BY id OFFSET $2 LIMIT $3;`
+
+	webhooks, e := r.query(ctx, q, issuer, (pageNumber-1)*pageSize, pageSize+1)
+	if e != nil {
+		return nil, false, e
+	}
+
+	hasNextPage := len(webhooks) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		webhooks = webhooks[:len(webhooks)-1]
+	}
+
+	return webhooks, hasNextPage, nil
+}
+
+// LoadEnabledByIssuer tries to load all enabled webhooks of the issuer, including the ones of all issuers.
+func (r *WebhookRepository) LoadEnabledByIssuer(ctx context.Context, issuer string) ([]*Webhook, *errors.Type) {
+	q := `SELECT id, url, issuer, events, secret, enabled, consecutive_failures, created_at, modified_at FROM webhooks
+			WHERE enabled AND (issuer = '' OR issuer = $1) ORDER BY id;`
+
+	return r.query(ctx, q, issuer)
+}
+
+// RecordSuccess forgets the failures of a webhook after a successful delivery.
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id int64) *errors.Type {
+	q := `UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0;`
+
+	if _, e := r.db.Exec(ctx, q, id); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed delivery attempt of a webhook and disables it once disableAfter consecutive attempts
+// have failed. The returned value reports whether the webhook is disabled afterwards.
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id int64, disableAfter int) (bool, *errors.Type) {
+	q := `UPDATE webhooks SET consecutive_failures = consecutive_failures + 1,
+			enabled = enabled AND consecutive_failures + 1 < $1, modified_at = NOW() WHERE id = $2
+			RETURNING enabled;`
+
+	var enabled bool
+	if e := r.db.QueryRow(ctx, q, disableAfter, id).Scan(&enabled); e != nil {
+		if e == pgx.ErrNoRows {
+			return false, errors.NotFound("webhook.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return false, et
+	}
+
+	return !enabled, nil
+}
+
+func (r *WebhookRepository) query(ctx context.Context, q string, args ...interface{}) ([]*Webhook, *errors.Type) {
+	rows, e := r.db.Query(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	webhooks := make([]*Webhook, 0)
+	for rows.Next() {
+		webhook, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) scan(row pgx.Row) (*Webhook, error) {
+	webhook := &Webhook{}
+	var events string
+
+	e := row.Scan(&webhook.ID, &webhook.URL, &webhook.Issuer, &events, &webhook.Secret, &webhook.Enabled,
+		&webhook.ConsecutiveFailures, &webhook.CreatedAt, &webhook.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if e := json.Unmarshal([]byte(events), &webhook.Events); e != nil {
+		return nil, e
+	}
+
+	return webhook, nil
+}
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"go.uber.org/zap"
+)
+
+// WebhookDelivery is the entity model of webhook_deliveries table. A delivery is an event payload that is, or has
+// been, sent to a webhook. EventID is the id of the event in the outbox, so an event is delivered once per webhook.
+type WebhookDelivery struct {
+	Model
+
+	WebhookID      int64
+	EventID        string
+	Event          EventType
+	TicketID       int64
+	Payload        string
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	ResponseStatus int
+	LastError      string
+	NextAttemptAt  time.Time
+	DeliveredAt    time.Time
+}
+
+// WebhookDeliveryRepository is the repository implementation of WebhookDelivery model.
+type WebhookDeliveryRepository struct {
+	logger *zap.SugaredLogger
+	db     *pgxpool.Pool
+}
+
+// NewWebhookDeliveryRepository returns back a newly created and ready to use WebhookDeliveryRepository.
+func NewWebhookDeliveryRepository(logger *zap.SugaredLogger, db *pgxpool.Pool) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{logger: logger, db: db}
+}
+
+// Insert tries to insert a pending delivery into webhook_deliveries table, to be sent as soon as possible. A delivery
+// of an event the webhook already has is ignored.
+func (r *WebhookDeliveryRepository) Insert(ctx context.Context, delivery WebhookDelivery) *errors.Type {
+	q := `INSERT INTO webhook_deliveries (webhook_id, event_id, event, ticket_id, payload, status, attempts,
+			response_status, last_error, next_attempt_at, created_at, modified_at) VALUES ($1, $2, $3, $4, $5, $6, 0,
+			0, '', NOW(), NOW(), NOW()) ON CONFLICT DO NOTHING;`
+
+	_, e := r.db.Exec(ctx, q, delivery.WebhookID, delivery.EventID, delivery.Event, delivery.TicketID,
+		delivery.Payload, WebhookDeliveryStatusPending)
+	if e != nil {
+		if strings.Contains(e.Error(), "webhook_deliveries_webhook_id_fkey") {
+			return errors.PreconditionFailed("webhook.not_exists", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	return nil
+}
+
+// LoadByID tries to load a delivery from webhook_deliveries table.
+func (r *WebhookDeliveryRepository) LoadByID(ctx context.Context, id int64) (*WebhookDelivery, *errors.Type) {
+	q := `SELECT id, webhook_id, event_id, event, ticket_id, payload, status, attempts, response_status, last_error,
+			next_attempt_at, delivered_at, created_at, modified_at FROM webhook_deliveries WHERE id = $1;`
+
+	delivery, e := r.scan(r.db.QueryRow(ctx, q, id))
+	if e != nil {
+		if e == pgx.ErrNoRows {
+			return nil, errors.NotFound("webhook_delivery.not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return delivery, nil
+}
+
+// Claim tries to lock at most limit due deliveries of enabled webhooks for the lease, counting an attempt for each.
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery,
+	*errors.Type) {
+
+	q := `UPDATE webhook_deliveries SET attempts = attempts + 1,
+			next_attempt_at = NOW() + $1 * INTERVAL '1 millisecond', modified_at = NOW()
+			WHERE id IN (SELECT d.id FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = $2 AND d.next_attempt_at <= NOW() AND w.enabled ORDER BY d.next_attempt_at LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED) RETURNING id, webhook_id, event_id, event, ticket_id, payload, status,
+			attempts, response_status, last_error, next_attempt_at, delivered_at, created_at, modified_at;`
+
+	return r.query(ctx, q, lease.Milliseconds(), WebhookDeliveryStatusPending, limit)
+}
+
+// MarkDelivered marks a delivery as delivered with the response status of the endpoint.
+func (r *WebhookDeliveryRepository) MarkDelivered(ctx context.Context, id int64, responseStatus int) *errors.Type {
+	q := `UPDATE webhook_deliveries SET status = $1, response_status = $2, last_error = '', delivered_at = NOW(),
+			modified_at = NOW() WHERE id = $3;`
+
+	return r.exec(ctx, q, WebhookDeliveryStatusDelivered, responseStatus, id)
+}
+
+// Retry records a failed attempt of a delivery and schedules the next one after the backoff.
+func (r *WebhookDeliveryRepository) Retry(ctx context.Context, id int64, responseStatus int, lastError string,
+	backoff time.Duration) *errors.Type {
+
+	q := `UPDATE webhook_deliveries SET response_status = $1, last_error = $2,
+			next_attempt_at = NOW() + $3 * INTERVAL '1 millisecond', modified_at = NOW() WHERE id = $4;`
+
+	return r.exec(ctx, q, responseStatus, lastError, backoff.Milliseconds(), id)
+}
+
+// MarkFailed marks a delivery as failed for good, after its last attempt.
+func (r *WebhookDeliveryRepository) MarkFailed(ctx context.Context, id int64, responseStatus int,
+	lastError string) *errors.Type {
+
+	q := `UPDATE webhook_deliveries SET status = $1, response_status = $2, last_error = $3, modified_at = NOW()
+			WHERE id = $4;`
+
+	return r.exec(ctx, q, WebhookDeliveryStatusFailed, responseStatus, lastError, id)
+}
+
+// Redeliver schedules a delivery, whatever its status, to be sent again as soon as possible with a fresh set of
+// attempts.
+func (r *WebhookDeliveryRepository) Redeliver(ctx context.Context, id int64) *errors.Type {
+	q := `UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = NOW(), modified_at = NOW()
+			WHERE id = $2;`
+
+	return r.exec(ctx, q, WebhookDeliveryStatusPending, id)
+}
+
+// Filter tries to filter the deliveries of a webhook, from the newest one. Empty status means all of the statuses.
+// If there is another page of result, the second returned value will be true, otherwise false.
+func (r *WebhookDeliveryRepository) Filter(ctx context.Context, webhookID int64, status WebhookDeliveryStatus,
+	pageNumber, pageSize int) ([]*WebhookDelivery, bool, *errors.Type) {
+
+	q := strings.Builder{}
+	args := make([]interface{}, 0)
+
+	q.WriteString(`SELECT id, webhook_id, event_id, event, ticket_id, payload, status, attempts, response_status,
+						last_error, next_attempt_at, delivered_at, created_at, modified_at FROM webhook_deliveries
+						WHERE webhook_id = $1`)
+	args = append(args, webhookID)
+
+	if status != "" {
+		args = append(args, status)
+		q.WriteString(` AND status = $` + strconv.Itoa(len(args)))
+	}
+
+	args = append(args, (pageNumber-1)*pageSize)
+	q.WriteString(` ORDER BY id DESC OFFSET $` + strconv.Itoa(len(args)))
+
+	args = append(args, pageSize+1)
+	q.WriteString(` LIMIT $` + strconv.Itoa(len(args)) + `;`)
+
+	deliveries, e := r.query(ctx, q.String(), args...)
+	if e != nil {
+		return nil, false, e
+	}
+
+	hasNextPage := len(deliveries) > pageSize
+	if hasNextPage {
+		// Drop the extra one.
+		deliveries = deliveries[:len(deliveries)-1]
+	}
+
+	return deliveries, hasNextPage, nil
+}
+
+func (r *WebhookDeliveryRepository) exec(ctx context.Context, q string, args ...interface{}) *errors.Type {
+	command, e := r.db.Exec(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return et
+	}
+
+	if command.RowsAffected() == 0 {
+		return errors.NotFound("webhook_delivery.not_found", "")
+	}
+
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) query(ctx context.Context, q string, args ...interface{}) ([]*WebhookDelivery,
+	*errors.Type) {
+
+	rows, e := r.db.Query(ctx, q, args...)
+	if e != nil {
+		et := errors.InternalServerError("unknown", "")
+		r.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer rows.Close()
+
+	deliveries := make([]*WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, e := r.scan(rows)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			r.logger.Error(et.FingerPrint, ": ", e.Error())
+			return nil, et
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookDeliveryRepository) scan(row pgx.Row) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	var deliveredAt sql.NullTime
+
+	e := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.Event, &delivery.TicketID,
+		&delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError,
+		&delivery.NextAttemptAt, &deliveredAt, &delivery.CreatedAt, &delivery.ModifiedAt)
+	if e != nil {
+		return nil, e
+	}
+
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = deliveredAt.Time
+	}
+
+	return delivery, nil
+}
+
+// WebhookDeliveryStatus model.
+type WebhookDeliveryStatus string
+
+// Different webhook delivery status instances.
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "FAILED"
+)
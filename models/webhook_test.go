@@ -0,0 +1,275 @@
+package models_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/test"
+	"github.com/jibitters/kiosk/test/containers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/testcontainers/testcontainers-go"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Webhook", func() {
+	var pg testcontainers.Container
+	var db *pgxpool.Pool
+	var repository *models.WebhookRepository
+	var deliveryRepository *models.WebhookDeliveryRepository
+
+	webhook := models.Webhook{
+		URL:     "https://example.com/hook",
+		Issuer:  "Microservice-A",
+		Events:  []models.EventType{models.EventTypeTicketCreated, models.EventTypeCommentCreated},
+		Secret:  "0123456789abcdef",
+		Enabled: true,
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID: 1,
+		EventID:   "6f1a4d0e-3c1b-4f4e-9a51-0c8e0b9f2d11",
+		Event:     models.EventTypeTicketCreated,
+		TicketID:  1,
+		Payload:   `{"event":"TICKET_CREATED","ticketID":1}`,
+	}
+
+	BeforeEach(func() {
+		container, port, e := containers.RunPostgres()
+		if e != nil {
+			Fail(e.Error())
+		} else {
+			pg = container
+		}
+
+		if pool, e := test.ConnectToDatabase(pgHost, port); e != nil {
+			Fail(e.Error())
+		} else {
+			db = pool
+			repository = models.NewWebhookRepository(zap.S(), db)
+			deliveryRepository = models.NewWebhookDeliveryRepository(zap.S(), db)
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+		_ = containers.Stop(pg)
+	})
+
+	Context("When Insert called", func() {
+		It("Should insert the webhook", func() {
+			e := repository.Insert(context.Background(), webhook)
+			Ω(e).Should(BeNil())
+
+			w, e := repository.LoadByID(context.Background(), 1)
+			Ω(e).Should(BeNil())
+			Ω(w.URL).Should(Equal(webhook.URL))
+			Ω(w.Events).Should(Equal(webhook.Events))
+			Ω(w.Secret).Should(Equal(webhook.Secret))
+			Ω(w.Enabled).Should(BeTrue())
+		})
+	})
+
+	Context("When LoadEnabledByIssuer called", func() {
+		It("Should load the enabled webhooks of the issuer and of all issuers", func() {
+			e := repository.Insert(context.Background(), webhook)
+			Ω(e).Should(BeNil())
+
+			all := webhook
+			all.Issuer = ""
+			e = repository.Insert(context.Background(), all)
+			Ω(e).Should(BeNil())
+
+			other := webhook
+			other.Issuer = "Microservice-B"
+			e = repository.Insert(context.Background(), other)
+			Ω(e).Should(BeNil())
+
+			disabled := webhook
+			disabled.Enabled = false
+			e = repository.Insert(context.Background(), disabled)
+			Ω(e).Should(BeNil())
+
+			ws, e := repository.LoadEnabledByIssuer(context.Background(), "Microservice-A")
+			Ω(e).Should(BeNil())
+			Ω(len(ws)).Should(Equal(2))
+			Ω(ws[0].ID).Should(Equal(int64(1)))
+			Ω(ws[1].ID).Should(Equal(int64(2)))
+			Ω(ws[0].Subscribes(models.EventTypeTicketCreated, "Microservice-A")).Should(BeTrue())
+			Ω(ws[0].Subscribes(models.EventTypeTicketDeleted, "Microservice-A")).Should(BeFalse())
+		})
+	})
+
+	Context("When RecordFailure called", func() {
+		It("Should disable the webhook after consecutive failures until it is updated", func() {
+			e := repository.Insert(context.Background(), webhook)
+			Ω(e).Should(BeNil())
+
+			disabled, e := repository.RecordFailure(context.Background(), 1, 2)
+			Ω(e).Should(BeNil())
+			Ω(disabled).Should(BeFalse())
+
+			e = repository.RecordSuccess(context.Background(), 1)
+			Ω(e).Should(BeNil())
+
+			disabled, e = repository.RecordFailure(context.Background(), 1, 2)
+			Ω(e).Should(BeNil())
+			Ω(disabled).Should(BeFalse())
+
+			disabled, e = repository.RecordFailure(context.Background(), 1, 2)
+			Ω(e).Should(BeNil())
+			Ω(disabled).Should(BeTrue())
+
+			w, e := repository.LoadByID(context.Background(), 1)
+			Ω(e).Should(BeNil())
+			Ω(w.Enabled).Should(BeFalse())
+			Ω(w.ConsecutiveFailures).Should(Equal(2))
+
+			w.Enabled = true
+			e = repository.Update(context.Background(), w)
+			Ω(e).Should(BeNil())
+
+			w, e = repository.LoadByID(context.Background(), 1)
+			Ω(e).Should(BeNil())
+			Ω(w.Enabled).Should(BeTrue())
+			Ω(w.ConsecutiveFailures).Should(Equal(0))
+		})
+
+		It("Should return not found error when the webhook does not exist", func() {
+			_, e := repository.RecordFailure(context.Background(), 1, 2)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("When DeleteByID called", func() {
+		It("Should delete the webhook and its deliveries", func() {
+			e := repository.Insert(context.Background(), webhook)
+			Ω(e).Should(BeNil())
+
+			e = deliveryRepository.Insert(context.Background(), delivery)
+			Ω(e).Should(BeNil())
+
+			e = repository.DeleteByID(context.Background(), 1)
+			Ω(e).Should(BeNil())
+
+			_, e = deliveryRepository.LoadByID(context.Background(), 1)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("When Claim called", func() {
+		It("Should claim due deliveries of enabled webhooks only once until their lease expires", func() {
+			e := repository.Insert(context.Background(), webhook)
+			Ω(e).Should(BeNil())
+
+			e = deliveryRepository.Insert(context.Background(), delivery)
+			Ω(e).Should(BeNil())
+
+			ds, e := deliveryRepository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(len(ds)).Should(Equal(1))
+			Ω(ds[0].Attempts).Should(Equal(1))
+			Ω(ds[0].Payload).Should(Equal(delivery.Payload))
+
+			ds, e = deliveryRepository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(ds).Should(BeEmpty())
+
+			e = deliveryRepository.Retry(context.Background(), 1, 503, "unexpected status code 503", 0)
+			Ω(e).Should(BeNil())
+
+			w, e := repository.LoadByID(context.Background(), 1)
+			Ω(e).Should(BeNil())
+			w.Enabled = false
+			e = repository.Update(context.Background(), w)
+			Ω(e).Should(BeNil())
+
+			ds, e = deliveryRepository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(ds).Should(BeEmpty())
+
+			w.Enabled = true
+			e = repository.Update(context.Background(), w)
+			Ω(e).Should(BeNil())
+
+			ds, e = deliveryRepository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(len(ds)).Should(Equal(1))
+			Ω(ds[0].Attempts).Should(Equal(2))
+			Ω(ds[0].ResponseStatus).Should(Equal(503))
+
+			e = deliveryRepository.MarkDelivered(context.Background(), 1, 200)
+			Ω(e).Should(BeNil())
+
+			ds, _, e = deliveryRepository.Filter(context.Background(), 1, models.WebhookDeliveryStatusDelivered, 1, 10)
+			Ω(e).Should(BeNil())
+			Ω(len(ds)).Should(Equal(1))
+			Ω(ds[0].DeliveredAt.IsZero()).Should(BeFalse())
+			Ω(ds[0].LastError).Should(BeEmpty())
+		})
+	})
+
+	Context("When Redeliver called", func() {
+		It("Should schedule a failed delivery again with fresh attempts", func() {
+			e := repository.Insert(context.Background(), webhook)
+			Ω(e).Should(BeNil())
+
+			e = deliveryRepository.Insert(context.Background(), delivery)
+			Ω(e).Should(BeNil())
+
+			e = deliveryRepository.MarkFailed(context.Background(), 1, 0, "connection refused")
+			Ω(e).Should(BeNil())
+
+			ds, e := deliveryRepository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(ds).Should(BeEmpty())
+
+			e = deliveryRepository.Redeliver(context.Background(), 1)
+			Ω(e).Should(BeNil())
+
+			ds, e = deliveryRepository.Claim(context.Background(), 10, time.Minute)
+			Ω(e).Should(BeNil())
+			Ω(len(ds)).Should(Equal(1))
+			Ω(ds[0].Attempts).Should(Equal(1))
+		})
+
+		It("Should return not found error when the delivery does not exist", func() {
+			e := deliveryRepository.Redeliver(context.Background(), 1)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("When a delivery of the same event is inserted twice", func() {
+		It("Should ignore the second one", func() {
+			e := repository.Insert(context.Background(), webhook)
+			Ω(e).Should(BeNil())
+
+			e = deliveryRepository.Insert(context.Background(), delivery)
+			Ω(e).Should(BeNil())
+
+			e = deliveryRepository.Insert(context.Background(), delivery)
+			Ω(e).Should(BeNil())
+
+			ds, _, e := deliveryRepository.Filter(context.Background(), 1, "", 1, 10)
+			Ω(e).Should(BeNil())
+			Ω(len(ds)).Should(Equal(1))
+			Ω(ds[0].EventID).Should(Equal(delivery.EventID))
+		})
+	})
+
+	Context("When a delivery of a missing webhook is inserted", func() {
+		It("Should return precondition failed error", func() {
+			e := deliveryRepository.Insert(context.Background(), delivery)
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Errors[0].Code).Should(Equal("webhook.not_exists"))
+		})
+	})
+})
@@ -6,3 +6,8 @@ env GOOS=freebsd GOARCH=amd64 go build -o kiosk-freebsd-$VERSION ./cmd/kiosk
 env GOOS=linux GOARCH=amd64 go build -o kiosk-linux-$VERSION ./cmd/kiosk
 env GOOS=darwin GOARCH=amd64 go build -o kiosk-macos-$VERSION ./cmd/kiosk
 env GOOS=windows GOARCH=amd64 go build -o kiosk-windows-$VERSION.exe ./cmd/kiosk
+
+env GOOS=freebsd GOARCH=amd64 go build -o kioskctl-freebsd-$VERSION ./cmd/kioskctl
+env GOOS=linux GOARCH=amd64 go build -o kioskctl-linux-$VERSION ./cmd/kioskctl
+env GOOS=darwin GOARCH=amd64 go build -o kioskctl-macos-$VERSION ./cmd/kioskctl
+env GOOS=windows GOARCH=amd64 go build -o kioskctl-windows-$VERSION.exe ./cmd/kioskctl
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// APIKeyService is a service implementation of the API keys of the issuer microservices.
+type APIKeyService struct {
+	logger           *zap.SugaredLogger
+	apiKeyRepository *models.APIKeyRepository
+	natsClient       *nc.Conn
+	authorizer       *Authorizer
+	rotationGrace    time.Duration
+	catalogue        *errors.Catalogue
+	stop             chan struct{}
+}
+
+// NewAPIKeyService returns a newly created and ready to use APIKeyService.
+func NewAPIKeyService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool, natsClient *nc.Conn,
+	authorizer *Authorizer, catalogue *errors.Catalogue) *APIKeyService {
+
+	rotationGrace := config.Get("api_keys.rotation_grace").DurationOrElse(24 * time.Hour)
+	logger.Info("api_keys.rotation_grace -> ", rotationGrace)
+
+	return &APIKeyService{
+		logger:           logger,
+		apiKeyRepository: models.NewAPIKeyRepository(logger, db),
+		natsClient:       natsClient,
+		authorizer:       authorizer,
+		rotationGrace:    rotationGrace,
+		catalogue:        catalogue,
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions so ready to be notified.
+func (s *APIKeyService) Start() error {
+	createAPIKeySubscription, e := s.natsClient.QueueSubscribe("kiosk.api_keys.create",
+		"kiosk.api_keys.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	rotateAPIKeySubscription, e := s.natsClient.QueueSubscribe("kiosk.api_keys.rotate",
+		"kiosk.api_keys.rotate_group", s.authorizer.identified(s.rotate))
+	if e != nil {
+		return e
+	}
+
+	revokeAPIKeySubscription, e := s.natsClient.QueueSubscribe("kiosk.api_keys.revoke",
+		"kiosk.api_keys.revoke_group", s.authorizer.identified(s.revoke))
+	if e != nil {
+		return e
+	}
+
+	filterAPIKeysSubscription, e := s.natsClient.QueueSubscribe("kiosk.api_keys.filter",
+		"kiosk.api_keys.filter_group", s.authorizer.identified(s.filter))
+	if e != nil {
+		return e
+	}
+
+	authenticateAPIKeySubscription, e := s.natsClient.QueueSubscribe("kiosk.api_keys.authenticate",
+		"kiosk.api_keys.authenticate_group", s.authenticate)
+	if e != nil {
+		return e
+	}
+
+	go s.await(createAPIKeySubscription, rotateAPIKeySubscription, revokeAPIKeySubscription,
+		filterAPIKeysSubscription, authenticateAPIKeySubscription)
+
+	return nil
+}
+
+func (s *APIKeyService) await(ss ...*nc.Subscription) {
+	<-s.stop
+	s.logger.Debug("APIKeyService: received stop signal!")
+
+	for _, s := range ss {
+		_ = s.Unsubscribe()
+	}
+}
+
+func (s *APIKeyService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createAPIKeyRequest := &data.CreateAPIKeyRequest{}
+	if e := json.Unmarshal(msg.Data, createAPIKeyRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createAPIKeyRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.api_keys.create", createAPIKeyRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	apiKey, key, e := s.apiKeyRepository.Insert(ctx, *createAPIKeyRequest.AsAPIKey())
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyWithKey(msg, apiKey, key)
+}
+
+func (s *APIKeyService) rotate(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rotateAPIKeyRequest := &data.RotateAPIKeyRequest{}
+	if e := json.Unmarshal(msg.Data, rotateAPIKeyRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := rotateAPIKeyRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorize(ctx, msg, "kiosk.api_keys.rotate", rotateAPIKeyRequest.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	apiKey, key, e := s.apiKeyRepository.Rotate(ctx, rotateAPIKeyRequest.ID, rotateAPIKeyRequest.ExpiryTime(),
+		s.rotationGrace)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyWithKey(msg, apiKey, key)
+}
+
+func (s *APIKeyService) revoke(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorize(ctx, msg, "kiosk.api_keys.revoke", id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.apiKeyRepository.Revoke(ctx, id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *APIKeyService) filter(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterAPIKeysRequest := &data.FilterAPIKeysRequest{}
+	if e := json.Unmarshal(msg.Data, filterAPIKeysRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterAPIKeysRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.api_keys.filter", filterAPIKeysRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	apiKeys, hasNextPage, e := s.apiKeyRepository.Filter(ctx, filterAPIKeysRequest.Issuer,
+		filterAPIKeysRequest.PageNumber, filterAPIKeysRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterAPIKeysResponse := &data.FilterAPIKeysResponse{}
+	filterAPIKeysResponse.LoadFromAPIKeys(apiKeys, hasNextPage)
+	s.reply(msg, filterAPIKeysResponse)
+}
+
+func (s *APIKeyService) authenticate(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	authenticateAPIKeyRequest := &data.AuthenticateAPIKeyRequest{}
+	if e := json.Unmarshal(msg.Data, authenticateAPIKeyRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := authenticateAPIKeyRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	identity, e := s.authorizer.Authenticate(ctx, authenticateAPIKeyRequest.Key)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.reply(msg, identity)
+}
+
+// authorize authorizes the identified caller of the message on the issuer of the key.
+func (s *APIKeyService) authorize(ctx context.Context, msg *nc.Msg, subject string, id int64) *errors.Type {
+	identity := identityOf(msg)
+	if identity == nil {
+		return nil
+	}
+
+	apiKey, e := s.apiKeyRepository.LoadByID(ctx, id)
+	if e != nil {
+		return e
+	}
+
+	return s.authorizer.AuthorizeIssuer(identity, subject, apiKey.Issuer)
+}
+
+func (s *APIKeyService) replyWithKey(msg *nc.Msg, apiKey *models.APIKey, key string) {
+	apiKeyResponse := &data.APIKeyResponse{}
+	apiKeyResponse.LoadFromAPIKey(apiKey)
+	apiKeyResponse.Key = key
+	s.reply(msg, apiKeyResponse)
+}
+
+func (s *APIKeyService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (s *APIKeyService) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component and its subscriptions.
+func (s *APIKeyService) Stop() {
+	s.stop <- struct{}{}
+}
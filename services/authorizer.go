@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// Scope is the set of the tickets that a role is granted a subject on.
+type Scope string
+
+// Scopes, from the widest one.
+const (
+	// ScopeAll grants every ticket.
+	ScopeAll Scope = "ALL"
+	// ScopeIssuer grants the tickets whose issuer is the tenant of the caller.
+	ScopeIssuer Scope = "ISSUER"
+	// ScopeOwner grants the tickets whose owner is the subject of the caller.
+	ScopeOwner Scope = "OWNER"
+)
+
+// AnySubject grants the scope on every subject of a role.
+const AnySubject = "*"
+
+// Policy maps the roles to the scopes they are granted on the subjects.
+type Policy map[string]map[string]Scope
+
+// DefaultPolicy returns back the policy of the roles of kiosk: customers see, comment on and rate their own tickets,
+// agents manage the tickets of their issuer and admins do everything, including deleting and configuring.
+func DefaultPolicy() Policy {
+	return Policy{
+		"customer": {
+			"kiosk.tickets.create":  ScopeOwner,
+			"kiosk.tickets.load":    ScopeOwner,
+			"kiosk.tickets.filter":  ScopeOwner,
+			"kiosk.tickets.rate":    ScopeOwner,
+			"kiosk.comments.create": ScopeOwner,
+			"kiosk.comments.load":   ScopeOwner,
+			"kiosk.streams.tickets": ScopeOwner,
+			"kiosk.streams.replay":  ScopeOwner,
+		},
+		"agent": {
+			"kiosk.tickets.create":                ScopeIssuer,
+			"kiosk.tickets.load":                  ScopeIssuer,
+			"kiosk.tickets.update":                ScopeIssuer,
+			"kiosk.tickets.filter":                ScopeIssuer,
+			"kiosk.comments.create":               ScopeIssuer,
+			"kiosk.comments.create_from_template": ScopeIssuer,
+			"kiosk.comments.load":                 ScopeIssuer,
+			"kiosk.comments.update":               ScopeIssuer,
+			"kiosk.macros.load":                   ScopeIssuer,
+			"kiosk.macros.filter":                 ScopeIssuer,
+			"kiosk.macros.execute":                ScopeIssuer,
+			"kiosk.canned_responses.load":         ScopeIssuer,
+			"kiosk.canned_responses.filter":       ScopeIssuer,
+			"kiosk.exports.create":                ScopeIssuer,
+			"kiosk.exports.load":                  ScopeIssuer,
+			"kiosk.notifications.filter":          ScopeIssuer,
+			"kiosk.escalations.filter":            ScopeIssuer,
+			"kiosk.reports.csat":                  ScopeIssuer,
+			"kiosk.streams.tickets":               ScopeIssuer,
+			"kiosk.streams.replay":                ScopeIssuer,
+		},
+		"admin": {
+			AnySubject: ScopeAll,
+		},
+	}
+}
+
+// LoadPolicy loads the policy of the rbac.policy_file JSON file, or returns back the default policy when no file is
+// configured.
+func LoadPolicy(logger *zap.SugaredLogger, config *configuring.Config) (Policy, error) {
+	policyFile := config.Get("rbac.policy_file").StringOrElse("")
+	logger.Info("rbac.policy_file -> ", policyFile)
+
+	if policyFile == "" {
+		return DefaultPolicy(), nil
+	}
+
+	in, e := ioutil.ReadFile(policyFile)
+	if e != nil {
+		return nil, e
+	}
+
+	policy := Policy{}
+	if e := json.Unmarshal(in, &policy); e != nil {
+		return nil, fmt.Errorf("invalid policy file %v: %v", policyFile, e)
+	}
+
+	for role, subjects := range policy {
+		for subject, scope := range subjects {
+			if scope != ScopeAll && scope != ScopeIssuer && scope != ScopeOwner {
+				return nil, fmt.Errorf("invalid scope %v of %v for %v", scope, subject, role)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// Authorizer evaluates the policy for the callers identified by the HTTP API or by their API keys. Requests without
+// identity, i.e. the ones of the internal microservices, are not restricted.
+type Authorizer struct {
+	policy           Policy
+	apiKeyRepository *models.APIKeyRepository
+	catalogue        *errors.Catalogue
+}
+
+// NewAuthorizer returns back a newly created and ready to use Authorizer. API keys are rejected when the repository is
+// nil.
+func NewAuthorizer(policy Policy, apiKeyRepository *models.APIKeyRepository, catalogue *errors.Catalogue) *Authorizer {
+	return &Authorizer{policy: policy, apiKeyRepository: apiKeyRepository, catalogue: catalogue}
+}
+
+// Authorize returns back forbidden unless the identity is granted the subject on the ticket. A nil ticket, i.e. one
+// that does not exist, is granted by every scope, so the handlers reply as they do for the other callers.
+func (a *Authorizer) Authorize(identity *data.Identity, subject string, ticket *models.Ticket) *errors.Type {
+	if identity == nil {
+		return nil
+	}
+
+	switch a.scope(identity, subject) {
+	case ScopeAll:
+		return nil
+	case ScopeIssuer:
+		if ticket == nil || (identity.Tenant != "" && ticket.Issuer == identity.Tenant) {
+			return nil
+		}
+	case ScopeOwner:
+		if ticket == nil || ticket.Owner == identity.Subject {
+			return nil
+		}
+	}
+
+	return errors.Forbidden("")
+}
+
+// AuthorizeFilter restricts the issuer and owner criteria values to the scope of the identity on the subject. Empty
+// criteria values are set to the ones of the scope and others are forbidden.
+func (a *Authorizer) AuthorizeFilter(identity *data.Identity, subject string, issuer, owner *string) *errors.Type {
+	if identity == nil {
+		return nil
+	}
+
+	restrict := func(criteria *string, value string) *errors.Type {
+		if value == "" || (*criteria != "" && *criteria != value) {
+			return errors.Forbidden("")
+		}
+
+		*criteria = value
+		return nil
+	}
+
+	switch a.scope(identity, subject) {
+	case ScopeAll:
+		return nil
+	case ScopeIssuer:
+		return restrict(issuer, identity.Tenant)
+	case ScopeOwner:
+		return restrict(owner, identity.Subject)
+	default:
+		return errors.Forbidden("")
+	}
+}
+
+// AuthorizeIssuer returns back forbidden unless the identity is granted the subject on the resources of the issuer,
+// e.g: its API keys. The owner scope grants none of them.
+func (a *Authorizer) AuthorizeIssuer(identity *data.Identity, subject, issuer string) *errors.Type {
+	if identity == nil {
+		return nil
+	}
+
+	switch a.scope(identity, subject) {
+	case ScopeAll:
+		return nil
+	case ScopeIssuer:
+		if identity.Tenant != "" && identity.Tenant == issuer {
+			return nil
+		}
+	}
+
+	return errors.Forbidden("")
+}
+
+// AuthorizeOwner binds the empty owner to the subject of the identity and returns back forbidden for any other one. API
+// keys act on behalf of the users of their issuer, so they may name any owner.
+func (a *Authorizer) AuthorizeOwner(identity *data.Identity, owner *string) *errors.Type {
+	if identity == nil || identity.KeyPrefix != "" {
+		return nil
+	}
+
+	if *owner == "" {
+		*owner = identity.Subject
+	}
+
+	if *owner != identity.Subject {
+		return errors.Forbidden("")
+	}
+
+	return nil
+}
+
+// AuthorizeIssuerFilter restricts the issuer criteria value to the scope of the identity on the subject, like
+// AuthorizeFilter does. The owner scope grants none of the issuers.
+func (a *Authorizer) AuthorizeIssuerFilter(identity *data.Identity, subject string, issuer *string) *errors.Type {
+	if identity == nil {
+		return nil
+	}
+
+	switch a.scope(identity, subject) {
+	case ScopeAll:
+		return nil
+	case ScopeIssuer:
+		if identity.Tenant != "" && (*issuer == "" || *issuer == identity.Tenant) {
+			*issuer = identity.Tenant
+			return nil
+		}
+	}
+
+	return errors.Forbidden("")
+}
+
+// AuthorizeAll returns back forbidden unless the identity is granted the subject on every issuer, e.g: on the rules,
+// which belong to no issuer.
+func (a *Authorizer) AuthorizeAll(identity *data.Identity, subject string) *errors.Type {
+	if identity == nil || a.scope(identity, subject) == ScopeAll {
+		return nil
+	}
+
+	return errors.Forbidden("")
+}
+
+// Authenticate returns back the identity of the active API key, or unauthorized.
+func (a *Authorizer) Authenticate(ctx context.Context, key string) (*data.Identity, *errors.Type) {
+	if a.apiKeyRepository == nil {
+		return nil, errors.Unauthorized("")
+	}
+
+	apiKey, e := a.apiKeyRepository.Authenticate(ctx, key)
+	if e != nil {
+		return nil, e
+	}
+
+	return apiKeyIdentity(apiKey), nil
+}
+
+// scope returns back the widest scope that the roles of the identity are granted on the subject, or empty if none.
+// API keys are granted the issuer scope on their operations only.
+func (a *Authorizer) scope(identity *data.Identity, subject string) Scope {
+	if identity.KeyPrefix != "" {
+		for _, o := range identity.Operations {
+			if o == subject {
+				return ScopeIssuer
+			}
+		}
+
+		return ""
+	}
+
+	widest := Scope("")
+	for _, role := range identity.Roles {
+		for _, s := range []string{subject, AnySubject} {
+			scope, ok := a.policy[role][s]
+			if !ok {
+				continue
+			}
+
+			if widest == "" || scope == ScopeAll || (scope == ScopeIssuer && widest == ScopeOwner) {
+				widest = scope
+			}
+		}
+	}
+
+	return widest
+}
+
+// authorize authorizes the identified caller of the message on the ticket returned by load. Tickets that do not exist
+// are authorized, so the handlers reply as they do for the other callers.
+func (a *Authorizer) authorize(msg *nc.Msg, subject string, load func() (*models.Ticket, *errors.Type)) *errors.Type {
+	identity := identityOf(msg)
+	if identity == nil {
+		return nil
+	}
+
+	t, e := load()
+	if e != nil && e.HTTPStatusCode != http.StatusNotFound {
+		return e
+	}
+
+	return a.Authorize(identity, subject, t)
+}
+
+// authorizeIssuer authorizes the identified caller of the message on the resource of the issuer returned by load, like
+// authorize does on the tickets.
+func (a *Authorizer) authorizeIssuer(msg *nc.Msg, subject string, load func() (string, *errors.Type)) *errors.Type {
+	identity := identityOf(msg)
+	if identity == nil {
+		return nil
+	}
+
+	issuer, e := load()
+	if e != nil {
+		if e.HTTPStatusCode != http.StatusNotFound {
+			return e
+		}
+
+		return a.Authorize(identity, subject, nil)
+	}
+
+	return a.AuthorizeIssuer(identity, subject, issuer)
+}
+
+// identified returns back the handler of the messages, which resolves the API key of the message, if any, to its
+// identity before the message is handled.
+func (a *Authorizer) identified(handler nc.MsgHandler) nc.MsgHandler {
+	return func(msg *nc.Msg) {
+		identified := &data.Identified{}
+		e := json.Unmarshal(msg.Data, identified)
+		if e != nil || identified.Identity != nil || identified.APIKey == "" {
+			handler(msg)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		identity, et := a.Authenticate(ctx, identified.APIKey)
+		if et != nil {
+			reply, _ := json.Marshal(localized(a.catalogue, msg, et))
+			_ = msg.Respond(reply)
+			return
+		}
+
+		fields := make(map[string]json.RawMessage)
+		_ = json.Unmarshal(msg.Data, &fields)
+		fields["identity"], _ = json.Marshal(identity)
+		delete(fields, "apiKey")
+		msg.Data, _ = json.Marshal(fields)
+
+		handler(msg)
+	}
+}
+
+// apiKeyIdentity returns back the identity of the API key, whose tenant is the issuer of the key.
+func apiKeyIdentity(apiKey *models.APIKey) *data.Identity {
+	return &data.Identity{
+		Subject:    "api-key:" + apiKey.Name,
+		Tenant:     apiKey.Issuer,
+		KeyPrefix:  apiKey.Prefix,
+		Operations: apiKey.Operations,
+	}
+}
+
+// identityOf returns back the identity of the caller of the message, if any.
+func identityOf(msg *nc.Msg) *data.Identity {
+	identified := &data.Identified{}
+	if e := json.Unmarshal(msg.Data, identified); e != nil {
+		return nil
+	}
+
+	return identified.Identity
+}
+
+// actorOf returns back the caller of the message as the actor of the changes it requests.
+func actorOf(msg *nc.Msg) models.Actor {
+	if identity := identityOf(msg); identity != nil {
+		return models.UserActor(identity.Subject)
+	}
+
+	return models.UserActor("")
+}
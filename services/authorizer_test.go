@@ -0,0 +1,275 @@
+package services_test
+
+import (
+	"context"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/services"
+	"github.com/jibitters/kiosk/web/data"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authorizer", func() {
+	authorizer := services.NewAuthorizer(services.DefaultPolicy(), nil, nil)
+
+	customer := &data.Identity{Subject: "user@example.com", Roles: []string{"customer"}}
+	agent := &data.Identity{Subject: "agent@example.com", Roles: []string{"agent"}, Tenant: "Microservice-A"}
+	admin := &data.Identity{Subject: "admin@example.com", Roles: []string{"admin"}}
+	apiKey := &data.Identity{Subject: "api-key:orders", Tenant: "Microservice-A", KeyPrefix: "0123456789abcdef",
+		Operations: []string{"kiosk.tickets.create", "kiosk.tickets.load", "kiosk.tickets.filter"}}
+
+	own := &models.Ticket{Model: models.Model{ID: 1}, Issuer: "Microservice-A", Owner: "user@example.com"}
+	ofIssuer := &models.Ticket{Model: models.Model{ID: 2}, Issuer: "Microservice-A", Owner: "another@example.com"}
+	other := &models.Ticket{Model: models.Model{ID: 3}, Issuer: "Microservice-B", Owner: "another@example.com"}
+
+	// The tickets of each subject that each role is granted, in the order of own, of issuer and other.
+	cases := []struct {
+		subject  string
+		customer []bool
+		agent    []bool
+		admin    []bool
+	}{
+		{"kiosk.tickets.create", []bool{true, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.tickets.load", []bool{true, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.tickets.update", []bool{false, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.tickets.delete", []bool{false, false, false}, []bool{false, false, false}, []bool{true, true, true}},
+		{"kiosk.comments.create", []bool{true, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.comments.create_from_template", []bool{false, false, false}, []bool{true, true, false},
+			[]bool{true, true, true}},
+		{"kiosk.comments.load", []bool{true, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.comments.update", []bool{false, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.comments.delete", []bool{false, false, false}, []bool{false, false, false}, []bool{true, true, true}},
+		{"kiosk.tickets.rate", []bool{true, false, false}, []bool{false, false, false}, []bool{true, true, true}},
+		{"kiosk.macros.execute", []bool{false, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.exports.load", []bool{false, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+		{"kiosk.streams.replay", []bool{true, false, false}, []bool{true, true, false}, []bool{true, true, true}},
+	}
+
+	expect := func(e *errors.Type, granted bool, description ...interface{}) {
+		if granted {
+			Ω(e).Should(BeNil(), description...)
+		} else {
+			Ω(e).ShouldNot(BeNil(), description...)
+			Ω(e.Errors[0].Code).Should(Equal("forbidden"))
+			Ω(e.HTTPStatusCode).Should(Equal(403))
+		}
+	}
+
+	Context("When Authorize called", func() {
+		It("Should grant each role the tickets of its scope on each subject", func() {
+			for _, c := range cases {
+				for i, t := range []*models.Ticket{own, ofIssuer, other} {
+					expect(authorizer.Authorize(customer, c.subject, t), c.customer[i], "customer", c.subject, t.ID)
+					expect(authorizer.Authorize(agent, c.subject, t), c.agent[i], "agent", c.subject, t.ID)
+					expect(authorizer.Authorize(admin, c.subject, t), c.admin[i], "admin", c.subject, t.ID)
+				}
+			}
+		})
+
+		It("Should not restrict the requests without identity", func() {
+			for _, c := range cases {
+				Ω(authorizer.Authorize(nil, c.subject, other)).Should(BeNil())
+			}
+		})
+
+		It("Should grant the widest scope of the roles", func() {
+			identity := &data.Identity{Subject: "user@example.com", Roles: []string{"customer", "agent"},
+				Tenant: "Microservice-A"}
+			expect(authorizer.Authorize(identity, "kiosk.tickets.load", ofIssuer), true)
+			expect(authorizer.Authorize(identity, "kiosk.tickets.load", other), false)
+
+			identity.Roles = append(identity.Roles, "admin")
+			expect(authorizer.Authorize(identity, "kiosk.tickets.load", other), true)
+		})
+
+		It("Should forbid the callers without a known role", func() {
+			expect(authorizer.Authorize(&data.Identity{Subject: "user@example.com"}, "kiosk.tickets.load", own),
+				false)
+			expect(authorizer.Authorize(&data.Identity{Subject: "user@example.com", Roles: []string{"guest"}},
+				"kiosk.tickets.load", own), false)
+		})
+
+		It("Should forbid the agents without a tenant", func() {
+			expect(authorizer.Authorize(&data.Identity{Subject: "agent@example.com", Roles: []string{"agent"}},
+				"kiosk.tickets.load", &models.Ticket{}), false)
+		})
+
+		It("Should grant the API keys the tickets of their issuer on their operations only", func() {
+			expect(authorizer.Authorize(apiKey, "kiosk.tickets.load", own), true)
+			expect(authorizer.Authorize(apiKey, "kiosk.tickets.load", ofIssuer), true)
+			expect(authorizer.Authorize(apiKey, "kiosk.tickets.load", other), false)
+			expect(authorizer.Authorize(apiKey, "kiosk.tickets.update", own), false)
+			expect(authorizer.Authorize(apiKey, "kiosk.comments.create", own), false)
+		})
+
+		It("Should ignore the roles of the API keys", func() {
+			identity := &data.Identity{Subject: "api-key:orders", Roles: []string{"admin"}, Tenant: "Microservice-A",
+				KeyPrefix: "0123456789abcdef", Operations: []string{"kiosk.tickets.load"}}
+			expect(authorizer.Authorize(identity, "kiosk.tickets.load", other), false)
+			expect(authorizer.Authorize(identity, "kiosk.tickets.delete", own), false)
+		})
+
+		It("Should leave the missing tickets to the handlers", func() {
+			expect(authorizer.Authorize(customer, "kiosk.tickets.load", nil), true)
+			expect(authorizer.Authorize(customer, "kiosk.tickets.delete", nil), false)
+		})
+	})
+
+	Context("When AuthorizeFilter called", func() {
+		It("Should restrict the criteria values to the scope of each role", func() {
+			issuer, owner := "", ""
+			expect(authorizer.AuthorizeFilter(customer, "kiosk.tickets.filter", &issuer, &owner), true)
+			Ω(owner).Should(Equal(customer.Subject))
+
+			issuer, owner = "", "another@example.com"
+			expect(authorizer.AuthorizeFilter(customer, "kiosk.tickets.filter", &issuer, &owner), false)
+
+			issuer, owner = "", "another@example.com"
+			expect(authorizer.AuthorizeFilter(agent, "kiosk.tickets.filter", &issuer, &owner), true)
+			Ω(issuer).Should(Equal(agent.Tenant))
+			Ω(owner).Should(Equal("another@example.com"))
+
+			issuer, owner = "Microservice-B", ""
+			expect(authorizer.AuthorizeFilter(agent, "kiosk.tickets.filter", &issuer, &owner), false)
+
+			issuer, owner = "Microservice-B", ""
+			expect(authorizer.AuthorizeFilter(admin, "kiosk.tickets.filter", &issuer, &owner), true)
+			Ω(issuer).Should(Equal("Microservice-B"))
+
+			issuer, owner = "", ""
+			expect(authorizer.AuthorizeFilter(nil, "kiosk.tickets.filter", &issuer, &owner), true)
+			Ω(issuer).Should(BeEmpty())
+			Ω(owner).Should(BeEmpty())
+		})
+
+		It("Should restrict the exports to the tickets of the issuer of the agents", func() {
+			issuer, owner := "", customer.Subject
+			expect(authorizer.AuthorizeFilter(customer, "kiosk.exports.create", &issuer, &owner), false)
+
+			issuer, owner = "", ""
+			expect(authorizer.AuthorizeFilter(agent, "kiosk.exports.create", &issuer, &owner), true)
+			Ω(issuer).Should(Equal(agent.Tenant))
+
+			issuer, owner = "Microservice-B", ""
+			expect(authorizer.AuthorizeFilter(agent, "kiosk.exports.create", &issuer, &owner), false)
+
+			issuer, owner = "", ""
+			expect(authorizer.AuthorizeFilter(admin, "kiosk.exports.create", &issuer, &owner), true)
+			Ω(issuer).Should(BeEmpty())
+
+			issuer, owner = "", ""
+			expect(authorizer.AuthorizeFilter(apiKey, "kiosk.exports.create", &issuer, &owner), false)
+		})
+	})
+
+	Context("When AuthorizeIssuer called", func() {
+		It("Should only grant the admins the API keys by default", func() {
+			for _, subject := range []string{"kiosk.api_keys.create", "kiosk.api_keys.rotate", "kiosk.api_keys.revoke",
+				"kiosk.api_keys.filter"} {
+
+				expect(authorizer.AuthorizeIssuer(admin, subject, "Microservice-A"), true, "admin", subject)
+				expect(authorizer.AuthorizeIssuer(agent, subject, "Microservice-A"), false, "agent", subject)
+				expect(authorizer.AuthorizeIssuer(customer, subject, "Microservice-A"), false, "customer", subject)
+				expect(authorizer.AuthorizeIssuer(apiKey, subject, "Microservice-A"), false, "api key", subject)
+				expect(authorizer.AuthorizeIssuer(nil, subject, "Microservice-A"), true, "internal", subject)
+			}
+		})
+
+		It("Should grant the issuer scope the resources of the tenant", func() {
+			authorizer := services.NewAuthorizer(services.Policy{"agent": {
+				"kiosk.api_keys.filter": services.ScopeIssuer, "kiosk.api_keys.create": services.ScopeOwner}}, nil, nil)
+
+			expect(authorizer.AuthorizeIssuer(agent, "kiosk.api_keys.filter", "Microservice-A"), true)
+			expect(authorizer.AuthorizeIssuer(agent, "kiosk.api_keys.filter", "Microservice-B"), false)
+			expect(authorizer.AuthorizeIssuer(agent, "kiosk.api_keys.filter", ""), false)
+			expect(authorizer.AuthorizeIssuer(agent, "kiosk.api_keys.create", "Microservice-A"), false)
+		})
+	})
+
+	Context("When the webhook subjects authorized", func() {
+		subjects := []string{"kiosk.webhooks.create", "kiosk.webhooks.load", "kiosk.webhooks.update",
+			"kiosk.webhooks.delete", "kiosk.webhooks.deliveries", "kiosk.webhooks.redeliver"}
+
+		It("Should only grant the admins the webhooks by default", func() {
+			for _, subject := range subjects {
+				expect(authorizer.AuthorizeIssuer(admin, subject, "Microservice-A"), true, "admin", subject)
+				expect(authorizer.AuthorizeIssuer(admin, subject, ""), true, "admin", subject)
+				expect(authorizer.AuthorizeIssuer(agent, subject, "Microservice-A"), false, "agent", subject)
+				expect(authorizer.AuthorizeIssuer(customer, subject, "Microservice-A"), false, "customer", subject)
+				expect(authorizer.AuthorizeIssuer(apiKey, subject, "Microservice-A"), false, "api key", subject)
+				expect(authorizer.AuthorizeIssuer(nil, subject, "Microservice-A"), true, "internal", subject)
+			}
+		})
+
+		It("Should grant the issuer scope the webhooks of the tenant only", func() {
+			authorizer := services.NewAuthorizer(services.Policy{"agent": {"kiosk.webhooks.load": services.ScopeIssuer,
+				"kiosk.webhooks.filter": services.ScopeIssuer}}, nil, nil)
+
+			expect(authorizer.AuthorizeIssuer(agent, "kiosk.webhooks.load", "Microservice-A"), true)
+			expect(authorizer.AuthorizeIssuer(agent, "kiosk.webhooks.load", "Microservice-B"), false)
+			expect(authorizer.AuthorizeIssuer(agent, "kiosk.webhooks.load", ""), false)
+
+			issuer := ""
+			expect(authorizer.AuthorizeIssuerFilter(agent, "kiosk.webhooks.filter", &issuer), true)
+			Ω(issuer).Should(Equal(agent.Tenant))
+
+			issuer = "Microservice-B"
+			expect(authorizer.AuthorizeIssuerFilter(agent, "kiosk.webhooks.filter", &issuer), false)
+		})
+
+		It("Should let the admins filter the webhooks of every issuer", func() {
+			issuer := ""
+			expect(authorizer.AuthorizeIssuerFilter(admin, "kiosk.webhooks.filter", &issuer), true)
+			Ω(issuer).Should(BeEmpty())
+
+			expect(authorizer.AuthorizeIssuerFilter(agent, "kiosk.webhooks.filter", &issuer), false)
+			expect(authorizer.AuthorizeIssuerFilter(customer, "kiosk.webhooks.filter", &issuer), false)
+		})
+	})
+
+	Context("When AuthorizeOwner called", func() {
+		It("Should bind the owner to the subject of the identity", func() {
+			owner := ""
+			expect(authorizer.AuthorizeOwner(customer, &owner), true)
+			Ω(owner).Should(Equal(customer.Subject))
+
+			owner = customer.Subject
+			expect(authorizer.AuthorizeOwner(customer, &owner), true)
+
+			owner = "another@example.com"
+			expect(authorizer.AuthorizeOwner(customer, &owner), false)
+			expect(authorizer.AuthorizeOwner(admin, &owner), false)
+		})
+
+		It("Should let the API keys and the requests without identity name any owner", func() {
+			owner := "another@example.com"
+			expect(authorizer.AuthorizeOwner(apiKey, &owner), true)
+			expect(authorizer.AuthorizeOwner(nil, &owner), true)
+			Ω(owner).Should(Equal("another@example.com"))
+		})
+	})
+
+	Context("When AuthorizeAll called", func() {
+		It("Should only grant the admins the rules and imports by default", func() {
+			for _, subject := range []string{"kiosk.rules.create", "kiosk.rules.test", "kiosk.imports.create",
+				"kiosk.imports.load"} {
+
+				expect(authorizer.AuthorizeAll(admin, subject), true, "admin", subject)
+				expect(authorizer.AuthorizeAll(agent, subject), false, "agent", subject)
+				expect(authorizer.AuthorizeAll(customer, subject), false, "customer", subject)
+				expect(authorizer.AuthorizeAll(nil, subject), true, "internal", subject)
+			}
+		})
+	})
+
+	Context("When Authenticate called without a repository", func() {
+		It("Should reject the API keys", func() {
+			identity, e := authorizer.Authenticate(context.Background(), "kiosk_0123456789abcdef_secret")
+			Ω(identity).Should(BeNil())
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.HTTPStatusCode).Should(Equal(401))
+		})
+	})
+})
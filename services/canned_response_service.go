@@ -0,0 +1,338 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"text/template"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// CannedResponseService is a service implementation of canned response related functionalities.
+type CannedResponseService struct {
+	logger                   *zap.SugaredLogger
+	cannedResponseRepository *models.CannedResponseRepository
+	natsClient               *nc.Conn
+	authorizer               *Authorizer
+	catalogue                *errors.Catalogue
+	stop                     chan struct{}
+}
+
+// NewCannedResponseService returns a newly created and ready to use CannedResponseService.
+func NewCannedResponseService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *nc.Conn,
+	authorizer *Authorizer, catalogue *errors.Catalogue) *CannedResponseService {
+
+	return &CannedResponseService{
+		logger:                   logger,
+		cannedResponseRepository: models.NewCannedResponseRepository(logger, db),
+		natsClient:               natsClient,
+		authorizer:               authorizer,
+		catalogue:                catalogue,
+		stop:                     make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions so ready to be notified.
+func (s *CannedResponseService) Start() error {
+	createCannedResponseSubscription, e := s.natsClient.QueueSubscribe("kiosk.canned_responses.create",
+		"kiosk.canned_responses.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	loadCannedResponseSubscription, e := s.natsClient.QueueSubscribe("kiosk.canned_responses.load",
+		"kiosk.canned_responses.load_group", s.authorizer.identified(s.load))
+	if e != nil {
+		return e
+	}
+
+	updateCannedResponseSubscription, e := s.natsClient.QueueSubscribe("kiosk.canned_responses.update",
+		"kiosk.canned_responses.update_group", s.authorizer.identified(s.update))
+	if e != nil {
+		return e
+	}
+
+	deleteCannedResponseSubscription, e := s.natsClient.QueueSubscribe("kiosk.canned_responses.delete",
+		"kiosk.canned_responses.delete_group", s.authorizer.identified(s.delete))
+	if e != nil {
+		return e
+	}
+
+	filterCannedResponsesSubscription, e := s.natsClient.QueueSubscribe("kiosk.canned_responses.filter",
+		"kiosk.canned_responses.filter_group", s.authorizer.identified(s.filter))
+	if e != nil {
+		return e
+	}
+
+	go s.await(createCannedResponseSubscription, loadCannedResponseSubscription, updateCannedResponseSubscription,
+		deleteCannedResponseSubscription, filterCannedResponsesSubscription)
+
+	return nil
+}
+
+func (s *CannedResponseService) await(ss ...*nc.Subscription) {
+	<-s.stop
+	s.logger.Debug("CannedResponseService: received stop signal!")
+
+	for _, s := range ss {
+		_ = s.Unsubscribe()
+	}
+}
+
+func (s *CannedResponseService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createCannedResponseRequest := &data.CreateCannedResponseRequest{}
+	if e := json.Unmarshal(msg.Data, createCannedResponseRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createCannedResponseRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.canned_responses.create",
+		createCannedResponseRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.cannedResponseRepository.Insert(ctx, *createCannedResponseRequest.AsCannedResponse()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *CannedResponseService) load(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	c, e := s.cannedResponseRepository.LoadByID(ctx, id.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	identity, issuer := identityOf(msg), c.Issuer
+	if identity != nil && issuer == "" {
+		// Global canned responses are shared by every issuer.
+		issuer = identity.Tenant
+	}
+
+	if e := s.authorizer.AuthorizeIssuer(identity, "kiosk.canned_responses.load", issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	cannedResponseResponse := &data.CannedResponseResponse{}
+	cannedResponseResponse.LoadFromCannedResponse(c)
+	s.reply(msg, cannedResponseResponse)
+}
+
+func (s *CannedResponseService) update(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updateCannedResponseRequest := &data.UpdateCannedResponseRequest{}
+	if e := json.Unmarshal(msg.Data, updateCannedResponseRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := updateCannedResponseRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	issuer := s.issuer(ctx, updateCannedResponseRequest.ID)
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.canned_responses.update", issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.cannedResponseRepository.Update(ctx, updateCannedResponseRequest.AsCannedResponse()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *CannedResponseService) delete(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.canned_responses.delete", s.issuer(ctx, id.ID)); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.cannedResponseRepository.DeleteByID(ctx, id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *CannedResponseService) filter(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterCannedResponsesRequest := &data.FilterCannedResponsesRequest{}
+	if e := json.Unmarshal(msg.Data, filterCannedResponsesRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterCannedResponsesRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuerFilter(identityOf(msg), "kiosk.canned_responses.filter",
+		&filterCannedResponsesRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	cs, hasNextPage, e := s.cannedResponseRepository.Filter(ctx, filterCannedResponsesRequest.Issuer,
+		filterCannedResponsesRequest.PageNumber, filterCannedResponsesRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterCannedResponsesResponse := &data.FilterCannedResponsesResponse{}
+	filterCannedResponsesResponse.LoadFromCannedResponses(cs, hasNextPage)
+	s.reply(msg, filterCannedResponsesResponse)
+}
+
+func (s *CannedResponseService) issuer(ctx context.Context, id int64) func() (string, *errors.Type) {
+	return func() (string, *errors.Type) {
+		c, e := s.cannedResponseRepository.LoadByID(ctx, id)
+		if e != nil {
+			return "", e
+		}
+
+		return c.Issuer, nil
+	}
+}
+
+func (s *CannedResponseService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (s *CannedResponseService) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component and it subscriptions.
+func (s *CannedResponseService) Stop() {
+	s.stop <- struct{}{}
+}
+
+// templateTicket is the data that canned response templates are rendered against, e.g: {{.Subject}} or
+// {{.Metadata.orderID}}.
+type templateTicket struct {
+	ID              int64
+	Issuer          string
+	Owner           string
+	Subject         string
+	Content         string
+	ImportanceLevel models.TicketImportanceLevel
+	Status          models.TicketStatus
+	Metadata        map[string]string
+	CreatedAt       time.Time
+	ModifiedAt      time.Time
+}
+
+// newTemplateTicket returns back the data that templates are rendered against for the ticket.
+func newTemplateTicket(ticket *models.Ticket) templateTicket {
+	return templateTicket{
+		ID:              ticket.ID,
+		Issuer:          ticket.Issuer,
+		Owner:           ticket.Owner,
+		Subject:         ticket.Subject,
+		Content:         ticket.Content,
+		ImportanceLevel: ticket.ImportanceLevel,
+		Status:          ticket.Status,
+		Metadata:        metadataValues(ticket.Metadata),
+		CreatedAt:       ticket.CreatedAt,
+		ModifiedAt:      ticket.ModifiedAt,
+	}
+}
+
+// renderCannedResponse renders the body of a canned response against a ticket. Canned responses of an issuer only
+// apply to the tickets of the same issuer.
+func renderCannedResponse(cannedResponse *models.CannedResponse, ticket *models.Ticket) (string, *errors.Type) {
+	if cannedResponse.Issuer != "" && cannedResponse.Issuer != ticket.Issuer {
+		return "", errors.PreconditionFailed("canned_response.issuer_mismatch", "")
+	}
+
+	t, e := template.New(cannedResponse.Name).Option("missingkey=zero").Parse(cannedResponse.Body)
+	if e != nil {
+		return "", errors.PreconditionFailed("canned_response.not_valid", e.Error())
+	}
+
+	out := &bytes.Buffer{}
+	if e := t.Execute(out, newTemplateTicket(ticket)); e != nil {
+		return "", errors.PreconditionFailed("canned_response.render_failed", e.Error())
+	}
+
+	if out.Len() == 0 {
+		return "", errors.PreconditionFailed("content.is_required", "")
+	}
+
+	if out.Len() > 5000 {
+		return "", errors.PreconditionFailed("content.invalid_length", "")
+	}
+
+	return out.String(), nil
+}
+
+// metadataValues returns back the top level keys of the metadata with their values, as strings for string values and
+// as JSON for the others. Metadata is free form, so it has keys only when it is a JSON object.
+func metadataValues(metadata string) map[string]string {
+	values := make(map[string]interface{})
+	_ = json.Unmarshal([]byte(metadata), &values)
+
+	out := make(map[string]string)
+	for k, v := range values {
+		if value, ok := v.(string); ok {
+			out[k] = value
+		} else {
+			value, _ := json.Marshal(v)
+			out[k] = string(value)
+		}
+	}
+
+	return out
+}
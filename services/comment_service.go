@@ -15,49 +15,72 @@ import (
 
 // CommentService is a service implementation of comment related functionalities.
 type CommentService struct {
-	logger            *zap.SugaredLogger
-	commentRepository *models.CommentRepository
-	natsClient        *nc.Conn
-	stop              chan struct{}
+	logger                   *zap.SugaredLogger
+	commentRepository        *models.CommentRepository
+	ticketRepository         *models.TicketRepository
+	cannedResponseRepository *models.CannedResponseRepository
+	natsClient               *nc.Conn
+	dispatcher               *EventDispatcher
+	authorizer               *Authorizer
+	rateLimiter              *RateLimiter
+	catalogue                *errors.Catalogue
+	stop                     chan struct{}
 }
 
 // NewCommentService returns a newly created and ready to use CommentService.
-func NewCommentService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *nc.Conn) *CommentService {
+func NewCommentService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *nc.Conn,
+	dispatcher *EventDispatcher, authorizer *Authorizer, rateLimiter *RateLimiter,
+	catalogue *errors.Catalogue) *CommentService {
+
 	return &CommentService{
-		logger:            logger,
-		commentRepository: models.NewCommentRepository(logger, db),
-		natsClient:        natsClient,
-		stop:              make(chan struct{}),
+		logger:                   logger,
+		commentRepository:        models.NewCommentRepository(logger, db),
+		ticketRepository:         models.NewTicketRepository(logger, db),
+		cannedResponseRepository: models.NewCannedResponseRepository(logger, db),
+		natsClient:               natsClient,
+		dispatcher:               dispatcher,
+		authorizer:               authorizer,
+		rateLimiter:              rateLimiter,
+		catalogue:                catalogue,
+		stop:                     make(chan struct{}),
 	}
 }
 
 // Start starts the subscriptions so ready to be notified.
 func (s *CommentService) Start() error {
 	createCommentSubscription, e := s.natsClient.QueueSubscribe("kiosk.comments.create",
-		"kiosk.comments.create_group", s.create)
+		"kiosk.comments.create_group", s.authorizer.identified(s.rateLimiter.limited(s.create)))
 	if e != nil {
 		return e
 	}
 
 	loadCommentSubscription, e := s.natsClient.QueueSubscribe("kiosk.comments.load",
-		"kiosk.comments.load_group", s.load)
+		"kiosk.comments.load_group", s.authorizer.identified(s.rateLimiter.limited(s.load)))
 	if e != nil {
 		return e
 	}
 
 	updateCommentSubscription, e := s.natsClient.QueueSubscribe("kiosk.comments.update",
-		"kiosk.comments.update_group", s.update)
+		"kiosk.comments.update_group", s.authorizer.identified(s.rateLimiter.limited(s.update)))
 	if e != nil {
 		return e
 	}
 
 	deleteCommentSubscription, e := s.natsClient.QueueSubscribe("kiosk.comments.delete",
-		"kiosk.comments.delete_group", s.delete)
+		"kiosk.comments.delete_group", s.authorizer.identified(s.rateLimiter.limited(s.delete)))
+	if e != nil {
+		return e
+	}
+
+	createCommentFromTemplateSubscription, e := s.natsClient.QueueSubscribe("kiosk.comments.create_from_template",
+		"kiosk.comments.create_from_template_group",
+		s.authorizer.identified(s.rateLimiter.limited(s.createFromTemplate)))
 	if e != nil {
 		return e
 	}
 
-	go s.await(createCommentSubscription, loadCommentSubscription, updateCommentSubscription, deleteCommentSubscription)
+	go s.await(createCommentSubscription, loadCommentSubscription, updateCommentSubscription, deleteCommentSubscription,
+		createCommentFromTemplateSubscription)
 
 	return nil
 }
@@ -86,12 +109,87 @@ func (s *CommentService) create(msg *nc.Msg) {
 		return
 	}
 
-	if e := s.commentRepository.Insert(ctx, *createCommentRequest.AsComment()); e != nil {
+	if e := s.authorizer.AuthorizeOwner(identityOf(msg), &createCommentRequest.Owner); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ticket := s.ticket(ctx, createCommentRequest.TicketID)
+	if e := s.authorizer.authorize(msg, "kiosk.comments.create", ticket); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	id, e := s.commentRepository.Insert(ctx, *createCommentRequest.AsComment())
+	if e != nil {
 		s.reply(msg, e)
 		return
 	}
 
 	s.replyNoContent(msg)
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeCommentCreated, TicketID: createCommentRequest.TicketID,
+		CommentID: id})
+}
+
+func (s *CommentService) createFromTemplate(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createCommentFromTemplateRequest := &data.CreateCommentFromTemplateRequest{}
+	if e := json.Unmarshal(msg.Data, createCommentFromTemplateRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createCommentFromTemplateRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeOwner(identityOf(msg), &createCommentFromTemplateRequest.Owner); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	c, e := s.cannedResponseRepository.LoadByID(ctx, createCommentFromTemplateRequest.CannedResponseID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	t, e := s.ticketRepository.LoadByID(ctx, createCommentFromTemplateRequest.TicketID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.Authorize(identityOf(msg), "kiosk.comments.create_from_template", t); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	content, e := renderCannedResponse(c, t)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	comment := createCommentFromTemplateRequest.AsComment(content)
+	if !createCommentFromTemplateRequest.Preview {
+		if comment.ID, e = s.commentRepository.Insert(ctx, *comment); e != nil {
+			s.reply(msg, e)
+			return
+		}
+	}
+
+	renderedCommentResponse := &data.RenderedCommentResponse{}
+	renderedCommentResponse.LoadFromComment(comment, createCommentFromTemplateRequest.Preview)
+	s.reply(msg, renderedCommentResponse)
+
+	if !createCommentFromTemplateRequest.Preview {
+		s.dispatcher.Dispatch(Event{Type: models.EventTypeCommentCreated, TicketID: comment.TicketID,
+			CommentID: comment.ID})
+	}
 }
 
 func (s *CommentService) load(msg *nc.Msg) {
@@ -110,6 +208,11 @@ func (s *CommentService) load(msg *nc.Msg) {
 		return
 	}
 
+	if e := s.authorizer.authorize(msg, "kiosk.comments.load", s.ticket(ctx, c.TicketID)); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
 	commentResponse := &data.CommentResponse{}
 	commentResponse.LoadFromComment(c)
 	s.reply(msg, commentResponse)
@@ -130,12 +233,25 @@ func (s *CommentService) update(msg *nc.Msg) {
 		return
 	}
 
-	if e := s.commentRepository.Update(ctx, updateCommentRequest.AsComment()); e != nil {
+	ticket := s.ticketOfComment(ctx, updateCommentRequest.ID)
+	if e := s.authorizer.authorize(msg, "kiosk.comments.update", ticket); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	var ticketID int64
+	if updated, e := s.commentRepository.LoadByID(ctx, updateCommentRequest.ID); e == nil {
+		ticketID = updated.TicketID
+	}
+
+	if e := s.commentRepository.Update(ctx, updateCommentRequest.AsComment(), actorOf(msg)); e != nil {
 		s.reply(msg, e)
 		return
 	}
 
 	s.replyNoContent(msg)
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeCommentUpdated, TicketID: ticketID,
+		CommentID: updateCommentRequest.ID})
 }
 
 func (s *CommentService) delete(msg *nc.Msg) {
@@ -148,16 +264,46 @@ func (s *CommentService) delete(msg *nc.Msg) {
 		return
 	}
 
-	if e := s.commentRepository.DeleteByID(ctx, id.ID); e != nil {
+	if e := s.authorizer.authorize(msg, "kiosk.comments.delete", s.ticketOfComment(ctx, id.ID)); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	var ticketID int64
+	if deleted, e := s.commentRepository.LoadByID(ctx, id.ID); e == nil {
+		ticketID = deleted.TicketID
+	}
+
+	if e := s.commentRepository.DeleteByID(ctx, id.ID, actorOf(msg)); e != nil {
 		s.reply(msg, e)
 		return
 	}
 
 	s.replyNoContent(msg)
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeCommentDeleted, TicketID: ticketID, CommentID: id.ID})
+}
+
+// ticket returns back the loader of the ticket for authorizing the callers.
+func (s *CommentService) ticket(ctx context.Context, id int64) func() (*models.Ticket, *errors.Type) {
+	return func() (*models.Ticket, *errors.Type) {
+		return s.ticketRepository.LoadByID(ctx, id)
+	}
+}
+
+// ticketOfComment returns back the loader of the ticket of the comment for authorizing the callers.
+func (s *CommentService) ticketOfComment(ctx context.Context, id int64) func() (*models.Ticket, *errors.Type) {
+	return func() (*models.Ticket, *errors.Type) {
+		c, e := s.commentRepository.LoadByID(ctx, id)
+		if e != nil {
+			return nil, e
+		}
+
+		return s.ticketRepository.LoadByID(ctx, c.TicketID)
+	}
 }
 
 func (s *CommentService) reply(msg *nc.Msg, t interface{}) {
-	reply, _ := json.Marshal(t)
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
 	_ = msg.Respond(reply)
 }
 
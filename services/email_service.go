@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/lireza/lib/configuring"
+	"go.uber.org/zap"
+)
+
+// EmailService is a service implementation of the email channel. Inbound emails are periodically read from a mail
+// source in background and turned into tickets, or into comments when they are replies to known tickets.
+type EmailService struct {
+	logger           *zap.SugaredLogger
+	ticketRepository *models.TicketRepository
+	emailRepository  *models.EmailRepository
+	dispatcher       *EventDispatcher
+	source           MailSource
+	pollInterval     time.Duration
+	batchSize        int
+	issuer           string
+	importanceLevel  models.TicketImportanceLevel
+	ctx              context.Context
+	cancel           context.CancelFunc
+	stop             chan struct{}
+}
+
+// NewEmailService returns a newly created and ready to use EmailService. The service is disabled, i.e. starting it
+// does nothing, when no maildir is configured.
+func NewEmailService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool,
+	dispatcher *EventDispatcher) (*EmailService, error) {
+
+	maildir := config.Get("email.maildir").StringOrElse("")
+	pollInterval := config.Get("email.poll_interval").DurationOrElse(30 * time.Second)
+	batchSize := config.Get("email.batch_size").IntOrElse(100)
+	issuer := config.Get("email.issuer").StringOrElse("email")
+	importanceLevel := config.Get("email.importance_level").StringOrElse(string(models.TicketImportanceLevelMedium))
+
+	logger.Info("email.maildir -> ", maildir)
+	logger.Info("email.poll_interval -> ", pollInterval)
+	logger.Info("email.batch_size -> ", batchSize)
+	logger.Info("email.issuer -> ", issuer)
+	logger.Info("email.importance_level -> ", importanceLevel)
+
+	switch models.TicketImportanceLevel(importanceLevel) {
+	case models.TicketImportanceLevelLow, models.TicketImportanceLevelMedium, models.TicketImportanceLevelHigh,
+		models.TicketImportanceLevelCritical:
+	default:
+		return nil, fmt.Errorf("invalid email.importance_level: %v", importanceLevel)
+	}
+
+	var source MailSource
+	if maildir != "" {
+		maildirSource, e := NewMaildirSource(maildir)
+		if e != nil {
+			return nil, e
+		}
+
+		source = maildirSource
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &EmailService{
+		logger:           logger,
+		ticketRepository: models.NewTicketRepository(logger, db),
+		emailRepository:  models.NewEmailRepository(logger, db),
+		dispatcher:       dispatcher,
+		source:           source,
+		pollInterval:     pollInterval,
+		batchSize:        batchSize,
+		issuer:           issuer,
+		importanceLevel:  models.TicketImportanceLevel(importanceLevel),
+		ctx:              ctx,
+		cancel:           cancel,
+		stop:             make(chan struct{}),
+	}, nil
+}
+
+// Start starts the background reader of the mail source.
+func (s *EmailService) Start() error {
+	go s.await()
+
+	return nil
+}
+
+func (s *EmailService) await() {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.logger.Debug("EmailService: received stop signal!")
+			return
+		case <-ticker.C:
+			if s.source != nil {
+				s.process()
+			}
+		}
+	}
+}
+
+// process ingests a batch of messages of the mail source. Messages failing because of temporary errors are left in
+// the source to be retried on the next round.
+func (s *EmailService) process() {
+	keys, e := s.source.Fetch(s.batchSize)
+	if e != nil {
+		s.logger.Warnf("EmailService: failed to fetch messages: %v", e.Error())
+		return
+	}
+
+	for _, key := range keys {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		failed, e := s.ingest(key)
+		if e != nil && !failed {
+			continue
+		}
+
+		if e != nil {
+			s.logger.Warnf("EmailService: rejected message %v: %v", key, e.Error())
+		}
+
+		if e := s.source.Done(key, failed); e != nil {
+			s.logger.Warnf("EmailService: failed to mark message %v as done: %v", key, e.Error())
+		}
+	}
+}
+
+// ingest turns a message into a ticket or a comment. The returned boolean reports whether the message is rejected
+// for good, e.g. when it is malformed.
+func (s *EmailService) ingest(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	reader, e := s.source.Open(key)
+	if e != nil {
+		return false, e
+	}
+	defer func() { _ = reader.Close() }()
+
+	inbound, e := parseEmail(reader)
+	if e != nil {
+		return true, e
+	}
+
+	if len(inbound.Sender) > 50 {
+		return true, errors.InvalidArgument("owner.invalid_length", "")
+	}
+
+	ticketID, et := s.thread(ctx, inbound)
+	if et != nil {
+		return false, et
+	}
+
+	email := &models.Email{MessageID: truncate(inbound.MessageID, 255), TicketID: ticketID, Sender: inbound.Sender}
+	metadata, _ := json.Marshal(map[string]string{"sender": inbound.Sender, "messageID": email.MessageID})
+
+	content := stripQuotedText(inbound.Body)
+	if content == "" {
+		content = "(no content)"
+	}
+
+	var ticket *models.Ticket
+	var comment *models.Comment
+	if ticketID == 0 {
+		subject := cleanSubject(inbound.Subject)
+		if subject == "" {
+			subject = "(no subject)"
+		}
+
+		ticket = &models.Ticket{
+			Issuer:          s.issuer,
+			Owner:           inbound.Sender,
+			Subject:         truncate(subject, 255),
+			Content:         truncate(content, 5000),
+			Metadata:        string(metadata),
+			ImportanceLevel: s.importanceLevel,
+		}
+	} else {
+		comment = &models.Comment{Owner: inbound.Sender, Content: truncate(content, 5000), Metadata: string(metadata)}
+	}
+
+	ingested, et := s.emailRepository.Ingest(ctx, email, ticket, comment)
+	if et != nil {
+		return false, et
+	}
+
+	if !ingested {
+		return false, nil
+	}
+
+	if comment == nil {
+		s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketCreated, TicketID: email.TicketID})
+	} else {
+		s.dispatcher.Dispatch(Event{Type: models.EventTypeCommentCreated, TicketID: email.TicketID,
+			CommentID: email.CommentID})
+	}
+
+	return false, nil
+}
+
+// thread returns back the ticket that a message replies to, or zero when it starts a new one. Replies are recognized
+// by the In-Reply-To and References headers, or by a [#ID] token in the subject when sent by the owner of the ticket.
+func (s *EmailService) thread(ctx context.Context, inbound *inboundEmail) (int64, *errors.Type) {
+	if len(inbound.References) > 0 {
+		ticketID, e := s.emailRepository.LoadTicketIDByMessageIDs(ctx, inbound.References)
+		if e == nil {
+			return ticketID, nil
+		}
+
+		if e.HTTPStatusCode != http.StatusNotFound {
+			return 0, e
+		}
+	}
+
+	if id, ok := ticketToken(inbound.Subject); ok {
+		t, e := s.ticketRepository.LoadByID(ctx, id)
+		if e == nil && t.Owner == inbound.Sender {
+			return t.ID, nil
+		}
+
+		if e != nil && e.HTTPStatusCode != http.StatusNotFound {
+			return 0, e
+		}
+	}
+
+	return 0, nil
+}
+
+// Stop stops the component and its background reader.
+func (s *EmailService) Stop() {
+	s.cancel()
+	s.stop <- struct{}{}
+}
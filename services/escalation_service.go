@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// EscalationService is a service implementation of time based escalations.
+type EscalationService struct {
+	logger                     *zap.SugaredLogger
+	escalationPolicyRepository *models.EscalationPolicyRepository
+	escalationRepository       *models.EscalationRepository
+	ticketRepository           *models.TicketRepository
+	natsClient                 *nc.Conn
+	dispatcher                 *EventDispatcher
+	pollInterval               time.Duration
+	batchSize                  int
+	commentOwner               string
+	ctx                        context.Context
+	cancel                     context.CancelFunc
+	authorizer                 *Authorizer
+	catalogue                  *errors.Catalogue
+	stop                       chan struct{}
+}
+
+// NewEscalationService returns a newly created and ready to use EscalationService.
+func NewEscalationService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool,
+	natsClient *nc.Conn, dispatcher *EventDispatcher, authorizer *Authorizer,
+	catalogue *errors.Catalogue) *EscalationService {
+
+	pollInterval := config.Get("escalations.poll_interval").DurationOrElse(time.Minute)
+	batchSize := config.Get("escalations.batch_size").IntOrElse(100)
+	commentOwner := config.Get("escalations.comment_owner").StringOrElse("kiosk")
+
+	logger.Info("escalations.poll_interval -> ", pollInterval)
+	logger.Info("escalations.batch_size -> ", batchSize)
+	logger.Info("escalations.comment_owner -> ", commentOwner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &EscalationService{
+		logger:                     logger,
+		escalationPolicyRepository: models.NewEscalationPolicyRepository(logger, db),
+		escalationRepository:       models.NewEscalationRepository(logger, db),
+		ticketRepository:           models.NewTicketRepository(logger, db),
+		natsClient:                 natsClient,
+		dispatcher:                 dispatcher,
+		pollInterval:               pollInterval,
+		batchSize:                  batchSize,
+		commentOwner:               commentOwner,
+		ctx:                        ctx,
+		cancel:                     cancel,
+		authorizer:                 authorizer,
+		catalogue:                  catalogue,
+		stop:                       make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions and the background evaluator so ready to be notified.
+func (s *EscalationService) Start() error {
+	createEscalationPolicySubscription, e := s.natsClient.QueueSubscribe("kiosk.escalation_policies.create",
+		"kiosk.escalation_policies.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	loadEscalationPolicySubscription, e := s.natsClient.QueueSubscribe("kiosk.escalation_policies.load",
+		"kiosk.escalation_policies.load_group", s.authorizer.identified(s.load))
+	if e != nil {
+		return e
+	}
+
+	updateEscalationPolicySubscription, e := s.natsClient.QueueSubscribe("kiosk.escalation_policies.update",
+		"kiosk.escalation_policies.update_group", s.authorizer.identified(s.update))
+	if e != nil {
+		return e
+	}
+
+	deleteEscalationPolicySubscription, e := s.natsClient.QueueSubscribe("kiosk.escalation_policies.delete",
+		"kiosk.escalation_policies.delete_group", s.authorizer.identified(s.delete))
+	if e != nil {
+		return e
+	}
+
+	filterEscalationPoliciesSubscription, e := s.natsClient.QueueSubscribe("kiosk.escalation_policies.filter",
+		"kiosk.escalation_policies.filter_group", s.authorizer.identified(s.filter))
+	if e != nil {
+		return e
+	}
+
+	filterEscalationsSubscription, e := s.natsClient.QueueSubscribe("kiosk.escalations.filter",
+		"kiosk.escalations.filter_group", s.authorizer.identified(s.escalations))
+	if e != nil {
+		return e
+	}
+
+	go s.await(createEscalationPolicySubscription, loadEscalationPolicySubscription,
+		updateEscalationPolicySubscription, deleteEscalationPolicySubscription, filterEscalationPoliciesSubscription,
+		filterEscalationsSubscription)
+
+	return nil
+}
+
+func (s *EscalationService) await(ss ...*nc.Subscription) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.logger.Debug("EscalationService: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case <-ticker.C:
+			s.process()
+		}
+	}
+}
+
+func (s *EscalationService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createEscalationPolicyRequest := &data.CreateEscalationPolicyRequest{}
+	if e := json.Unmarshal(msg.Data, createEscalationPolicyRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createEscalationPolicyRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.escalation_policies.create",
+		createEscalationPolicyRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e = s.escalationPolicyRepository.Insert(ctx, *createEscalationPolicyRequest.AsEscalationPolicy())
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *EscalationService) load(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	p, e := s.escalationPolicyRepository.LoadByID(ctx, id.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.escalation_policies.load", p.Issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	escalationPolicyResponse := &data.EscalationPolicyResponse{}
+	escalationPolicyResponse.LoadFromEscalationPolicy(p)
+	s.reply(msg, escalationPolicyResponse)
+}
+
+func (s *EscalationService) update(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updateEscalationPolicyRequest := &data.UpdateEscalationPolicyRequest{}
+	if e := json.Unmarshal(msg.Data, updateEscalationPolicyRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := updateEscalationPolicyRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	issuer := s.issuer(ctx, updateEscalationPolicyRequest.ID)
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.escalation_policies.update", issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.escalation_policies.update",
+		updateEscalationPolicyRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e = s.escalationPolicyRepository.Update(ctx, updateEscalationPolicyRequest.AsEscalationPolicy())
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *EscalationService) delete(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.escalation_policies.delete", s.issuer(ctx, id.ID)); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.escalationPolicyRepository.DeleteByID(ctx, id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *EscalationService) filter(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterEscalationPoliciesRequest := &data.FilterEscalationPoliciesRequest{}
+	if e := json.Unmarshal(msg.Data, filterEscalationPoliciesRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterEscalationPoliciesRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuerFilter(identityOf(msg), "kiosk.escalation_policies.filter",
+		&filterEscalationPoliciesRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ps, hasNextPage, e := s.escalationPolicyRepository.Filter(ctx, filterEscalationPoliciesRequest.Issuer,
+		filterEscalationPoliciesRequest.PageNumber, filterEscalationPoliciesRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterEscalationPoliciesResponse := &data.FilterEscalationPoliciesResponse{}
+	filterEscalationPoliciesResponse.LoadFromEscalationPolicies(ps, hasNextPage)
+	s.reply(msg, filterEscalationPoliciesResponse)
+}
+
+func (s *EscalationService) escalations(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterEscalationsRequest := &data.FilterEscalationsRequest{}
+	if e := json.Unmarshal(msg.Data, filterEscalationsRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterEscalationsRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ticket := s.ticket(ctx, filterEscalationsRequest.TicketID)
+	if e := s.authorizer.authorize(msg, "kiosk.escalations.filter", ticket); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	es, e := s.escalationRepository.LoadByTicketID(ctx, filterEscalationsRequest.TicketID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	escalationsResponse := &data.EscalationsResponse{}
+	escalationsResponse.LoadFromEscalations(es)
+	s.reply(msg, escalationsResponse)
+}
+
+// process evaluates all enabled policies, step by step, and escalates the tickets that are due.
+func (s *EscalationService) process() {
+	policies, e := s.escalationPolicyRepository.LoadEnabled(s.ctx)
+	if e != nil {
+		return
+	}
+
+	for _, p := range policies {
+		for step := range p.Steps {
+			tickets, e := s.escalationRepository.LoadCandidates(s.ctx, p, step, s.batchSize)
+			if e != nil {
+				return
+			}
+
+			for _, t := range tickets {
+				s.escalate(p, step, t)
+			}
+		}
+	}
+}
+
+func (s *EscalationService) escalate(policy *models.EscalationPolicy, step int, ticket *models.Ticket) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	escalationStep := policy.Steps[step]
+	escalation := models.Escalation{
+		TicketID:  ticket.ID,
+		PolicyID:  policy.ID,
+		Step:      step,
+		Action:    escalationStep.Action,
+		IdleSince: ticket.ModifiedAt,
+	}
+
+	switch escalationStep.Action {
+	case models.EscalationActionRaiseImportanceLevel:
+		previous := ticket.ImportanceLevel
+		ticket.ImportanceLevel = raiseImportanceLevel(previous)
+		if ticket.ImportanceLevel == previous {
+			escalation.Details = fmt.Sprintf("importance level is already %v", previous)
+		} else {
+			escalation.Details = fmt.Sprintf("importance level raised from %v to %v", previous, ticket.ImportanceLevel)
+		}
+	case models.EscalationActionReassign:
+		metadata, e := applyToMetadata(ticket.Metadata, models.MacroAction{Type: models.MacroActionTypeSetMetadata,
+			Key: "assignee", Value: escalationStep.Assignee})
+		if e != nil {
+			escalation.Details = fmt.Sprintf("could not reassign to %v, metadata is not a JSON object",
+				escalationStep.Assignee)
+		} else {
+			ticket.Metadata = metadata
+			escalation.Details = fmt.Sprintf("reassigned to %v", escalationStep.Assignee)
+		}
+	case models.EscalationActionNotify:
+		escalation.Details = fmt.Sprintf("notified %v", escalationStep.Topic)
+		if escalationStep.Supervisor != "" {
+			escalation.Details = fmt.Sprintf("notified %v on %v", escalationStep.Supervisor, escalationStep.Topic)
+		}
+	}
+
+	comment := &models.Comment{
+		Owner: s.commentOwner,
+		Content: fmt.Sprintf("Escalated by %q policy after %v of inactivity: %v.", policy.Name,
+			escalationStep.IdleTime, escalation.Details),
+	}
+
+	recorded, e := s.escalationRepository.Record(ctx, escalation, ticket, comment)
+	if e != nil || !recorded {
+		return
+	}
+
+	if escalationStep.Action == models.EscalationActionNotify {
+		ticketResponse := &data.TicketResponse{}
+		ticketResponse.LoadFromTicket(ticket)
+
+		notification, _ := json.Marshal(&data.EscalationNotification{
+			PolicyID:   policy.ID,
+			PolicyName: policy.Name,
+			Step:       step,
+			Supervisor: escalationStep.Supervisor,
+			Ticket:     ticketResponse,
+		})
+		if e := s.natsClient.Publish(escalationStep.Topic, notification); e != nil {
+			s.logger.Warnf("EscalationService: failed to notify %v: %v", escalationStep.Topic, e.Error())
+		}
+
+		return
+	}
+
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketUpdated, TicketID: ticket.ID})
+}
+
+func (s *EscalationService) issuer(ctx context.Context, id int64) func() (string, *errors.Type) {
+	return func() (string, *errors.Type) {
+		p, e := s.escalationPolicyRepository.LoadByID(ctx, id)
+		if e != nil {
+			return "", e
+		}
+
+		return p.Issuer, nil
+	}
+}
+
+func (s *EscalationService) ticket(ctx context.Context, id int64) func() (*models.Ticket, *errors.Type) {
+	return func() (*models.Ticket, *errors.Type) {
+		return s.ticketRepository.LoadByID(ctx, id)
+	}
+}
+
+func (s *EscalationService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (s *EscalationService) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component and it subscriptions.
+func (s *EscalationService) Stop() {
+	s.cancel()
+	s.stop <- struct{}{}
+}
+
+// raiseImportanceLevel returns back the importance level that is one step higher, up to critical.
+func raiseImportanceLevel(importanceLevel models.TicketImportanceLevel) models.TicketImportanceLevel {
+	switch importanceLevel {
+	case models.TicketImportanceLevelLow:
+		return models.TicketImportanceLevelMedium
+	case models.TicketImportanceLevelMedium:
+		return models.TicketImportanceLevelHigh
+	default:
+		return models.TicketImportanceLevelCritical
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/jibitters/kiosk/models"
+	"go.uber.org/zap"
+)
+
+// Event is a ticket or comment lifecycle event that is dispatched after a successful change.
+type Event struct {
+	Type      models.EventType
+	TicketID  int64
+	CommentID int64
+
+	// PreviousStatus holds the status of the ticket before a TICKET_UPDATED change, when known, so listeners can tell
+	// status changes apart from the other updates.
+	PreviousStatus models.TicketStatus
+
+	// Rules holds the ids of the automation rules that led to this event, in order, to stop rules triggering each other.
+	Rules []int64
+}
+
+// EventDispatcher delivers lifecycle events to the in-process listeners. Listeners are called synchronously, so they
+// should hand over the events to their own workers instead of doing the actual work in place.
+type EventDispatcher struct {
+	logger    *zap.SugaredLogger
+	mutex     sync.RWMutex
+	listeners []func(Event)
+}
+
+// NewEventDispatcher returns a newly created and ready to use EventDispatcher.
+func NewEventDispatcher(logger *zap.SugaredLogger) *EventDispatcher {
+	return &EventDispatcher{logger: logger}
+}
+
+// Subscribe registers the listener so it will be notified about all subsequent events.
+func (d *EventDispatcher) Subscribe(listener func(Event)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.listeners = append(d.listeners, listener)
+}
+
+// Dispatch delivers the event to all listeners.
+func (d *EventDispatcher) Dispatch(event Event) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	d.logger.Debugf("EventDispatcher: dispatching %v of ticket %v", event.Type, event.TicketID)
+	for _, listener := range d.listeners {
+		listener(event)
+	}
+}
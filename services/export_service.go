@@ -0,0 +1,346 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// ExportService is a service implementation of ticket export functionalities.
+type ExportService struct {
+	logger           *zap.SugaredLogger
+	exportRepository *models.ExportRepository
+	ticketRepository *models.TicketRepository
+	natsClient       *nc.Conn
+	storageDirectory string
+	pollInterval     time.Duration
+	lease            time.Duration
+	batchSize        int
+	ctx              context.Context
+	cancel           context.CancelFunc
+	authorizer       *Authorizer
+	catalogue        *errors.Catalogue
+	stop             chan struct{}
+}
+
+// NewExportService returns a newly created and ready to use ExportService.
+func NewExportService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool,
+	natsClient *nc.Conn, authorizer *Authorizer, catalogue *errors.Catalogue) *ExportService {
+
+	storageDirectory := config.Get("exports.storage_directory").StringOrElse("./exports")
+	pollInterval := config.Get("exports.poll_interval").DurationOrElse(5 * time.Second)
+	lease := config.Get("exports.lease").DurationOrElse(time.Minute)
+	batchSize := config.Get("exports.batch_size").IntOrElse(100)
+
+	logger.Info("exports.storage_directory -> ", storageDirectory)
+	logger.Info("exports.poll_interval -> ", pollInterval)
+	logger.Info("exports.lease -> ", lease)
+	logger.Info("exports.batch_size -> ", batchSize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ExportService{
+		logger:           logger,
+		exportRepository: models.NewExportRepository(logger, db),
+		ticketRepository: models.NewTicketRepository(logger, db),
+		natsClient:       natsClient,
+		storageDirectory: storageDirectory,
+		pollInterval:     pollInterval,
+		lease:            lease,
+		batchSize:        batchSize,
+		ctx:              ctx,
+		cancel:           cancel,
+		authorizer:       authorizer,
+		catalogue:        catalogue,
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions and the background worker so ready to be notified.
+func (s *ExportService) Start() error {
+	if e := os.MkdirAll(s.storageDirectory, 0750); e != nil {
+		return e
+	}
+
+	createExportSubscription, e := s.natsClient.QueueSubscribe("kiosk.exports.create",
+		"kiosk.exports.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	loadExportSubscription, e := s.natsClient.QueueSubscribe("kiosk.exports.load",
+		"kiosk.exports.load_group", s.authorizer.identified(s.load))
+	if e != nil {
+		return e
+	}
+
+	go s.await(createExportSubscription, loadExportSubscription)
+
+	return nil
+}
+
+func (s *ExportService) await(ss ...*nc.Subscription) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.logger.Debug("ExportService: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case <-ticker.C:
+			s.process()
+		}
+	}
+}
+
+func (s *ExportService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createExportRequest := &data.CreateExportRequest{}
+	if e := json.Unmarshal(msg.Data, createExportRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createExportRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeFilter(identityOf(msg), "kiosk.exports.create", &createExportRequest.Issuer,
+		&createExportRequest.Owner); e != nil {
+
+		s.reply(msg, e)
+		return
+	}
+
+	export := createExportRequest.AsExport()
+	export.FileName = fmt.Sprintf("export-%v.%v", uuid.New().String(), strings.ToLower(string(export.Format)))
+
+	id, e := s.exportRepository.Insert(ctx, *export)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	export, e = s.exportRepository.LoadByID(ctx, id)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	exportResponse := &data.ExportResponse{}
+	exportResponse.LoadFromExport(export)
+	s.reply(msg, exportResponse)
+}
+
+func (s *ExportService) load(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	export, e := s.exportRepository.LoadByID(ctx, id.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ticket := &models.Ticket{Issuer: export.Issuer, Owner: export.Owner}
+	if e := s.authorizer.Authorize(identityOf(msg), "kiosk.exports.load", ticket); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	exportResponse := &data.ExportResponse{}
+	exportResponse.LoadFromExport(export)
+	s.reply(msg, exportResponse)
+}
+
+// process claims at most one export job and runs it to the end, unless the service is stopping.
+func (s *ExportService) process() {
+	export, e := s.exportRepository.Claim(s.ctx, s.lease)
+	if e != nil || export == nil {
+		return
+	}
+
+	s.logger.Info("ExportService: processing export ", export.ID, " from ticket ", export.LastTicketID)
+
+	if e := s.run(export); e != nil {
+		if s.ctx.Err() != nil {
+			// Interrupted by stop, so hand the job over to whichever node claims it next.
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_ = s.exportRepository.Checkpoint(ctx, export, 0)
+			return
+		}
+
+		if et, ok := e.(*errors.Type); ok && et.HTTPStatusCode == http.StatusPreconditionFailed {
+			s.logger.Warn("ExportService: export ", export.ID, " was claimed by another node, stopped")
+			return
+		}
+
+		s.logger.Error("ExportService: export ", export.ID, " failed: ", e.Error())
+		_ = s.exportRepository.Fail(s.ctx, export, e.Error())
+		return
+	}
+
+	if et := s.exportRepository.Complete(s.ctx, export); et != nil {
+		if et.HTTPStatusCode == http.StatusPreconditionFailed {
+			s.logger.Warn("ExportService: export ", export.ID, " was claimed by another node, stopped")
+		}
+
+		return
+	}
+
+	s.logger.Info("ExportService: export ", export.ID, " completed with ", export.ExportedTickets, " tickets")
+}
+
+// run appends matching tickets to the export file batch by batch, checkpointing after every batch, so an interrupted
+// run resumes from the last checkpoint by truncating the partially written tail.
+func (s *ExportService) run(export *models.Export) error {
+	file, e := os.OpenFile(filepath.Join(s.storageDirectory, export.FileName), os.O_CREATE|os.O_WRONLY, 0640)
+	if e != nil {
+		return e
+	}
+	defer func() { _ = file.Close() }()
+
+	if e := file.Truncate(export.FileOffset); e != nil {
+		return e
+	}
+
+	if _, e := file.Seek(export.FileOffset, io.SeekStart); e != nil {
+		return e
+	}
+
+	writer := bufio.NewWriter(file)
+	if export.Format == models.ExportFormatCSV && export.FileOffset == 0 {
+		if e := s.writeCSV(writer, csvHeader); e != nil {
+			return e
+		}
+	}
+
+	for {
+		if e := s.ctx.Err(); e != nil {
+			return e
+		}
+
+		tickets, et := s.ticketRepository.FilterAfter(s.ctx, export.Issuer, export.Owner, export.ImportanceLevel,
+			export.Status, export.FromDate, export.ToDate, export.LastTicketID, s.batchSize)
+		if et != nil {
+			return et
+		}
+
+		if len(tickets) == 0 {
+			if e := writer.Flush(); e != nil {
+				return e
+			}
+
+			return file.Sync()
+		}
+
+		for _, t := range tickets {
+			if e := s.writeTicket(writer, export.Format, t); e != nil {
+				return e
+			}
+		}
+
+		if e := writer.Flush(); e != nil {
+			return e
+		}
+
+		offset, e := file.Seek(0, io.SeekCurrent)
+		if e != nil {
+			return e
+		}
+
+		export.ExportedTickets += int64(len(tickets))
+		export.LastTicketID = tickets[len(tickets)-1].ID
+		export.FileOffset = offset
+
+		if et := s.exportRepository.Checkpoint(s.ctx, export, s.lease); et != nil {
+			return et
+		}
+	}
+}
+
+var csvHeader = []string{"type", "id", "ticket_id", "issuer", "owner", "subject", "content", "metadata",
+	"importance_level", "status", "created_at", "modified_at"}
+
+func (s *ExportService) writeTicket(w io.Writer, format models.ExportFormat, ticket *models.Ticket) error {
+	if format == models.ExportFormatNDJSON {
+		ticketResponse := &data.TicketResponse{}
+		ticketResponse.LoadFromTicket(ticket)
+
+		return json.NewEncoder(w).Encode(ticketResponse)
+	}
+
+	e := s.writeCSV(w, []string{"TICKET", strconv.FormatInt(ticket.ID, 10), "", ticket.Issuer, ticket.Owner,
+		ticket.Subject, ticket.Content, ticket.Metadata, string(ticket.ImportanceLevel), string(ticket.Status),
+		ticket.CreatedAt.Format(time.RFC3339Nano), ticket.ModifiedAt.Format(time.RFC3339Nano)})
+	if e != nil {
+		return e
+	}
+
+	for _, c := range ticket.Comments {
+		e := s.writeCSV(w, []string{"COMMENT", strconv.FormatInt(c.ID, 10), strconv.FormatInt(c.TicketID, 10), "",
+			c.Owner, "", c.Content, c.Metadata, "", "", c.CreatedAt.Format(time.RFC3339Nano),
+			c.ModifiedAt.Format(time.RFC3339Nano)})
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (s *ExportService) writeCSV(w io.Writer, record []string) error {
+	writer := csv.NewWriter(w)
+	if e := writer.Write(record); e != nil {
+		return e
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *ExportService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+// Stop stops the component, its subscriptions and interrupts the running export job if any.
+func (s *ExportService) Stop() {
+	s.cancel()
+	s.stop <- struct{}{}
+}
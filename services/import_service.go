@@ -0,0 +1,513 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// ImportService is a service implementation of ticket import functionalities.
+type ImportService struct {
+	logger           *zap.SugaredLogger
+	importRepository *models.ImportRepository
+	natsClient       *nc.Conn
+	storageDirectory string
+	pollInterval     time.Duration
+	lease            time.Duration
+	ctx              context.Context
+	cancel           context.CancelFunc
+	authorizer       *Authorizer
+	catalogue        *errors.Catalogue
+	stop             chan struct{}
+}
+
+// NewImportService returns a newly created and ready to use ImportService.
+func NewImportService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool,
+	natsClient *nc.Conn, authorizer *Authorizer, catalogue *errors.Catalogue) *ImportService {
+
+	storageDirectory := config.Get("imports.storage_directory").StringOrElse("./imports")
+	pollInterval := config.Get("imports.poll_interval").DurationOrElse(5 * time.Second)
+	lease := config.Get("imports.lease").DurationOrElse(time.Minute)
+
+	logger.Info("imports.storage_directory -> ", storageDirectory)
+	logger.Info("imports.poll_interval -> ", pollInterval)
+	logger.Info("imports.lease -> ", lease)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ImportService{
+		logger:           logger,
+		importRepository: models.NewImportRepository(logger, db),
+		natsClient:       natsClient,
+		storageDirectory: storageDirectory,
+		pollInterval:     pollInterval,
+		lease:            lease,
+		ctx:              ctx,
+		cancel:           cancel,
+		authorizer:       authorizer,
+		catalogue:        catalogue,
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions and the background worker so ready to be notified.
+func (s *ImportService) Start() error {
+	createImportSubscription, e := s.natsClient.QueueSubscribe("kiosk.imports.create",
+		"kiosk.imports.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	loadImportSubscription, e := s.natsClient.QueueSubscribe("kiosk.imports.load",
+		"kiosk.imports.load_group", s.authorizer.identified(s.load))
+	if e != nil {
+		return e
+	}
+
+	go s.await(createImportSubscription, loadImportSubscription)
+
+	return nil
+}
+
+func (s *ImportService) await(ss ...*nc.Subscription) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.logger.Debug("ImportService: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case <-ticker.C:
+			s.process()
+		}
+	}
+}
+
+func (s *ImportService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createImportRequest := &data.CreateImportRequest{}
+	if e := json.Unmarshal(msg.Data, createImportRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createImportRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.imports.create"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if _, e := os.Stat(filepath.Join(s.storageDirectory, createImportRequest.FileName)); e != nil {
+		if os.IsNotExist(e) {
+			s.reply(msg, errors.NotFound("import.file_not_found", ""))
+		} else {
+			et := errors.InternalServerError("unknown", "")
+			s.logger.Error(et.FingerPrint, ": ", e.Error())
+			s.reply(msg, et)
+		}
+
+		return
+	}
+
+	i := createImportRequest.AsImport()
+	i.Status = models.ImportStatusPending
+
+	id, et := s.importRepository.Insert(ctx, *i)
+	if et != nil {
+		s.reply(msg, et)
+		return
+	}
+
+	i, et = s.importRepository.LoadByID(ctx, id)
+	if et != nil {
+		s.reply(msg, et)
+		return
+	}
+
+	importResponse := &data.ImportResponse{}
+	importResponse.LoadFromImport(i)
+	s.reply(msg, importResponse)
+}
+
+func (s *ImportService) load(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.imports.load"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	i, e := s.importRepository.LoadByID(ctx, id.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	importResponse := &data.ImportResponse{}
+	importResponse.LoadFromImport(i)
+	s.reply(msg, importResponse)
+}
+
+// ImportFile imports the file at the provided path synchronously and returns back the final import report.
+func (s *ImportService) ImportFile(ctx context.Context, source string, format models.ImportFormat,
+	path string) (*models.Import, *errors.Type) {
+
+	createImportRequest := &data.CreateImportRequest{Source: source, Format: format, FileName: filepath.Base(path)}
+	if e := createImportRequest.Validate(); e != nil {
+		return nil, e
+	}
+
+	file, e := os.Open(path)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, errors.NotFound("import.file_not_found", "")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		s.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+	defer func() { _ = file.Close() }()
+
+	i := createImportRequest.AsImport()
+	i.Status = models.ImportStatusRunning
+
+	id, et := s.importRepository.Insert(ctx, *i)
+	if et != nil {
+		return nil, et
+	}
+
+	i.ID = id
+	checkpoint := func() *errors.Type { return s.importRepository.Update(ctx, i) }
+
+	i.Status = models.ImportStatusCompleted
+	if e := s.run(ctx, i, file, checkpoint); e != nil {
+		s.logger.Error("ImportService: import ", i.ID, " failed: ", e.Error())
+		i.Status = models.ImportStatusFailed
+	}
+
+	// The context may be already cancelled, so the final report is saved with a fresh one.
+	updateCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = s.importRepository.Update(updateCtx, i)
+	s.logger.Info("ImportService: import ", i.ID, " finished, imported ", i.ImportedRows, ", skipped ",
+		i.SkippedRows, ", rejected ", i.RejectedRows)
+
+	return i, nil
+}
+
+// process claims at most one import and runs it to the end, unless the service is stopping.
+func (s *ImportService) process() {
+	i, et := s.importRepository.Claim(s.ctx, s.lease)
+	if et != nil || i == nil {
+		return
+	}
+
+	s.logger.Info("ImportService: processing import ", i.ID, " from row ", i.TotalRows)
+
+	e := s.runFile(i)
+	if e != nil {
+		if s.ctx.Err() != nil {
+			// Interrupted by stop, so hand the import over to whichever node claims it next.
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_ = s.importRepository.Checkpoint(ctx, i, 0)
+			return
+		}
+
+		if et, ok := e.(*errors.Type); ok && et.HTTPStatusCode == http.StatusPreconditionFailed {
+			s.logger.Warn("ImportService: import ", i.ID, " was claimed by another node, stopped")
+			return
+		}
+
+		s.logger.Error("ImportService: import ", i.ID, " failed: ", e.Error())
+		_ = s.importRepository.Fail(s.ctx, i)
+		return
+	}
+
+	if et := s.importRepository.Complete(s.ctx, i); et != nil {
+		if et.HTTPStatusCode == http.StatusPreconditionFailed {
+			s.logger.Warn("ImportService: import ", i.ID, " was claimed by another node, stopped")
+		}
+
+		return
+	}
+
+	s.logger.Info("ImportService: import ", i.ID, " completed, imported ", i.ImportedRows, ", skipped ",
+		i.SkippedRows, ", rejected ", i.RejectedRows)
+}
+
+func (s *ImportService) runFile(i *models.Import) error {
+	file, e := os.Open(filepath.Join(s.storageDirectory, i.FileName))
+	if e != nil {
+		return e
+	}
+	defer func() { _ = file.Close() }()
+
+	return s.run(s.ctx, i, file, func() *errors.Type { return s.importRepository.Checkpoint(s.ctx, i, s.lease) })
+}
+
+// run imports the records of the reader that the import has not handled yet, checkpointing every 100 records. Tickets
+// imported after the last checkpoint are reported as skipped once resumed.
+func (s *ImportService) run(ctx context.Context, i *models.Import, reader io.Reader,
+	checkpoint func() *errors.Type) error {
+
+	s.logger.Info("ImportService: importing ", i.FileName, " from ", i.Source)
+
+	handled := i.TotalRows
+	handle := func(row int64, record *data.ImportTicketRecord, et *errors.Type) error {
+		if handled > 0 {
+			handled--
+			return nil
+		}
+
+		if et == nil {
+			et = record.Validate()
+		}
+
+		var imported bool
+		if et == nil {
+			importCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			imported, et = s.importRepository.ImportTicket(importCtx, i.Source, record.ExternalID, record.AsTicket())
+			cancel()
+
+			// Interrupted, so the record is left to the next run.
+			if e := ctx.Err(); e != nil {
+				return e
+			}
+		}
+
+		i.TotalRows++
+		switch {
+		case et != nil:
+			i.RejectedRows++
+			i.Rejections = append(i.Rejections, s.rejection(row, record, et))
+		case imported:
+			i.ImportedRows++
+		default:
+			i.SkippedRows++
+		}
+
+		if i.TotalRows%100 == 0 {
+			if et := checkpoint(); et != nil {
+				return et
+			}
+		}
+
+		return nil
+	}
+
+	if i.Format == models.ImportFormatCSV {
+		return s.readCSV(ctx, reader, handle)
+	}
+
+	return s.readNDJSON(ctx, reader, handle)
+}
+
+func (s *ImportService) rejection(row int64, record *data.ImportTicketRecord, et *errors.Type) models.ImportRejection {
+	rejection := models.ImportRejection{Row: row}
+	if record != nil {
+		rejection.ExternalID = record.ExternalID
+	}
+
+	for _, e := range et.Errors {
+		rejection.Codes = append(rejection.Codes, e.Code)
+	}
+
+	return rejection
+}
+
+type importHandler func(row int64, record *data.ImportTicketRecord, et *errors.Type) error
+
+// readNDJSON reads one ticket record per line.
+func (s *ImportService) readNDJSON(ctx context.Context, reader io.Reader, handle importHandler) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var row int64
+	for scanner.Scan() {
+		if e := ctx.Err(); e != nil {
+			return e
+		}
+
+		row++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		record := &data.ImportTicketRecord{}
+		if e := json.Unmarshal(scanner.Bytes(), record); e != nil {
+			if e := handle(row, nil, errors.InvalidRequestBody()); e != nil {
+				return e
+			}
+
+			continue
+		}
+
+		if e := handle(row, record, nil); e != nil {
+			return e
+		}
+	}
+
+	return scanner.Err()
+}
+
+// readCSV reads the same layout that the CSV exports have: a header row followed by rows of TICKET type, each followed
+// by rows of COMMENT type whose ticket_id column refers to the id column of the ticket.
+func (s *ImportService) readCSV(ctx context.Context, reader io.Reader, handle importHandler) error {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+
+	header, e := r.Read()
+	if e != nil {
+		if e == io.EOF {
+			return nil
+		}
+
+		return e
+	}
+
+	columns := make(map[string]int)
+	for i, c := range header {
+		columns[c] = i
+	}
+
+	for _, c := range csvHeader {
+		if _, ok := columns[c]; !ok {
+			return errors.InvalidArgument("header.not_valid", c)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		if columns[name] < len(record) {
+			return record[columns[name]]
+		}
+
+		return ""
+	}
+
+	var row, ticketRow int64 = 1, 0
+	var ticket *data.ImportTicketRecord
+	flush := func() error {
+		if ticket == nil {
+			return nil
+		}
+
+		t := ticket
+		ticket = nil
+		return handle(ticketRow, t, nil)
+	}
+
+	for {
+		if e := ctx.Err(); e != nil {
+			return e
+		}
+
+		record, e := r.Read()
+		if e == io.EOF {
+			break
+		}
+
+		row++
+		if e != nil {
+			if _, ok := e.(*csv.ParseError); ok {
+				if e := handle(row, nil, errors.InvalidArgument("row.not_valid", "")); e != nil {
+					return e
+				}
+
+				continue
+			}
+
+			return e
+		}
+
+		switch column(record, "type") {
+		case "TICKET":
+			if e := flush(); e != nil {
+				return e
+			}
+
+			ticketRow = row
+			ticket = &data.ImportTicketRecord{
+				ExternalID:      column(record, "id"),
+				Issuer:          column(record, "issuer"),
+				Owner:           column(record, "owner"),
+				Subject:         column(record, "subject"),
+				Content:         column(record, "content"),
+				Metadata:        column(record, "metadata"),
+				ImportanceLevel: models.TicketImportanceLevel(column(record, "importance_level")),
+				Status:          models.TicketStatus(column(record, "status")),
+				CreatedAt:       column(record, "created_at"),
+				ModifiedAt:      column(record, "modified_at"),
+			}
+		case "COMMENT":
+			if ticket == nil || ticket.ExternalID != column(record, "ticket_id") {
+				if e := handle(row, nil, errors.InvalidArgument("comments.ticketID.not_valid", "")); e != nil {
+					return e
+				}
+
+				continue
+			}
+
+			ticket.Comments = append(ticket.Comments, &data.ImportCommentRecord{
+				Owner:      column(record, "owner"),
+				Content:    column(record, "content"),
+				Metadata:   column(record, "metadata"),
+				CreatedAt:  column(record, "created_at"),
+				ModifiedAt: column(record, "modified_at"),
+			})
+		default:
+			if e := handle(row, nil, errors.InvalidArgument("type.not_valid", "")); e != nil {
+				return e
+			}
+		}
+	}
+
+	return flush()
+}
+
+func (s *ImportService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+// Stop stops the component, its subscriptions and interrupts the running imports if any.
+func (s *ImportService) Stop() {
+	s.cancel()
+	s.stop <- struct{}{}
+}
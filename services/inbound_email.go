@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// inboundEmail is the parsed form of an RFC 5322 message, keeping only what tickets and comments are made of.
+type inboundEmail struct {
+	MessageID  string
+	Sender     string
+	Subject    string
+	References []string
+	Body       string
+}
+
+var (
+	messageIDPattern   = regexp.MustCompile(`<[^<>\s]+>`)
+	ticketTokenPattern = regexp.MustCompile(`\[#(\d+)\]`)
+	replyPrefixPattern = regexp.MustCompile(`(?i)^\s*((re|fw|fwd|aw|sv)\s*:\s*)+`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+	attributionPattern = regexp.MustCompile(`(?i)^on\s.+wrote:\s*$`)
+)
+
+// parseEmail parses an RFC 5322 message. Messages without a Message-ID get one derived from their content, so they
+// are still ingested only once.
+func parseEmail(reader io.Reader) (*inboundEmail, error) {
+	raw, e := ioutil.ReadAll(reader)
+	if e != nil {
+		return nil, e
+	}
+
+	msg, e := mail.ReadMessage(bytes.NewReader(raw))
+	if e != nil {
+		return nil, e
+	}
+
+	from, e := mail.ParseAddress(msg.Header.Get("From"))
+	if e != nil {
+		return nil, fmt.Errorf("invalid sender: %v", e)
+	}
+
+	decoder := &mime.WordDecoder{}
+	subject, e := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if e != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	messageID := messageIDPattern.FindString(msg.Header.Get("Message-ID"))
+	if messageID == "" {
+		sum := sha256.Sum256(raw)
+		messageID = "<" + hex.EncodeToString(sum[:]) + "@kiosk>"
+	}
+
+	references := messageIDPattern.FindAllString(msg.Header.Get("In-Reply-To"), -1)
+	references = append(references, messageIDPattern.FindAllString(msg.Header.Get("References"), -1)...)
+
+	body, e := readBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if e != nil {
+		return nil, e
+	}
+
+	return &inboundEmail{
+		MessageID:  messageID,
+		Sender:     strings.ToLower(from.Address),
+		Subject:    strings.TrimSpace(sanitize(subject)),
+		References: references,
+		Body:       sanitize(body),
+	}, nil
+}
+
+// readBody returns back the text of a message body, preferring the plain text alternative of multipart messages and
+// falling back to the html one stripped of its tags.
+func readBody(contentType, transferEncoding string, body io.Reader) (string, error) {
+	mediaType, params, e := mime.ParseMediaType(contentType)
+	if e != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+
+		var html string
+		for {
+			part, e := reader.NextPart()
+			if e == io.EOF {
+				break
+			}
+			if e != nil {
+				return "", e
+			}
+
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+
+			text, e := readBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if e != nil {
+				return "", e
+			}
+
+			if partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); partType == "text/html" {
+				if html == "" {
+					html = text
+				}
+				continue
+			}
+
+			if text != "" {
+				return text, nil
+			}
+		}
+
+		return html, nil
+	}
+
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(transferEncoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineSkipper{reader: body})
+	}
+
+	content, e := ioutil.ReadAll(body)
+	if e != nil {
+		return "", e
+	}
+
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	if mediaType == "text/html" {
+		text = htmlTagPattern.ReplaceAllString(text, "")
+	}
+
+	return text, nil
+}
+
+// newlineSkipper drops the line breaks that base64 encoded bodies are wrapped with.
+type newlineSkipper struct {
+	reader io.Reader
+}
+
+func (s *newlineSkipper) Read(p []byte) (int, error) {
+	n, e := s.reader.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+
+	return j, e
+}
+
+// stripQuotedText removes the quoted parts of a reply, i.e. the lines starting with '>', and everything after the
+// attribution line of the quote, a forwarded or original message separator or the signature delimiter.
+func stripQuotedText(body string) string {
+	lines := make([]string, 0)
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if line == "-- " || attributionPattern.MatchString(trimmed) ||
+			strings.HasPrefix(trimmed, "-----Original Message-----") ||
+			strings.HasPrefix(trimmed, "---------- Forwarded message") {
+			break
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// ticketToken returns back the ticket identifier of a [#ID] token in the subject, if any.
+func ticketToken(subject string) (int64, bool) {
+	match := ticketTokenPattern.FindStringSubmatch(subject)
+	if match == nil {
+		return 0, false
+	}
+
+	id, e := strconv.ParseInt(match[1], 10, 64)
+	if e != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// cleanSubject removes the reply and forward prefixes of a subject.
+func cleanSubject(subject string) string {
+	return strings.TrimSpace(replyPrefixPattern.ReplaceAllString(subject, ""))
+}
+
+// sanitize drops the invalid UTF-8 sequences and NUL characters, that can not be stored, of texts in unsupported
+// charsets.
+func sanitize(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+}
+
+// truncate cuts s to at most n bytes without splitting a multi byte character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	s = s[:n]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+
+	return s
+}
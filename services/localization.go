@@ -0,0 +1,30 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+)
+
+// localized returns back the reply of the message, populating the messages of the errors from the catalogue in the
+// language of the message. Replies other than errors are returned back as they are.
+func localized(catalogue *errors.Catalogue, msg *nc.Msg, t interface{}) interface{} {
+	et, ok := t.(*errors.Type)
+	if !ok {
+		return t
+	}
+
+	return catalogue.Localize(et, languageOf(msg))
+}
+
+// languageOf returns back the language field of the message, if any.
+func languageOf(msg *nc.Msg) string {
+	localized := &data.Localized{}
+	if e := json.Unmarshal(msg.Data, localized); e != nil {
+		return ""
+	}
+
+	return localized.Language
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// MacroService is a service implementation of macro related functionalities.
+type MacroService struct {
+	logger                   *zap.SugaredLogger
+	macroRepository          *models.MacroRepository
+	ticketRepository         *models.TicketRepository
+	cannedResponseRepository *models.CannedResponseRepository
+	natsClient               *nc.Conn
+	dispatcher               *EventDispatcher
+	authorizer               *Authorizer
+	catalogue                *errors.Catalogue
+	stop                     chan struct{}
+}
+
+// NewMacroService returns a newly created and ready to use MacroService.
+func NewMacroService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *nc.Conn,
+	dispatcher *EventDispatcher, authorizer *Authorizer, catalogue *errors.Catalogue) *MacroService {
+
+	return &MacroService{
+		logger:                   logger,
+		macroRepository:          models.NewMacroRepository(logger, db),
+		ticketRepository:         models.NewTicketRepository(logger, db),
+		cannedResponseRepository: models.NewCannedResponseRepository(logger, db),
+		natsClient:               natsClient,
+		dispatcher:               dispatcher,
+		authorizer:               authorizer,
+		catalogue:                catalogue,
+		stop:                     make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions so ready to be notified.
+func (s *MacroService) Start() error {
+	createMacroSubscription, e := s.natsClient.QueueSubscribe("kiosk.macros.create",
+		"kiosk.macros.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	loadMacroSubscription, e := s.natsClient.QueueSubscribe("kiosk.macros.load",
+		"kiosk.macros.load_group", s.authorizer.identified(s.load))
+	if e != nil {
+		return e
+	}
+
+	updateMacroSubscription, e := s.natsClient.QueueSubscribe("kiosk.macros.update",
+		"kiosk.macros.update_group", s.authorizer.identified(s.update))
+	if e != nil {
+		return e
+	}
+
+	deleteMacroSubscription, e := s.natsClient.QueueSubscribe("kiosk.macros.delete",
+		"kiosk.macros.delete_group", s.authorizer.identified(s.delete))
+	if e != nil {
+		return e
+	}
+
+	filterMacrosSubscription, e := s.natsClient.QueueSubscribe("kiosk.macros.filter",
+		"kiosk.macros.filter_group", s.authorizer.identified(s.filter))
+	if e != nil {
+		return e
+	}
+
+	executeMacroSubscription, e := s.natsClient.QueueSubscribe("kiosk.macros.execute",
+		"kiosk.macros.execute_group", s.authorizer.identified(s.execute))
+	if e != nil {
+		return e
+	}
+
+	go s.await(createMacroSubscription, loadMacroSubscription, updateMacroSubscription, deleteMacroSubscription,
+		filterMacrosSubscription, executeMacroSubscription)
+
+	return nil
+}
+
+func (s *MacroService) await(ss ...*nc.Subscription) {
+	<-s.stop
+	s.logger.Debug("MacroService: received stop signal!")
+
+	for _, s := range ss {
+		_ = s.Unsubscribe()
+	}
+}
+
+func (s *MacroService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createMacroRequest := &data.CreateMacroRequest{}
+	if e := json.Unmarshal(msg.Data, createMacroRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createMacroRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.macros.create", createMacroRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.macroRepository.Insert(ctx, *createMacroRequest.AsMacro()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *MacroService) load(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	m, e := s.macroRepository.LoadByID(ctx, id.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	identity, issuer := identityOf(msg), m.Issuer
+	if identity != nil && issuer == "" {
+		// Global macros are shared by every issuer.
+		issuer = identity.Tenant
+	}
+
+	if e := s.authorizer.AuthorizeIssuer(identity, "kiosk.macros.load", issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	macroResponse := &data.MacroResponse{}
+	macroResponse.LoadFromMacro(m)
+	s.reply(msg, macroResponse)
+}
+
+func (s *MacroService) update(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updateMacroRequest := &data.UpdateMacroRequest{}
+	if e := json.Unmarshal(msg.Data, updateMacroRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := updateMacroRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.macros.update", s.issuer(ctx, updateMacroRequest.ID)); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.macroRepository.Update(ctx, updateMacroRequest.AsMacro()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *MacroService) delete(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.macros.delete", s.issuer(ctx, id.ID)); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.macroRepository.DeleteByID(ctx, id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *MacroService) filter(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterMacrosRequest := &data.FilterMacrosRequest{}
+	if e := json.Unmarshal(msg.Data, filterMacrosRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterMacrosRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuerFilter(identityOf(msg), "kiosk.macros.filter", &filterMacrosRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ms, hasNextPage, e := s.macroRepository.Filter(ctx, filterMacrosRequest.Issuer, filterMacrosRequest.PageNumber,
+		filterMacrosRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterMacrosResponse := &data.FilterMacrosResponse{}
+	filterMacrosResponse.LoadFromMacros(ms, hasNextPage)
+	s.reply(msg, filterMacrosResponse)
+}
+
+func (s *MacroService) execute(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	executeMacroRequest := &data.ExecuteMacroRequest{}
+	if e := json.Unmarshal(msg.Data, executeMacroRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := executeMacroRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeOwner(identityOf(msg), &executeMacroRequest.Owner); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	m, e := s.macroRepository.LoadByID(ctx, executeMacroRequest.MacroID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	t, e := s.ticketRepository.LoadByID(ctx, executeMacroRequest.TicketID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.Authorize(identityOf(msg), "kiosk.macros.execute", t); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	comments, e := s.apply(ctx, m, t, executeMacroRequest.Owner)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if executeMacroRequest.DryRun {
+		ticketResponse := &data.TicketResponse{}
+		ticketResponse.LoadFromTicket(previewTicket(t, comments))
+		s.reply(msg, ticketResponse)
+		return
+	}
+
+	previousStatus, e := s.ticketRepository.Apply(ctx, t, comments, models.UserActor(executeMacroRequest.Owner), nil)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	t, e = s.ticketRepository.LoadByID(ctx, t.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ticketResponse := &data.TicketResponse{}
+	ticketResponse.LoadFromTicket(t)
+	s.reply(msg, ticketResponse)
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketUpdated, TicketID: t.ID, PreviousStatus: previousStatus})
+}
+
+// apply applies the actions of the macro to the ticket in order and returns back the comments that should be added
+// to the ticket. Nothing is persisted here.
+func (s *MacroService) apply(ctx context.Context, macro *models.Macro, ticket *models.Ticket,
+	owner string) ([]*models.Comment, *errors.Type) {
+
+	if macro.Issuer != "" && macro.Issuer != ticket.Issuer {
+		return nil, errors.PreconditionFailed("macro.issuer_mismatch", "")
+	}
+
+	return applyTicketActions(ctx, s.cannedResponseRepository, macro.Actions, ticket, owner)
+}
+
+// applyTicketActions applies the actions to the ticket in order and returns back the comments that should be added to
+// the ticket. The owner is used for the comments that do not specify their own.
+func applyTicketActions(ctx context.Context, cannedResponseRepository *models.CannedResponseRepository,
+	actions []models.MacroAction, ticket *models.Ticket, owner string) ([]*models.Comment, *errors.Type) {
+
+	comments := make([]*models.Comment, 0)
+	for _, a := range actions {
+		switch a.Type {
+		case models.MacroActionTypeSetStatus:
+			ticket.Status = a.Status
+		case models.MacroActionTypeSetImportanceLevel:
+			ticket.ImportanceLevel = a.ImportanceLevel
+		case models.MacroActionTypeSetSubject:
+			ticket.Subject = a.Subject
+		case models.MacroActionTypeAddTag, models.MacroActionTypeRemoveTag, models.MacroActionTypeSetMetadata:
+			metadata, e := applyToMetadata(ticket.Metadata, a)
+			if e != nil {
+				return nil, e
+			}
+
+			ticket.Metadata = metadata
+		case models.MacroActionTypeAddComment:
+			comment := &models.Comment{Owner: a.Owner, Content: a.Content}
+			if comment.Owner == "" {
+				comment.Owner = owner
+			}
+
+			if comment.Owner == "" {
+				return nil, errors.InvalidArgument("owner.is_required", "")
+			}
+
+			if a.CannedResponseID > 0 {
+				c, e := cannedResponseRepository.LoadByID(ctx, a.CannedResponseID)
+				if e != nil {
+					return nil, e
+				}
+
+				if comment.Content, e = renderCannedResponse(c, ticket); e != nil {
+					return nil, e
+				}
+			}
+
+			comments = append(comments, comment)
+		default:
+			return nil, errors.PreconditionFailed("macro.action_not_supported", string(a.Type))
+		}
+	}
+
+	return comments, nil
+}
+
+// previewTicket adds the not yet persisted comments to the ticket, as if they were just added.
+func previewTicket(ticket *models.Ticket, comments []*models.Comment) *models.Ticket {
+	now := time.Now().UTC()
+	for _, c := range comments {
+		c.TicketID = ticket.ID
+		c.CreatedAt = now
+		c.ModifiedAt = now
+	}
+
+	// Comments are ordered from the newest one.
+	for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+		comments[i], comments[j] = comments[j], comments[i]
+	}
+
+	ticket.Comments = append(comments, ticket.Comments...)
+	ticket.ModifiedAt = now
+
+	return ticket
+}
+
+// applyToMetadata applies tag and metadata actions to the metadata of a ticket. Tags are kept as an array under the
+// tags key, so such actions are only applicable when the metadata is either empty or a JSON object.
+func applyToMetadata(metadata string, action models.MacroAction) (string, *errors.Type) {
+	values := make(map[string]interface{})
+	if metadata != "" {
+		if e := json.Unmarshal([]byte(metadata), &values); e != nil {
+			return "", errors.PreconditionFailed("metadata.not_json_object", "")
+		}
+	}
+
+	tags := make([]interface{}, 0)
+	if existing, ok := values["tags"].([]interface{}); ok {
+		tags = existing
+	}
+
+	switch action.Type {
+	case models.MacroActionTypeAddTag:
+		for _, t := range tags {
+			if t == action.Tag {
+				return metadata, nil
+			}
+		}
+
+		values["tags"] = append(tags, action.Tag)
+	case models.MacroActionTypeRemoveTag:
+		remaining := make([]interface{}, 0)
+		for _, t := range tags {
+			if t != action.Tag {
+				remaining = append(remaining, t)
+			}
+		}
+
+		values["tags"] = remaining
+	case models.MacroActionTypeSetMetadata:
+		values[action.Key] = action.Value
+	}
+
+	out, _ := json.Marshal(values)
+	return string(out), nil
+}
+
+func (s *MacroService) issuer(ctx context.Context, id int64) func() (string, *errors.Type) {
+	return func() (string, *errors.Type) {
+		m, e := s.macroRepository.LoadByID(ctx, id)
+		if e != nil {
+			return "", e
+		}
+
+		return m.Issuer, nil
+	}
+}
+
+func (s *MacroService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (s *MacroService) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component and it subscriptions.
+func (s *MacroService) Stop() {
+	s.stop <- struct{}{}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MailSource is a mailbox that inbound emails are read from. Done marks a message as processed, successfully or not,
+// so it is not fetched again.
+type MailSource interface {
+	Fetch(limit int) ([]string, error)
+	Open(key string) (io.ReadCloser, error)
+	Done(key string, failed bool) error
+}
+
+// MaildirSource is a MailSource reading from a maildir, so any IMAP mailbox can be used by synchronizing it into a
+// local maildir. Unprocessed messages are the ones in new and processed ones are moved to cur, flagged when failed.
+type MaildirSource struct {
+	directory string
+}
+
+// NewMaildirSource returns back a newly created MaildirSource, creating the maildir when it does not exist.
+func NewMaildirSource(directory string) (*MaildirSource, error) {
+	for _, d := range []string{"tmp", "new", "cur"} {
+		if e := os.MkdirAll(filepath.Join(directory, d), 0755); e != nil {
+			return nil, e
+		}
+	}
+
+	return &MaildirSource{directory: directory}, nil
+}
+
+// Fetch returns back the keys of at most limit messages of new, oldest delivered first.
+func (s *MaildirSource) Fetch(limit int) ([]string, error) {
+	files, e := ioutil.ReadDir(filepath.Join(s.directory, "new"))
+	if e != nil {
+		return nil, e
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	keys := make([]string, 0)
+	for _, f := range files {
+		if len(keys) == limit {
+			break
+		}
+
+		if f.Mode().IsRegular() && !strings.HasPrefix(f.Name(), ".") {
+			keys = append(keys, f.Name())
+		}
+	}
+
+	return keys, nil
+}
+
+// Open opens the message of the key for reading.
+func (s *MaildirSource) Open(key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.directory, "new", key))
+}
+
+// Done moves the message of the key to cur, as seen or as flagged when failed. A message that is already moved, e.g.
+// by another node, is ignored.
+func (s *MaildirSource) Done(key string, failed bool) error {
+	flags := ":2,S"
+	if failed {
+		flags = ":2,F"
+	}
+
+	e := os.Rename(filepath.Join(s.directory, "new", key), filepath.Join(s.directory, "cur", key+flags))
+	if os.IsNotExist(e) {
+		return nil
+	}
+
+	return e
+}
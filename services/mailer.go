@@ -0,0 +1,87 @@
+package services
+
+import (
+	"bytes"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Mailer delivers composed RFC 5322 messages to their recipients.
+type Mailer interface {
+	Send(from string, to []string, message []byte) error
+}
+
+// SMTPMailer is a Mailer delivering through an SMTP server. Authentication is used when a username is provided and
+// the connection is upgraded with STARTTLS whenever the server supports it.
+type SMTPMailer struct {
+	address string
+	auth    smtp.Auth
+}
+
+// NewSMTPMailer returns back a newly created SMTPMailer for the server at address, e.g: smtp.example.com:587.
+func NewSMTPMailer(address, username, password string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(address)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{address: address, auth: auth}
+}
+
+// Send sends the message to the recipients.
+func (m *SMTPMailer) Send(from string, to []string, message []byte) error {
+	return smtp.SendMail(m.address, m.auth, from, to, message)
+}
+
+// composeEmail composes a multipart/alternative message of the plain text and html bodies. The message identifier
+// is kept the same across delivery attempts, so the receiving side can tell the duplicates of a message apart.
+func composeEmail(from, to, subject, text, html, messageID string, date time.Time) ([]byte, error) {
+	message := &bytes.Buffer{}
+	writer := multipart.NewWriter(message)
+
+	header := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + date.Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	message.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, e := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if e != nil {
+			return nil, e
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, e := qp.Write([]byte(part.body)); e != nil {
+			return nil, e
+		}
+
+		if e := qp.Close(); e != nil {
+			return nil, e
+		}
+	}
+
+	if e := writer.Close(); e != nil {
+		return nil, e
+	}
+
+	return message.Bytes(), nil
+}
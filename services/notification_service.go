@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// NotificationService is a service implementation of outbound email notifications.
+type NotificationService struct {
+	logger                 *zap.SugaredLogger
+	ticketRepository       *models.TicketRepository
+	notificationRepository *models.NotificationRepository
+	natsClient             *nc.Conn
+	outboxRelay            *OutboxRelay
+	mailer                 Mailer
+	templates              *notificationTemplates
+	enabled                bool
+	from                   string
+	ignoredOwners          []string
+	pollInterval           time.Duration
+	batchSize              int
+	lease                  time.Duration
+	maxAttempts            int
+	backoff                time.Duration
+	maxBackoff             time.Duration
+	ctx                    context.Context
+	cancel                 context.CancelFunc
+	authorizer             *Authorizer
+	catalogue              *errors.Catalogue
+	stop                   chan struct{}
+}
+
+// NewNotificationService returns a newly created and ready to use NotificationService. Notifications are disabled,
+// i.e. nothing is stored nor sent, when no SMTP server is configured.
+func NewNotificationService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool,
+	natsClient *nc.Conn, outboxRelay *OutboxRelay, authorizer *Authorizer,
+	catalogue *errors.Catalogue) (*NotificationService, error) {
+
+	smtpAddress := config.Get("notifications.smtp.address").StringOrElse("")
+	smtpUsername := config.Get("notifications.smtp.username").StringOrElse("")
+	smtpPassword := config.Get("notifications.smtp.password").StringOrElse("")
+	from := config.Get("notifications.from").StringOrElse("kiosk@localhost")
+	defaultLocale := config.Get("notifications.default_locale").StringOrElse("en")
+	templatesDirectory := config.Get("notifications.templates_directory").StringOrElse("")
+	ignoredOwners := config.Get("notifications.ignored_comment_owners").SliceOfStringOrElse([]string{"kiosk"})
+	pollInterval := config.Get("notifications.poll_interval").DurationOrElse(5 * time.Second)
+	batchSize := config.Get("notifications.batch_size").IntOrElse(50)
+	lease := config.Get("notifications.lease").DurationOrElse(time.Minute)
+	maxAttempts := config.Get("notifications.max_attempts").IntOrElse(8)
+	backoff := config.Get("notifications.backoff").DurationOrElse(30 * time.Second)
+	maxBackoff := config.Get("notifications.max_backoff").DurationOrElse(time.Hour)
+
+	logger.Info("notifications.smtp.address -> ", smtpAddress)
+	logger.Info("notifications.smtp.username -> ", smtpUsername)
+	logger.Info("notifications.from -> ", from)
+	logger.Info("notifications.default_locale -> ", defaultLocale)
+	logger.Info("notifications.templates_directory -> ", templatesDirectory)
+	logger.Info("notifications.ignored_comment_owners -> ", ignoredOwners)
+	logger.Info("notifications.poll_interval -> ", pollInterval)
+	logger.Info("notifications.batch_size -> ", batchSize)
+	logger.Info("notifications.lease -> ", lease)
+	logger.Info("notifications.max_attempts -> ", maxAttempts)
+	logger.Info("notifications.backoff -> ", backoff)
+	logger.Info("notifications.max_backoff -> ", maxBackoff)
+
+	if _, e := mail.ParseAddress(from); e != nil {
+		return nil, fmt.Errorf("invalid notifications.from: %v", e)
+	}
+
+	templates, e := loadNotificationTemplates(templatesDirectory, defaultLocale)
+	if e != nil {
+		return nil, e
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &NotificationService{
+		logger:                 logger,
+		ticketRepository:       models.NewTicketRepository(logger, db),
+		notificationRepository: models.NewNotificationRepository(logger, db),
+		natsClient:             natsClient,
+		outboxRelay:            outboxRelay,
+		mailer:                 NewSMTPMailer(smtpAddress, smtpUsername, smtpPassword),
+		templates:              templates,
+		enabled:                smtpAddress != "",
+		from:                   from,
+		ignoredOwners:          ignoredOwners,
+		pollInterval:           pollInterval,
+		batchSize:              batchSize,
+		lease:                  lease,
+		maxAttempts:            maxAttempts,
+		backoff:                backoff,
+		maxBackoff:             maxBackoff,
+		ctx:                    ctx,
+		cancel:                 cancel,
+		authorizer:             authorizer,
+		catalogue:              catalogue,
+		stop:                   make(chan struct{}),
+	}, nil
+}
+
+// Start starts the subscriptions and, when notifications are enabled, the background sender.
+func (s *NotificationService) Start() error {
+	filterNotificationsSubscription, e := s.natsClient.QueueSubscribe("kiosk.notifications.filter",
+		"kiosk.notifications.filter_group", s.authorizer.identified(s.filter))
+	if e != nil {
+		return e
+	}
+
+	if s.enabled {
+		s.outboxRelay.Subscribe(s.enqueue)
+	}
+
+	go s.await(filterNotificationsSubscription)
+
+	return nil
+}
+
+func (s *NotificationService) await(ss ...*nc.Subscription) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.logger.Debug("NotificationService: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case <-ticker.C:
+			if s.enabled {
+				s.deliver()
+			}
+		}
+	}
+}
+
+func (s *NotificationService) filter(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterNotificationsRequest := &data.FilterNotificationsRequest{}
+	if e := json.Unmarshal(msg.Data, filterNotificationsRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterNotificationsRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ticket := func() (*models.Ticket, *errors.Type) {
+		return s.ticketRepository.LoadByID(ctx, filterNotificationsRequest.TicketID)
+	}
+	if e := s.authorizer.authorize(msg, "kiosk.notifications.filter", ticket); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ns, hasNextPage, e := s.notificationRepository.Filter(ctx, filterNotificationsRequest.TicketID,
+		filterNotificationsRequest.Status, filterNotificationsRequest.PageNumber, filterNotificationsRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterNotificationsResponse := &data.FilterNotificationsResponse{}
+	filterNotificationsResponse.LoadFromNotifications(ns, hasNextPage)
+	s.reply(msg, filterNotificationsResponse)
+}
+
+// enqueue renders the notification of the event, if any, and stores it in the outbox.
+func (s *NotificationService) enqueue(message *models.OutboxMessage) *errors.Type {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	var notificationEvent models.NotificationEvent
+	var key string
+	switch message.Type {
+	case models.OutboxMessageTypeTicketCreated:
+		notificationEvent = models.NotificationEventTicketCreated
+		key = fmt.Sprintf("%v:%v", notificationEvent, message.TicketID)
+	case models.OutboxMessageTypeCommentCreated:
+		notificationEvent = models.NotificationEventCommentAdded
+		key = fmt.Sprintf("%v:%v", notificationEvent, message.Comment.ID)
+	case models.OutboxMessageTypeTicketStatusChanged:
+		notificationEvent = models.NotificationEventStatusChanged
+		key = fmt.Sprintf("%v:%v", notificationEvent, message.EventID)
+	default:
+		return nil
+	}
+
+	ticket := message.Ticket
+	if ticket == nil {
+		return nil
+	}
+
+	values := metadataValues(ticket.Metadata)
+	recipient := s.recipient(ticket, values)
+	if recipient == "" {
+		return nil
+	}
+
+	notificationData := notificationData{Ticket: newTemplateTicket(ticket), PreviousStatus: message.PreviousStatus}
+	if comment := message.Comment; notificationEvent == models.NotificationEventCommentAdded {
+		if comment.Owner == ticket.Owner || strings.EqualFold(comment.Owner, recipient) || s.ignored(comment.Owner) {
+			return nil
+		}
+
+		notificationData.Comment = comment
+	}
+
+	locale, subject, text, html, renderError := s.templates.render(notificationEvent, values["locale"],
+		notificationData)
+	if renderError != nil {
+		s.logger.Warnf("NotificationService: failed to render %v of ticket %v: %v", notificationEvent, ticket.ID,
+			renderError.Error())
+		return nil
+	}
+
+	_, e := s.notificationRepository.Insert(ctx, models.Notification{
+		Key:       key,
+		TicketID:  ticket.ID,
+		Event:     notificationEvent,
+		Recipient: recipient,
+		Locale:    locale,
+		Subject:   truncate(subject, 255),
+		TextBody:  text,
+		HTMLBody:  html,
+	})
+	if e != nil {
+		if e.HTTPStatusCode != http.StatusPreconditionFailed {
+			return e
+		}
+
+		s.logger.Warnf("NotificationService: dropped %v of deleted ticket %v", notificationEvent, ticket.ID)
+	}
+
+	return nil
+}
+
+// recipient returns back the email address the notifications of a ticket are sent to, which is the email key of the
+// metadata, the sender key of tickets created from emails or the owner itself, whichever is a valid address first.
+func (s *NotificationService) recipient(ticket *models.Ticket, values map[string]string) string {
+	for _, candidate := range []string{values["email"], values["sender"], ticket.Owner} {
+		if address, e := mail.ParseAddress(candidate); e == nil && address.Address == candidate {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+func (s *NotificationService) ignored(owner string) bool {
+	for _, o := range s.ignoredOwners {
+		if o == owner {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliver sends the due notifications of the outbox. Failed deliveries are retried with exponential backoff until
+// they run out of attempts.
+func (s *NotificationService) deliver() {
+	notifications, e := s.notificationRepository.Claim(s.ctx, s.batchSize, s.lease)
+	if e != nil {
+		return
+	}
+
+	for _, n := range notifications {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		e := s.send(n)
+
+		ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+		switch {
+		case e == nil:
+			_ = s.notificationRepository.MarkSent(ctx, n.ID)
+		case n.Attempts >= s.maxAttempts:
+			s.logger.Warnf("NotificationService: gave up on notification %v: %v", n.ID, e.Error())
+			_ = s.notificationRepository.MarkFailed(ctx, n.ID, e.Error())
+		default:
+			_ = s.notificationRepository.Retry(ctx, n.ID, e.Error(), s.nextBackoff(n.Attempts))
+		}
+		cancel()
+	}
+}
+
+func (s *NotificationService) send(notification *models.Notification) error {
+	domain := "localhost"
+	if at := strings.LastIndex(s.from, "@"); at >= 0 {
+		domain = strings.TrimSuffix(s.from[at+1:], ">")
+	}
+	messageID := "<notification." + strconv.FormatInt(notification.ID, 10) + "@" + domain + ">"
+
+	message, e := composeEmail(s.from, notification.Recipient, notification.Subject, notification.TextBody,
+		notification.HTMLBody, messageID, notification.CreatedAt)
+	if e != nil {
+		return e
+	}
+
+	from, _ := mail.ParseAddress(s.from)
+	return s.mailer.Send(from.Address, []string{notification.Recipient}, message)
+}
+
+// nextBackoff returns back the delay before the next attempt, doubling after every failed attempt up to the maximum.
+func (s *NotificationService) nextBackoff(attempts int) time.Duration {
+	backoff := s.backoff
+	for i := 1; i < attempts && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > s.maxBackoff {
+		backoff = s.maxBackoff
+	}
+
+	return backoff
+}
+
+func (s *NotificationService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+// Stop stops the component, its subscriptions and the background sender.
+func (s *NotificationService) Stop() {
+	s.cancel()
+	s.stop <- struct{}{}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// notificationTemplate is the set of templates a notification email is rendered with.
+type notificationTemplate struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+// notificationData is the data that notification templates are rendered against, e.g: {{.Ticket.Subject}},
+// {{.Comment.Content}} or {{.PreviousStatus}}.
+type notificationData struct {
+	Ticket         templateTicket
+	Comment        *models.Comment
+	PreviousStatus models.TicketStatus
+}
+
+// notificationTemplates holds the templates of every event per locale.
+type notificationTemplates struct {
+	defaultLocale string
+	templates     map[string]map[models.NotificationEvent]*notificationTemplate
+}
+
+// defaultNotificationTemplates are the built in English templates, used for the events and locales that have no
+// templates of their own.
+var defaultNotificationTemplates = map[models.NotificationEvent][3]string{
+	models.NotificationEventTicketCreated: {
+		`[#{{.Ticket.ID}}] {{.Ticket.Subject}}`,
+		"Hello,\n\nWe have received your request and will get back to you soon.\n\n{{.Ticket.Content}}\n\n" +
+			"Please reply to this email to add more details.\n",
+		`<p>Hello,</p><p>We have received your request and will get back to you soon.</p>` +
+			`<blockquote>{{.Ticket.Content}}</blockquote><p>Please reply to this email to add more details.</p>`,
+	},
+	models.NotificationEventCommentAdded: {
+		`Re: [#{{.Ticket.ID}}] {{.Ticket.Subject}}`,
+		"Hello,\n\n{{.Comment.Owner}} replied to your request:\n\n{{.Comment.Content}}\n\n" +
+			"Please reply to this email to answer.\n",
+		`<p>Hello,</p><p>{{.Comment.Owner}} replied to your request:</p>` +
+			`<blockquote>{{.Comment.Content}}</blockquote><p>Please reply to this email to answer.</p>`,
+	},
+	models.NotificationEventStatusChanged: {
+		`[#{{.Ticket.ID}}] {{.Ticket.Subject}} is {{.Ticket.Status}}`,
+		"Hello,\n\nThe status of your request changed from {{.PreviousStatus}} to {{.Ticket.Status}}.\n",
+		`<p>Hello,</p><p>The status of your request changed from {{.PreviousStatus}} to {{.Ticket.Status}}.</p>`,
+	},
+}
+
+// loadNotificationTemplates parses the built in templates and the ones of the directory, e.g:
+// fa/comment_added.txt.tmpl. Missing files fall back to the default locale, then to the built in ones.
+func loadNotificationTemplates(directory, defaultLocale string) (*notificationTemplates, error) {
+	sources := make(map[string]map[models.NotificationEvent][3]string)
+	sources[defaultLocale] = make(map[models.NotificationEvent][3]string)
+	for event, source := range defaultNotificationTemplates {
+		sources[defaultLocale][event] = source
+	}
+
+	if directory != "" {
+		locales, e := ioutil.ReadDir(directory)
+		if e != nil && !os.IsNotExist(e) {
+			return nil, e
+		}
+
+		for _, locale := range locales {
+			if !locale.IsDir() {
+				continue
+			}
+
+			if sources[locale.Name()] == nil {
+				sources[locale.Name()] = make(map[models.NotificationEvent][3]string)
+			}
+
+			for event := range defaultNotificationTemplates {
+				source := sources[defaultLocale][event]
+				for i, kind := range []string{"subject", "txt", "html"} {
+					name := strings.ToLower(string(event)) + "." + kind + ".tmpl"
+					content, e := ioutil.ReadFile(filepath.Join(directory, locale.Name(), name))
+					if os.IsNotExist(e) {
+						continue
+					}
+					if e != nil {
+						return nil, e
+					}
+
+					source[i] = string(content)
+				}
+
+				sources[locale.Name()][event] = source
+			}
+		}
+	}
+
+	templates := &notificationTemplates{
+		defaultLocale: defaultLocale,
+		templates:     make(map[string]map[models.NotificationEvent]*notificationTemplate),
+	}
+	for locale, events := range sources {
+		templates.templates[locale] = make(map[models.NotificationEvent]*notificationTemplate)
+		for event, source := range events {
+			name := locale + "/" + strings.ToLower(string(event))
+
+			subject, e := template.New(name).Option("missingkey=zero").Parse(source[0])
+			if e != nil {
+				return nil, e
+			}
+
+			text, e := template.New(name).Option("missingkey=zero").Parse(source[1])
+			if e != nil {
+				return nil, e
+			}
+
+			html, e := htmltemplate.New(name).Option("missingkey=zero").Parse(source[2])
+			if e != nil {
+				return nil, e
+			}
+
+			templates.templates[locale][event] = &notificationTemplate{subject: subject, text: text, html: html}
+		}
+	}
+
+	return templates, nil
+}
+
+// render renders the subject, plain text and html bodies of the event in the locale, or in the default locale when
+// the locale has no templates. The returned locale is the one actually used.
+func (t *notificationTemplates) render(event models.NotificationEvent, locale string,
+	data notificationData) (string, string, string, string, error) {
+
+	if t.templates[locale] == nil {
+		locale = t.defaultLocale
+	}
+	templates := t.templates[locale][event]
+
+	subject := &bytes.Buffer{}
+	if e := templates.subject.Execute(subject, data); e != nil {
+		return "", "", "", "", e
+	}
+
+	text := &bytes.Buffer{}
+	if e := templates.text.Execute(text, data); e != nil {
+		return "", "", "", "", e
+	}
+
+	html := &bytes.Buffer{}
+	if e := templates.html.Execute(html, data); e != nil {
+		return "", "", "", "", e
+	}
+
+	return locale, strings.TrimSpace(subject.String()), text.String(), html.String(), nil
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// OutboxRelay publishes the domain events written to the outbox on nats in the order they were written. A message is
+// marked as published only once nats has acknowledged it, so every event is delivered at least once.
+type OutboxRelay struct {
+	logger           *zap.SugaredLogger
+	mutex            sync.RWMutex
+	listeners        []func(*models.OutboxMessage) *errors.Type
+	outboxRepository *models.OutboxRepository
+	natsClient       *nc.Conn
+	subjectPrefix    string
+	pollInterval     time.Duration
+	batchSize        int
+	flushTimeout     time.Duration
+	retention        time.Duration
+	replayLimit      int
+	maxAttempts      int
+	attempts         map[int64]int
+	lastPurge        time.Time
+	pending          prometheus.Gauge
+	lag              prometheus.Gauge
+	published        prometheus.Counter
+	failures         prometheus.Counter
+	ctx              context.Context
+	cancel           context.CancelFunc
+	authorizer       *Authorizer
+	catalogue        *errors.Catalogue
+	stop             chan struct{}
+}
+
+// NewOutboxRelay returns a newly created and ready to use OutboxRelay. Publishing is disabled when the subject prefix
+// is empty, in which case the events are kept in the outbox unless there are listeners.
+func NewOutboxRelay(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool,
+	natsClient *nc.Conn, authorizer *Authorizer, catalogue *errors.Catalogue) *OutboxRelay {
+
+	subjectPrefix := config.Get("events.subject_prefix").StringOrElse("kiosk.events")
+	pollInterval := config.Get("events.poll_interval").DurationOrElse(time.Second)
+	batchSize := config.Get("events.batch_size").IntOrElse(100)
+	flushTimeout := config.Get("events.flush_timeout").DurationOrElse(5 * time.Second)
+	retention := config.Get("events.retention").DurationOrElse(24 * time.Hour)
+	replayLimit := config.Get("events.replay_limit").IntOrElse(1000)
+	maxAttempts := config.Get("events.max_attempts").IntOrElse(10)
+
+	logger.Info("events.subject_prefix -> ", subjectPrefix)
+	logger.Info("events.poll_interval -> ", pollInterval)
+	logger.Info("events.batch_size -> ", batchSize)
+	logger.Info("events.flush_timeout -> ", flushTimeout)
+	logger.Info("events.retention -> ", retention)
+	logger.Info("events.replay_limit -> ", replayLimit)
+	logger.Info("events.max_attempts -> ", maxAttempts)
+
+	pending := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kiosk",
+		Subsystem: "outbox",
+		Name:      "pending_messages",
+		Help:      "Number of domain events waiting in the outbox to be published.",
+	})
+	lag := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kiosk",
+		Subsystem: "outbox",
+		Name:      "lag_seconds",
+		Help:      "How long the oldest domain event waiting in the outbox has been waiting.",
+	})
+	published := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "kiosk",
+		Subsystem: "outbox",
+		Name:      "published_messages_total",
+		Help:      "Number of domain events published from the outbox.",
+	})
+	failures := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "kiosk",
+		Subsystem: "outbox",
+		Name:      "publish_failures_total",
+		Help:      "Number of failed attempts to publish domain events from the outbox.",
+	})
+	prometheus.MustRegister(pending, lag, published, failures)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &OutboxRelay{
+		logger:           logger,
+		outboxRepository: models.NewOutboxRepository(logger, db),
+		natsClient:       natsClient,
+		subjectPrefix:    subjectPrefix,
+		pollInterval:     pollInterval,
+		batchSize:        batchSize,
+		flushTimeout:     flushTimeout,
+		retention:        retention,
+		replayLimit:      replayLimit,
+		maxAttempts:      maxAttempts,
+		attempts:         make(map[int64]int),
+		pending:          pending,
+		lag:              lag,
+		published:        published,
+		failures:         failures,
+		ctx:              ctx,
+		cancel:           cancel,
+		authorizer:       authorizer,
+		catalogue:        catalogue,
+		stop:             make(chan struct{}, 1),
+	}
+}
+
+// Subscribe registers the listener so it will be handed all subsequent events. A listener failing on an event stops
+// relaying until the event is retried, so listeners must tolerate the same event more than once.
+func (r *OutboxRelay) Subscribe(listener func(*models.OutboxMessage) *errors.Type) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// Start starts the subscriptions and the background relay.
+func (r *OutboxRelay) Start() error {
+	streamTicketsSubscription, e := r.natsClient.QueueSubscribe("kiosk.streams.tickets",
+		"kiosk.streams.tickets_group", r.authorizer.identified(r.tickets))
+	if e != nil {
+		return e
+	}
+
+	replayEventsSubscription, e := r.natsClient.QueueSubscribe("kiosk.streams.replay",
+		"kiosk.streams.replay_group", r.authorizer.identified(r.replay))
+	if e != nil {
+		return e
+	}
+
+	go r.await(streamTicketsSubscription, replayEventsSubscription)
+
+	return nil
+}
+
+func (r *OutboxRelay) await(ss ...*nc.Subscription) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			r.logger.Debug("OutboxRelay: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case <-ticker.C:
+			r.relay()
+
+			r.measure()
+			r.purge()
+		}
+	}
+}
+
+// relay publishes the pending messages batch by batch, until there is nothing left or publishing fails.
+func (r *OutboxRelay) relay() {
+	r.mutex.RLock()
+	listening := len(r.listeners) > 0
+	r.mutex.RUnlock()
+
+	if r.subjectPrefix == "" && !listening {
+		return
+	}
+
+	for r.ctx.Err() == nil {
+		published, e := r.outboxRepository.Relay(r.ctx, r.batchSize, r.publish)
+		if e != nil || published < r.batchSize {
+			return
+		}
+	}
+}
+
+// publish hands the messages to the listeners and publishes them in order, and returns back how many of the leading
+// ones are done. Publishing stops at the first failure, so the events of a ticket stay in order.
+func (r *OutboxRelay) publish(messages []*models.OutboxMessage) int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	published := 0
+	for _, m := range messages {
+		if !r.notify(m) {
+			break
+		}
+
+		if r.subjectPrefix == "" {
+			published++
+			continue
+		}
+
+		envelope := &data.EventEnvelope{}
+		envelope.LoadFromOutboxMessage(m)
+		message, _ := json.Marshal(envelope)
+
+		if e := r.natsClient.Publish(r.subjectPrefix+"."+string(m.Type), message); e != nil {
+			r.logger.Warnf("OutboxRelay: failed to publish %v of ticket %v: %v", m.Type, m.TicketID, e.Error())
+			r.failures.Inc()
+			break
+		}
+
+		published++
+	}
+
+	if published == 0 || r.subjectPrefix == "" {
+		return published
+	}
+
+	// Messages are buffered by the client, so they are only known to be sent once the server answers a flush.
+	if e := r.natsClient.FlushTimeout(r.flushTimeout); e != nil {
+		r.logger.Warnf("OutboxRelay: failed to flush %v messages: %v", published, e.Error())
+		r.failures.Inc()
+		return 0
+	}
+
+	r.published.Add(float64(published))
+	return published
+}
+
+// notify hands the message to every listener and returns back whether all of them handled it. A listener failing on
+// the message max attempts times is skipped, so a single message can not hold up the relay forever.
+func (r *OutboxRelay) notify(message *models.OutboxMessage) bool {
+	for _, listener := range r.listeners {
+		if e := listener(message); e != nil {
+			r.failures.Inc()
+
+			// Only the relay goroutine counts the attempts, so they need no lock.
+			r.attempts[message.ID]++
+			if r.attempts[message.ID] < r.maxAttempts {
+				r.logger.Warnf("OutboxRelay: failed to handle %v of ticket %v: %v", message.Type, message.TicketID,
+					e.Errors[0].Code)
+				return false
+			}
+
+			r.logger.Errorf("OutboxRelay: skipped a listener of %v of ticket %v after %v attempts: %v", message.Type,
+				message.TicketID, r.attempts[message.ID], e.Errors[0].Code)
+		}
+	}
+
+	delete(r.attempts, message.ID)
+	return true
+}
+
+// tickets replies the criteria of a stream of the tickets restricted to the scope of the caller.
+func (r *OutboxRelay) tickets(msg *nc.Msg) {
+	streamTicketsRequest := &data.StreamTicketsRequest{}
+	if e := json.Unmarshal(msg.Data, streamTicketsRequest); e != nil {
+		r.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := streamTicketsRequest.Validate(); e != nil {
+		r.reply(msg, e)
+		return
+	}
+
+	if e := r.authorizer.AuthorizeFilter(identityOf(msg), "kiosk.streams.tickets", &streamTicketsRequest.Issuer,
+		&streamTicketsRequest.Owner); e != nil {
+
+		r.reply(msg, e)
+		return
+	}
+
+	r.reply(msg, streamTicketsRequest)
+}
+
+func (r *OutboxRelay) replay(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	replayEventsRequest := &data.ReplayEventsRequest{}
+	if e := json.Unmarshal(msg.Data, replayEventsRequest); e != nil {
+		r.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := replayEventsRequest.Validate(); e != nil {
+		r.reply(msg, e)
+		return
+	}
+
+	identity := identityOf(msg)
+	if e := r.authorizer.Authorize(identity, "kiosk.streams.replay", nil); e != nil {
+		r.reply(msg, e)
+		return
+	}
+
+	messages, hasMore, e := r.outboxRepository.Replay(ctx, replayEventsRequest.After, r.replayLimit)
+	if e != nil {
+		r.reply(msg, e)
+		return
+	}
+
+	replayEventsResponse := &data.ReplayEventsResponse{Events: make([]*data.EventEnvelope, 0, len(messages)),
+		HasMore: hasMore}
+	for _, m := range messages {
+		// Events of the tickets out of the scope of the caller are left out.
+		if r.authorizer.Authorize(identity, "kiosk.streams.replay", m.Ticket) != nil {
+			continue
+		}
+
+		envelope := &data.EventEnvelope{}
+		envelope.LoadFromOutboxMessage(m)
+		replayEventsResponse.Events = append(replayEventsResponse.Events, envelope)
+	}
+
+	r.reply(msg, replayEventsResponse)
+}
+
+func (r *OutboxRelay) measure() {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	pending, lag, e := r.outboxRepository.Lag(ctx)
+	if e != nil {
+		return
+	}
+
+	r.pending.Set(float64(pending))
+	r.lag.Set(lag.Seconds())
+}
+
+// purge deletes the messages published before the retention, at most once an hour.
+func (r *OutboxRelay) purge() {
+	if time.Since(r.lastPurge) < time.Hour {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, time.Minute)
+	defer cancel()
+
+	if e := r.outboxRepository.Purge(ctx, r.retention); e == nil {
+		r.lastPurge = time.Now()
+	}
+}
+
+func (r *OutboxRelay) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(r.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+// Stop stops the component, its subscriptions and the background relay. It does not wait for a relay that is not
+// started yet, as the listeners subscribe before it starts.
+func (r *OutboxRelay) Stop() {
+	r.cancel()
+	r.stop <- struct{}{}
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// RateLimitKey is the value of the requests that the callers are rate limited by.
+type RateLimitKey string
+
+// Rate limit keys.
+const (
+	// RateLimitByIssuer limits the callers by the tenant of their identity.
+	RateLimitByIssuer RateLimitKey = "ISSUER"
+	// RateLimitByOwner limits the callers by the subject of their identity.
+	RateLimitByOwner RateLimitKey = "OWNER"
+	// RateLimitByAPIKey limits the callers by the prefix of their API key.
+	RateLimitByAPIKey RateLimitKey = "API_KEY"
+)
+
+// RateLimit is a token bucket limit of an operation, i.e. a nats subject or http, for each value of its key.
+type RateLimit struct {
+	Operation string       `json:"operation"`
+	By        RateLimitKey `json:"by"`
+	Rate      float64      `json:"rate"`
+	Burst     int          `json:"burst"`
+}
+
+// LoadRateLimits loads the rate limits of the rate_limits.file JSON file, e.g: [{"operation": "kiosk.tickets.create",
+// "by": "ISSUER", "rate": 10, "burst": 50}], or returns back no limits when no file is configured.
+func LoadRateLimits(logger *zap.SugaredLogger, config *configuring.Config) ([]RateLimit, error) {
+	file := config.Get("rate_limits.file").StringOrElse("")
+	logger.Info("rate_limits.file -> ", file)
+
+	if file == "" {
+		return []RateLimit{}, nil
+	}
+
+	in, e := ioutil.ReadFile(file)
+	if e != nil {
+		return nil, e
+	}
+
+	limits := make([]RateLimit, 0)
+	if e := json.Unmarshal(in, &limits); e != nil {
+		return nil, fmt.Errorf("invalid rate limits file %v: %v", file, e)
+	}
+
+	for _, limit := range limits {
+		if limit.Operation == "" {
+			return nil, fmt.Errorf("rate limit with no operation in %v", file)
+		}
+
+		if limit.By != RateLimitByIssuer && limit.By != RateLimitByOwner && limit.By != RateLimitByAPIKey {
+			return nil, fmt.Errorf("invalid key %v of the rate limit of %v", limit.By, limit.Operation)
+		}
+
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			return nil, fmt.Errorf("rate limit of %v by %v needs a positive rate and burst", limit.Operation,
+				limit.By)
+		}
+	}
+
+	return limits, nil
+}
+
+// RateLimiter enforces the rate limits on the ticket and comment subjects and, through the kiosk.rate_limits.take
+// subject, on the HTTP API. The buckets are kept in the database so the limits hold across the kiosk nodes.
+type RateLimiter struct {
+	logger              *zap.SugaredLogger
+	rateLimitRepository *models.RateLimitRepository
+	natsClient          *nc.Conn
+	limits              map[string][]RateLimit
+	purgeInterval       time.Duration
+	idle                time.Duration
+	catalogue           *errors.Catalogue
+	stop                chan struct{}
+}
+
+// NewRateLimiter returns a newly created and ready to use RateLimiter.
+func NewRateLimiter(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool, natsClient *nc.Conn,
+	limits []RateLimit, catalogue *errors.Catalogue) *RateLimiter {
+
+	purgeInterval := config.Get("rate_limits.purge_interval").DurationOrElse(10 * time.Minute)
+	logger.Info("rate_limits.purge_interval -> ", purgeInterval)
+
+	operations := make(map[string][]RateLimit)
+	idle := time.Duration(0)
+	for _, limit := range limits {
+		operations[limit.Operation] = append(operations[limit.Operation], limit)
+
+		// The time it takes to refill an empty bucket, after which the bucket is the same as a missing one.
+		if refill := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second)); refill > idle {
+			idle = refill
+		}
+	}
+
+	return &RateLimiter{
+		logger:              logger,
+		rateLimitRepository: models.NewRateLimitRepository(logger, db),
+		natsClient:          natsClient,
+		limits:              operations,
+		purgeInterval:       purgeInterval,
+		idle:                idle,
+		catalogue:           catalogue,
+		stop:                make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions and the background purge of the idle buckets.
+func (l *RateLimiter) Start() error {
+	takeRateLimitSubscription, e := l.natsClient.QueueSubscribe("kiosk.rate_limits.take",
+		"kiosk.rate_limits.take_group", l.take)
+	if e != nil {
+		return e
+	}
+
+	go l.await(takeRateLimitSubscription)
+
+	return nil
+}
+
+func (l *RateLimiter) await(ss ...*nc.Subscription) {
+	ticker := time.NewTicker(l.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			l.logger.Debug("RateLimiter: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case <-ticker.C:
+			if len(l.limits) > 0 {
+				l.purge()
+			}
+		}
+	}
+}
+
+// Take takes a token of each rate limit of the operation for the caller, by the value of the identity for the key of
+// the limit, or by the client when the identity has no value for it. It returns back too many requests, along with the
+// longest wait, when a limit is exceeded.
+func (l *RateLimiter) Take(ctx context.Context, operation string, identity *data.Identity,
+	client string) *errors.Type {
+
+	limited := false
+	retryAfter := time.Duration(0)
+
+	for _, limit := range l.limits[operation] {
+		value := rateLimitValue(limit.By, identity, client)
+		if value == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%v:%v:%v", operation, limit.By, value)
+		taken, wait, e := l.rateLimitRepository.Take(ctx, key, limit.Rate, limit.Burst)
+		// Requests are not limited while the buckets are not available.
+		if e != nil || taken {
+			continue
+		}
+
+		limited = true
+		if wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if limited {
+		l.logger.Debug("RateLimiter: limited ", operation, " for ", retryAfter)
+		return errors.TooManyRequests(retryAfter)
+	}
+
+	return nil
+}
+
+// limited returns back the handler of the messages, which replies the messages exceeding the rate limits of their
+// subject right away. It relies on the identity of the messages, so it is wrapped by the identified handler.
+func (l *RateLimiter) limited(handler nc.MsgHandler) nc.MsgHandler {
+	return func(msg *nc.Msg) {
+		if len(l.limits[msg.Subject]) == 0 {
+			handler(msg)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// Internal callers are limited by the issuer and owner of their requests, or by their connection otherwise.
+		identity, client := identityOf(msg), ""
+		if identity == nil {
+			identity, client = requesterOf(msg), natsClientOf(msg)
+		}
+
+		if e := l.Take(ctx, msg.Subject, identity, client); e != nil {
+			l.reply(msg, e)
+			return
+		}
+
+		handler(msg)
+	}
+}
+
+func (l *RateLimiter) take(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	takeRateLimitRequest := &data.TakeRateLimitRequest{}
+	if e := json.Unmarshal(msg.Data, takeRateLimitRequest); e != nil {
+		l.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := takeRateLimitRequest.Validate(); e != nil {
+		l.reply(msg, e)
+		return
+	}
+
+	// The client is named by the web servers for the anonymous HTTP requests only, so callers with an API key, i.e. the
+	// external ones, can not take the buckets of others.
+	if identityOf(msg) != nil || apiKeyOf(msg) != "" {
+		if takeRateLimitRequest.Client != "" {
+			l.reply(msg, errors.Forbidden(""))
+			return
+		}
+	}
+
+	if e := l.Take(ctx, takeRateLimitRequest.Operation, identityOf(msg), takeRateLimitRequest.Client); e != nil {
+		l.reply(msg, e)
+		return
+	}
+
+	l.replyNoContent(msg)
+}
+
+// purge deletes the buckets idle long enough to be refilled.
+func (l *RateLimiter) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_ = l.rateLimitRepository.Purge(ctx, l.idle)
+}
+
+func (l *RateLimiter) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(l.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (l *RateLimiter) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component, its subscriptions and the background purge.
+func (l *RateLimiter) Stop() {
+	l.stop <- struct{}{}
+}
+
+// rateLimitValue returns back the value of the key for the caller, which is its client when its identity has none.
+func rateLimitValue(by RateLimitKey, identity *data.Identity, client string) string {
+	value := ""
+	if identity != nil {
+		switch by {
+		case RateLimitByIssuer:
+			value = identity.Tenant
+		case RateLimitByOwner:
+			value = identity.Subject
+		case RateLimitByAPIKey:
+			value = identity.KeyPrefix
+		}
+	}
+
+	if value == "" && client != "" {
+		return "client:" + client
+	}
+
+	return value
+}
+
+// requesterOf returns back the issuer and the owner of the request of the message as the identity of its caller, or
+// nil when the request has none of them.
+func requesterOf(msg *nc.Msg) *data.Identity {
+	requester := &struct {
+		Issuer string `json:"issuer"`
+		Owner  string `json:"owner"`
+	}{}
+	if e := json.Unmarshal(msg.Data, requester); e != nil || (requester.Issuer == "" && requester.Owner == "") {
+		return nil
+	}
+
+	return &data.Identity{Tenant: requester.Issuer, Subject: requester.Owner}
+}
+
+// apiKeyOf returns back the API key sent along with the message, if any.
+func apiKeyOf(msg *nc.Msg) string {
+	identified := &data.Identified{}
+	if e := json.Unmarshal(msg.Data, identified); e != nil {
+		return ""
+	}
+
+	return identified.APIKey
+}
+
+// natsClientOf returns back the connection that sent the message, i.e. the inbox prefix of its reply subject, as nats
+// clients share one inbox prefix for all the requests of a connection.
+func natsClientOf(msg *nc.Msg) string {
+	if strings.Count(msg.Reply, ".") > 1 {
+		return msg.Reply[:strings.LastIndex(msg.Reply, ".")]
+	}
+
+	return msg.Reply
+}
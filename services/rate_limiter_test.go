@@ -0,0 +1,85 @@
+package services_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jibitters/kiosk/services"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("RateLimiter", func() {
+	load := func(limits string) ([]services.RateLimit, error) {
+		directory, _ := ioutil.TempDir("", "rate_limits")
+		defer func() { _ = os.RemoveAll(directory) }()
+
+		file := filepath.Join(directory, "rate_limits.json")
+		Ω(ioutil.WriteFile(file, []byte(limits), 0600)).Should(Succeed())
+
+		configFile := filepath.Join(directory, "kiosk.json")
+		Ω(ioutil.WriteFile(configFile, []byte(`{"rate_limits": {"file": "`+file+`"}}`), 0600)).Should(Succeed())
+
+		config := configuring.New()
+		_, e := config.LoadJSON(configFile)
+		Ω(e).Should(BeNil())
+
+		return services.LoadRateLimits(zap.NewNop().Sugar(), config)
+	}
+
+	Context("When LoadRateLimits called", func() {
+		It("Should load the limits of the file", func() {
+			limits, e := load(`[{"operation": "kiosk.tickets.create", "by": "ISSUER", "rate": 10, "burst": 50},
+				{"operation": "http", "by": "API_KEY", "rate": 0.5, "burst": 1}]`)
+			Ω(e).Should(BeNil())
+			Ω(limits).Should(Equal([]services.RateLimit{
+				{Operation: "kiosk.tickets.create", By: services.RateLimitByIssuer, Rate: 10, Burst: 50},
+				{Operation: "http", By: services.RateLimitByAPIKey, Rate: 0.5, Burst: 1},
+			}))
+		})
+
+		It("Should reject the invalid limits", func() {
+			for _, limits := range []string{
+				`{}`,
+				`[{"by": "ISSUER", "rate": 10, "burst": 50}]`,
+				`[{"operation": "http", "by": "IP", "rate": 10, "burst": 50}]`,
+				`[{"operation": "http", "by": "OWNER", "rate": 0, "burst": 50}]`,
+				`[{"operation": "http", "by": "OWNER", "rate": 10, "burst": 0}]`,
+			} {
+				_, e := load(limits)
+				Ω(e).ShouldNot(BeNil(), limits)
+			}
+		})
+
+		It("Should load no limits without a file", func() {
+			limits, e := services.LoadRateLimits(zap.NewNop().Sugar(), configuring.New())
+			Ω(e).Should(BeNil())
+			Ω(limits).Should(BeEmpty())
+		})
+	})
+
+	Context("When Take called", func() {
+		It("Should not limit the operations without limits", func() {
+			limiter := services.NewRateLimiter(zap.NewNop().Sugar(), configuring.New(), nil, nil,
+				[]services.RateLimit{{Operation: "http", By: services.RateLimitByOwner, Rate: 1, Burst: 1}}, nil)
+
+			identity := &data.Identity{Subject: "user@example.com"}
+			Ω(limiter.Take(context.Background(), "kiosk.tickets.create", identity, "")).Should(BeNil())
+		})
+
+		It("Should not limit the callers with neither a value for the key nor client", func() {
+			limiter := services.NewRateLimiter(zap.NewNop().Sugar(), configuring.New(), nil, nil,
+				[]services.RateLimit{{Operation: "http", By: services.RateLimitByIssuer, Rate: 1, Burst: 1}}, nil)
+
+			Ω(limiter.Take(context.Background(), "http", nil, "")).Should(BeNil())
+
+			identity := &data.Identity{Subject: "user@example.com"}
+			Ω(limiter.Take(context.Background(), "http", identity, "")).Should(BeNil())
+		})
+	})
+})
@@ -0,0 +1,634 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// RuleService is a service implementation of the automation rules engine. Rules are managed through nats and are
+// evaluated in background against the lifecycle events of the tickets and comments.
+type RuleService struct {
+	logger                   *zap.SugaredLogger
+	ruleRepository           *models.RuleRepository
+	ruleExecutionRepository  *models.RuleExecutionRepository
+	ticketRepository         *models.TicketRepository
+	commentRepository        *models.CommentRepository
+	cannedResponseRepository *models.CannedResponseRepository
+	natsClient               *nc.Conn
+	dispatcher               *EventDispatcher
+	httpClient               *http.Client
+	events                   chan Event
+	maxDepth                 int
+	ctx                      context.Context
+	cancel                   context.CancelFunc
+	authorizer               *Authorizer
+	catalogue                *errors.Catalogue
+	stop                     chan struct{}
+}
+
+// NewRuleService returns a newly created and ready to use RuleService. The webhooks of the rules are called through
+// the networks allowed to the webhooks.
+func NewRuleService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool, natsClient *nc.Conn,
+	dispatcher *EventDispatcher, authorizer *Authorizer, catalogue *errors.Catalogue) (*RuleService, error) {
+
+	queueSize := config.Get("rules.queue_size").IntOrElse(1000)
+	maxDepth := config.Get("rules.max_depth").IntOrElse(3)
+	webhookTimeout := config.Get("rules.webhook_timeout").DurationOrElse(5 * time.Second)
+
+	logger.Info("rules.queue_size -> ", queueSize)
+	logger.Info("rules.max_depth -> ", maxDepth)
+	logger.Info("rules.webhook_timeout -> ", webhookTimeout)
+
+	allowedNetworks := config.Get("webhooks.allowed_networks").SliceOfStringOrElse([]string{})
+	deniedNetworks := config.Get("webhooks.denied_networks").SliceOfStringOrElse(DefaultDeniedWebhookNetworks)
+	transport, e := NewWebhookTransport(allowedNetworks, deniedNetworks, webhookTimeout)
+	if e != nil {
+		return nil, fmt.Errorf("invalid webhooks networks: %v", e)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &RuleService{
+		logger:                   logger,
+		ruleRepository:           models.NewRuleRepository(logger, db),
+		ruleExecutionRepository:  models.NewRuleExecutionRepository(logger, db),
+		ticketRepository:         models.NewTicketRepository(logger, db),
+		commentRepository:        models.NewCommentRepository(logger, db),
+		cannedResponseRepository: models.NewCannedResponseRepository(logger, db),
+		natsClient:               natsClient,
+		dispatcher:               dispatcher,
+		httpClient:               &http.Client{Transport: transport, Timeout: webhookTimeout},
+		events:                   make(chan Event, queueSize),
+		maxDepth:                 maxDepth,
+		ctx:                      ctx,
+		cancel:                   cancel,
+		authorizer:               authorizer,
+		catalogue:                catalogue,
+		stop:                     make(chan struct{}),
+	}, nil
+}
+
+// Start starts the subscriptions and the background worker so ready to be notified.
+func (s *RuleService) Start() error {
+	createRuleSubscription, e := s.natsClient.QueueSubscribe("kiosk.rules.create",
+		"kiosk.rules.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	loadRuleSubscription, e := s.natsClient.QueueSubscribe("kiosk.rules.load",
+		"kiosk.rules.load_group", s.authorizer.identified(s.load))
+	if e != nil {
+		return e
+	}
+
+	updateRuleSubscription, e := s.natsClient.QueueSubscribe("kiosk.rules.update",
+		"kiosk.rules.update_group", s.authorizer.identified(s.update))
+	if e != nil {
+		return e
+	}
+
+	deleteRuleSubscription, e := s.natsClient.QueueSubscribe("kiosk.rules.delete",
+		"kiosk.rules.delete_group", s.authorizer.identified(s.delete))
+	if e != nil {
+		return e
+	}
+
+	filterRulesSubscription, e := s.natsClient.QueueSubscribe("kiosk.rules.filter",
+		"kiosk.rules.filter_group", s.authorizer.identified(s.filter))
+	if e != nil {
+		return e
+	}
+
+	testRuleSubscription, e := s.natsClient.QueueSubscribe("kiosk.rules.test",
+		"kiosk.rules.test_group", s.authorizer.identified(s.test))
+	if e != nil {
+		return e
+	}
+
+	filterRuleExecutionsSubscription, e := s.natsClient.QueueSubscribe("kiosk.rules.executions",
+		"kiosk.rules.executions_group", s.authorizer.identified(s.executions))
+	if e != nil {
+		return e
+	}
+
+	s.dispatcher.Subscribe(s.enqueue)
+
+	go s.await(createRuleSubscription, loadRuleSubscription, updateRuleSubscription, deleteRuleSubscription,
+		filterRulesSubscription, testRuleSubscription, filterRuleExecutionsSubscription)
+
+	return nil
+}
+
+func (s *RuleService) await(ss ...*nc.Subscription) {
+	for {
+		select {
+		case <-s.stop:
+			s.logger.Debug("RuleService: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case event := <-s.events:
+			s.process(event)
+		}
+	}
+}
+
+// enqueue hands over the event to the background worker. Events are dropped when the worker falls behind, as the
+// changes have already been persisted and replying to clients should never wait for the rules.
+func (s *RuleService) enqueue(event Event) {
+	select {
+	case s.events <- event:
+	default:
+		s.logger.Warnf("RuleService: dropped %v of ticket %v, the queue is full", event.Type, event.TicketID)
+	}
+}
+
+func (s *RuleService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createRuleRequest := &data.CreateRuleRequest{}
+	if e := json.Unmarshal(msg.Data, createRuleRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createRuleRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.rules.create"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.ruleRepository.Insert(ctx, *createRuleRequest.AsRule()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *RuleService) load(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.rules.load"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	r, e := s.ruleRepository.LoadByID(ctx, id.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ruleResponse := &data.RuleResponse{}
+	ruleResponse.LoadFromRule(r)
+	s.reply(msg, ruleResponse)
+}
+
+func (s *RuleService) update(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updateRuleRequest := &data.UpdateRuleRequest{}
+	if e := json.Unmarshal(msg.Data, updateRuleRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := updateRuleRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.rules.update"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.ruleRepository.Update(ctx, updateRuleRequest.AsRule()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *RuleService) delete(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.rules.delete"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.ruleRepository.DeleteByID(ctx, id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *RuleService) filter(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterRulesRequest := &data.FilterRulesRequest{}
+	if e := json.Unmarshal(msg.Data, filterRulesRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterRulesRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.rules.filter"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	rs, hasNextPage, e := s.ruleRepository.Filter(ctx, filterRulesRequest.PageNumber, filterRulesRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterRulesResponse := &data.FilterRulesResponse{}
+	filterRulesResponse.LoadFromRules(rs, hasNextPage)
+	s.reply(msg, filterRulesResponse)
+}
+
+func (s *RuleService) test(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	testRuleRequest := &data.TestRuleRequest{}
+	if e := json.Unmarshal(msg.Data, testRuleRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := testRuleRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.rules.test"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	r, e := s.ruleRepository.LoadByID(ctx, testRuleRequest.RuleID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	t, e := s.ticketRepository.LoadByID(ctx, testRuleRequest.TicketID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	// Comment events are tested against the latest comment of the ticket.
+	var comment *models.Comment
+	if len(t.Comments) > 0 {
+		comment = t.Comments[0]
+	}
+
+	results, matched := evaluateRule(r, t, comment, time.Now().UTC())
+
+	testRuleResponse := &data.TestRuleResponse{Matched: matched, Conditions: results}
+	if matched {
+		comments, e := applyTicketActions(ctx, s.cannedResponseRepository, ticketActions(r), t, "")
+		if e != nil {
+			s.reply(msg, e)
+			return
+		}
+
+		testRuleResponse.Ticket = &data.TicketResponse{}
+		testRuleResponse.Ticket.LoadFromTicket(previewTicket(t, comments))
+	}
+
+	s.reply(msg, testRuleResponse)
+}
+
+func (s *RuleService) executions(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterRuleExecutionsRequest := &data.FilterRuleExecutionsRequest{}
+	if e := json.Unmarshal(msg.Data, filterRuleExecutionsRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterRuleExecutionsRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeAll(identityOf(msg), "kiosk.rules.executions"); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	es, hasNextPage, e := s.ruleExecutionRepository.Filter(ctx, filterRuleExecutionsRequest.RuleID,
+		filterRuleExecutionsRequest.PageNumber, filterRuleExecutionsRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterRuleExecutionsResponse := &data.FilterRuleExecutionsResponse{}
+	filterRuleExecutionsResponse.LoadFromRuleExecutions(es, hasNextPage)
+	s.reply(msg, filterRuleExecutionsResponse)
+}
+
+// process evaluates all enabled rules of the event type against the ticket of the event, in the order of their
+// creation. Each rule sees the changes of the previous ones.
+func (s *RuleService) process(event Event) {
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+
+	rules, e := s.ruleRepository.LoadEnabledByEvent(ctx, event.Type)
+	if e != nil || len(rules) == 0 {
+		return
+	}
+
+	var comment *models.Comment
+	if event.CommentID > 0 {
+		if comment, e = s.commentRepository.LoadByID(ctx, event.CommentID); e != nil {
+			s.logger.Warnf("RuleService: failed to load comment %v: %v", event.CommentID, e.Errors[0].Code)
+			return
+		}
+
+		event.TicketID = comment.TicketID
+	}
+
+	for _, r := range rules {
+		t, e := s.ticketRepository.LoadByID(ctx, event.TicketID)
+		if e != nil {
+			s.logger.Warnf("RuleService: failed to load ticket %v: %v", event.TicketID, e.Errors[0].Code)
+			return
+		}
+
+		s.execute(ctx, r, event, t, comment)
+	}
+}
+
+// execute executes the actions of the rule if it matches and records the execution log.
+func (s *RuleService) execute(ctx context.Context, rule *models.Rule, event Event, ticket *models.Ticket,
+	comment *models.Comment) {
+
+	if _, matched := evaluateRule(rule, ticket, comment, time.Now().UTC()); !matched {
+		return
+	}
+
+	execution := models.RuleExecution{RuleID: rule.ID, TicketID: ticket.ID, Event: event.Type}
+
+	switch {
+	case containsRule(event.Rules, rule.ID):
+		execution.Status = models.RuleExecutionStatusSkipped
+		execution.Details = "rule.loop_detected"
+	case len(event.Rules) >= s.maxDepth:
+		execution.Status = models.RuleExecutionStatusSkipped
+		execution.Details = "rule.max_depth_exceeded"
+	default:
+		if e := s.apply(ctx, rule, event, ticket); e != nil {
+			execution.Status = models.RuleExecutionStatusFailed
+			execution.Details = e.Errors[0].Code
+			if e.Errors[0].Message != "" {
+				execution.Details += ": " + e.Errors[0].Message
+			}
+		} else {
+			execution.Status = models.RuleExecutionStatusApplied
+		}
+	}
+
+	if e := s.ruleExecutionRepository.Insert(ctx, execution); e != nil {
+		s.logger.Warnf("RuleService: failed to record execution of rule %v: %v", rule.ID, e.Errors[0].Code)
+	}
+}
+
+// apply persists the ticket changing actions of the rule in a single transaction and then runs the notifying ones,
+// in order, with the resulting ticket.
+func (s *RuleService) apply(ctx context.Context, rule *models.Rule, event Event, ticket *models.Ticket) *errors.Type {
+	actions := ticketActions(rule)
+	if len(actions) > 0 {
+		comments, e := applyTicketActions(ctx, s.cannedResponseRepository, actions, ticket, "")
+		if e != nil {
+			return e
+		}
+
+		previousStatus, e := s.ticketRepository.Apply(ctx, ticket, comments,
+			models.SystemActor(models.ActorTypeRule, rule.ID), nil)
+		if e != nil {
+			return e
+		}
+
+		if ticket, e = s.ticketRepository.LoadByID(ctx, ticket.ID); e != nil {
+			return e
+		}
+
+		chain := append(append(make([]int64, 0, len(event.Rules)+1), event.Rules...), rule.ID)
+		s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketUpdated, TicketID: ticket.ID, Rules: chain,
+			PreviousStatus: previousStatus})
+	}
+
+	ticketResponse := &data.TicketResponse{}
+	ticketResponse.LoadFromTicket(ticket)
+	payload, _ := json.Marshal(ticketResponse)
+
+	for _, a := range rule.Actions {
+		switch a.Type {
+		case models.RuleActionTypePublishMessage:
+			if e := s.natsClient.Publish(a.Topic, payload); e != nil {
+				return errors.PreconditionFailed("rule.publish_failed", e.Error())
+			}
+		case models.RuleActionTypeCallWebhook:
+			if e := s.callWebhook(ctx, a.URL, payload); e != nil {
+				return errors.PreconditionFailed("rule.webhook_failed", e.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *RuleService) callWebhook(ctx context.Context, url string, payload []byte) error {
+	request, e := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if e != nil {
+		return e
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, e := s.httpClient.Do(request)
+	if e != nil {
+		return e
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %v", response.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *RuleService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (s *RuleService) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component and it subscriptions.
+func (s *RuleService) Stop() {
+	s.cancel()
+	s.stop <- struct{}{}
+}
+
+// ticketActions returns back the ticket changing actions of the rule as macro actions.
+func ticketActions(rule *models.Rule) []models.MacroAction {
+	actions := make([]models.MacroAction, 0)
+	for _, a := range rule.Actions {
+		if a.IsTicketAction() {
+			actions = append(actions, a.AsMacroAction())
+		}
+	}
+
+	return actions
+}
+
+func containsRule(rules []int64, id int64) bool {
+	for _, r := range rules {
+		if r == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// evaluateRule reports whether all conditions of the rule match the ticket and the comment of an event.
+func evaluateRule(rule *models.Rule, ticket *models.Ticket, comment *models.Comment,
+	now time.Time) ([]*data.RuleConditionResult, bool) {
+
+	results := make([]*data.RuleConditionResult, 0, len(rule.Conditions))
+	matched := true
+	for _, c := range rule.Conditions {
+		actual := conditionField(c.Field, ticket, comment, now)
+		result := &data.RuleConditionResult{RuleCondition: c, Actual: actual, Matched: compare(c, actual)}
+
+		matched = matched && result.Matched
+		results = append(results, result)
+	}
+
+	return results, matched
+}
+
+func conditionField(field string, ticket *models.Ticket, comment *models.Comment, now time.Time) string {
+	switch field {
+	case "issuer":
+		return ticket.Issuer
+	case "owner":
+		return ticket.Owner
+	case "subject":
+		return ticket.Subject
+	case "content":
+		return ticket.Content
+	case "importance_level":
+		return string(ticket.ImportanceLevel)
+	case "status":
+		return string(ticket.Status)
+	case "minutes_since_modified":
+		return strconv.Itoa(int(now.Sub(ticket.ModifiedAt).Minutes()))
+	case "comment.owner":
+		if comment != nil {
+			return comment.Owner
+		}
+	case "comment.content":
+		if comment != nil {
+			return comment.Content
+		}
+	default:
+		return metadataValues(ticket.Metadata)[strings.TrimPrefix(field, "metadata.")]
+	}
+
+	return ""
+}
+
+func compare(condition models.RuleCondition, actual string) bool {
+	switch condition.Operator {
+	case models.RuleConditionOperatorEquals:
+		return actual == condition.Value
+	case models.RuleConditionOperatorNotEquals:
+		return actual != condition.Value
+	case models.RuleConditionOperatorContains:
+		return strings.Contains(actual, condition.Value)
+	case models.RuleConditionOperatorMatches:
+		matched, _ := regexp.MatchString(condition.Value, actual)
+		return matched
+	case models.RuleConditionOperatorGreaterThan, models.RuleConditionOperatorLessThan:
+		a, e := strconv.ParseFloat(actual, 64)
+		if e != nil {
+			return false
+		}
+
+		v, e := strconv.ParseFloat(condition.Value, 64)
+		if e != nil {
+			return false
+		}
+
+		if condition.Operator == models.RuleConditionOperatorGreaterThan {
+			return a > v
+		}
+
+		return a < v
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// SatisfactionService is a service implementation of customer satisfaction related functionalities.
+type SatisfactionService struct {
+	logger                 *zap.SugaredLogger
+	ticketRepository       *models.TicketRepository
+	satisfactionRepository *models.SatisfactionRepository
+	natsClient             *nc.Conn
+	authorizer             *Authorizer
+	catalogue              *errors.Catalogue
+	stop                   chan struct{}
+}
+
+// NewSatisfactionService returns a newly created and ready to use SatisfactionService.
+func NewSatisfactionService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *nc.Conn,
+	authorizer *Authorizer, catalogue *errors.Catalogue) *SatisfactionService {
+
+	return &SatisfactionService{
+		logger:                 logger,
+		ticketRepository:       models.NewTicketRepository(logger, db),
+		satisfactionRepository: models.NewSatisfactionRepository(logger, db),
+		natsClient:             natsClient,
+		authorizer:             authorizer,
+		catalogue:              catalogue,
+		stop:                   make(chan struct{}),
+	}
+}
+
+// Start starts the subscriptions so ready to be notified.
+func (s *SatisfactionService) Start() error {
+	rateTicketSubscription, e := s.natsClient.QueueSubscribe("kiosk.tickets.rate", "kiosk.tickets.rate_group",
+		s.authorizer.identified(s.rate))
+	if e != nil {
+		return e
+	}
+
+	csatReportSubscription, e := s.natsClient.QueueSubscribe("kiosk.reports.csat", "kiosk.reports.csat_group",
+		s.authorizer.identified(s.report))
+	if e != nil {
+		return e
+	}
+
+	go s.await(rateTicketSubscription, csatReportSubscription)
+
+	return nil
+}
+
+func (s *SatisfactionService) await(ss ...*nc.Subscription) {
+	<-s.stop
+	s.logger.Debug("SatisfactionService: received stop signal!")
+
+	for _, s := range ss {
+		_ = s.Unsubscribe()
+	}
+}
+
+func (s *SatisfactionService) rate(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rateTicketRequest := &data.RateTicketRequest{}
+	if e := json.Unmarshal(msg.Data, rateTicketRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := rateTicketRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeOwner(identityOf(msg), &rateTicketRequest.Owner); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	t, e := s.ticketRepository.LoadByID(ctx, rateTicketRequest.TicketID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.Authorize(identityOf(msg), "kiosk.tickets.rate", t); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if t.Owner != rateTicketRequest.Owner {
+		s.reply(msg, errors.PreconditionFailed("ticket.owner_mismatch", ""))
+		return
+	}
+
+	if t.Status != models.TicketStatusResolved && t.Status != models.TicketStatusClosed {
+		s.reply(msg, errors.PreconditionFailed("ticket.not_resolved", ""))
+		return
+	}
+
+	if t.Satisfaction != nil {
+		s.reply(msg, errors.AlreadyExists("satisfaction.already_exists", ""))
+		return
+	}
+
+	// The assignee comes from the free form metadata of the ticket, so it is checked against its column.
+	assignee := metadataValues(t.Metadata)["assignee"]
+	v := data.NewValidator()
+	if !v.MaxLength("metadata.assignee", assignee, 50) {
+		s.reply(msg, v.Errors())
+		return
+	}
+
+	satisfaction := models.Satisfaction{
+		TicketID: t.ID,
+		Issuer:   t.Issuer,
+		Assignee: assignee,
+		Owner:    rateTicketRequest.Owner,
+		Rating:   rateTicketRequest.Rating,
+		Comment:  rateTicketRequest.Comment,
+	}
+	if e := s.satisfactionRepository.Insert(ctx, satisfaction); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *SatisfactionService) report(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	csatReportRequest := &data.CSATReportRequest{}
+	if e := json.Unmarshal(msg.Data, csatReportRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := csatReportRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuerFilter(identityOf(msg), "kiosk.reports.csat", &csatReportRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	fromDate, toDate := csatReportRequest.Dates()
+	summaries, e := s.satisfactionRepository.Report(ctx, csatReportRequest.Issuer, fromDate, toDate)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	csatReportResponse := &data.CSATReportResponse{}
+	csatReportResponse.LoadFromSatisfactionSummaries(summaries, fromDate, toDate)
+	s.reply(msg, csatReportResponse)
+}
+
+func (s *SatisfactionService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (s *SatisfactionService) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component and it subscriptions.
+func (s *SatisfactionService) Stop() {
+	s.stop <- struct{}{}
+}
@@ -0,0 +1,19 @@
+package services_test
+
+import (
+	"flag"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func init() {
+	// Passed to every suite by the test script.
+	flag.String("pg.host", "localhost", "")
+}
+
+func TestServices(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Services Suite")
+}
@@ -18,15 +18,26 @@ type TicketService struct {
 	logger           *zap.SugaredLogger
 	ticketRepository *models.TicketRepository
 	natsClient       *nc.Conn
+	dispatcher       *EventDispatcher
+	authorizer       *Authorizer
+	rateLimiter      *RateLimiter
+	catalogue        *errors.Catalogue
 	stop             chan struct{}
 }
 
 // NewTicketService returns a newly created and ready to use TicketService.
-func NewTicketService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *nc.Conn) *TicketService {
+func NewTicketService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *nc.Conn,
+	dispatcher *EventDispatcher, authorizer *Authorizer, rateLimiter *RateLimiter,
+	catalogue *errors.Catalogue) *TicketService {
+
 	return &TicketService{
 		logger:           logger,
 		ticketRepository: models.NewTicketRepository(logger, db),
 		natsClient:       natsClient,
+		dispatcher:       dispatcher,
+		authorizer:       authorizer,
+		rateLimiter:      rateLimiter,
+		catalogue:        catalogue,
 		stop:             make(chan struct{}),
 	}
 }
@@ -34,37 +45,43 @@ func NewTicketService(logger *zap.SugaredLogger, db *pgxpool.Pool, natsClient *n
 // Start starts the subscriptions so ready to be notified.
 func (s *TicketService) Start() error {
 	createTicketSubscription, e := s.natsClient.QueueSubscribe("kiosk.tickets.create",
-		"kiosk.tickets.create_group", s.create)
+		"kiosk.tickets.create_group", s.authorizer.identified(s.rateLimiter.limited(s.create)))
 	if e != nil {
 		return e
 	}
 
 	loadTicketSubscription, e := s.natsClient.QueueSubscribe("kiosk.tickets.load",
-		"kiosk.tickets.load_group", s.load)
+		"kiosk.tickets.load_group", s.authorizer.identified(s.rateLimiter.limited(s.load)))
 	if e != nil {
 		return e
 	}
 
 	updateTicketSubscription, e := s.natsClient.QueueSubscribe("kiosk.tickets.update",
-		"kiosk.tickets.update_group", s.update)
+		"kiosk.tickets.update_group", s.authorizer.identified(s.rateLimiter.limited(s.update)))
+	if e != nil {
+		return e
+	}
+
+	patchTicketSubscription, e := s.natsClient.QueueSubscribe("kiosk.tickets.patch",
+		"kiosk.tickets.patch_group", s.authorizer.identified(s.rateLimiter.limited(s.patch)))
 	if e != nil {
 		return e
 	}
 
 	deleteTicketSubscription, e := s.natsClient.QueueSubscribe("kiosk.tickets.delete",
-		"kiosk.tickets.delete_group", s.delete)
+		"kiosk.tickets.delete_group", s.authorizer.identified(s.rateLimiter.limited(s.delete)))
 	if e != nil {
 		return e
 	}
 
 	filterTicketsSubscription, e := s.natsClient.QueueSubscribe("kiosk.tickets.filter",
-		"kiosk.tickets.filter_group", s.filter)
+		"kiosk.tickets.filter_group", s.authorizer.identified(s.rateLimiter.limited(s.filter)))
 	if e != nil {
 		return e
 	}
 
-	go s.await(createTicketSubscription, loadTicketSubscription, updateTicketSubscription, deleteTicketSubscription,
-		filterTicketsSubscription)
+	go s.await(createTicketSubscription, loadTicketSubscription, updateTicketSubscription, patchTicketSubscription,
+		deleteTicketSubscription, filterTicketsSubscription)
 
 	return nil
 }
@@ -93,12 +110,19 @@ func (s *TicketService) create(msg *nc.Msg) {
 		return
 	}
 
-	if e := s.ticketRepository.Insert(ctx, *createTicketRequest.AsTicket()); e != nil {
+	if e := s.authorizer.Authorize(identityOf(msg), "kiosk.tickets.create", createTicketRequest.AsTicket()); e != nil {
 		s.reply(msg, e)
 		return
 	}
 
-	s.replyNoContent(msg)
+	id, e := s.ticketRepository.Insert(ctx, *createTicketRequest.AsTicket(), actorOf(msg))
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.reply(msg, &data.ID{ID: id})
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketCreated, TicketID: id})
 }
 
 func (s *TicketService) load(msg *nc.Msg) {
@@ -117,6 +141,11 @@ func (s *TicketService) load(msg *nc.Msg) {
 		return
 	}
 
+	if e := s.authorizer.Authorize(identityOf(msg), "kiosk.tickets.load", t); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
 	ticketResponse := &data.TicketResponse{}
 	ticketResponse.LoadFromTicket(t)
 	s.reply(msg, ticketResponse)
@@ -137,12 +166,65 @@ func (s *TicketService) update(msg *nc.Msg) {
 		return
 	}
 
-	if e := s.ticketRepository.Update(ctx, updateTicketRequest.AsTicket()); e != nil {
+	identity := identityOf(msg)
+	if e := s.authorizer.Authorize(identity, "kiosk.tickets.update", nil); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	previousStatus, e := s.ticketRepository.Apply(ctx, updateTicketRequest.AsTicket(), nil, actorOf(msg),
+		s.authorized(identity, "kiosk.tickets.update"))
+	if e != nil {
 		s.reply(msg, e)
 		return
 	}
 
 	s.replyNoContent(msg)
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketUpdated, TicketID: updateTicketRequest.ID,
+		PreviousStatus: previousStatus})
+}
+
+func (s *TicketService) patch(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	patchTicketRequest := &data.PatchTicketRequest{}
+	if e := json.Unmarshal(msg.Data, patchTicketRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := patchTicketRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	identity := identityOf(msg)
+	if e := s.authorizer.Authorize(identity, "kiosk.tickets.update", nil); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	// The patch is applied to the locked ticket, so the concurrent changes of the other fields are kept.
+	ticket := &models.Ticket{Model: models.Model{ID: patchTicketRequest.ID}}
+	patch := func(locked *models.Ticket) *errors.Type {
+		if e := s.authorizer.Authorize(identity, "kiosk.tickets.update", locked); e != nil {
+			return e
+		}
+
+		*ticket = *patchTicketRequest.AsTicket(locked)
+		return nil
+	}
+
+	previousStatus, e := s.ticketRepository.Apply(ctx, ticket, nil, actorOf(msg), patch)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketUpdated, TicketID: patchTicketRequest.ID,
+		PreviousStatus: previousStatus})
 }
 
 func (s *TicketService) delete(msg *nc.Msg) {
@@ -155,12 +237,20 @@ func (s *TicketService) delete(msg *nc.Msg) {
 		return
 	}
 
-	if e := s.ticketRepository.DeleteByID(ctx, id.ID); e != nil {
+	identity := identityOf(msg)
+	if e := s.authorizer.Authorize(identity, "kiosk.tickets.delete", nil); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.ticketRepository.DeleteByID(ctx, id.ID, actorOf(msg), s.authorized(identity, "kiosk.tickets.delete"))
+	if e != nil {
 		s.reply(msg, e)
 		return
 	}
 
 	s.replyNoContent(msg)
+	s.dispatcher.Dispatch(Event{Type: models.EventTypeTicketDeleted, TicketID: id.ID})
 }
 
 func (s *TicketService) filter(msg *nc.Msg) {
@@ -178,6 +268,13 @@ func (s *TicketService) filter(msg *nc.Msg) {
 		return
 	}
 
+	if e := s.authorizer.AuthorizeFilter(identityOf(msg), "kiosk.tickets.filter", &filterTicketsRequest.Issuer,
+		&filterTicketsRequest.Owner); e != nil {
+
+		s.reply(msg, e)
+		return
+	}
+
 	ts, hasNextPage, e := s.ticketRepository.Filter(ctx, filterTicketsRequest.Issuer, filterTicketsRequest.Owner,
 		filterTicketsRequest.ImportanceLevel, filterTicketsRequest.Status, filterTicketsRequest.FromDate,
 		filterTicketsRequest.ToDate, filterTicketsRequest.PageNumber, filterTicketsRequest.PageSize)
@@ -191,8 +288,15 @@ func (s *TicketService) filter(msg *nc.Msg) {
 	s.reply(msg, filterTicketsResponse)
 }
 
+// authorized returns back the check authorizing the identity on the ticket as the repository sees it.
+func (s *TicketService) authorized(identity *data.Identity, subject string) func(*models.Ticket) *errors.Type {
+	return func(ticket *models.Ticket) *errors.Type {
+		return s.authorizer.Authorize(identity, subject, ticket)
+	}
+}
+
 func (s *TicketService) reply(msg *nc.Msg, t interface{}) {
-	reply, _ := json.Marshal(t)
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
 	_ = msg.Respond(reply)
 }
 
@@ -0,0 +1,595 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// WebhookService is a service implementation of outbound webhooks.
+type WebhookService struct {
+	logger                    *zap.SugaredLogger
+	webhookRepository         *models.WebhookRepository
+	webhookDeliveryRepository *models.WebhookDeliveryRepository
+	natsClient                *nc.Conn
+	outboxRelay               *OutboxRelay
+	httpClient                *http.Client
+	pollInterval              time.Duration
+	batchSize                 int
+	lease                     time.Duration
+	maxAttempts               int
+	backoff                   time.Duration
+	maxBackoff                time.Duration
+	disableAfter              int
+	ctx                       context.Context
+	cancel                    context.CancelFunc
+	authorizer                *Authorizer
+	catalogue                 *errors.Catalogue
+	stop                      chan struct{}
+}
+
+// NewWebhookService returns a newly created and ready to use WebhookService.
+func NewWebhookService(logger *zap.SugaredLogger, config *configuring.Config, db *pgxpool.Pool, natsClient *nc.Conn,
+	outboxRelay *OutboxRelay, authorizer *Authorizer, catalogue *errors.Catalogue) (*WebhookService, error) {
+
+	timeout := config.Get("webhooks.timeout").DurationOrElse(5 * time.Second)
+	pollInterval := config.Get("webhooks.poll_interval").DurationOrElse(5 * time.Second)
+	batchSize := config.Get("webhooks.batch_size").IntOrElse(50)
+	lease := config.Get("webhooks.lease").DurationOrElse(time.Minute)
+	maxAttempts := config.Get("webhooks.max_attempts").IntOrElse(8)
+	backoff := config.Get("webhooks.backoff").DurationOrElse(30 * time.Second)
+	maxBackoff := config.Get("webhooks.max_backoff").DurationOrElse(time.Hour)
+	disableAfter := config.Get("webhooks.disable_after").IntOrElse(20)
+	allowedNetworks := config.Get("webhooks.allowed_networks").SliceOfStringOrElse([]string{})
+	deniedNetworks := config.Get("webhooks.denied_networks").SliceOfStringOrElse(DefaultDeniedWebhookNetworks)
+
+	logger.Info("webhooks.timeout -> ", timeout)
+	logger.Info("webhooks.poll_interval -> ", pollInterval)
+	logger.Info("webhooks.batch_size -> ", batchSize)
+	logger.Info("webhooks.lease -> ", lease)
+	logger.Info("webhooks.max_attempts -> ", maxAttempts)
+	logger.Info("webhooks.backoff -> ", backoff)
+	logger.Info("webhooks.max_backoff -> ", maxBackoff)
+	logger.Info("webhooks.disable_after -> ", disableAfter)
+	logger.Info("webhooks.allowed_networks -> ", allowedNetworks)
+	logger.Info("webhooks.denied_networks -> ", deniedNetworks)
+
+	transport, e := NewWebhookTransport(allowedNetworks, deniedNetworks, timeout)
+	if e != nil {
+		return nil, fmt.Errorf("invalid webhooks networks: %v", e)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WebhookService{
+		logger:                    logger,
+		webhookRepository:         models.NewWebhookRepository(logger, db),
+		webhookDeliveryRepository: models.NewWebhookDeliveryRepository(logger, db),
+		natsClient:                natsClient,
+		outboxRelay:               outboxRelay,
+		httpClient:                &http.Client{Transport: transport, Timeout: timeout},
+		pollInterval:              pollInterval,
+		batchSize:                 batchSize,
+		lease:                     lease,
+		maxAttempts:               maxAttempts,
+		backoff:                   backoff,
+		maxBackoff:                maxBackoff,
+		disableAfter:              disableAfter,
+		ctx:                       ctx,
+		cancel:                    cancel,
+		authorizer:                authorizer,
+		catalogue:                 catalogue,
+		stop:                      make(chan struct{}),
+	}, nil
+}
+
+// Start starts the subscriptions and the background sender.
+func (s *WebhookService) Start() error {
+	createWebhookSubscription, e := s.natsClient.QueueSubscribe("kiosk.webhooks.create",
+		"kiosk.webhooks.create_group", s.authorizer.identified(s.create))
+	if e != nil {
+		return e
+	}
+
+	loadWebhookSubscription, e := s.natsClient.QueueSubscribe("kiosk.webhooks.load",
+		"kiosk.webhooks.load_group", s.authorizer.identified(s.load))
+	if e != nil {
+		return e
+	}
+
+	updateWebhookSubscription, e := s.natsClient.QueueSubscribe("kiosk.webhooks.update",
+		"kiosk.webhooks.update_group", s.authorizer.identified(s.update))
+	if e != nil {
+		return e
+	}
+
+	deleteWebhookSubscription, e := s.natsClient.QueueSubscribe("kiosk.webhooks.delete",
+		"kiosk.webhooks.delete_group", s.authorizer.identified(s.delete))
+	if e != nil {
+		return e
+	}
+
+	filterWebhooksSubscription, e := s.natsClient.QueueSubscribe("kiosk.webhooks.filter",
+		"kiosk.webhooks.filter_group", s.authorizer.identified(s.filter))
+	if e != nil {
+		return e
+	}
+
+	filterWebhookDeliveriesSubscription, e := s.natsClient.QueueSubscribe("kiosk.webhooks.deliveries",
+		"kiosk.webhooks.deliveries_group", s.authorizer.identified(s.deliveries))
+	if e != nil {
+		return e
+	}
+
+	redeliverWebhookSubscription, e := s.natsClient.QueueSubscribe("kiosk.webhooks.redeliver",
+		"kiosk.webhooks.redeliver_group", s.authorizer.identified(s.redeliver))
+	if e != nil {
+		return e
+	}
+
+	s.outboxRelay.Subscribe(s.enqueue)
+
+	go s.await(createWebhookSubscription, loadWebhookSubscription, updateWebhookSubscription,
+		deleteWebhookSubscription, filterWebhooksSubscription, filterWebhookDeliveriesSubscription,
+		redeliverWebhookSubscription)
+
+	return nil
+}
+
+func (s *WebhookService) await(ss ...*nc.Subscription) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.logger.Debug("WebhookService: received stop signal!")
+
+			for _, s := range ss {
+				_ = s.Unsubscribe()
+			}
+
+			return
+		case <-ticker.C:
+			s.deliver()
+		}
+	}
+}
+
+func (s *WebhookService) create(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createWebhookRequest := &data.CreateWebhookRequest{}
+	if e := json.Unmarshal(msg.Data, createWebhookRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := createWebhookRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.webhooks.create", createWebhookRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.webhookRepository.Insert(ctx, *createWebhookRequest.AsWebhook()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *WebhookService) load(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	w, e := s.webhookRepository.LoadByID(ctx, id.ID)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.webhooks.load", w.Issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	webhookResponse := &data.WebhookResponse{}
+	webhookResponse.LoadFromWebhook(w)
+	s.reply(msg, webhookResponse)
+}
+
+func (s *WebhookService) update(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updateWebhookRequest := &data.UpdateWebhookRequest{}
+	if e := json.Unmarshal(msg.Data, updateWebhookRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := updateWebhookRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	issuer := s.issuer(ctx, updateWebhookRequest.ID)
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.webhooks.update", issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuer(identityOf(msg), "kiosk.webhooks.update", updateWebhookRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.webhookRepository.Update(ctx, updateWebhookRequest.AsWebhook()); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *WebhookService) delete(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.webhooks.delete", s.issuer(ctx, id.ID)); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.webhookRepository.DeleteByID(ctx, id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+func (s *WebhookService) filter(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterWebhooksRequest := &data.FilterWebhooksRequest{}
+	if e := json.Unmarshal(msg.Data, filterWebhooksRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterWebhooksRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	e := s.authorizer.AuthorizeIssuerFilter(identityOf(msg), "kiosk.webhooks.filter", &filterWebhooksRequest.Issuer)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ws, hasNextPage, e := s.webhookRepository.Filter(ctx, filterWebhooksRequest.Issuer,
+		filterWebhooksRequest.PageNumber, filterWebhooksRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterWebhooksResponse := &data.FilterWebhooksResponse{}
+	filterWebhooksResponse.LoadFromWebhooks(ws, hasNextPage)
+	s.reply(msg, filterWebhooksResponse)
+}
+
+func (s *WebhookService) deliveries(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filterWebhookDeliveriesRequest := &data.FilterWebhookDeliveriesRequest{}
+	if e := json.Unmarshal(msg.Data, filterWebhookDeliveriesRequest); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	if e := filterWebhookDeliveriesRequest.Validate(); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	issuer := s.issuer(ctx, filterWebhookDeliveriesRequest.WebhookID)
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.webhooks.deliveries", issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	ds, hasNextPage, e := s.webhookDeliveryRepository.Filter(ctx, filterWebhookDeliveriesRequest.WebhookID,
+		filterWebhookDeliveriesRequest.Status, filterWebhookDeliveriesRequest.PageNumber,
+		filterWebhookDeliveriesRequest.PageSize)
+	if e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	filterWebhookDeliveriesResponse := &data.FilterWebhookDeliveriesResponse{}
+	filterWebhookDeliveriesResponse.LoadFromWebhookDeliveries(ds, hasNextPage)
+	s.reply(msg, filterWebhookDeliveriesResponse)
+}
+
+func (s *WebhookService) redeliver(msg *nc.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := &data.ID{}
+	if e := json.Unmarshal(msg.Data, id); e != nil {
+		s.reply(msg, errors.InvalidRequestBody())
+		return
+	}
+
+	issuer := func() (string, *errors.Type) {
+		d, e := s.webhookDeliveryRepository.LoadByID(ctx, id.ID)
+		if e != nil {
+			return "", e
+		}
+
+		return s.issuer(ctx, d.WebhookID)()
+	}
+	if e := s.authorizer.authorizeIssuer(msg, "kiosk.webhooks.redeliver", issuer); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	if e := s.webhookDeliveryRepository.Redeliver(ctx, id.ID); e != nil {
+		s.reply(msg, e)
+		return
+	}
+
+	s.replyNoContent(msg)
+}
+
+// enqueue stores a delivery of the event for every webhook subscribed to it, with the ticket and the comment of the
+// event as they were right after it, or right before it for deletions.
+func (s *WebhookService) enqueue(message *models.OutboxMessage) *errors.Type {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	event, ok := webhookEvents[message.Type]
+	if !ok {
+		return nil
+	}
+
+	// Without the ticket there is no telling which webhooks are subscribed to the event.
+	if message.Ticket == nil {
+		s.logger.Warnf("WebhookService: dropped %v of ticket %v with no ticket", event, message.TicketID)
+		return nil
+	}
+
+	payload := &data.WebhookPayload{
+		Event:      event,
+		OccurredAt: message.CreatedAt.UTC().Format(time.RFC3339Nano),
+		TicketID:   message.TicketID,
+		Issuer:     message.Ticket.Issuer,
+		Ticket:     &data.TicketResponse{},
+	}
+	payload.Ticket.LoadFromTicket(message.Ticket)
+
+	if message.Comment != nil {
+		payload.CommentID = message.Comment.ID
+		payload.Comment = &data.CommentResponse{}
+		payload.Comment.LoadFromComment(message.Comment)
+	}
+
+	webhooks, e := s.webhookRepository.LoadEnabledByIssuer(ctx, payload.Issuer)
+	if e != nil {
+		return e
+	}
+
+	body, _ := json.Marshal(payload)
+	for _, w := range webhooks {
+		if !w.Subscribes(event, payload.Issuer) {
+			continue
+		}
+
+		delivery := models.WebhookDelivery{WebhookID: w.ID, EventID: message.EventID, Event: event,
+			TicketID: payload.TicketID, Payload: string(body)}
+		if e := s.webhookDeliveryRepository.Insert(ctx, delivery); e != nil {
+			if e.HTTPStatusCode != http.StatusPreconditionFailed {
+				return e
+			}
+
+			s.logger.Warnf("WebhookService: dropped %v of ticket %v for deleted webhook %v", event,
+				payload.TicketID, w.ID)
+		}
+	}
+
+	return nil
+}
+
+// deliver posts the due deliveries of the enabled webhooks. Failed deliveries are retried with exponential backoff
+// until they run out of attempts, and every failure counts towards disabling the webhook.
+func (s *WebhookService) deliver() {
+	deliveries, e := s.webhookDeliveryRepository.Claim(s.ctx, s.batchSize, s.lease)
+	if e != nil {
+		return
+	}
+
+	webhooks := make(map[int64]*models.Webhook)
+	for _, d := range deliveries {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+
+		w, ok := webhooks[d.WebhookID]
+		if !ok {
+			if w, e = s.webhookRepository.LoadByID(ctx, d.WebhookID); e != nil {
+				s.logger.Warnf("WebhookService: skipped delivery %v of webhook %v: %v", d.ID, d.WebhookID,
+					e.Errors[0].Code)
+				cancel()
+				continue
+			}
+
+			webhooks[d.WebhookID] = w
+		}
+		cancel()
+
+		// The webhook may have been disabled by an earlier delivery of the same batch.
+		if !w.Enabled {
+			continue
+		}
+
+		status, e := s.post(w, d)
+
+		ctx, cancel = context.WithTimeout(s.ctx, 5*time.Second)
+		if e == nil {
+			_ = s.webhookDeliveryRepository.MarkDelivered(ctx, d.ID, status)
+			_ = s.webhookRepository.RecordSuccess(ctx, w.ID)
+			cancel()
+			continue
+		}
+
+		if d.Attempts >= s.maxAttempts {
+			s.logger.Warnf("WebhookService: gave up on delivery %v: %v", d.ID, e.Error())
+			_ = s.webhookDeliveryRepository.MarkFailed(ctx, d.ID, status, e.Error())
+		} else {
+			_ = s.webhookDeliveryRepository.Retry(ctx, d.ID, status, e.Error(), s.nextBackoff(d.Attempts))
+		}
+
+		if disabled, _ := s.webhookRepository.RecordFailure(ctx, w.ID, s.disableAfter); disabled {
+			s.logger.Warnf("WebhookService: disabled webhook %v after %v consecutive failures", w.ID,
+				s.disableAfter)
+			w.Enabled = false
+		}
+		cancel()
+	}
+}
+
+// post posts the payload of the delivery to the webhook and returns back the response status, if any. Anything but
+// a 2xx response is a failure.
+func (s *WebhookService) post(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, e := http.NewRequestWithContext(s.ctx, http.MethodPost, webhook.URL,
+		bytes.NewReader([]byte(delivery.Payload)))
+	if e != nil {
+		return 0, e
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "kiosk-webhooks")
+	request.Header.Set("X-Kiosk-Event", string(delivery.Event))
+	request.Header.Set("X-Kiosk-Delivery", strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set("X-Kiosk-Timestamp", timestamp)
+	request.Header.Set("X-Kiosk-Signature", signWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	response, e := s.httpClient.Do(request)
+	if e != nil {
+		return 0, e
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status code %v", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+// nextBackoff returns back the delay before the next attempt, doubling after every failed attempt up to the maximum.
+func (s *WebhookService) nextBackoff(attempts int) time.Duration {
+	backoff := s.backoff
+	for i := 1; i < attempts && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > s.maxBackoff {
+		backoff = s.maxBackoff
+	}
+
+	return backoff
+}
+
+func (s *WebhookService) issuer(ctx context.Context, id int64) func() (string, *errors.Type) {
+	return func() (string, *errors.Type) {
+		w, e := s.webhookRepository.LoadByID(ctx, id)
+		if e != nil {
+			return "", e
+		}
+
+		return w.Issuer, nil
+	}
+}
+
+func (s *WebhookService) reply(msg *nc.Msg, t interface{}) {
+	reply, _ := json.Marshal(localized(s.catalogue, msg, t))
+	_ = msg.Respond(reply)
+}
+
+func (s *WebhookService) replyNoContent(msg *nc.Msg) {
+	_ = msg.Respond([]byte(""))
+}
+
+// Stop stops the component, its subscriptions and the background sender.
+func (s *WebhookService) Stop() {
+	s.cancel()
+	s.stop <- struct{}{}
+}
+
+// signWebhookPayload returns back the signature of a webhook payload, i.e. "sha256=" followed by the hex encoded
+// HMAC-SHA256 of "<timestamp>.<payload>" keyed with the secret.
+func signWebhookPayload(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "." + payload))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEvents maps the outbox messages to the events of the webhooks. Status changes are delivered as updates.
+var webhookEvents = map[models.OutboxMessageType]models.EventType{
+	models.OutboxMessageTypeTicketCreated:  models.EventTypeTicketCreated,
+	models.OutboxMessageTypeTicketUpdated:  models.EventTypeTicketUpdated,
+	models.OutboxMessageTypeTicketDeleted:  models.EventTypeTicketDeleted,
+	models.OutboxMessageTypeCommentCreated: models.EventTypeCommentCreated,
+	models.OutboxMessageTypeCommentUpdated: models.EventTypeCommentUpdated,
+	models.OutboxMessageTypeCommentDeleted: models.EventTypeCommentDeleted,
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// DefaultDeniedWebhookNetworks are the networks webhooks can not reach by default, i.e. the unspecified, loopback,
+// private, shared and link-local addresses, so webhooks can not be used to reach the internal services.
+var DefaultDeniedWebhookNetworks = []string{
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10",
+}
+
+// NewWebhookTransport returns back a transport that refuses to connect to the denied networks, unless they are allowed.
+// Addresses are checked when dialing, so neither resolving the host again nor redirects get around it.
+func NewWebhookTransport(allowedNetworks, deniedNetworks []string, timeout time.Duration) (*http.Transport, error) {
+	allowed, e := parseNetworks(allowedNetworks)
+	if e != nil {
+		return nil, e
+	}
+
+	denied, e := parseNetworks(deniedNetworks)
+	if e != nil {
+		return nil, e
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, e := net.SplitHostPort(address)
+			if e != nil {
+				return e
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || (containsIP(denied, ip) && !containsIP(allowed, ip)) || ip.IsMulticast() {
+				return fmt.Errorf("webhooks can not connect to %v", host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport, nil
+}
+
+func parseNetworks(networks []string) ([]*net.IPNet, error) {
+	parsed := make([]*net.IPNet, 0, len(networks))
+	for _, n := range networks {
+		_, network, e := net.ParseCIDR(n)
+		if e != nil {
+			return nil, fmt.Errorf("invalid network %v: %v", n, e)
+		}
+
+		parsed = append(parsed, network)
+	}
+
+	return parsed, nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
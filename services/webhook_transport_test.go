@@ -0,0 +1,63 @@
+package services_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/jibitters/kiosk/services"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebhookTransport", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	post := func(transport *http.Transport) error {
+		response, e := (&http.Client{Transport: transport}).Post(server.URL, "application/json", nil)
+		if e == nil {
+			_ = response.Body.Close()
+		}
+
+		return e
+	}
+
+	It("Should refuse to connect to the denied networks", func() {
+		transport, e := services.NewWebhookTransport(nil, services.DefaultDeniedWebhookNetworks, time.Second)
+		Ω(e).Should(BeNil())
+
+		e = post(transport)
+		Ω(e).ShouldNot(BeNil())
+		Ω(e.Error()).Should(ContainSubstring("webhooks can not connect to 127.0.0.1"))
+	})
+
+	It("Should connect to the denied networks that are allowed as well", func() {
+		transport, e := services.NewWebhookTransport([]string{"127.0.0.0/8"}, services.DefaultDeniedWebhookNetworks,
+			time.Second)
+		Ω(e).Should(BeNil())
+
+		Ω(post(transport)).Should(Succeed())
+	})
+
+	It("Should connect to the networks that are not denied", func() {
+		transport, e := services.NewWebhookTransport(nil, []string{"10.0.0.0/8"}, time.Second)
+		Ω(e).Should(BeNil())
+
+		Ω(post(transport)).Should(Succeed())
+	})
+
+	It("Should return an error for invalid networks", func() {
+		_, e := services.NewWebhookTransport([]string{"localhost"}, nil, time.Second)
+		Ω(e).ShouldNot(BeNil())
+	})
+})
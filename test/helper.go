@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/jibitters/kiosk/db/postgres"
@@ -21,17 +20,19 @@ func ConnectToDatabase(host string, port int) (*pgxpool.Pool, error) {
 		return nil, e
 	}
 
-	file, e := ioutil.TempFile(directory, "1_*.up.sql")
-	if e != nil {
-		return nil, e
-	}
+	for i, migration := range migrations {
+		file, e := ioutil.TempFile(directory, fmt.Sprintf("%v_*.up.sql", i+1))
+		if e != nil {
+			return nil, e
+		}
 
-	defer func() { _ = file.Close() }()
+		_, _ = file.WriteString(migration)
+		_ = file.Close()
+	}
 
-	_, _ = file.WriteString(first)
 	cs := fmt.Sprintf("postgres://user:password@%v:%v/kiosk?sslmode=disable", host, port)
 	_ = os.Setenv("DB_POSTGRES_CONNECTION_STRING", cs)
-	_ = os.Setenv("DB_POSTGRES_MIGRATION_DIRECTORY", "file://"+filepath.Dir(file.Name()))
+	_ = os.Setenv("DB_POSTGRES_MIGRATION_DIRECTORY", "file://"+directory)
 
 	if e := postgres.Migrate(zap.S(), config); e != nil {
 		return nil, e
@@ -45,6 +46,9 @@ func ConnectToDatabase(host string, port int) (*pgxpool.Pool, error) {
 	return db, nil
 }
 
+var migrations = []string{first, second, third, fourth, fifth, sixth, seventh, eighth, ninth, tenth, eleventh,
+	twelfth, thirteenth, fourteenth}
+
 var first = `
 -- Tickets table definition.
 CREATE TABLE tickets
@@ -79,3 +83,337 @@ CREATE TABLE comments
 
 CREATE INDEX comments_ticket_id_created_at ON comments (ticket_id, created_at);
 `
+
+var second = `
+-- Exports table definition. Exports keep the token of their last claim, so a node that lost the lease of a job can no
+-- longer change it.
+CREATE TABLE exports
+(
+    id               BIGSERIAL    NOT NULL,
+    format           VARCHAR(25)  NOT NULL,
+    issuer           VARCHAR(50)  NOT NULL,
+    owner            VARCHAR(50)  NOT NULL,
+    importance_level VARCHAR(25)  NOT NULL,
+    status           VARCHAR(25)  NOT NULL,
+    from_date        TIMESTAMP    NOT NULL,
+    to_date          TIMESTAMP    NOT NULL,
+    job_status       VARCHAR(25)  NOT NULL,
+    file_name        VARCHAR(255) NOT NULL,
+    exported_tickets BIGINT       NOT NULL,
+    last_ticket_id   BIGINT       NOT NULL,
+    file_offset      BIGINT       NOT NULL,
+    failure_reason   TEXT,
+    locked_until     TIMESTAMP,
+    locked_by        VARCHAR(36),
+    created_at       TIMESTAMP    NOT NULL,
+    modified_at      TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX exports_job_status_locked_until ON exports (job_status, locked_until);
+`
+
+var third = `
+-- Imports table definition. Imports are claimed by kiosk nodes like exports are, so imports of a stopped node are
+-- resumed by the others.
+CREATE TABLE imports
+(
+    id            BIGSERIAL    NOT NULL,
+    source        VARCHAR(50)  NOT NULL,
+    format        VARCHAR(25)  NOT NULL,
+    file_name     VARCHAR(255) NOT NULL,
+    status        VARCHAR(25)  NOT NULL,
+    total_rows    BIGINT       NOT NULL,
+    imported_rows BIGINT       NOT NULL,
+    skipped_rows  BIGINT       NOT NULL,
+    rejected_rows BIGINT       NOT NULL,
+    rejections    TEXT,
+    locked_until  TIMESTAMP,
+    locked_by     VARCHAR(36),
+    created_at    TIMESTAMP    NOT NULL,
+    modified_at   TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+-- Import mappings table definition, maps identifiers of external systems to ticket identifiers.
+CREATE TABLE import_mappings
+(
+    source      VARCHAR(50)  NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    ticket_id   BIGINT       NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    created_at  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (source, external_id)
+);
+`
+
+var fourth = `
+-- Canned responses table definition. Canned responses with empty issuer are global.
+CREATE TABLE canned_responses
+(
+    id          BIGSERIAL    NOT NULL,
+    issuer      VARCHAR(50)  NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    body        TEXT         NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (issuer, name)
+);
+`
+
+var fifth = `
+-- Macros table definition. Macros with empty issuer are global.
+CREATE TABLE macros
+(
+    id          BIGSERIAL    NOT NULL,
+    issuer      VARCHAR(50)  NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    actions     TEXT         NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (issuer, name)
+);
+`
+
+var sixth = `
+-- Rules table definition.
+CREATE TABLE rules
+(
+    id          BIGSERIAL    NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    event       VARCHAR(25)  NOT NULL,
+    conditions  TEXT         NOT NULL,
+    actions     TEXT         NOT NULL,
+    enabled     BOOLEAN      NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (name)
+);
+
+CREATE INDEX rules_event_enabled ON rules (event, enabled);
+
+-- Rule executions table definition.
+CREATE TABLE rule_executions
+(
+    id          BIGSERIAL   NOT NULL,
+    rule_id     BIGINT      NOT NULL REFERENCES rules ON DELETE CASCADE,
+    ticket_id   BIGINT      NOT NULL,
+    event       VARCHAR(25) NOT NULL,
+    status      VARCHAR(25) NOT NULL,
+    details     TEXT        NOT NULL,
+    created_at  TIMESTAMP   NOT NULL,
+    modified_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX rule_executions_rule_id_id ON rule_executions (rule_id, id);
+`
+
+var seventh = `
+-- Escalation policies table definition. Policies with empty issuer apply to tickets of all issuers.
+CREATE TABLE escalation_policies
+(
+    id          BIGSERIAL    NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    issuer      VARCHAR(50)  NOT NULL,
+    statuses    TEXT         NOT NULL,
+    steps       TEXT         NOT NULL,
+    enabled     BOOLEAN      NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (name)
+);
+
+-- Escalations table definition. Each step of a policy is taken at most once per idle period of a ticket, which starts
+-- at the last modification of the ticket.
+CREATE TABLE escalations
+(
+    id          BIGSERIAL   NOT NULL,
+    ticket_id   BIGINT      NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    policy_id   BIGINT      NOT NULL REFERENCES escalation_policies ON DELETE CASCADE,
+    step        INT         NOT NULL,
+    action      VARCHAR(25) NOT NULL,
+    details     TEXT        NOT NULL,
+    idle_since  TIMESTAMP   NOT NULL,
+    created_at  TIMESTAMP   NOT NULL,
+    modified_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (ticket_id, policy_id, step, idle_since)
+);
+`
+
+var eighth = `
+-- Satisfactions table definition. Issuer and assignee are kept as they were when the ticket was rated, for reports.
+CREATE TABLE satisfactions
+(
+    id          BIGSERIAL   NOT NULL,
+    ticket_id   BIGINT      NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    issuer      VARCHAR(50) NOT NULL,
+    assignee    VARCHAR(50) NOT NULL,
+    owner       VARCHAR(50) NOT NULL,
+    rating      SMALLINT    NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment     TEXT        NOT NULL,
+    created_at  TIMESTAMP   NOT NULL,
+    modified_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (ticket_id)
+);
+
+CREATE INDEX satisfactions_issuer_created_at ON satisfactions (issuer, created_at);
+`
+
+var ninth = `
+-- Emails table definition. Keeps the message identifiers of inbound emails to thread replies into their tickets and to
+-- ingest every email only once.
+CREATE TABLE emails
+(
+    id          BIGSERIAL    NOT NULL,
+    message_id  VARCHAR(255) NOT NULL,
+    ticket_id   BIGINT       NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    comment_id  BIGINT REFERENCES comments ON DELETE CASCADE,
+    sender      VARCHAR(50)  NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    modified_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (message_id)
+);
+
+CREATE INDEX emails_ticket_id ON emails (ticket_id);
+`
+
+var tenth = `
+-- Notifications table definition. It is the outbox of the outbound emails, every notification is rendered once and
+-- delivered with retries until it is sent or runs out of attempts.
+CREATE TABLE notifications
+(
+    id              BIGSERIAL    NOT NULL,
+    key             VARCHAR(255) NOT NULL,
+    ticket_id       BIGINT       NOT NULL REFERENCES tickets ON DELETE CASCADE,
+    event           VARCHAR(50)  NOT NULL,
+    recipient       VARCHAR(255) NOT NULL,
+    locale          VARCHAR(10)  NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    text_body       TEXT         NOT NULL,
+    html_body       TEXT         NOT NULL,
+    status          VARCHAR(25)  NOT NULL,
+    attempts        INT          NOT NULL,
+    last_error      TEXT         NOT NULL,
+    next_attempt_at TIMESTAMP    NOT NULL,
+    sent_at         TIMESTAMP,
+    created_at      TIMESTAMP    NOT NULL,
+    modified_at     TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (key)
+);
+
+CREATE INDEX notifications_status_next_attempt_at ON notifications (status, next_attempt_at);
+CREATE INDEX notifications_ticket_id ON notifications (ticket_id);
+`
+
+var eleventh = `
+-- Webhooks table definition. Events is a JSON array of event types and an empty issuer subscribes to the tickets of
+-- all issuers.
+CREATE TABLE webhooks
+(
+    id                   BIGSERIAL     NOT NULL,
+    url                  VARCHAR(2048) NOT NULL,
+    issuer               VARCHAR(50)   NOT NULL,
+    events               TEXT          NOT NULL,
+    secret               VARCHAR(255)  NOT NULL,
+    enabled              BOOLEAN       NOT NULL,
+    consecutive_failures INT           NOT NULL,
+    created_at           TIMESTAMP     NOT NULL,
+    modified_at          TIMESTAMP     NOT NULL,
+    PRIMARY KEY (id)
+);
+
+-- Webhook deliveries table definition. The payload is kept as it was when the event happened, so redeliveries send
+-- exactly the same content, and the id of the outbox event is kept, so an event relayed more than once is delivered
+-- once per webhook.
+CREATE TABLE webhook_deliveries
+(
+    id              BIGSERIAL   NOT NULL,
+    webhook_id      BIGINT      NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+    event_id        VARCHAR(36) NOT NULL,
+    event           VARCHAR(50) NOT NULL,
+    ticket_id       BIGINT      NOT NULL,
+    payload         TEXT        NOT NULL,
+    status          VARCHAR(25) NOT NULL,
+    attempts        INT         NOT NULL,
+    response_status INT         NOT NULL,
+    last_error      TEXT        NOT NULL,
+    next_attempt_at TIMESTAMP   NOT NULL,
+    delivered_at    TIMESTAMP,
+    created_at      TIMESTAMP   NOT NULL,
+    modified_at     TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX webhook_deliveries_status_next_attempt_at ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE UNIQUE INDEX webhook_deliveries_webhook_id_event_id ON webhook_deliveries (webhook_id, event_id);
+`
+
+var twelfth = `
+-- Outbox table definition. Domain events are written in the same transaction as the changes they announce and are
+-- relayed to nats in the order of their ids. Ticket and comment are JSON snapshots of the resources at the time.
+CREATE TABLE outbox
+(
+    id              BIGSERIAL    NOT NULL,
+    event_id        VARCHAR(36)  NOT NULL,
+    type            VARCHAR(50)  NOT NULL,
+    ticket_id       BIGINT       NOT NULL,
+    actor_type      VARCHAR(25)  NOT NULL,
+    actor_id        VARCHAR(255) NOT NULL,
+    previous_status VARCHAR(25)  NOT NULL,
+    ticket          TEXT,
+    comment         TEXT,
+    created_at      TIMESTAMP    NOT NULL,
+    published_at    TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX outbox_pending ON outbox (id) WHERE published_at IS NULL;
+CREATE INDEX outbox_published_at ON outbox (published_at);
+`
+
+var thirteenth = `
+-- API keys table definition. Only the SHA-256 hash of the keys is stored, they are looked up by their public prefix.
+-- Operations is a JSON array of the subjects the key is allowed to request and a NULL expires_at never expires.
+CREATE TABLE api_keys
+(
+    id           BIGSERIAL    NOT NULL,
+    issuer       VARCHAR(50)  NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL,
+    hash         VARCHAR(64)  NOT NULL,
+    operations   TEXT         NOT NULL,
+    expires_at   TIMESTAMP,
+    revoked_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at   TIMESTAMP    NOT NULL,
+    modified_at  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX api_keys_prefix ON api_keys (prefix);
+CREATE INDEX api_keys_issuer_name ON api_keys (issuer, name);
+`
+
+var fourteenth = `
+-- Rate limit buckets table definition. Each row is the token bucket of a rate limit for a caller, shared by the kiosk
+-- nodes, holding the tokens left at updated_at and whether the last request took one.
+CREATE TABLE rate_limit_buckets
+(
+    key        TEXT             NOT NULL,
+    tokens     DOUBLE PRECISION NOT NULL,
+    allowed    BOOLEAN          NOT NULL,
+    updated_at TIMESTAMP        NOT NULL,
+    PRIMARY KEY (key)
+);
+
+CREATE INDEX rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
+`
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// APIKeyResponse model definition. The key is only sent back when it is created, since only its hash is stored.
+type APIKeyResponse struct {
+	ID         int64    `json:"ID"`
+	Key        string   `json:"key,omitempty"`
+	Issuer     string   `json:"issuer"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Operations []string `json:"operations"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	RevokedAt  string   `json:"revokedAt,omitempty"`
+	LastUsedAt string   `json:"lastUsedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+	ModifiedAt string   `json:"modifiedAt"`
+}
+
+// LoadFromAPIKey populates the fields of current model from provided API key.
+func (r *APIKeyResponse) LoadFromAPIKey(apiKey *models.APIKey) {
+	r.ID = apiKey.ID
+	r.Issuer = apiKey.Issuer
+	r.Name = apiKey.Name
+	r.Prefix = apiKey.Prefix
+	r.Operations = apiKey.Operations
+	if !apiKey.ExpiresAt.IsZero() {
+		r.ExpiresAt = apiKey.ExpiresAt.Format(time.RFC3339Nano)
+	}
+	if !apiKey.RevokedAt.IsZero() {
+		r.RevokedAt = apiKey.RevokedAt.Format(time.RFC3339Nano)
+	}
+	if !apiKey.LastUsedAt.IsZero() {
+		r.LastUsedAt = apiKey.LastUsedAt.Format(time.RFC3339Nano)
+	}
+	r.CreatedAt = apiKey.CreatedAt.Format(time.RFC3339Nano)
+	r.ModifiedAt = apiKey.ModifiedAt.Format(time.RFC3339Nano)
+}
+
+// FilterAPIKeysResponse model definition.
+type FilterAPIKeysResponse struct {
+	APIKeys     []*APIKeyResponse `json:"apiKeys,omitempty"`
+	HasNextPage bool              `json:"hasNextPage"`
+}
+
+// LoadFromAPIKeys populates the fields of current model from provided API keys.
+func (r *FilterAPIKeysResponse) LoadFromAPIKeys(apiKeys []*models.APIKey, hasNextPage bool) {
+	for _, k := range apiKeys {
+		apiKeyResponse := &APIKeyResponse{}
+		apiKeyResponse.LoadFromAPIKey(k)
+		r.APIKeys = append(r.APIKeys, apiKeyResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
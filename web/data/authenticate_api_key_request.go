@@ -0,0 +1,16 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// AuthenticateAPIKeyRequest model definition.
+type AuthenticateAPIKeyRequest struct {
+	Key string `json:"key"`
+}
+
+// Validate validates the request.
+func (r *AuthenticateAPIKeyRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Length("key", r.Key, 255)
+
+	return v.Errors()
+}
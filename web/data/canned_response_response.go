@@ -0,0 +1,46 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// CannedResponseResponse model definition.
+type CannedResponseResponse struct {
+	ID         int64  `json:"ID"`
+	Issuer     string `json:"issuer,omitempty"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	CreatedAt  string `json:"createdAt"`
+	ModifiedAt string `json:"modifiedAt"`
+}
+
+// LoadFromCannedResponse populates the fields of current model from provided canned response.
+func (r *CannedResponseResponse) LoadFromCannedResponse(cannedResponse *models.CannedResponse) {
+	r.ID = cannedResponse.ID
+	r.Issuer = cannedResponse.Issuer
+	r.Name = cannedResponse.Name
+	r.Body = cannedResponse.Body
+	r.CreatedAt = cannedResponse.CreatedAt.Format(time.RFC3339Nano)
+	r.ModifiedAt = cannedResponse.ModifiedAt.Format(time.RFC3339Nano)
+}
+
+// FilterCannedResponsesResponse model definition.
+type FilterCannedResponsesResponse struct {
+	CannedResponses []*CannedResponseResponse `json:"cannedResponses,omitempty"`
+	HasNextPage     bool                      `json:"hasNextPage"`
+}
+
+// LoadFromCannedResponses populates the fields of current model from provided canned responses.
+func (r *FilterCannedResponsesResponse) LoadFromCannedResponses(cannedResponses []*models.CannedResponse,
+	hasNextPage bool) {
+
+	for _, c := range cannedResponses {
+		cannedResponseResponse := &CannedResponseResponse{}
+		cannedResponseResponse.LoadFromCannedResponse(c)
+		r.CannedResponses = append(r.CannedResponses, cannedResponseResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
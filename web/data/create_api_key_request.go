@@ -0,0 +1,73 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// APIKeyOperations are the subjects that the API keys can be allowed to request.
+var APIKeyOperations = []string{
+	"kiosk.tickets.create",
+	"kiosk.tickets.load",
+	"kiosk.tickets.update",
+	"kiosk.tickets.delete",
+	"kiosk.tickets.filter",
+	"kiosk.comments.create",
+	"kiosk.comments.create_from_template",
+	"kiosk.comments.load",
+	"kiosk.comments.update",
+	"kiosk.comments.delete",
+}
+
+// CreateAPIKeyRequest model definition.
+type CreateAPIKeyRequest struct {
+	Issuer     string   `json:"issuer"`
+	Name       string   `json:"name"`
+	Operations []string `json:"operations"`
+	ExpiresAt  string   `json:"expiresAt"`
+
+	expiresAt time.Time
+}
+
+// Validate validates the request.
+func (r *CreateAPIKeyRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Length("issuer", r.Issuer, 50)
+	v.Length("name", r.Name, 100)
+
+	if v.Check(len(r.Operations) > 0, "operations", "is_required") {
+		for i, operation := range r.Operations {
+			v.OneOf(element("operations", i), operation, APIKeyOperations...)
+		}
+	}
+
+	r.expiresAt = parseExpiresAt(v, r.ExpiresAt)
+
+	return v.Errors()
+}
+
+// AsAPIKey converts this request model into API key model. Should be called after a successful validation.
+func (r *CreateAPIKeyRequest) AsAPIKey() *models.APIKey {
+	return &models.APIKey{
+		Issuer:     r.Issuer,
+		Name:       r.Name,
+		Operations: r.Operations,
+		ExpiresAt:  r.expiresAt,
+	}
+}
+
+// parseExpiresAt parses the optional expiry of the keys, which must be in future.
+func parseExpiresAt(v *Validator, expiresAt string) time.Time {
+	if expiresAt == "" {
+		return time.Time{}
+	}
+
+	t, valid := v.Time("expiresAt", expiresAt)
+	if valid && !v.Check(t.After(time.Now()), "expiresAt", "not_valid") {
+		return time.Time{}
+	}
+
+	return t
+}
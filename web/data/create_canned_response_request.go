@@ -0,0 +1,45 @@
+package data
+
+import (
+	"text/template"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateCannedResponseRequest model definition.
+type CreateCannedResponseRequest struct {
+	Issuer string `json:"issuer"`
+	Name   string `json:"name"`
+	Body   string `json:"body"`
+}
+
+// Validate validates the request.
+func (r *CreateCannedResponseRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.MaxLength("issuer", r.Issuer, 50)
+	v.Length("name", r.Name, 100)
+	validateCannedResponseBody(v, r.Name, r.Body)
+
+	return v.Errors()
+}
+
+// AsCannedResponse converts this request model into canned response model.
+func (r *CreateCannedResponseRequest) AsCannedResponse() *models.CannedResponse {
+	return &models.CannedResponse{
+		Issuer: r.Issuer,
+		Name:   r.Name,
+		Body:   r.Body,
+	}
+}
+
+// validateCannedResponseBody checks that the body is a valid template, whose parse error is the message of the error.
+func validateCannedResponseBody(v *Validator, name, body string) {
+	if !v.Length("body", body, 5000) {
+		return
+	}
+
+	if _, e := template.New(name).Parse(body); e != nil {
+		v.Add("body", "not_valid", e.Error(), nil)
+	}
+}
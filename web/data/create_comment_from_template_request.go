@@ -0,0 +1,54 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateCommentFromTemplateRequest model definition. When preview is true the rendered comment is only returned back
+// and nothing is persisted.
+type CreateCommentFromTemplateRequest struct {
+	TicketID         int64  `json:"ticketID"`
+	CannedResponseID int64  `json:"cannedResponseID"`
+	Owner            string `json:"owner"`
+	Metadata         string `json:"metadata"`
+	Preview          bool   `json:"preview"`
+}
+
+// Validate validates the request.
+func (r *CreateCommentFromTemplateRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ticketID", r.TicketID)
+	v.ID("cannedResponseID", r.CannedResponseID)
+	v.Length("owner", r.Owner, 50)
+
+	return v.Errors()
+}
+
+// AsComment converts this request model along with the rendered content into comment model.
+func (r *CreateCommentFromTemplateRequest) AsComment(content string) *models.Comment {
+	return &models.Comment{
+		TicketID: r.TicketID,
+		Owner:    r.Owner,
+		Content:  content,
+		Metadata: r.Metadata,
+	}
+}
+
+// RenderedCommentResponse model definition.
+type RenderedCommentResponse struct {
+	TicketID int64  `json:"ticketID"`
+	Owner    string `json:"owner"`
+	Content  string `json:"content"`
+	Metadata string `json:"metadata,omitempty"`
+	Preview  bool   `json:"preview"`
+}
+
+// LoadFromComment populates the fields of current model from provided comment.
+func (r *RenderedCommentResponse) LoadFromComment(comment *models.Comment, preview bool) {
+	r.TicketID = comment.TicketID
+	r.Owner = comment.Owner
+	r.Content = comment.Content
+	r.Metadata = comment.Metadata
+	r.Preview = preview
+}
@@ -15,27 +15,12 @@ type CreateCommentRequest struct {
 
 // Validate validates the request.
 func (r *CreateCommentRequest) Validate() *errors.Type {
-	if r.TicketID <= 0 {
-		return errors.InvalidArgument("ticketID.invalid", "")
-	}
-
-	if len(r.Owner) == 0 {
-		return errors.InvalidArgument("owner.is_required", "")
-	}
-
-	if len(r.Owner) > 50 {
-		return errors.InvalidArgument("owner.invalid_length", "")
-	}
-
-	if len(r.Content) == 0 {
-		return errors.InvalidArgument("content.is_required", "")
-	}
-
-	if len(r.Content) > 5000 {
-		return errors.InvalidArgument("content.invalid_length", "")
-	}
+	v := NewValidator()
+	v.ID("ticketID", r.TicketID)
+	v.Length("owner", r.Owner, 50)
+	v.Length("content", r.Content, 5000)
 
-	return nil
+	return v.Errors()
 }
 
 // AsComment converts this request model into comment model.
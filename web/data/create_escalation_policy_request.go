@@ -0,0 +1,82 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateEscalationPolicyRequest model definition.
+type CreateEscalationPolicyRequest struct {
+	Name     string                  `json:"name"`
+	Issuer   string                  `json:"issuer"`
+	Statuses []models.TicketStatus   `json:"statuses"`
+	Steps    []models.EscalationStep `json:"steps"`
+	Enabled  bool                    `json:"enabled"`
+}
+
+// Validate validates the request.
+func (r *CreateEscalationPolicyRequest) Validate() *errors.Type {
+	v := NewValidator()
+	validateEscalationPolicy(v, r.Name, r.Issuer, r.Statuses, r.Steps)
+
+	return v.Errors()
+}
+
+// AsEscalationPolicy converts this request model into escalation policy model.
+func (r *CreateEscalationPolicyRequest) AsEscalationPolicy() *models.EscalationPolicy {
+	return &models.EscalationPolicy{
+		Name:     r.Name,
+		Issuer:   r.Issuer,
+		Statuses: r.Statuses,
+		Steps:    r.Steps,
+		Enabled:  r.Enabled,
+	}
+}
+
+func validateEscalationPolicy(v *Validator, name, issuer string, statuses []models.TicketStatus,
+	steps []models.EscalationStep) {
+
+	v.Length("name", name, 100)
+	v.MaxLength("issuer", issuer, 50)
+
+	// Resolved and closed tickets are done with, so they never escalate.
+	if v.Check(len(statuses) > 0, "statuses", "is_required") {
+		for i, s := range statuses {
+			v.OneOf(element("statuses", i), string(s), string(models.TicketStatusNew),
+				string(models.TicketStatusReplied), string(models.TicketStatusBlocked))
+		}
+	}
+
+	if !v.Items("steps", len(steps), 10) {
+		return
+	}
+
+	var previous time.Duration
+	for i, s := range steps {
+		step := element("steps", i)
+
+		idle, e := time.ParseDuration(s.IdleTime)
+		if v.Check(e == nil && idle > 0, step+".idleTime", "not_valid") {
+			// Steps are taken one after another, so their idle times should increase.
+			v.Check(idle > previous, step+".idleTime", "not_increasing")
+			previous = idle
+		}
+
+		switch s.Action {
+		case models.EscalationActionRaiseImportanceLevel:
+		case models.EscalationActionReassign:
+			v.Length(step+".assignee", s.Assignee, 50)
+		case models.EscalationActionNotify:
+			if v.Required(step+".topic", s.Topic) {
+				v.Check(len(s.Topic) <= 255 && !strings.HasPrefix(s.Topic, "kiosk."), step+".topic", "not_valid")
+			}
+
+			v.MaxLength(step+".supervisor", s.Supervisor, 50)
+		default:
+			v.Add(step+".action", "not_valid", "", nil)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateExportRequest model definition.
+type CreateExportRequest struct {
+	Format          models.ExportFormat          `json:"format"`
+	Issuer          string                       `json:"issuer"`
+	Owner           string                       `json:"owner"`
+	ImportanceLevel models.TicketImportanceLevel `json:"importanceLevel"`
+	Status          models.TicketStatus          `json:"status"`
+	FromDate        string                       `json:"fromDate"`
+	ToDate          string                       `json:"toDate"`
+
+	fromDate time.Time
+	toDate   time.Time
+}
+
+// Validate validates the request.
+func (r *CreateExportRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.OneOf("format", string(r.Format), string(models.ExportFormatCSV), string(models.ExportFormatNDJSON))
+	v.MaxLength("issuer", r.Issuer, 50)
+	v.MaxLength("owner", r.Owner, 50)
+
+	if r.ImportanceLevel != "" {
+		v.ImportanceLevel("importanceLevel", r.ImportanceLevel)
+	}
+
+	if r.Status != "" {
+		v.Status("status", r.Status)
+	}
+
+	if r.FromDate == "" {
+		r.FromDate = "2000-01-01T00:00:00Z"
+	}
+
+	if r.ToDate == "" {
+		r.ToDate = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	r.fromDate, _ = v.Time("fromDate", r.FromDate)
+	r.toDate, _ = v.Time("toDate", r.ToDate)
+
+	return v.Errors()
+}
+
+// AsExport converts this request model into export model. Should be called after a successful validation.
+func (r *CreateExportRequest) AsExport() *models.Export {
+	return &models.Export{
+		Format:          r.Format,
+		Issuer:          r.Issuer,
+		Owner:           r.Owner,
+		ImportanceLevel: r.ImportanceLevel,
+		Status:          r.Status,
+		FromDate:        r.fromDate,
+		ToDate:          r.toDate,
+	}
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateImportRequest model definition.
+type CreateImportRequest struct {
+	Source   string              `json:"source"`
+	Format   models.ImportFormat `json:"format"`
+	FileName string              `json:"fileName"`
+}
+
+// Validate validates the request.
+func (r *CreateImportRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Length("source", r.Source, 50)
+	v.OneOf("format", string(r.Format), string(models.ImportFormatCSV), string(models.ImportFormatNDJSON))
+
+	if v.Required("fileName", r.FileName) {
+		v.Check(len(r.FileName) <= 255 && r.FileName != "." && r.FileName != ".." &&
+			!strings.ContainsAny(r.FileName, `/\`), "fileName", "not_valid")
+	}
+
+	return v.Errors()
+}
+
+// AsImport converts this request model into import model.
+func (r *CreateImportRequest) AsImport() *models.Import {
+	return &models.Import{
+		Source:   r.Source,
+		Format:   r.Format,
+		FileName: r.FileName,
+	}
+}
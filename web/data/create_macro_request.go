@@ -0,0 +1,64 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateMacroRequest model definition.
+type CreateMacroRequest struct {
+	Issuer  string               `json:"issuer"`
+	Name    string               `json:"name"`
+	Actions []models.MacroAction `json:"actions"`
+}
+
+// Validate validates the request.
+func (r *CreateMacroRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.MaxLength("issuer", r.Issuer, 50)
+	v.Length("name", r.Name, 100)
+	validateMacroActions(v, r.Actions)
+
+	return v.Errors()
+}
+
+// AsMacro converts this request model into macro model.
+func (r *CreateMacroRequest) AsMacro() *models.Macro {
+	return &models.Macro{
+		Issuer:  r.Issuer,
+		Name:    r.Name,
+		Actions: r.Actions,
+	}
+}
+
+func validateMacroActions(v *Validator, actions []models.MacroAction) {
+	if !v.Items("actions", len(actions), 25) {
+		return
+	}
+
+	for i, a := range actions {
+		validateMacroAction(v, element("actions", i), a)
+	}
+}
+
+// validateMacroAction validates the fields of the action, whose path is action.
+func validateMacroAction(v *Validator, action string, a models.MacroAction) {
+	switch a.Type {
+	case models.MacroActionTypeSetStatus:
+		v.UpdateStatus(action+".status", a.Status)
+	case models.MacroActionTypeSetImportanceLevel:
+		v.ImportanceLevel(action+".importanceLevel", a.ImportanceLevel)
+	case models.MacroActionTypeSetSubject:
+		v.Length(action+".subject", a.Subject, 255)
+	case models.MacroActionTypeAddTag, models.MacroActionTypeRemoveTag:
+		v.Length(action+".tag", a.Tag, 50)
+	case models.MacroActionTypeSetMetadata:
+		v.Length(action+".key", a.Key, 50)
+	case models.MacroActionTypeAddComment:
+		v.Check(len(a.Content) > 0 || a.CannedResponseID > 0, action+".content", "is_required")
+		v.MaxLength(action+".content", a.Content, 5000)
+		v.MaxLength(action+".owner", a.Owner, 50)
+	default:
+		v.Add(action+".type", "not_valid", "", nil)
+	}
+}
@@ -0,0 +1,113 @@
+package data
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateRuleRequest model definition.
+type CreateRuleRequest struct {
+	Name       string                 `json:"name"`
+	Event      models.EventType       `json:"event"`
+	Conditions []models.RuleCondition `json:"conditions"`
+	Actions    []models.RuleAction    `json:"actions"`
+	Enabled    bool                   `json:"enabled"`
+}
+
+// Validate validates the request.
+func (r *CreateRuleRequest) Validate() *errors.Type {
+	v := NewValidator()
+	validateRule(v, r.Name, r.Event, r.Conditions, r.Actions)
+
+	return v.Errors()
+}
+
+// AsRule converts this request model into rule model.
+func (r *CreateRuleRequest) AsRule() *models.Rule {
+	return &models.Rule{
+		Name:       r.Name,
+		Event:      r.Event,
+		Conditions: r.Conditions,
+		Actions:    r.Actions,
+		Enabled:    r.Enabled,
+	}
+}
+
+func validateRule(v *Validator, name string, event models.EventType, conditions []models.RuleCondition,
+	actions []models.RuleAction) {
+
+	v.Length("name", name, 100)
+
+	// Rules act on the ticket of the event, so events without a ticket to act on are not supported.
+	v.OneOf("event", string(event), string(models.EventTypeTicketCreated), string(models.EventTypeTicketUpdated),
+		string(models.EventTypeCommentCreated), string(models.EventTypeCommentUpdated))
+
+	validateRuleConditions(v, conditions)
+	validateRuleActions(v, actions)
+}
+
+func validateRuleConditions(v *Validator, conditions []models.RuleCondition) {
+	if !v.MaxItems("conditions", len(conditions), 25) {
+		return
+	}
+
+	for i, c := range conditions {
+		condition := element("conditions", i)
+
+		switch c.Field {
+		case "issuer", "owner", "subject", "content", "importance_level", "status", "comment.owner",
+			"comment.content", "minutes_since_modified":
+		default:
+			v.Check(strings.HasPrefix(c.Field, "metadata.") && len(c.Field) > len("metadata."),
+				condition+".field", "not_valid")
+		}
+
+		if !v.MaxLength(condition+".value", c.Value, 255) {
+			continue
+		}
+
+		switch c.Operator {
+		case models.RuleConditionOperatorEquals, models.RuleConditionOperatorNotEquals,
+			models.RuleConditionOperatorContains:
+		case models.RuleConditionOperatorMatches:
+			_, e := regexp.Compile(c.Value)
+			v.Check(e == nil, condition+".value", "not_valid")
+		case models.RuleConditionOperatorGreaterThan, models.RuleConditionOperatorLessThan:
+			_, e := strconv.ParseFloat(c.Value, 64)
+			v.Check(e == nil, condition+".value", "not_valid")
+		default:
+			v.Add(condition+".operator", "not_valid", "", nil)
+		}
+	}
+}
+
+func validateRuleActions(v *Validator, actions []models.RuleAction) {
+	if !v.Items("actions", len(actions), 25) {
+		return
+	}
+
+	for i, a := range actions {
+		action := element("actions", i)
+
+		switch a.Type {
+		case models.RuleActionTypePublishMessage:
+			// Publishing to the subjects of kiosk itself would let rules bypass the loop protection.
+			if v.Length(action+".topic", a.Topic, 255) {
+				v.Check(!strings.HasPrefix(a.Topic, "kiosk."), action+".topic", "not_valid")
+			}
+		case models.RuleActionTypeCallWebhook:
+			v.URL(action+".url", a.URL, 2048)
+		default:
+			validateMacroAction(v, action, a.AsMacroAction())
+
+			// There is no requester to own the comments added by rules.
+			if a.Type == models.RuleActionTypeAddComment {
+				v.Required(action+".owner", a.Owner)
+			}
+		}
+	}
+}
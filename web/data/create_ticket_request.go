@@ -17,47 +17,14 @@ type CreateTicketRequest struct {
 
 // Validate validates the request.
 func (r *CreateTicketRequest) Validate() *errors.Type {
-	if len(r.Issuer) == 0 {
-		return errors.InvalidArgument("issuer.is_required", "")
-	}
-
-	if len(r.Issuer) > 50 {
-		return errors.InvalidArgument("issuer.invalid_length", "")
-	}
-
-	if len(r.Owner) == 0 {
-		return errors.InvalidArgument("owner.is_required", "")
-	}
-
-	if len(r.Owner) > 50 {
-		return errors.InvalidArgument("owner.invalid_length", "")
-	}
-
-	if len(r.Subject) == 0 {
-		return errors.InvalidArgument("subject.is_required", "")
-	}
-
-	if len(r.Subject) > 255 {
-		return errors.InvalidArgument("subject.invalid_length", "")
-	}
-
-	if len(r.Content) == 0 {
-		return errors.InvalidArgument("content.is_required", "")
-	}
-
-	if len(r.Content) > 5000 {
-		return errors.InvalidArgument("content.invalid_length", "")
-	}
-
-	if r.ImportanceLevel != models.TicketImportanceLevelLow &&
-		r.ImportanceLevel != models.TicketImportanceLevelMedium &&
-		r.ImportanceLevel != models.TicketImportanceLevelHigh &&
-		r.ImportanceLevel != models.TicketImportanceLevelCritical {
-
-		return errors.InvalidArgument("importanceLevel.not_valid", "")
-	}
-
-	return nil
+	v := NewValidator()
+	v.Length("issuer", r.Issuer, 50)
+	v.Length("owner", r.Owner, 50)
+	v.Length("subject", r.Subject, 255)
+	v.Length("content", r.Content, 5000)
+	v.ImportanceLevel("importanceLevel", r.ImportanceLevel)
+
+	return v.Errors()
 }
 
 // AsTicket converts this request model into ticket model.
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// CreateWebhookRequest model definition.
+type CreateWebhookRequest struct {
+	URL     string             `json:"url"`
+	Issuer  string             `json:"issuer"`
+	Events  []models.EventType `json:"events"`
+	Secret  string             `json:"secret"`
+	Enabled bool               `json:"enabled"`
+}
+
+// Validate validates the request.
+func (r *CreateWebhookRequest) Validate() *errors.Type {
+	v := NewValidator()
+	validateWebhook(v, r.URL, r.Issuer, r.Events, r.Secret)
+
+	return v.Errors()
+}
+
+// AsWebhook converts this request model into webhook model.
+func (r *CreateWebhookRequest) AsWebhook() *models.Webhook {
+	return &models.Webhook{
+		URL:     r.URL,
+		Issuer:  r.Issuer,
+		Events:  r.Events,
+		Secret:  r.Secret,
+		Enabled: r.Enabled,
+	}
+}
+
+func validateWebhook(v *Validator, webhookURL, issuer string, events []models.EventType, secret string) {
+	if v.Required("url", webhookURL) {
+		v.URL("url", webhookURL, 2048)
+	}
+
+	v.MaxLength("issuer", issuer, 50)
+
+	if v.Check(len(events) > 0, "events", "is_required") {
+		for i, event := range events {
+			v.OneOf(element("events", i), string(event), string(models.EventTypeTicketCreated),
+				string(models.EventTypeTicketUpdated), string(models.EventTypeTicketDeleted),
+				string(models.EventTypeCommentCreated), string(models.EventTypeCommentUpdated),
+				string(models.EventTypeCommentDeleted))
+		}
+	}
+
+	// Short secrets would make the signatures easy to forge.
+	if len(secret) < 16 || len(secret) > 255 {
+		v.Add("secret", "invalid_length", "", map[string]interface{}{"min": 16, "max": 255})
+	}
+}
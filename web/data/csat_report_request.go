@@ -0,0 +1,41 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+)
+
+// CSATReportRequest model definition.
+type CSATReportRequest struct {
+	Issuer   string `json:"issuer"`
+	FromDate string `json:"fromDate"`
+	ToDate   string `json:"toDate"`
+
+	fromDate time.Time
+	toDate   time.Time
+}
+
+// Validate validates the request.
+func (r *CSATReportRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.MaxLength("issuer", r.Issuer, 50)
+
+	if r.FromDate == "" {
+		r.FromDate = "2000-01-01T00:00:00Z"
+	}
+
+	if r.ToDate == "" {
+		r.ToDate = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	r.fromDate, _ = v.Time("fromDate", r.FromDate)
+	r.toDate, _ = v.Time("toDate", r.ToDate)
+
+	return v.Errors()
+}
+
+// Dates returns back the parsed report period. Should be called after a successful validation.
+func (r *CSATReportRequest) Dates() (time.Time, time.Time) {
+	return r.fromDate, r.toDate
+}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// EscalationPolicyResponse model definition.
+type EscalationPolicyResponse struct {
+	ID         int64                   `json:"ID"`
+	Name       string                  `json:"name"`
+	Issuer     string                  `json:"issuer,omitempty"`
+	Statuses   []models.TicketStatus   `json:"statuses"`
+	Steps      []models.EscalationStep `json:"steps"`
+	Enabled    bool                    `json:"enabled"`
+	CreatedAt  string                  `json:"createdAt"`
+	ModifiedAt string                  `json:"modifiedAt"`
+}
+
+// LoadFromEscalationPolicy populates the fields of current model from provided escalation policy.
+func (r *EscalationPolicyResponse) LoadFromEscalationPolicy(policy *models.EscalationPolicy) {
+	r.ID = policy.ID
+	r.Name = policy.Name
+	r.Issuer = policy.Issuer
+	r.Statuses = policy.Statuses
+	r.Steps = policy.Steps
+	r.Enabled = policy.Enabled
+	r.CreatedAt = policy.CreatedAt.Format(time.RFC3339Nano)
+	r.ModifiedAt = policy.ModifiedAt.Format(time.RFC3339Nano)
+}
+
+// FilterEscalationPoliciesResponse model definition.
+type FilterEscalationPoliciesResponse struct {
+	Policies    []*EscalationPolicyResponse `json:"policies,omitempty"`
+	HasNextPage bool                        `json:"hasNextPage"`
+}
+
+// LoadFromEscalationPolicies populates the fields of current model from provided escalation policies.
+func (r *FilterEscalationPoliciesResponse) LoadFromEscalationPolicies(policies []*models.EscalationPolicy,
+	hasNextPage bool) {
+
+	for _, p := range policies {
+		escalationPolicyResponse := &EscalationPolicyResponse{}
+		escalationPolicyResponse.LoadFromEscalationPolicy(p)
+		r.Policies = append(r.Policies, escalationPolicyResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
+
+// EscalationResponse model definition.
+type EscalationResponse struct {
+	ID        int64                   `json:"ID"`
+	TicketID  int64                   `json:"ticketID"`
+	PolicyID  int64                   `json:"policyID"`
+	Step      int                     `json:"step"`
+	Action    models.EscalationAction `json:"action"`
+	Details   string                  `json:"details"`
+	IdleSince string                  `json:"idleSince"`
+	CreatedAt string                  `json:"createdAt"`
+}
+
+// LoadFromEscalation populates the fields of current model from provided escalation.
+func (r *EscalationResponse) LoadFromEscalation(escalation *models.Escalation) {
+	r.ID = escalation.ID
+	r.TicketID = escalation.TicketID
+	r.PolicyID = escalation.PolicyID
+	r.Step = escalation.Step
+	r.Action = escalation.Action
+	r.Details = escalation.Details
+	r.IdleSince = escalation.IdleSince.Format(time.RFC3339Nano)
+	r.CreatedAt = escalation.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// EscalationsResponse model definition.
+type EscalationsResponse struct {
+	Escalations []*EscalationResponse `json:"escalations,omitempty"`
+}
+
+// LoadFromEscalations populates the fields of current model from provided escalations.
+func (r *EscalationsResponse) LoadFromEscalations(escalations []*models.Escalation) {
+	for _, e := range escalations {
+		escalationResponse := &EscalationResponse{}
+		escalationResponse.LoadFromEscalation(e)
+		r.Escalations = append(r.Escalations, escalationResponse)
+	}
+}
+
+// EscalationNotification model definition. It is published to the topic of notifying escalation steps.
+type EscalationNotification struct {
+	PolicyID   int64           `json:"policyID"`
+	PolicyName string          `json:"policyName"`
+	Step       int             `json:"step"`
+	Supervisor string          `json:"supervisor,omitempty"`
+	Ticket     *TicketResponse `json:"ticket"`
+}
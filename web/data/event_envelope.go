@@ -0,0 +1,61 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// EventEnvelopeVersion is the version of the envelope and of the payloads of the published domain events. It is only
+// increased on breaking changes, so consumers can safely ignore unknown fields.
+const EventEnvelopeVersion = 1
+
+// EventEnvelope model definition. It wraps the domain events published on nats, which may be delivered more than once
+// with the same id.
+type EventEnvelope struct {
+	Version    int        `json:"version"`
+	ID         string     `json:"ID"`
+	Sequence   int64      `json:"sequence"`
+	Type       string     `json:"type"`
+	OccurredAt string     `json:"occurredAt"`
+	Actor      EventActor `json:"actor"`
+	Data       EventData  `json:"data"`
+}
+
+// EventActor model definition.
+type EventActor struct {
+	Type models.ActorType `json:"type"`
+	ID   string           `json:"ID,omitempty"`
+}
+
+// EventData model definition. Ticket is the ticket of the event, or the deleted ticket as it was right before the
+// deletion, and comment is the comment of the comment events.
+type EventData struct {
+	Ticket         *TicketResponse     `json:"ticket,omitempty"`
+	Comment        *CommentResponse    `json:"comment,omitempty"`
+	PreviousStatus models.TicketStatus `json:"previousStatus,omitempty"`
+}
+
+// LoadFromOutboxMessage populates the fields of current model from provided outbox message.
+func (r *EventEnvelope) LoadFromOutboxMessage(message *models.OutboxMessage) {
+	r.Version = EventEnvelopeVersion
+	r.ID = message.EventID
+	r.Sequence = message.ID
+	r.Type = string(message.Type)
+	r.OccurredAt = message.CreatedAt.Format(time.RFC3339Nano)
+	r.Actor = EventActor{Type: message.Actor.Type, ID: message.Actor.ID}
+
+	if message.Ticket != nil {
+		r.Data.Ticket = &TicketResponse{}
+		r.Data.Ticket.LoadFromTicket(message.Ticket)
+	}
+
+	if message.Comment != nil {
+		r.Data.Comment = &CommentResponse{}
+		r.Data.Comment.LoadFromComment(message.Comment)
+	}
+
+	if message.Type == models.OutboxMessageTypeTicketStatusChanged {
+		r.Data.PreviousStatus = message.PreviousStatus
+	}
+}
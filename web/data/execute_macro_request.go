@@ -0,0 +1,22 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// ExecuteMacroRequest model definition. The owner is used for the comments that macro actions add without an owner of
+// their own. When dryRun is true the resulting ticket is only returned back and nothing is persisted.
+type ExecuteMacroRequest struct {
+	MacroID  int64  `json:"macroID"`
+	TicketID int64  `json:"ticketID"`
+	Owner    string `json:"owner"`
+	DryRun   bool   `json:"dryRun"`
+}
+
+// Validate validates the request.
+func (r *ExecuteMacroRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("macroID", r.MacroID)
+	v.ID("ticketID", r.TicketID)
+	v.MaxLength("owner", r.Owner, 50)
+
+	return v.Errors()
+}
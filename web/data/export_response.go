@@ -0,0 +1,43 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// ExportResponse model definition.
+type ExportResponse struct {
+	ID              int64                        `json:"ID"`
+	Format          models.ExportFormat          `json:"format"`
+	Issuer          string                       `json:"issuer,omitempty"`
+	Owner           string                       `json:"owner,omitempty"`
+	ImportanceLevel models.TicketImportanceLevel `json:"importanceLevel,omitempty"`
+	Status          models.TicketStatus          `json:"status,omitempty"`
+	FromDate        string                       `json:"fromDate"`
+	ToDate          string                       `json:"toDate"`
+	JobStatus       models.ExportJobStatus       `json:"jobStatus"`
+	FileName        string                       `json:"fileName"`
+	ExportedTickets int64                        `json:"exportedTickets"`
+	FailureReason   string                       `json:"failureReason,omitempty"`
+	CreatedAt       string                       `json:"createdAt"`
+	ModifiedAt      string                       `json:"modifiedAt"`
+}
+
+// LoadFromExport populates the fields of current model from provided export.
+func (r *ExportResponse) LoadFromExport(export *models.Export) {
+	r.ID = export.ID
+	r.Format = export.Format
+	r.Issuer = export.Issuer
+	r.Owner = export.Owner
+	r.ImportanceLevel = export.ImportanceLevel
+	r.Status = export.Status
+	r.FromDate = export.FromDate.Format(time.RFC3339Nano)
+	r.ToDate = export.ToDate.Format(time.RFC3339Nano)
+	r.JobStatus = export.JobStatus
+	r.FileName = export.FileName
+	r.ExportedTickets = export.ExportedTickets
+	r.FailureReason = export.FailureReason
+	r.CreatedAt = export.CreatedAt.Format(time.RFC3339Nano)
+	r.ModifiedAt = export.ModifiedAt.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,19 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// FilterCannedResponsesRequest model definition.
+type FilterCannedResponsesRequest struct {
+	Issuer     string `json:"issuer"`
+	PageNumber int    `json:"pageNumber"`
+	PageSize   int    `json:"pageSize"`
+}
+
+// Validate validates the request.
+func (r *FilterCannedResponsesRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.MaxLength("issuer", r.Issuer, 50)
+	v.Page(r.PageNumber, r.PageSize, 25)
+
+	return v.Errors()
+}
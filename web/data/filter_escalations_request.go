@@ -0,0 +1,16 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// FilterEscalationsRequest model definition.
+type FilterEscalationsRequest struct {
+	TicketID int64 `json:"ticketID"`
+}
+
+// Validate validates the request.
+func (r *FilterEscalationsRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ticketID", r.TicketID)
+
+	return v.Errors()
+}
@@ -0,0 +1,29 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// FilterNotificationsRequest model definition.
+type FilterNotificationsRequest struct {
+	TicketID   int64                     `json:"ticketID"`
+	Status     models.NotificationStatus `json:"status"`
+	PageNumber int                       `json:"pageNumber"`
+	PageSize   int                       `json:"pageSize"`
+}
+
+// Validate validates the request.
+func (r *FilterNotificationsRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ticketID", r.TicketID)
+
+	if r.Status != "" {
+		v.OneOf("status", string(r.Status), string(models.NotificationStatusPending),
+			string(models.NotificationStatusSent), string(models.NotificationStatusFailed))
+	}
+
+	v.Page(r.PageNumber, r.PageSize, 25)
+
+	return v.Errors()
+}
@@ -0,0 +1,19 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// FilterRuleExecutionsRequest model definition.
+type FilterRuleExecutionsRequest struct {
+	RuleID     int64 `json:"ruleID"`
+	PageNumber int   `json:"pageNumber"`
+	PageSize   int   `json:"pageSize"`
+}
+
+// Validate validates the request.
+func (r *FilterRuleExecutionsRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ruleID", r.RuleID)
+	v.Page(r.PageNumber, r.PageSize, 25)
+
+	return v.Errors()
+}
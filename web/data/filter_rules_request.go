@@ -0,0 +1,17 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// FilterRulesRequest model definition.
+type FilterRulesRequest struct {
+	PageNumber int `json:"pageNumber"`
+	PageSize   int `json:"pageSize"`
+}
+
+// Validate validates the request.
+func (r *FilterRulesRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Page(r.PageNumber, r.PageSize, 25)
+
+	return v.Errors()
+}
@@ -21,30 +21,11 @@ type FilterTicketsRequest struct {
 
 // Validate validates the request.
 func (r *FilterTicketsRequest) Validate() *errors.Type {
-	if len(r.Issuer) > 50 {
-		return errors.InvalidArgument("issuer.invalid_length", "")
-	}
-
-	if len(r.Owner) > 50 {
-		return errors.InvalidArgument("owner.invalid_length", "")
-	}
-
-	if r.ImportanceLevel != models.TicketImportanceLevelLow &&
-		r.ImportanceLevel != models.TicketImportanceLevelMedium &&
-		r.ImportanceLevel != models.TicketImportanceLevelHigh &&
-		r.ImportanceLevel != models.TicketImportanceLevelCritical {
-
-		return errors.InvalidArgument("importanceLevel.not_valid", "")
-	}
-
-	if r.Status != models.TicketStatusNew &&
-		r.Status != models.TicketStatusReplied &&
-		r.Status != models.TicketStatusResolved &&
-		r.Status != models.TicketStatusClosed &&
-		r.Status != models.TicketStatusBlocked {
-
-		return errors.InvalidArgument("status.not_valid", "")
-	}
+	v := NewValidator()
+	v.MaxLength("issuer", r.Issuer, 50)
+	v.MaxLength("owner", r.Owner, 50)
+	v.ImportanceLevel("importanceLevel", r.ImportanceLevel)
+	v.Status("status", r.Status)
 
 	if r.FromDate == "" {
 		r.FromDate = "2000-01-01T00:00:00Z"
@@ -54,13 +35,7 @@ func (r *FilterTicketsRequest) Validate() *errors.Type {
 		r.ToDate = time.Now().UTC().Format(time.RFC3339Nano)
 	}
 
-	if r.PageNumber < 1 {
-		return errors.InvalidArgument("pageNumber.not_valid", "")
-	}
-
-	if r.PageSize < 1 || r.PageSize > 25 {
-		return errors.InvalidArgument("pageSize.not_valid", "")
-	}
+	v.Page(r.PageNumber, r.PageSize, 25)
 
-	return nil
+	return v.Errors()
 }
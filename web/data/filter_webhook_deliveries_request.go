@@ -0,0 +1,29 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// FilterWebhookDeliveriesRequest model definition.
+type FilterWebhookDeliveriesRequest struct {
+	WebhookID  int64                        `json:"webhookID"`
+	Status     models.WebhookDeliveryStatus `json:"status"`
+	PageNumber int                          `json:"pageNumber"`
+	PageSize   int                          `json:"pageSize"`
+}
+
+// Validate validates the request.
+func (r *FilterWebhookDeliveriesRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("webhookID", r.WebhookID)
+
+	if r.Status != "" {
+		v.OneOf("status", string(r.Status), string(models.WebhookDeliveryStatusPending),
+			string(models.WebhookDeliveryStatusDelivered), string(models.WebhookDeliveryStatusFailed))
+	}
+
+	v.Page(r.PageNumber, r.PageSize, 25)
+
+	return v.Errors()
+}
@@ -0,0 +1,27 @@
+package data
+
+// Identity model definition, the authenticated caller of a request passed on by the HTTP API.
+type Identity struct {
+	Subject    string   `json:"subject"`
+	Roles      []string `json:"roles,omitempty"`
+	Tenant     string   `json:"tenant,omitempty"`
+	KeyPrefix  string   `json:"keyPrefix,omitempty"`
+	Operations []string `json:"operations,omitempty"`
+}
+
+// HasRole returns back true when the caller has the role.
+func (i *Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Identified model definition, the identity field of the requests, which is empty for unauthenticated callers.
+type Identified struct {
+	Identity *Identity `json:"identity,omitempty"`
+	APIKey   string    `json:"apiKey,omitempty"`
+}
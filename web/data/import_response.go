@@ -0,0 +1,42 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// ImportResponse model definition.
+type ImportResponse struct {
+	ID           int64                    `json:"ID"`
+	Source       string                   `json:"source"`
+	Format       models.ImportFormat      `json:"format"`
+	FileName     string                   `json:"fileName,omitempty"`
+	Status       models.ImportStatus      `json:"status"`
+	TotalRows    int64                    `json:"totalRows"`
+	ImportedRows int64                    `json:"importedRows"`
+	SkippedRows  int64                    `json:"skippedRows"`
+	RejectedRows int64                    `json:"rejectedRows"`
+	Rejections   []models.ImportRejection `json:"rejections,omitempty"`
+	CreatedAt    string                   `json:"createdAt,omitempty"`
+	ModifiedAt   string                   `json:"modifiedAt,omitempty"`
+}
+
+// LoadFromImport populates the fields of current model from provided import.
+func (r *ImportResponse) LoadFromImport(i *models.Import) {
+	r.ID = i.ID
+	r.Source = i.Source
+	r.Format = i.Format
+	r.FileName = i.FileName
+	r.Status = i.Status
+	r.TotalRows = i.TotalRows
+	r.ImportedRows = i.ImportedRows
+	r.SkippedRows = i.SkippedRows
+	r.RejectedRows = i.RejectedRows
+	r.Rejections = i.Rejections
+
+	if !i.CreatedAt.IsZero() {
+		r.CreatedAt = i.CreatedAt.Format(time.RFC3339Nano)
+		r.ModifiedAt = i.ModifiedAt.Format(time.RFC3339Nano)
+	}
+}
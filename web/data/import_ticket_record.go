@@ -0,0 +1,108 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// ImportTicketRecord model definition, a ticket of an external system along with its comments.
+type ImportTicketRecord struct {
+	ExternalID      string                       `json:"externalID"`
+	Issuer          string                       `json:"issuer"`
+	Owner           string                       `json:"owner"`
+	Subject         string                       `json:"subject"`
+	Content         string                       `json:"content"`
+	Metadata        string                       `json:"metadata"`
+	ImportanceLevel models.TicketImportanceLevel `json:"importanceLevel"`
+	Status          models.TicketStatus          `json:"status"`
+	CreatedAt       string                       `json:"createdAt"`
+	ModifiedAt      string                       `json:"modifiedAt"`
+	Comments        []*ImportCommentRecord       `json:"comments"`
+}
+
+// ImportCommentRecord model definition.
+type ImportCommentRecord struct {
+	Owner      string `json:"owner"`
+	Content    string `json:"content"`
+	Metadata   string `json:"metadata"`
+	CreatedAt  string `json:"createdAt"`
+	ModifiedAt string `json:"modifiedAt"`
+}
+
+// Validate validates the record.
+func (r *ImportTicketRecord) Validate() *errors.Type {
+	v := NewValidator()
+	v.Length("externalID", r.ExternalID, 255)
+	v.Length("issuer", r.Issuer, 50)
+	v.Length("owner", r.Owner, 50)
+	v.Length("subject", r.Subject, 255)
+	v.Required("content", r.Content)
+	v.ImportanceLevel("importanceLevel", r.ImportanceLevel)
+	v.Status("status", r.Status)
+	validateImportTimes(v, "", &r.CreatedAt, &r.ModifiedAt)
+
+	for i, c := range r.Comments {
+		c.validate(v, element("comments", i)+".")
+	}
+
+	return v.Errors()
+}
+
+// Validate validates the record.
+func (r *ImportCommentRecord) Validate() *errors.Type {
+	v := NewValidator()
+	r.validate(v, "comments.")
+
+	return v.Errors()
+}
+
+// AsTicket converts this record into ticket model. Should be called after a successful validation.
+func (r *ImportTicketRecord) AsTicket() *models.Ticket {
+	createdAt, _ := time.Parse(time.RFC3339Nano, r.CreatedAt)
+	modifiedAt, _ := time.Parse(time.RFC3339Nano, r.ModifiedAt)
+
+	ticket := &models.Ticket{
+		Model:           models.Model{CreatedAt: createdAt.UTC(), ModifiedAt: modifiedAt.UTC()},
+		Issuer:          r.Issuer,
+		Owner:           r.Owner,
+		Subject:         r.Subject,
+		Content:         r.Content,
+		Metadata:        r.Metadata,
+		ImportanceLevel: r.ImportanceLevel,
+		Status:          r.Status,
+	}
+
+	for _, c := range r.Comments {
+		createdAt, _ := time.Parse(time.RFC3339Nano, c.CreatedAt)
+		modifiedAt, _ := time.Parse(time.RFC3339Nano, c.ModifiedAt)
+
+		ticket.Comments = append(ticket.Comments, &models.Comment{
+			Model:    models.Model{CreatedAt: createdAt.UTC(), ModifiedAt: modifiedAt.UTC()},
+			Owner:    c.Owner,
+			Content:  c.Content,
+			Metadata: c.Metadata,
+		})
+	}
+
+	return ticket
+}
+
+// validate validates the record, naming its fields after the prefix.
+func (r *ImportCommentRecord) validate(v *Validator, prefix string) {
+	v.Length(prefix+"owner", r.Owner, 50)
+	v.Required(prefix+"content", r.Content)
+	validateImportTimes(v, prefix, &r.CreatedAt, &r.ModifiedAt)
+}
+
+// validateImportTimes validates the creation and modification times of the records, the latter defaults to the former.
+func validateImportTimes(v *Validator, prefix string, createdAt, modifiedAt *string) {
+	v.Time(prefix+"createdAt", *createdAt)
+
+	if *modifiedAt == "" {
+		*modifiedAt = *createdAt
+	}
+
+	v.Time(prefix+"modifiedAt", *modifiedAt)
+}
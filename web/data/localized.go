@@ -0,0 +1,6 @@
+package data
+
+// Localized model definition, the language field of the requests, in the format of the Accept-Language header.
+type Localized struct {
+	Language string `json:"language,omitempty"`
+}
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// MacroResponse model definition.
+type MacroResponse struct {
+	ID         int64                `json:"ID"`
+	Issuer     string               `json:"issuer,omitempty"`
+	Name       string               `json:"name"`
+	Actions    []models.MacroAction `json:"actions"`
+	CreatedAt  string               `json:"createdAt"`
+	ModifiedAt string               `json:"modifiedAt"`
+}
+
+// LoadFromMacro populates the fields of current model from provided macro.
+func (r *MacroResponse) LoadFromMacro(macro *models.Macro) {
+	r.ID = macro.ID
+	r.Issuer = macro.Issuer
+	r.Name = macro.Name
+	r.Actions = macro.Actions
+	r.CreatedAt = macro.CreatedAt.Format(time.RFC3339Nano)
+	r.ModifiedAt = macro.ModifiedAt.Format(time.RFC3339Nano)
+}
+
+// FilterMacrosResponse model definition.
+type FilterMacrosResponse struct {
+	Macros      []*MacroResponse `json:"macros,omitempty"`
+	HasNextPage bool             `json:"hasNextPage"`
+}
+
+// LoadFromMacros populates the fields of current model from provided macros.
+func (r *FilterMacrosResponse) LoadFromMacros(macros []*models.Macro, hasNextPage bool) {
+	for _, m := range macros {
+		macroResponse := &MacroResponse{}
+		macroResponse.LoadFromMacro(m)
+		r.Macros = append(r.Macros, macroResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
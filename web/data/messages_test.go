@@ -0,0 +1,97 @@
+package data_test
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Messages", func() {
+	requests := []interface{ Validate() *errors.Type }{
+		&data.AuthenticateAPIKeyRequest{}, &data.CreateAPIKeyRequest{}, &data.CreateCannedResponseRequest{},
+		&data.CreateCommentFromTemplateRequest{}, &data.CreateCommentRequest{}, &data.CreateEscalationPolicyRequest{},
+		&data.CreateExportRequest{}, &data.CreateImportRequest{}, &data.CreateMacroRequest{}, &data.CreateRuleRequest{},
+		&data.CreateTicketRequest{}, &data.CreateWebhookRequest{}, &data.CSATReportRequest{},
+		&data.ExecuteMacroRequest{}, &data.FilterAPIKeysRequest{}, &data.FilterCannedResponsesRequest{},
+		&data.FilterEscalationPoliciesRequest{}, &data.FilterEscalationsRequest{}, &data.FilterMacrosRequest{},
+		&data.FilterNotificationsRequest{}, &data.FilterRuleExecutionsRequest{}, &data.FilterRulesRequest{},
+		&data.FilterTicketsRequest{}, &data.FilterWebhookDeliveriesRequest{}, &data.FilterWebhooksRequest{},
+		&data.ImportCommentRecord{}, &data.ImportTicketRecord{}, &data.PatchTicketRequest{},
+		&data.RateTicketRequest{}, &data.ReplayEventsRequest{}, &data.RotateAPIKeyRequest{},
+		&data.StreamTicketsRequest{}, &data.TakeRateLimitRequest{}, &data.TestRuleRequest{},
+		&data.UpdateCannedResponseRequest{}, &data.UpdateCommentRequest{}, &data.UpdateEscalationPolicyRequest{},
+		&data.UpdateMacroRequest{}, &data.UpdateRuleRequest{}, &data.UpdateTicketRequest{},
+		&data.UpdateWebhookRequest{},
+	}
+
+	// fill sets every string of the value to text, every number to number and every list to count items, so the
+	// validations of all of the fields fail.
+	var fill func(value reflect.Value, text string, number int64, count int)
+	fill = func(value reflect.Value, text string, number int64, count int) {
+		switch value.Kind() {
+		case reflect.String:
+			value.SetString(text)
+		case reflect.Int, reflect.Int64:
+			value.SetInt(number)
+		case reflect.Ptr:
+			value.Set(reflect.New(value.Type().Elem()))
+			fill(value.Elem(), text, number, count)
+		case reflect.Slice:
+			value.Set(reflect.MakeSlice(value.Type(), count, count))
+			for i := 0; i < count; i++ {
+				fill(value.Index(i), text, number, count)
+			}
+		case reflect.Struct:
+			for i := 0; i < value.NumField(); i++ {
+				if value.Field(i).CanSet() {
+					fill(value.Field(i), text, number, count)
+				}
+			}
+		}
+	}
+
+	// failures returns back the errors of the requests with the fields either empty, too long or not valid.
+	failures := func() []errors.Error {
+		failures := make([]errors.Error, 0)
+		for _, request := range requests {
+			for _, mode := range []struct {
+				text   string
+				number int64
+				count  int
+			}{{"", 0, 0}, {strings.Repeat("a", 3000), -1, 26}, {"x", 0, 1}} {
+				value := reflect.New(reflect.TypeOf(request).Elem())
+				fill(value.Elem(), mode.text, mode.number, mode.count)
+
+				if e := value.Interface().(interface{ Validate() *errors.Type }).Validate(); e != nil {
+					failures = append(failures, e.Errors...)
+				}
+			}
+		}
+
+		steps := &data.CreateEscalationPolicyRequest{Steps: []models.EscalationStep{{IdleTime: "2h"}, {IdleTime: "1h"}}}
+		failures = append(failures, steps.Validate().Errors...)
+
+		return failures
+	}
+
+	It("should have a message for every code of the validators in every language", func() {
+		failures := failures()
+		Ω(failures).ShouldNot(BeEmpty())
+
+		for _, language := range []string{"en", "fa"} {
+			catalogue, e := errors.LoadCatalogue("../../messages", language)
+			Ω(e).Should(BeNil())
+
+			for _, failure := range failures {
+				message := catalogue.Message(language, failure)
+				Ω(message).ShouldNot(BeEmpty(), language+": "+failure.Code)
+				Ω(message).ShouldNot(ContainSubstring("{"), language+": "+failure.Code)
+			}
+		}
+	})
+})
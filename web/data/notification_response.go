@@ -0,0 +1,60 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// NotificationResponse model definition.
+type NotificationResponse struct {
+	ID            int64                     `json:"ID"`
+	TicketID      int64                     `json:"ticketID"`
+	Event         models.NotificationEvent  `json:"event"`
+	Recipient     string                    `json:"recipient"`
+	Locale        string                    `json:"locale"`
+	Subject       string                    `json:"subject"`
+	Status        models.NotificationStatus `json:"status"`
+	Attempts      int                       `json:"attempts"`
+	LastError     string                    `json:"lastError,omitempty"`
+	NextAttemptAt string                    `json:"nextAttemptAt,omitempty"`
+	SentAt        string                    `json:"sentAt,omitempty"`
+	CreatedAt     string                    `json:"createdAt"`
+}
+
+// LoadFromNotification populates the fields of current model from provided notification.
+func (r *NotificationResponse) LoadFromNotification(notification *models.Notification) {
+	r.ID = notification.ID
+	r.TicketID = notification.TicketID
+	r.Event = notification.Event
+	r.Recipient = notification.Recipient
+	r.Locale = notification.Locale
+	r.Subject = notification.Subject
+	r.Status = notification.Status
+	r.Attempts = notification.Attempts
+	r.LastError = notification.LastError
+	if notification.Status == models.NotificationStatusPending {
+		r.NextAttemptAt = notification.NextAttemptAt.Format(time.RFC3339Nano)
+	}
+	if !notification.SentAt.IsZero() {
+		r.SentAt = notification.SentAt.Format(time.RFC3339Nano)
+	}
+	r.CreatedAt = notification.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// FilterNotificationsResponse model definition.
+type FilterNotificationsResponse struct {
+	Notifications []*NotificationResponse `json:"notifications,omitempty"`
+	HasNextPage   bool                    `json:"hasNextPage"`
+}
+
+// LoadFromNotifications populates the fields of current model from provided notifications.
+func (r *FilterNotificationsResponse) LoadFromNotifications(notifications []*models.Notification, hasNextPage bool) {
+	for _, n := range notifications {
+		notificationResponse := &NotificationResponse{}
+		notificationResponse.LoadFromNotification(n)
+		r.Notifications = append(r.Notifications, notificationResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
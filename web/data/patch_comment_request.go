@@ -0,0 +1,6 @@
+package data
+
+// PatchCommentRequest model definition. Only the provided fields are changed.
+type PatchCommentRequest struct {
+	Metadata *string `json:"metadata"`
+}
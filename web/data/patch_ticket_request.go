@@ -0,0 +1,65 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// PatchTicketRequest model definition. Only the provided fields are changed.
+type PatchTicketRequest struct {
+	ID              int64                         `json:"ID"`
+	Subject         *string                       `json:"subject"`
+	Metadata        *string                       `json:"metadata"`
+	ImportanceLevel *models.TicketImportanceLevel `json:"importanceLevel"`
+	Status          *models.TicketStatus          `json:"status"`
+}
+
+// Validate validates the request.
+func (r *PatchTicketRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ID", r.ID)
+
+	if r.Subject != nil {
+		v.Length("subject", *r.Subject, 255)
+	}
+
+	if r.ImportanceLevel != nil {
+		v.ImportanceLevel("importanceLevel", *r.ImportanceLevel)
+	}
+
+	if r.Status != nil {
+		v.UpdateStatus("status", *r.Status)
+	}
+
+	return v.Errors()
+}
+
+// AsTicket converts this request model into the changed ticket, keeping the fields of the ticket that are not provided
+// as they are.
+func (r *PatchTicketRequest) AsTicket(ticket *models.Ticket) *models.Ticket {
+	changed := &models.Ticket{
+		Model:           models.Model{ID: r.ID},
+		Subject:         ticket.Subject,
+		Metadata:        ticket.Metadata,
+		ImportanceLevel: ticket.ImportanceLevel,
+		Status:          ticket.Status,
+	}
+
+	if r.Subject != nil {
+		changed.Subject = *r.Subject
+	}
+
+	if r.Metadata != nil {
+		changed.Metadata = *r.Metadata
+	}
+
+	if r.ImportanceLevel != nil {
+		changed.ImportanceLevel = *r.ImportanceLevel
+	}
+
+	if r.Status != nil {
+		changed.Status = *r.Status
+	}
+
+	return changed
+}
@@ -0,0 +1,23 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+)
+
+// RateTicketRequest model definition.
+type RateTicketRequest struct {
+	TicketID int64  `json:"ticketID"`
+	Owner    string `json:"owner"`
+	Rating   int    `json:"rating"`
+	Comment  string `json:"comment"`
+}
+
+// Validate validates the request.
+func (r *RateTicketRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Length("owner", r.Owner, 50)
+	v.Range("rating", r.Rating, 1, 5)
+	v.MaxLength("comment", r.Comment, 1000)
+
+	return v.Errors()
+}
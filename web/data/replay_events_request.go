@@ -0,0 +1,16 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// ReplayEventsRequest model definition. After is the sequence of the last event seen by the client.
+type ReplayEventsRequest struct {
+	After int64 `json:"after"`
+}
+
+// Validate validates the request.
+func (r *ReplayEventsRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Check(r.After > 0, "after", "invalid")
+
+	return v.Errors()
+}
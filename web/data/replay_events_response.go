@@ -0,0 +1,7 @@
+package data
+
+// ReplayEventsResponse model definition. HasMore is true when there were more events to replay than the limit.
+type ReplayEventsResponse struct {
+	Events  []*EventEnvelope `json:"events"`
+	HasMore bool             `json:"hasMore"`
+}
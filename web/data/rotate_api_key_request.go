@@ -0,0 +1,30 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+)
+
+// RotateAPIKeyRequest model definition.
+type RotateAPIKeyRequest struct {
+	ID        int64  `json:"ID"`
+	ExpiresAt string `json:"expiresAt"`
+
+	expiresAt time.Time
+}
+
+// Validate validates the request.
+func (r *RotateAPIKeyRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ID", r.ID)
+	r.expiresAt = parseExpiresAt(v, r.ExpiresAt)
+
+	return v.Errors()
+}
+
+// ExpiryTime returns back the expiry of the new key, or zero if it never expires. Should be called after a successful
+// validation.
+func (r *RotateAPIKeyRequest) ExpiryTime() time.Time {
+	return r.expiresAt
+}
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// RuleResponse model definition.
+type RuleResponse struct {
+	ID         int64                  `json:"ID"`
+	Name       string                 `json:"name"`
+	Event      models.EventType       `json:"event"`
+	Conditions []models.RuleCondition `json:"conditions"`
+	Actions    []models.RuleAction    `json:"actions"`
+	Enabled    bool                   `json:"enabled"`
+	CreatedAt  string                 `json:"createdAt"`
+	ModifiedAt string                 `json:"modifiedAt"`
+}
+
+// LoadFromRule populates the fields of current model from provided rule.
+func (r *RuleResponse) LoadFromRule(rule *models.Rule) {
+	r.ID = rule.ID
+	r.Name = rule.Name
+	r.Event = rule.Event
+	r.Conditions = rule.Conditions
+	r.Actions = rule.Actions
+	r.Enabled = rule.Enabled
+	r.CreatedAt = rule.CreatedAt.Format(time.RFC3339Nano)
+	r.ModifiedAt = rule.ModifiedAt.Format(time.RFC3339Nano)
+}
+
+// FilterRulesResponse model definition.
+type FilterRulesResponse struct {
+	Rules       []*RuleResponse `json:"rules,omitempty"`
+	HasNextPage bool            `json:"hasNextPage"`
+}
+
+// LoadFromRules populates the fields of current model from provided rules.
+func (r *FilterRulesResponse) LoadFromRules(rules []*models.Rule, hasNextPage bool) {
+	for _, rule := range rules {
+		ruleResponse := &RuleResponse{}
+		ruleResponse.LoadFromRule(rule)
+		r.Rules = append(r.Rules, ruleResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
+
+// RuleConditionResult model definition. Actual is the value of the field that the condition was evaluated against.
+type RuleConditionResult struct {
+	models.RuleCondition
+
+	Actual  string `json:"actual"`
+	Matched bool   `json:"matched"`
+}
+
+// TestRuleResponse model definition. When the rule matches, ticket is the ticket as it would be after applying the
+// ticket changing actions of the rule.
+type TestRuleResponse struct {
+	Matched    bool                   `json:"matched"`
+	Conditions []*RuleConditionResult `json:"conditions"`
+	Ticket     *TicketResponse        `json:"ticket,omitempty"`
+}
+
+// RuleExecutionResponse model definition.
+type RuleExecutionResponse struct {
+	ID        int64                      `json:"ID"`
+	RuleID    int64                      `json:"ruleID"`
+	TicketID  int64                      `json:"ticketID"`
+	Event     models.EventType           `json:"event"`
+	Status    models.RuleExecutionStatus `json:"status"`
+	Details   string                     `json:"details,omitempty"`
+	CreatedAt string                     `json:"createdAt"`
+}
+
+// LoadFromRuleExecution populates the fields of current model from provided rule execution.
+func (r *RuleExecutionResponse) LoadFromRuleExecution(execution *models.RuleExecution) {
+	r.ID = execution.ID
+	r.RuleID = execution.RuleID
+	r.TicketID = execution.TicketID
+	r.Event = execution.Event
+	r.Status = execution.Status
+	r.Details = execution.Details
+	r.CreatedAt = execution.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// FilterRuleExecutionsResponse model definition.
+type FilterRuleExecutionsResponse struct {
+	Executions  []*RuleExecutionResponse `json:"executions,omitempty"`
+	HasNextPage bool                     `json:"hasNextPage"`
+}
+
+// LoadFromRuleExecutions populates the fields of current model from provided rule executions.
+func (r *FilterRuleExecutionsResponse) LoadFromRuleExecutions(executions []*models.RuleExecution, hasNextPage bool) {
+	for _, e := range executions {
+		ruleExecutionResponse := &RuleExecutionResponse{}
+		ruleExecutionResponse.LoadFromRuleExecution(e)
+		r.Executions = append(r.Executions, ruleExecutionResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// SatisfactionResponse model definition.
+type SatisfactionResponse struct {
+	Owner     string `json:"owner"`
+	Rating    int    `json:"rating"`
+	Comment   string `json:"comment,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// LoadFromSatisfaction populates the fields of current model from provided satisfaction.
+func (r *SatisfactionResponse) LoadFromSatisfaction(satisfaction *models.Satisfaction) {
+	r.Owner = satisfaction.Owner
+	r.Rating = satisfaction.Rating
+	r.Comment = satisfaction.Comment
+	r.CreatedAt = satisfaction.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// CSATSummaryResponse model definition. CSAT is the percentage of 4 and 5 ratings and ratings holds the number of
+// each score from 1 to 5.
+type CSATSummaryResponse struct {
+	Count         int64    `json:"count"`
+	AverageRating float64  `json:"averageRating"`
+	CSAT          float64  `json:"csat"`
+	Ratings       [5]int64 `json:"ratings"`
+
+	sum int64
+}
+
+func (r *CSATSummaryResponse) add(summary *models.SatisfactionSummary) {
+	r.Count += summary.Count
+	r.sum += summary.Sum
+	for i, c := range summary.Ratings {
+		r.Ratings[i] += c
+	}
+
+	if r.Count > 0 {
+		r.AverageRating = float64(r.sum) / float64(r.Count)
+		r.CSAT = float64(r.Ratings[3]+r.Ratings[4]) * 100 / float64(r.Count)
+	}
+}
+
+// CSATAssigneeResponse model definition. Empty assignee stands for the tickets that were not assigned when rated.
+type CSATAssigneeResponse struct {
+	Assignee string `json:"assignee"`
+	CSATSummaryResponse
+}
+
+// CSATIssuerResponse model definition.
+type CSATIssuerResponse struct {
+	Issuer string `json:"issuer"`
+	CSATSummaryResponse
+	Assignees []*CSATAssigneeResponse `json:"assignees"`
+}
+
+// CSATReportResponse model definition.
+type CSATReportResponse struct {
+	FromDate string                `json:"fromDate"`
+	ToDate   string                `json:"toDate"`
+	Issuers  []*CSATIssuerResponse `json:"issuers"`
+}
+
+// LoadFromSatisfactionSummaries populates the fields of current model from provided summaries. Summaries are expected
+// to be ordered by issuer.
+func (r *CSATReportResponse) LoadFromSatisfactionSummaries(summaries []*models.SatisfactionSummary, fromDate,
+	toDate time.Time) {
+
+	r.FromDate = fromDate.Format(time.RFC3339Nano)
+	r.ToDate = toDate.Format(time.RFC3339Nano)
+	r.Issuers = make([]*CSATIssuerResponse, 0)
+
+	var issuer *CSATIssuerResponse
+	for _, s := range summaries {
+		if issuer == nil || issuer.Issuer != s.Issuer {
+			issuer = &CSATIssuerResponse{Issuer: s.Issuer, Assignees: make([]*CSATAssigneeResponse, 0)}
+			r.Issuers = append(r.Issuers, issuer)
+		}
+
+		assignee := &CSATAssigneeResponse{Assignee: s.Assignee}
+		assignee.add(s)
+		issuer.Assignees = append(issuer.Assignees, assignee)
+		issuer.add(s)
+	}
+}
@@ -0,0 +1,48 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// StreamTicketsRequest model definition. It selects the ticket and comment events pushed to a stream, by the ticket as
+// it is after the event. Empty criteria match all of the tickets.
+type StreamTicketsRequest struct {
+	TicketID        int64                        `json:"ticketID"`
+	Issuer          string                       `json:"issuer"`
+	Owner           string                       `json:"owner"`
+	ImportanceLevel models.TicketImportanceLevel `json:"importanceLevel"`
+	Status          models.TicketStatus          `json:"status"`
+}
+
+// Validate validates the request.
+func (r *StreamTicketsRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Check(r.TicketID >= 0, "ticketID", "invalid")
+	v.MaxLength("issuer", r.Issuer, 50)
+	v.MaxLength("owner", r.Owner, 50)
+
+	if r.ImportanceLevel != "" {
+		v.ImportanceLevel("importanceLevel", r.ImportanceLevel)
+	}
+
+	if r.Status != "" {
+		v.Status("status", r.Status)
+	}
+
+	return v.Errors()
+}
+
+// Matches reports whether the event is selected by the request.
+func (r *StreamTicketsRequest) Matches(envelope *EventEnvelope) bool {
+	ticket := envelope.Data.Ticket
+	if ticket == nil {
+		return false
+	}
+
+	return (r.TicketID == 0 || ticket.ID == r.TicketID) &&
+		(r.Issuer == "" || ticket.Issuer == r.Issuer) &&
+		(r.Owner == "" || ticket.Owner == r.Owner) &&
+		(r.ImportanceLevel == "" || ticket.ImportanceLevel == r.ImportanceLevel) &&
+		(r.Status == "" || ticket.Status == r.Status)
+}
@@ -0,0 +1,7 @@
+package data
+
+// StreamTokenResponse model definition. The token is passed to the streams as the token query parameter.
+type StreamTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+}
@@ -0,0 +1,20 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// TakeRateLimitRequest model definition. The rate limits of the operation are taken for the identity of the request,
+// or for the client, e.g: the remote address of an HTTP request, when there is no identity. Only the internal callers,
+// i.e. the ones without identity or API key, may name a client.
+type TakeRateLimitRequest struct {
+	Operation string `json:"operation"`
+	Client    string `json:"client,omitempty"`
+}
+
+// Validate validates the request.
+func (r *TakeRateLimitRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.Length("operation", r.Operation, 100)
+	v.Length("client", r.Client, 100)
+
+	return v.Errors()
+}
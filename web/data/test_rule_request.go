@@ -0,0 +1,18 @@
+package data
+
+import "github.com/jibitters/kiosk/errors"
+
+// TestRuleRequest model definition, evaluating a rule against an existing ticket without executing its actions.
+type TestRuleRequest struct {
+	RuleID   int64 `json:"ruleID"`
+	TicketID int64 `json:"ticketID"`
+}
+
+// Validate validates the request.
+func (r *TestRuleRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ruleID", r.RuleID)
+	v.ID("ticketID", r.TicketID)
+
+	return v.Errors()
+}
@@ -17,6 +17,7 @@ type TicketResponse struct {
 	ImportanceLevel models.TicketImportanceLevel `json:"importanceLevel"`
 	Status          models.TicketStatus          `json:"status"`
 	Comments        []*CommentResponse           `json:"comments,omitempty"`
+	Satisfaction    *SatisfactionResponse        `json:"satisfaction,omitempty"`
 	CreatedAt       string                       `json:"createdAt"`
 	ModifiedAt      string                       `json:"modifiedAt"`
 }
@@ -38,6 +39,11 @@ func (r *TicketResponse) LoadFromTicket(ticket *models.Ticket) {
 		r.Comments = append(r.Comments, cr)
 	}
 
+	if ticket.Satisfaction != nil {
+		r.Satisfaction = &SatisfactionResponse{}
+		r.Satisfaction.LoadFromSatisfaction(ticket.Satisfaction)
+	}
+
 	r.CreatedAt = ticket.CreatedAt.Format(time.RFC3339Nano)
 	r.ModifiedAt = ticket.ModifiedAt.Format(time.RFC3339Nano)
 }
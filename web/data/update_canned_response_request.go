@@ -0,0 +1,32 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// UpdateCannedResponseRequest model definition.
+type UpdateCannedResponseRequest struct {
+	ID   int64  `json:"ID"`
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+// Validate validates the request.
+func (r *UpdateCannedResponseRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ID", r.ID)
+	v.Length("name", r.Name, 100)
+	validateCannedResponseBody(v, r.Name, r.Body)
+
+	return v.Errors()
+}
+
+// AsCannedResponse converts this request model into canned response model.
+func (r *UpdateCannedResponseRequest) AsCannedResponse() *models.CannedResponse {
+	return &models.CannedResponse{
+		Model: models.Model{ID: r.ID},
+		Name:  r.Name,
+		Body:  r.Body,
+	}
+}
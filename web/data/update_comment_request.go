@@ -13,11 +13,10 @@ type UpdateCommentRequest struct {
 
 // Validate validates the request.
 func (r *UpdateCommentRequest) Validate() *errors.Type {
-	if r.ID <= 0 {
-		return errors.InvalidArgument("ID.invalid", "")
-	}
+	v := NewValidator()
+	v.ID("ID", r.ID)
 
-	return nil
+	return v.Errors()
 }
 
 // AsComment converts this request model into comment model.
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// UpdateEscalationPolicyRequest model definition.
+type UpdateEscalationPolicyRequest struct {
+	ID       int64                   `json:"ID"`
+	Name     string                  `json:"name"`
+	Issuer   string                  `json:"issuer"`
+	Statuses []models.TicketStatus   `json:"statuses"`
+	Steps    []models.EscalationStep `json:"steps"`
+	Enabled  bool                    `json:"enabled"`
+}
+
+// Validate validates the request.
+func (r *UpdateEscalationPolicyRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ID", r.ID)
+	validateEscalationPolicy(v, r.Name, r.Issuer, r.Statuses, r.Steps)
+
+	return v.Errors()
+}
+
+// AsEscalationPolicy converts this request model into escalation policy model.
+func (r *UpdateEscalationPolicyRequest) AsEscalationPolicy() *models.EscalationPolicy {
+	return &models.EscalationPolicy{
+		Model:    models.Model{ID: r.ID},
+		Name:     r.Name,
+		Issuer:   r.Issuer,
+		Statuses: r.Statuses,
+		Steps:    r.Steps,
+		Enabled:  r.Enabled,
+	}
+}
@@ -0,0 +1,32 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// UpdateMacroRequest model definition.
+type UpdateMacroRequest struct {
+	ID      int64                `json:"ID"`
+	Name    string               `json:"name"`
+	Actions []models.MacroAction `json:"actions"`
+}
+
+// Validate validates the request.
+func (r *UpdateMacroRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ID", r.ID)
+	v.Length("name", r.Name, 100)
+	validateMacroActions(v, r.Actions)
+
+	return v.Errors()
+}
+
+// AsMacro converts this request model into macro model.
+func (r *UpdateMacroRequest) AsMacro() *models.Macro {
+	return &models.Macro{
+		Model:   models.Model{ID: r.ID},
+		Name:    r.Name,
+		Actions: r.Actions,
+	}
+}
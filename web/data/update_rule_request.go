@@ -0,0 +1,37 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// UpdateRuleRequest model definition.
+type UpdateRuleRequest struct {
+	ID         int64                  `json:"ID"`
+	Name       string                 `json:"name"`
+	Event      models.EventType       `json:"event"`
+	Conditions []models.RuleCondition `json:"conditions"`
+	Actions    []models.RuleAction    `json:"actions"`
+	Enabled    bool                   `json:"enabled"`
+}
+
+// Validate validates the request.
+func (r *UpdateRuleRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ID", r.ID)
+	validateRule(v, r.Name, r.Event, r.Conditions, r.Actions)
+
+	return v.Errors()
+}
+
+// AsRule converts this request model into rule model.
+func (r *UpdateRuleRequest) AsRule() *models.Rule {
+	return &models.Rule{
+		Model:      models.Model{ID: r.ID},
+		Name:       r.Name,
+		Event:      r.Event,
+		Conditions: r.Conditions,
+		Actions:    r.Actions,
+		Enabled:    r.Enabled,
+	}
+}
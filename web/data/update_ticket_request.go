@@ -16,35 +16,13 @@ type UpdateTicketRequest struct {
 
 // Validate validates the request.
 func (r *UpdateTicketRequest) Validate() *errors.Type {
-	if r.ID <= 0 {
-		return errors.InvalidArgument("ID.invalid", "")
-	}
-
-	if len(r.Subject) == 0 {
-		return errors.InvalidArgument("subject.is_required", "")
-	}
-
-	if len(r.Subject) > 255 {
-		return errors.InvalidArgument("subject.invalid_length", "")
-	}
-
-	if r.ImportanceLevel != models.TicketImportanceLevelLow &&
-		r.ImportanceLevel != models.TicketImportanceLevelMedium &&
-		r.ImportanceLevel != models.TicketImportanceLevelHigh &&
-		r.ImportanceLevel != models.TicketImportanceLevelCritical {
-
-		return errors.InvalidArgument("importanceLevel.not_valid", "")
-	}
-
-	if r.Status != models.TicketStatusReplied &&
-		r.Status != models.TicketStatusResolved &&
-		r.Status != models.TicketStatusClosed &&
-		r.Status != models.TicketStatusBlocked {
-
-		return errors.InvalidArgument("status.not_valid", "")
-	}
+	v := NewValidator()
+	v.ID("ID", r.ID)
+	v.Length("subject", r.Subject, 255)
+	v.ImportanceLevel("importanceLevel", r.ImportanceLevel)
+	v.UpdateStatus("status", r.Status)
 
-	return nil
+	return v.Errors()
 }
 
 // AsTicket converts this request model into ticket model.
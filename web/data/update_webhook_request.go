@@ -0,0 +1,37 @@
+package data
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// UpdateWebhookRequest model definition.
+type UpdateWebhookRequest struct {
+	ID      int64              `json:"ID"`
+	URL     string             `json:"url"`
+	Issuer  string             `json:"issuer"`
+	Events  []models.EventType `json:"events"`
+	Secret  string             `json:"secret"`
+	Enabled bool               `json:"enabled"`
+}
+
+// Validate validates the request.
+func (r *UpdateWebhookRequest) Validate() *errors.Type {
+	v := NewValidator()
+	v.ID("ID", r.ID)
+	validateWebhook(v, r.URL, r.Issuer, r.Events, r.Secret)
+
+	return v.Errors()
+}
+
+// AsWebhook converts this request model into webhook model.
+func (r *UpdateWebhookRequest) AsWebhook() *models.Webhook {
+	return &models.Webhook{
+		Model:   models.Model{ID: r.ID},
+		URL:     r.URL,
+		Issuer:  r.Issuer,
+		Events:  r.Events,
+		Secret:  r.Secret,
+		Enabled: r.Enabled,
+	}
+}
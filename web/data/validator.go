@@ -0,0 +1,178 @@
+package data
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// importanceLevels are the importance levels of the tickets.
+var importanceLevels = []string{
+	string(models.TicketImportanceLevelLow),
+	string(models.TicketImportanceLevelMedium),
+	string(models.TicketImportanceLevelHigh),
+	string(models.TicketImportanceLevelCritical),
+}
+
+// statuses are the statuses of the tickets.
+var statuses = []string{
+	string(models.TicketStatusNew),
+	string(models.TicketStatusReplied),
+	string(models.TicketStatusResolved),
+	string(models.TicketStatusClosed),
+	string(models.TicketStatusBlocked),
+}
+
+// indexes matches the indexes of the field paths.
+var indexes = regexp.MustCompile(`\[\d+]`)
+
+// Validator collects the errors of the fields of a request, so they are all replied together. The codes are the JSON
+// paths of the fields without the indexes followed by the failure, e.g: actions.subject.invalid_length.
+type Validator struct {
+	errors []errors.Error
+}
+
+// NewValidator returns back a newly created and ready to use Validator.
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// Add adds an error of the field with the failure, the message and the parameters of the error, if any.
+func (v *Validator) Add(field, failure, message string, params map[string]interface{}) {
+	v.errors = append(v.errors, errors.Error{
+		Code:    indexes.ReplaceAllString(field, "") + "." + failure,
+		Message: message,
+		Field:   field,
+		Params:  params,
+	})
+}
+
+// Check adds an error of the field with the failure unless valid.
+func (v *Validator) Check(valid bool, field, failure string) bool {
+	if !valid {
+		v.Add(field, failure, "", nil)
+	}
+
+	return valid
+}
+
+// ID checks that the id of the field is positive.
+func (v *Validator) ID(field string, id int64) bool {
+	return v.Check(id > 0, field, "invalid")
+}
+
+// Required checks that the value of the field is not empty.
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(len(value) > 0, field, "is_required")
+}
+
+// MaxLength checks that the value of the field is not longer than max bytes.
+func (v *Validator) MaxLength(field, value string, max int) bool {
+	if len(value) > max {
+		v.Add(field, "invalid_length", "", map[string]interface{}{"max": max})
+		return false
+	}
+
+	return true
+}
+
+// Length checks that the value of the field is not empty nor longer than max bytes.
+func (v *Validator) Length(field, value string, max int) bool {
+	return v.Required(field, value) && v.MaxLength(field, value, max)
+}
+
+// MaxItems checks that the field has at most max items.
+func (v *Validator) MaxItems(field string, count, max int) bool {
+	if count > max {
+		v.Add(field, "invalid_length", "", map[string]interface{}{"max": max})
+		return false
+	}
+
+	return true
+}
+
+// Items checks that the field has at least one item and at most max ones.
+func (v *Validator) Items(field string, count, max int) bool {
+	return v.Check(count > 0, field, "is_required") && v.MaxItems(field, count, max)
+}
+
+// Range checks that the value of the field is between min and max, inclusive.
+func (v *Validator) Range(field string, value, min, max int) bool {
+	if value < min || value > max {
+		v.Add(field, "not_valid", "", map[string]interface{}{"min": min, "max": max})
+		return false
+	}
+
+	return true
+}
+
+// OneOf checks that the value of the field is one of the allowed values.
+func (v *Validator) OneOf(field, value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if a == value {
+			return true
+		}
+	}
+
+	v.Add(field, "not_valid", "", map[string]interface{}{"allowed": allowed})
+	return false
+}
+
+// ImportanceLevel checks that the field is an importance level of the tickets.
+func (v *Validator) ImportanceLevel(field string, level models.TicketImportanceLevel) bool {
+	return v.OneOf(field, string(level), importanceLevels...)
+}
+
+// Status checks that the field is a status of the tickets.
+func (v *Validator) Status(field string, status models.TicketStatus) bool {
+	return v.OneOf(field, string(status), statuses...)
+}
+
+// UpdateStatus checks that the field is a status the tickets can be updated to, i.e. any status but new.
+func (v *Validator) UpdateStatus(field string, status models.TicketStatus) bool {
+	return v.OneOf(field, string(status), statuses[1:]...)
+}
+
+// Page checks the page number and the page size of the filter requests, which is at most max.
+func (v *Validator) Page(pageNumber, pageSize, max int) bool {
+	valid := v.Check(pageNumber >= 1, "pageNumber", "not_valid")
+	return v.Range("pageSize", pageSize, 1, max) && valid
+}
+
+// Time returns back the RFC 3339 time of the field, in UTC, and checks that it is valid.
+func (v *Validator) Time(field, value string) (time.Time, bool) {
+	t, e := time.Parse(time.RFC3339Nano, value)
+	if !v.Check(e == nil, field, "not_valid") {
+		return time.Time{}, false
+	}
+
+	return t.UTC(), true
+}
+
+// URL checks that the field is an absolute http or https URL, which is not longer than max bytes.
+func (v *Validator) URL(field, value string, max int) bool {
+	if !v.MaxLength(field, value, max) {
+		return false
+	}
+
+	u, e := url.Parse(value)
+	return v.Check(e == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field, "not_valid")
+}
+
+// Errors returns back the errors of the fields as one invalid arguments error, or nil when all of them are valid.
+func (v *Validator) Errors() *errors.Type {
+	if len(v.errors) == 0 {
+		return nil
+	}
+
+	return errors.InvalidArguments(v.errors)
+}
+
+// element returns back the path of the item at the index of the field.
+func element(field string, index int) string {
+	return fmt.Sprintf("%v[%v]", field, index)
+}
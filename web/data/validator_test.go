@@ -0,0 +1,132 @@
+package data_test
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validator", func() {
+	codes := func(e *errors.Type) []string {
+		codes := make([]string, 0, len(e.Errors))
+		for _, err := range e.Errors {
+			codes = append(codes, err.Code)
+		}
+
+		return codes
+	}
+
+	It("should return no errors for the valid fields", func() {
+		v := data.NewValidator()
+		Ω(v.Length("issuer", "1", 50)).Should(BeTrue())
+		Ω(v.ImportanceLevel("importanceLevel", models.TicketImportanceLevelHigh)).Should(BeTrue())
+		Ω(v.Page(1, 25, 25)).Should(BeTrue())
+
+		Ω(v.Errors()).Should(BeNil())
+	})
+
+	It("should collect the errors of all of the fields in order", func() {
+		v := data.NewValidator()
+		v.Length("issuer", "", 50)
+		v.Length("owner", strings.Repeat("a", 51), 50)
+		v.Status("status", "UNKNOWN")
+		v.Page(0, 26, 25)
+
+		e := v.Errors()
+		Ω(e).ShouldNot(BeNil())
+		Ω(e.HTTPStatusCode).Should(Equal(http.StatusBadRequest))
+		Ω(codes(e)).Should(Equal([]string{"issuer.is_required", "owner.invalid_length", "status.not_valid",
+			"pageNumber.not_valid", "pageSize.not_valid"}))
+		Ω(e.Errors[1].Field).Should(Equal("owner"))
+		Ω(e.Errors[1].Params).Should(HaveKeyWithValue("max", 50))
+		Ω(e.Errors[4].Params).Should(Equal(map[string]interface{}{"min": 1, "max": 25}))
+	})
+
+	It("should strip the indexes of the field paths from the codes", func() {
+		v := data.NewValidator()
+		v.MaxLength("actions[1].subject", strings.Repeat("a", 256), 255)
+
+		e := v.Errors()
+		Ω(e.Errors[0].Field).Should(Equal("actions[1].subject"))
+		Ω(e.Errors[0].Code).Should(Equal("actions.subject.invalid_length"))
+	})
+
+	It("should not allow the tickets to be updated to new", func() {
+		v := data.NewValidator()
+		Ω(v.UpdateStatus("status", models.TicketStatusNew)).Should(BeFalse())
+		Ω(v.UpdateStatus("status", models.TicketStatusClosed)).Should(BeTrue())
+
+		Ω(codes(v.Errors())).Should(Equal([]string{"status.not_valid"}))
+	})
+
+	It("should check the times and URLs", func() {
+		v := data.NewValidator()
+		t, valid := v.Time("createdAt", "2020-01-02T03:04:05+03:30")
+		Ω(valid).Should(BeTrue())
+		Ω(t.Location().String()).Should(Equal("UTC"))
+
+		_, valid = v.Time("modifiedAt", "yesterday")
+		Ω(valid).Should(BeFalse())
+		Ω(v.URL("url", "https://example.com/hooks", 255)).Should(BeTrue())
+		Ω(v.URL("callback", "example.com", 255)).Should(BeFalse())
+
+		Ω(codes(v.Errors())).Should(Equal([]string{"modifiedAt.not_valid", "callback.not_valid"}))
+	})
+})
+
+var _ = Describe("Requests", func() {
+	It("should return back every invalid field of a request together", func() {
+		request := &data.CreateTicketRequest{
+			Issuer:          "",
+			Owner:           "owner",
+			Subject:         strings.Repeat("a", 256),
+			Content:         "content",
+			ImportanceLevel: "URGENT",
+		}
+
+		e := request.Validate()
+		Ω(e).ShouldNot(BeNil())
+		Ω(e.Errors).Should(HaveLen(3))
+		Ω(e.Errors[0].Code).Should(Equal("issuer.is_required"))
+		Ω(e.Errors[1].Code).Should(Equal("subject.invalid_length"))
+		Ω(e.Errors[2].Code).Should(Equal("importanceLevel.not_valid"))
+	})
+
+	It("should name the fields of the comments of the import records by their index", func() {
+		record := &data.ImportTicketRecord{
+			ExternalID:      "1",
+			Issuer:          "issuer",
+			Owner:           "owner",
+			Subject:         "subject",
+			Content:         "content",
+			ImportanceLevel: models.TicketImportanceLevelLow,
+			Status:          models.TicketStatusClosed,
+			CreatedAt:       "2020-01-02T03:04:05Z",
+			Comments: []*data.ImportCommentRecord{
+				{Owner: "owner", Content: "content", CreatedAt: "2020-01-02T03:04:05Z"},
+				{Owner: "owner", CreatedAt: "2020-01-02T03:04:05Z"},
+			},
+		}
+
+		e := record.Validate()
+		Ω(e.Errors).Should(HaveLen(1))
+		Ω(e.Errors[0].Field).Should(Equal("comments[1].content"))
+		Ω(e.Errors[0].Code).Should(Equal("comments.content.is_required"))
+		Ω(record.ModifiedAt).Should(Equal(record.CreatedAt))
+	})
+
+	It("should accept only the plain file names of the import directory", func() {
+		request := &data.CreateImportRequest{Source: "crm", Format: models.ImportFormatCSV, FileName: "tickets.csv"}
+		Ω(request.Validate()).Should(BeNil())
+
+		for _, fileName := range []string{".", "..", "../tickets.csv", "imports/tickets.csv", `..\tickets.csv`} {
+			request.FileName = fileName
+			Ω(request.Validate()).ShouldNot(BeNil(), fileName)
+		}
+	})
+})
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jibitters/kiosk/models"
+)
+
+// WebhookResponse model definition. The secret is never sent back.
+type WebhookResponse struct {
+	ID                  int64              `json:"ID"`
+	URL                 string             `json:"url"`
+	Issuer              string             `json:"issuer,omitempty"`
+	Events              []models.EventType `json:"events"`
+	Enabled             bool               `json:"enabled"`
+	ConsecutiveFailures int                `json:"consecutiveFailures"`
+	CreatedAt           string             `json:"createdAt"`
+	ModifiedAt          string             `json:"modifiedAt"`
+}
+
+// LoadFromWebhook populates the fields of current model from provided webhook.
+func (r *WebhookResponse) LoadFromWebhook(webhook *models.Webhook) {
+	r.ID = webhook.ID
+	r.URL = webhook.URL
+	r.Issuer = webhook.Issuer
+	r.Events = webhook.Events
+	r.Enabled = webhook.Enabled
+	r.ConsecutiveFailures = webhook.ConsecutiveFailures
+	r.CreatedAt = webhook.CreatedAt.Format(time.RFC3339Nano)
+	r.ModifiedAt = webhook.ModifiedAt.Format(time.RFC3339Nano)
+}
+
+// FilterWebhooksResponse model definition.
+type FilterWebhooksResponse struct {
+	Webhooks    []*WebhookResponse `json:"webhooks,omitempty"`
+	HasNextPage bool               `json:"hasNextPage"`
+}
+
+// LoadFromWebhooks populates the fields of current model from provided webhooks.
+func (r *FilterWebhooksResponse) LoadFromWebhooks(webhooks []*models.Webhook, hasNextPage bool) {
+	for _, w := range webhooks {
+		webhookResponse := &WebhookResponse{}
+		webhookResponse.LoadFromWebhook(w)
+		r.Webhooks = append(r.Webhooks, webhookResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
+
+// WebhookDeliveryResponse model definition.
+type WebhookDeliveryResponse struct {
+	ID             int64                        `json:"ID"`
+	WebhookID      int64                        `json:"webhookID"`
+	Event          models.EventType             `json:"event"`
+	TicketID       int64                        `json:"ticketID"`
+	Payload        string                       `json:"payload"`
+	Status         models.WebhookDeliveryStatus `json:"status"`
+	Attempts       int                          `json:"attempts"`
+	ResponseStatus int                          `json:"responseStatus,omitempty"`
+	LastError      string                       `json:"lastError,omitempty"`
+	NextAttemptAt  string                       `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    string                       `json:"deliveredAt,omitempty"`
+	CreatedAt      string                       `json:"createdAt"`
+}
+
+// LoadFromWebhookDelivery populates the fields of current model from provided webhook delivery.
+func (r *WebhookDeliveryResponse) LoadFromWebhookDelivery(delivery *models.WebhookDelivery) {
+	r.ID = delivery.ID
+	r.WebhookID = delivery.WebhookID
+	r.Event = delivery.Event
+	r.TicketID = delivery.TicketID
+	r.Payload = delivery.Payload
+	r.Status = delivery.Status
+	r.Attempts = delivery.Attempts
+	r.ResponseStatus = delivery.ResponseStatus
+	r.LastError = delivery.LastError
+	if delivery.Status == models.WebhookDeliveryStatusPending {
+		r.NextAttemptAt = delivery.NextAttemptAt.Format(time.RFC3339Nano)
+	}
+	if !delivery.DeliveredAt.IsZero() {
+		r.DeliveredAt = delivery.DeliveredAt.Format(time.RFC3339Nano)
+	}
+	r.CreatedAt = delivery.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// FilterWebhookDeliveriesResponse model definition.
+type FilterWebhookDeliveriesResponse struct {
+	Deliveries  []*WebhookDeliveryResponse `json:"deliveries,omitempty"`
+	HasNextPage bool                       `json:"hasNextPage"`
+}
+
+// LoadFromWebhookDeliveries populates the fields of current model from provided webhook deliveries.
+func (r *FilterWebhookDeliveriesResponse) LoadFromWebhookDeliveries(deliveries []*models.WebhookDelivery,
+	hasNextPage bool) {
+
+	for _, d := range deliveries {
+		webhookDeliveryResponse := &WebhookDeliveryResponse{}
+		webhookDeliveryResponse.LoadFromWebhookDelivery(d)
+		r.Deliveries = append(r.Deliveries, webhookDeliveryResponse)
+	}
+
+	r.HasNextPage = hasNextPage
+}
+
+// WebhookPayload model definition. It is the body posted to the webhooks, where ticket and comment are the ones of
+// the event as they were right after it, or right before it for deletions.
+type WebhookPayload struct {
+	Event      models.EventType `json:"event"`
+	OccurredAt string           `json:"occurredAt"`
+	TicketID   int64            `json:"ticketID"`
+	CommentID  int64            `json:"commentID,omitempty"`
+	Issuer     string           `json:"issuer"`
+	Ticket     *TicketResponse  `json:"ticket,omitempty"`
+	Comment    *CommentResponse `json:"comment,omitempty"`
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jibitters/kiosk/web/data"
+)
+
+// Authenticator verifies the bearer JWTs of the HTTP API. HS256 tokens are verified with the secret and RS256 and ES256
+// ones with the public key of their kid header, or with the only key when they have no kid.
+type Authenticator struct {
+	secret   []byte
+	keys     map[string]crypto.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// NewAuthenticator returns back a newly created and ready to use Authenticator. Tokens must be issued by the issuer and
+// for the audience, unless they are empty, and the leeway is allowed on the expiry and not before claims.
+func NewAuthenticator(secret string, keys map[string]crypto.PublicKey, issuer, audience string,
+	leeway time.Duration) *Authenticator {
+
+	return &Authenticator{secret: []byte(secret), keys: keys, issuer: issuer, audience: audience, leeway: leeway}
+}
+
+// claims holds the claims of the tokens that are used.
+type claims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Roles     json.RawMessage `json:"roles"`
+	Tenant    string          `json:"tenant"`
+}
+
+// Authenticate verifies the token at now and returns back the identity of its subject, roles and tenant claims. The
+// roles are either an array or a space separated string.
+func (a *Authenticator) Authenticate(token string, now time.Time) (*data.Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is not a JWS compact serialization")
+	}
+
+	header := &struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}{}
+	if e := decodeSegment(parts[0], header); e != nil {
+		return nil, fmt.Errorf("invalid header: %v", e)
+	}
+
+	signature, e := base64.RawURLEncoding.DecodeString(parts[2])
+	if e != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %v", e)
+	}
+
+	if e := a.verify(header.Algorithm, header.KeyID, parts[0]+"."+parts[1], signature); e != nil {
+		return nil, e
+	}
+
+	c := &claims{}
+	if e := decodeSegment(parts[1], c); e != nil {
+		return nil, fmt.Errorf("invalid claims: %v", e)
+	}
+
+	if c.ExpiresAt == nil || now.Add(-a.leeway).Unix() >= *c.ExpiresAt {
+		return nil, fmt.Errorf("token is expired or has no expiry")
+	}
+
+	if c.NotBefore != nil && now.Add(a.leeway).Unix() < *c.NotBefore {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+
+	if a.issuer != "" && c.Issuer != a.issuer {
+		return nil, fmt.Errorf("token is issued by %v", c.Issuer)
+	}
+
+	if a.audience != "" && !hasAudience(c.Audience, a.audience) {
+		return nil, fmt.Errorf("token is not issued for %v", a.audience)
+	}
+
+	if c.Subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	identity := &data.Identity{Subject: c.Subject, Tenant: c.Tenant}
+	if len(c.Roles) > 0 && json.Unmarshal(c.Roles, &identity.Roles) != nil {
+		roles := ""
+		if e := json.Unmarshal(c.Roles, &roles); e != nil {
+			return nil, fmt.Errorf("invalid roles claim")
+		}
+
+		identity.Roles = strings.Fields(roles)
+	}
+
+	return identity, nil
+}
+
+// verify verifies the signature of the signing input with the key of the algorithm. The key types are checked, so the
+// public keys are never used as HMAC secrets.
+func (a *Authenticator) verify(algorithm, keyID, input string, signature []byte) error {
+	hash := sha256.Sum256([]byte(input))
+
+	switch algorithm {
+	case "HS256":
+		if len(a.secret) == 0 {
+			return fmt.Errorf("HS256 is not accepted")
+		}
+
+		mac := hmac.New(sha256.New, a.secret)
+		_, _ = mac.Write([]byte(input))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("invalid signature")
+		}
+
+		return nil
+	case "RS256":
+		key, ok := a.key(keyID).(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("no RSA key for kid %q", keyID)
+		}
+
+		if e := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); e != nil {
+			return fmt.Errorf("invalid signature")
+		}
+
+		return nil
+	case "ES256":
+		key, ok := a.key(keyID).(*ecdsa.PublicKey)
+		if !ok || key.Curve != elliptic.P256() {
+			return fmt.Errorf("no P-256 key for kid %q", keyID)
+		}
+
+		if len(signature) != 64 {
+			return fmt.Errorf("invalid signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, hash[:], r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("algorithm %q is not accepted", algorithm)
+	}
+}
+
+func (a *Authenticator) key(keyID string) crypto.PublicKey {
+	if key, ok := a.keys[keyID]; ok {
+		return key
+	}
+
+	if keyID == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, t interface{}) error {
+	in, e := base64.RawURLEncoding.DecodeString(segment)
+	if e != nil {
+		return e
+	}
+
+	return json.Unmarshal(in, t)
+}
+
+// hasAudience returns back true when the audience claim, either a string or an array, contains the audience.
+func hasAudience(claim json.RawMessage, audience string) bool {
+	audiences := make([]string, 0)
+	if json.Unmarshal(claim, &audiences) != nil {
+		a := ""
+		if json.Unmarshal(claim, &a) != nil {
+			return false
+		}
+
+		audiences = append(audiences, a)
+	}
+
+	for _, a := range audiences {
+		if a == audience {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadPublicKey loads the PEM encoded RSA or ECDSA public key, or certificate, of the file.
+func LoadPublicKey(file string) (crypto.PublicKey, error) {
+	in, e := ioutil.ReadFile(file)
+	if e != nil {
+		return nil, e
+	}
+
+	block, _ := pem.Decode(in)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %v", file)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		certificate, e := x509.ParseCertificate(block.Bytes)
+		if e != nil {
+			return nil, e
+		}
+
+		return certificate.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %v in %v", block.Type, file)
+	}
+}
+
+// LoadJWKS loads the RSA and P-256 keys of the JSON Web Key Set file by their kid. Keys of the other types are ignored.
+func LoadJWKS(file string) (map[string]crypto.PublicKey, error) {
+	in, e := ioutil.ReadFile(file)
+	if e != nil {
+		return nil, e
+	}
+
+	jwks := &struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}{}
+	if e := json.Unmarshal(in, jwks); e != nil {
+		return nil, e
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range jwks.Keys {
+		switch {
+		case k.KeyType == "RSA":
+			n, e1 := base64.RawURLEncoding.DecodeString(k.N)
+			exponent, e2 := base64.RawURLEncoding.DecodeString(k.E)
+			if e1 != nil || e2 != nil {
+				return nil, fmt.Errorf("invalid RSA key %q", k.KeyID)
+			}
+
+			keys[k.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(exponent).Int64())}
+		case k.KeyType == "EC" && k.Curve == "P-256":
+			x, e1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, e2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if e1 != nil || e2 != nil {
+				return nil, fmt.Errorf("invalid EC key %q", k.KeyID)
+			}
+
+			key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !key.Curve.IsOnCurve(key.X, key.Y) {
+				return nil, fmt.Errorf("invalid EC key %q", k.KeyID)
+			}
+
+			keys[k.KeyID] = key
+		}
+	}
+
+	return keys, nil
+}
+
+type identityKey struct{}
+
+// withIdentity returns back a copy of the context carrying the identity of the caller.
+func withIdentity(ctx context.Context, identity *data.Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// identityOf returns back the identity of the caller carried by the context, if any.
+func identityOf(ctx context.Context) *data.Identity {
+	identity, _ := ctx.Value(identityKey{}).(*data.Identity)
+	return identity
+}
@@ -5,7 +5,9 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/web/data"
 	nc "github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
@@ -21,32 +23,143 @@ func NewCommentHandler(logger *zap.SugaredLogger, natsClient *nc.Conn) *CommentH
 	return &CommentHandler{logger: logger, natsClient: natsClient}
 }
 
-// Create creates a new comment with specified information.
+// Create creates a new comment with specified information. When the route has a ticket id, the comment is created for
+// that ticket.
 func (h *CommentHandler) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		in, _ := ioutil.ReadAll(r.Body)
 
-		response, e := h.natsClient.RequestWithContext(r.Context(), "kiosk.comments.create", in)
-		if e != nil {
-			if e == nc.ErrTimeout {
-				et := errors.RequestTimeout("")
-				writeError(w, et)
-			} else {
-				et := errors.InternalServerError("unknown", "")
-				h.logger.Error(et.FingerPrint, ": ", e.Error())
-				writeError(w, et)
+		if _, ok := mux.Vars(r)["id"]; ok {
+			ticketID, et := parseID(r)
+			if et != nil {
+				writeError(w, r, et)
+				return
 			}
 
+			createCommentRequest := &data.CreateCommentRequest{}
+			if e := json.Unmarshal(in, createCommentRequest); e != nil {
+				writeError(w, r, errors.InvalidRequestBody())
+				return
+			}
+			createCommentRequest.TicketID = ticketID
+
+			in, _ = json.Marshal(createCommentRequest)
+		}
+
+		if _, et := request(h.logger, h.natsClient, r, "kiosk.comments.create", in); et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		writeNoContent(w)
+	}
+}
+
+// Load returns back a comment.
+func (h *CommentHandler) Load() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		commentResponse, et := h.load(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		write(w, commentResponse)
+	}
+}
+
+// Update replaces the changeable information of a comment.
+func (h *CommentHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, et := parseID(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		updateCommentRequest := &data.UpdateCommentRequest{}
+		if ok := parse(h.logger, w, r, updateCommentRequest); !ok {
 			return
 		}
+		updateCommentRequest.ID = id
+
+		h.update(w, r, updateCommentRequest)
+	}
+}
 
-		et := &errors.Type{}
-		_ = json.Unmarshal(response.Data, et)
-		if et.FingerPrint != "" {
-			writeError(w, et)
+// Patch changes the provided information of a comment, keeping the rest as they are.
+func (h *CommentHandler) Patch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, et := parseID(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		patchCommentRequest := &data.PatchCommentRequest{}
+		if ok := parse(h.logger, w, r, patchCommentRequest); !ok {
+			return
+		}
+
+		// The metadata is the only changeable information, so there is nothing to keep but the comment to check.
+		if patchCommentRequest.Metadata == nil {
+			if _, et := h.load(r); et != nil {
+				writeError(w, r, et)
+				return
+			}
+
+			writeNoContent(w)
+			return
+		}
+
+		h.update(w, r, &data.UpdateCommentRequest{ID: id, Metadata: *patchCommentRequest.Metadata})
+	}
+}
+
+// Delete deletes a comment.
+func (h *CommentHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, et := parseID(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		in, _ := json.Marshal(data.ID{ID: id})
+		if _, et := request(h.logger, h.natsClient, r, "kiosk.comments.delete", in); et != nil {
+			writeError(w, r, et)
 			return
 		}
 
 		writeNoContent(w)
 	}
 }
+
+func (h *CommentHandler) load(r *http.Request) (*data.CommentResponse, *errors.Type) {
+	id, et := parseID(r)
+	if et != nil {
+		return nil, et
+	}
+
+	in, _ := json.Marshal(data.ID{ID: id})
+	out, et := request(h.logger, h.natsClient, r, "kiosk.comments.load", in)
+	if et != nil {
+		return nil, et
+	}
+
+	commentResponse := &data.CommentResponse{}
+	_ = json.Unmarshal(out, commentResponse)
+	return commentResponse, nil
+}
+
+func (h *CommentHandler) update(w http.ResponseWriter, r *http.Request,
+	updateCommentRequest *data.UpdateCommentRequest) {
+
+	in, _ := json.Marshal(updateCommentRequest)
+	if _, et := request(h.logger, h.natsClient, r, "kiosk.comments.update", in); et != nil {
+		writeError(w, r, et)
+		return
+	}
+
+	writeNoContent(w)
+}
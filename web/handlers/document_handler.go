@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DocumentHandler is the handler implementation of static documents, e.g: the API specifications.
+type DocumentHandler struct {
+	document []byte
+}
+
+// NewDocumentHandler returns back a newly created and ready to use DocumentHandler, serving the JSON encoding of the
+// document.
+func NewDocumentHandler(document interface{}) *DocumentHandler {
+	out, _ := json.Marshal(document)
+	return &DocumentHandler{document: out}
+}
+
+// Serve returns back the document.
+func (h *DocumentHandler) Serve() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(h.document)
+	}
+}
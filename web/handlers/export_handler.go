@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// ExportHandler is the handler implementation of exports related resource.
+type ExportHandler struct {
+	logger           *zap.SugaredLogger
+	natsClient       *nc.Conn
+	storageDirectory string
+	writeTimeout     time.Duration
+}
+
+// NewExportHandler returns back a newly created and ready to use ExportHandler. Downloads may take longer than the
+// write timeout of the server, as long as every chunk is written within it.
+func NewExportHandler(logger *zap.SugaredLogger, natsClient *nc.Conn, storageDirectory string,
+	writeTimeout time.Duration) *ExportHandler {
+
+	return &ExportHandler{logger: logger, natsClient: natsClient, storageDirectory: storageDirectory,
+		writeTimeout: writeTimeout}
+}
+
+// Create creates a new export job with specified filter criteria.
+func (h *ExportHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		in, _ := ioutil.ReadAll(r.Body)
+
+		out, et := request(h.logger, h.natsClient, r, "kiosk.exports.create", in)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		exportResponse := &data.ExportResponse{}
+		_ = json.Unmarshal(out, exportResponse)
+		w.WriteHeader(http.StatusAccepted)
+		write(w, exportResponse)
+	}
+}
+
+// Load returns back the current status of an export job.
+func (h *ExportHandler) Load() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exportResponse, et := h.load(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		write(w, exportResponse)
+	}
+}
+
+// Download streams the file of a completed export job.
+func (h *ExportHandler) Download() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exportResponse, et := h.load(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		if exportResponse.JobStatus != models.ExportJobStatusCompleted {
+			writeError(w, r, errors.PreconditionFailed("export.not_completed", ""))
+			return
+		}
+
+		file, e := os.Open(filepath.Join(h.storageDirectory, filepath.Base(exportResponse.FileName)))
+		if e != nil {
+			if os.IsNotExist(e) {
+				writeError(w, r, errors.NotFound("export.file_not_found", ""))
+			} else {
+				et := errors.InternalServerError("unknown", "")
+				h.logger.Error(et.FingerPrint, ": ", e.Error())
+				writeError(w, r, et)
+			}
+
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		contentType := "text/csv; charset=utf-8"
+		if exportResponse.Format == models.ExportFormatNDJSON {
+			contentType = "application/x-ndjson; charset=utf-8"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+exportResponse.FileName+`"`)
+		if _, e := io.Copy(&deadlineWriter{writer: w, conn: writeConnOf(r), timeout: h.writeTimeout}, file); e != nil {
+			h.logger.Warn("Could not stream export file: ", e.Error())
+		}
+	}
+}
+
+func (h *ExportHandler) load(r *http.Request) (*data.ExportResponse, *errors.Type) {
+	id, et := parseID(r)
+	if et != nil {
+		return nil, et
+	}
+
+	in, _ := json.Marshal(data.ID{ID: id})
+	out, et := request(h.logger, h.natsClient, r, "kiosk.exports.load", in)
+	if et != nil {
+		return nil, et
+	}
+
+	exportResponse := &data.ExportResponse{}
+	_ = json.Unmarshal(out, exportResponse)
+	return exportResponse, nil
+}
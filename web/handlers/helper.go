@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
+	"github.com/gorilla/mux"
 	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
@@ -15,7 +19,7 @@ func parse(logger *zap.SugaredLogger, w http.ResponseWriter, r *http.Request, t
 		et := errors.InternalServerError("unknown", "")
 		logger.Error(et.FingerPrint, ": ", e.Error())
 
-		writeError(w, et)
+		writeError(w, r, et)
 		return false
 	}
 
@@ -23,13 +27,85 @@ func parse(logger *zap.SugaredLogger, w http.ResponseWriter, r *http.Request, t
 		et := errors.InvalidRequestBody()
 		logger.Warn(et.FingerPrint, ": Could not parse json: ", string(in))
 
-		writeError(w, et)
+		writeError(w, r, et)
 		return false
 	}
 
 	return true
 }
 
+// parseID returns back the id path parameter of the request.
+func parseID(r *http.Request) (int64, *errors.Type) {
+	id, e := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if e != nil {
+		return 0, errors.InvalidArgument("ID.invalid", "")
+	}
+
+	return id, nil
+}
+
+// withIdentityField returns back the JSON object message along with the identity field. Any identity field of the
+// message is replaced, so callers can not claim another identity.
+func withIdentityField(in []byte, identity *data.Identity) []byte {
+	fields := make(map[string]json.RawMessage)
+	if json.Unmarshal(in, &fields) != nil {
+		return in
+	}
+
+	fields["identity"], _ = json.Marshal(identity)
+	out, _ := json.Marshal(fields)
+	return out
+}
+
+// withLanguageField returns back the JSON object message along with the language field, unless it has one.
+func withLanguageField(in []byte, language string) []byte {
+	fields := make(map[string]json.RawMessage)
+	if json.Unmarshal(in, &fields) != nil {
+		return in
+	}
+
+	if _, ok := fields["language"]; ok {
+		return in
+	}
+
+	fields["language"], _ = json.Marshal(language)
+	out, _ := json.Marshal(fields)
+	return out
+}
+
+// request sends the message on the subject, along with the identity of the caller if authenticated and the language of
+// the request, and returns back the reply, or the error replied instead.
+func request(logger *zap.SugaredLogger, natsClient *nc.Conn, r *http.Request, subject string, in []byte) ([]byte,
+	*errors.Type) {
+
+	if identity := identityOf(r.Context()); identity != nil {
+		in = withIdentityField(in, identity)
+	}
+
+	if language := languageOf(r.Context()); language != "" {
+		in = withLanguageField(in, language)
+	}
+
+	response, e := natsClient.RequestWithContext(r.Context(), subject, in)
+	if e != nil {
+		if e == nc.ErrTimeout {
+			return nil, errors.RequestTimeout("")
+		}
+
+		et := errors.InternalServerError("unknown", "")
+		logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	et := &errors.Type{}
+	_ = json.Unmarshal(response.Data, et)
+	if et.FingerPrint != "" {
+		return nil, et
+	}
+
+	return response.Data, nil
+}
+
 func write(w http.ResponseWriter, t interface{}) {
 	out, _ := json.Marshal(t)
 	_, _ = w.Write(out)
@@ -39,7 +115,14 @@ func writeNoContent(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func writeError(w http.ResponseWriter, e *errors.Type) {
+// writeError writes the error, along with its messages in the language of the request.
+func writeError(w http.ResponseWriter, r *http.Request, e *errors.Type) {
+	localize(r.Context(), e)
+
+	if e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+
 	out, _ := json.Marshal(e)
 	w.WriteHeader(e.HTTPStatusCode)
 	_, _ = w.Write(out)
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/jibitters/kiosk/errors"
+)
+
+// locale is the catalogue of the messages of the errors along with the language preferences of a request.
+type locale struct {
+	catalogue *errors.Catalogue
+	language  string
+}
+
+type localeKey struct{}
+
+// withLocale returns back a copy of the context carrying the catalogue and the language preferences of the request,
+// e.g: the value of its Accept-Language header.
+func withLocale(ctx context.Context, catalogue *errors.Catalogue, language string) context.Context {
+	return context.WithValue(ctx, localeKey{}, &locale{catalogue: catalogue, language: language})
+}
+
+// languageOf returns back the language preferences carried by the context, if any.
+func languageOf(ctx context.Context) string {
+	if l, ok := ctx.Value(localeKey{}).(*locale); ok {
+		return l.language
+	}
+
+	return ""
+}
+
+// localize populates the empty messages of the error in the language carried by the context, if any.
+func localize(ctx context.Context, e *errors.Type) *errors.Type {
+	if l, ok := ctx.Value(localeKey{}).(*locale); ok {
+		return l.catalogue.Localize(e, l.language)
+	}
+
+	return e
+}
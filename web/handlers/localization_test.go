@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/web/handlers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Localization", func() {
+	catalogue := errors.NewCatalogue("en", map[string]map[string]string{
+		"en": {"unauthorized": "The request is not authenticated.", "not_found": "{field} is not found."},
+		"fa": {"unauthorized": "درخواست احراز هویت نشده است."},
+	})
+
+	message := func(w *httptest.ResponseRecorder) string {
+		et := &errors.Type{}
+		Ω(json.Unmarshal(w.Body.Bytes(), et)).Should(Succeed())
+		return et.Errors[0].Message
+	}
+
+	Context("When LocalizationMiddleware called", func() {
+		meddlers := handlers.NewMeddlers(zap.NewNop().Sugar(), nil, nil, false, catalogue)
+		handler := meddlers.LocalizationMiddleware(meddlers.AuthenticationMiddleware(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })))
+
+		It("Should populate the messages of the errors in the language of the Accept-Language header", func() {
+			for language, expected := range map[string]string{"fa-IR,fa;q=0.9": "درخواست احراز هویت نشده است.",
+				"de": "The request is not authenticated.", "": "The request is not authenticated."} {
+
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/v1/tickets", nil)
+				r.Header.Set("X-API-Key", "kiosk_0123456789abcdef_secret")
+				r.Header.Set("Accept-Language", language)
+				handler.ServeHTTP(w, r)
+
+				Ω(w.Code).Should(Equal(http.StatusUnauthorized))
+				Ω(message(w)).Should(Equal(expected))
+			}
+		})
+
+		It("Should populate the messages of the unknown routes", func() {
+			w := httptest.NewRecorder()
+			meddlers.NotFoundHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/unknown", nil))
+
+			Ω(w.Code).Should(Equal(http.StatusNotFound))
+			Ω(message(w)).Should(Equal("route is not found."))
+		})
+
+		It("Should leave the messages empty without a catalogue", func() {
+			meddlers := handlers.NewMeddlers(zap.NewNop().Sugar(), nil, nil, false, nil)
+
+			w := httptest.NewRecorder()
+			meddlers.NotFoundHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/unknown", nil))
+			Ω(message(w)).Should(BeEmpty())
+		})
+	})
+})
@@ -1,13 +1,41 @@
 package handlers
 
-import "net/http"
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
 
 // Meddlers holds different middleware implementations and provide some components for use in implementations.
-type Meddlers struct{}
+type Meddlers struct {
+	logger        *zap.SugaredLogger
+	authenticator *Authenticator
+	natsClient    *nc.Conn
+	rateLimited   bool
+	catalogue     *errors.Catalogue
+}
 
-// NewMeddlers returns a newly created and ready to use Meddlers.
-func NewMeddlers() *Meddlers {
-	return &Meddlers{}
+// NewMeddlers returns a newly created and ready to use Meddlers. Only the API keys are authenticated when the
+// authenticator is nil, and the messages of the errors are not localized when the catalogue is nil.
+func NewMeddlers(logger *zap.SugaredLogger, authenticator *Authenticator, natsClient *nc.Conn, rateLimited bool,
+	catalogue *errors.Catalogue) *Meddlers {
+
+	return &Meddlers{
+		logger:        logger,
+		authenticator: authenticator,
+		natsClient:    natsClient,
+		rateLimited:   rateLimited,
+		catalogue:     catalogue,
+	}
 }
 
 // JSONContentTypeHeaderMiddleware adds application/json content type header to responses.
@@ -17,3 +45,162 @@ func (ms *Meddlers) JSONContentTypeHeaderMiddleware(handler http.Handler) http.H
 		handler.ServeHTTP(w, r)
 	})
 }
+
+// LocalizationMiddleware selects the language of the messages of the errors by the Accept-Language header of the
+// requests, and passes it on to the nats requests of the handlers that have no language field.
+func (ms *Meddlers) LocalizationMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, ms.localized(r))
+	})
+}
+
+// localized returns back a copy of the request carrying its language, or the request itself without a catalogue.
+func (ms *Meddlers) localized(r *http.Request) *http.Request {
+	if ms.catalogue == nil {
+		return r
+	}
+
+	return r.WithContext(withLocale(r.Context(), ms.catalogue, r.Header.Get("Accept-Language")))
+}
+
+// AuthenticationMiddleware authenticates the requests by their bearer JWTs or API keys and passes the identity of the
+// caller on to the nats requests of the handlers.
+func (ms *Meddlers) AuthenticationMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+
+		key := r.Header.Get("X-API-Key")
+		if key == "" && strings.HasPrefix(authorization, "Bearer "+models.APIKeyPrefix) {
+			key = strings.TrimPrefix(authorization, "Bearer ")
+		}
+
+		if key != "" {
+			identity, et := ms.authenticateAPIKey(r, key)
+			if et != nil {
+				if et.HTTPStatusCode == http.StatusUnauthorized {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="kiosk", error="invalid_token"`)
+				}
+
+				writeError(w, r, et)
+				return
+			}
+
+			handler.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
+			return
+		}
+
+		if ms.authenticator == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kiosk"`)
+			writeError(w, r, errors.Unauthorized(""))
+			return
+		}
+
+		identity, e := ms.authenticator.Authenticate(strings.TrimPrefix(authorization, "Bearer "), time.Now())
+		if e != nil {
+			et := errors.Unauthorized("")
+			ms.logger.Warn(et.FingerPrint, ": Could not authenticate: ", e.Error())
+
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kiosk", error="invalid_token"`)
+			writeError(w, r, et)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
+	})
+}
+
+// authenticateAPIKey returns back the identity of the API key, which is authenticated on nats.
+func (ms *Meddlers) authenticateAPIKey(r *http.Request, key string) (*data.Identity, *errors.Type) {
+	if ms.natsClient == nil {
+		return nil, errors.Unauthorized("")
+	}
+
+	in, _ := json.Marshal(&data.AuthenticateAPIKeyRequest{Key: key})
+	out, et := request(ms.logger, ms.natsClient, r, "kiosk.api_keys.authenticate", in)
+	if et != nil {
+		if et.HTTPStatusCode == http.StatusUnauthorized {
+			ms.logger.Warn(et.FingerPrint, ": Could not authenticate the API key")
+		}
+
+		return nil, et
+	}
+
+	identity := &data.Identity{}
+	if e := json.Unmarshal(out, identity); e != nil {
+		et := errors.InternalServerError("unknown", "")
+		ms.logger.Error(et.FingerPrint, ": ", e.Error())
+		return nil, et
+	}
+
+	return identity, nil
+}
+
+// RateLimitMiddleware takes the http rate limits for the identity of the requests, or their remote address when they
+// have none, on nats and responds with too many requests, along with the Retry-After header, when a limit is exceeded.
+func (ms *Meddlers) RateLimitMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ms.rateLimited || ms.natsClient == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// The remote address is named only for the requests without identity, which are limited by it.
+		client := ""
+		if identityOf(r.Context()) == nil {
+			host, _, e := net.SplitHostPort(r.RemoteAddr)
+			if e != nil {
+				host = r.RemoteAddr
+			}
+
+			client = host
+		}
+
+		in, _ := json.Marshal(&data.TakeRateLimitRequest{Operation: "http", Client: client})
+		if _, et := request(ms.logger, ms.natsClient, r, "kiosk.rate_limits.take", in); et != nil {
+			if et.HTTPStatusCode == http.StatusTooManyRequests {
+				writeError(w, r, et)
+				return
+			}
+
+			ms.logger.Warn(et.FingerPrint, ": Could not take the rate limits")
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// NotFoundHandler responds to the requests matching no route.
+func (ms *Meddlers) NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		writeError(w, ms.localized(r), errors.NotFound("route.not_found", ""))
+	})
+}
+
+// MethodNotAllowedHandler responds to the requests matching a route of the router with another method, along with the
+// methods allowed by the route.
+func (ms *Meddlers) MethodNotAllowedHandler(router *mux.Router) http.Handler {
+	methods := []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := make([]string, 0, len(methods))
+		for _, method := range methods {
+			request := r.Clone(r.Context())
+			request.Method = method
+
+			match := &mux.RouteMatch{}
+			if router.Match(request, match) && match.MatchErr == nil {
+				allowed = append(allowed, method)
+			}
+		}
+
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, ms.localized(r), errors.MethodNotAllowed(""))
+	})
+}
@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+	nc "github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// StreamTokenAllOwners is the owner of the stream tokens that grant access to the tickets of all owners, e.g: agents.
+const StreamTokenAllOwners = "*"
+
+// StreamHandler is the handler implementation of real-time streams, which are Server-Sent Events of the domain events.
+type StreamHandler struct {
+	logger            *zap.SugaredLogger
+	natsClient        *nc.Conn
+	subjectPrefix     string
+	secret            string
+	tokenTTL          time.Duration
+	heartbeatInterval time.Duration
+	writeTimeout      time.Duration
+	bufferSize        int
+}
+
+// NewStreamHandler returns back a newly created and ready to use StreamHandler. Streams require a token signed with
+// the secret, issued for tokenTTL, and are disabled when the secret is empty.
+func NewStreamHandler(logger *zap.SugaredLogger, natsClient *nc.Conn, subjectPrefix, secret string,
+	tokenTTL, heartbeatInterval, writeTimeout time.Duration, bufferSize int) *StreamHandler {
+
+	return &StreamHandler{
+		logger:            logger,
+		natsClient:        natsClient,
+		subjectPrefix:     subjectPrefix,
+		secret:            secret,
+		tokenTTL:          tokenTTL,
+		heartbeatInterval: heartbeatInterval,
+		writeTimeout:      writeTimeout,
+		bufferSize:        bufferSize,
+	}
+}
+
+// Token issues a stream token of the caller, which grants the tickets of the caller alone in the owner scope.
+func (h *StreamHandler) Token() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.subjectPrefix == "" || h.secret == "" {
+			writeError(w, r, errors.ServiceUnavailable(""))
+			return
+		}
+
+		scoped, et := h.scope(r, &data.StreamTicketsRequest{})
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		owner := scoped.Owner
+		if owner == "" {
+			owner = StreamTokenAllOwners
+		}
+
+		expiresAt := time.Now().Add(h.tokenTTL)
+		w.WriteHeader(http.StatusCreated)
+		write(w, &data.StreamTokenResponse{Token: SignStreamToken(h.secret, owner, identityOf(r.Context()), expiresAt),
+			ExpiresAt: expiresAt.UTC().Format(time.RFC3339Nano)})
+	}
+}
+
+// Authenticated returns back the handler of the streams, which authenticates the requests carrying no credentials,
+// e.g: of the browsers, by the identity of their stream token and the other ones by authenticated.
+func (h *StreamHandler) Authenticated(authenticated, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.secret != "" && r.Header.Get("Authorization") == "" && r.Header.Get("X-API-Key") == "" {
+			if claims, ok := verifyStreamToken(h.secret, r.URL.Query().Get("token"), time.Now()); ok {
+				handler.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), claims.Identity)))
+				return
+			}
+		}
+
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// Tickets streams the ticket and comment events of the tickets matching the criteria values, allowed by the token and
+// in the scope of the caller.
+func (h *StreamHandler) Tickets() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.subjectPrefix == "" || h.secret == "" {
+			writeError(w, r, errors.ServiceUnavailable(""))
+			return
+		}
+
+		owner, et := h.authorize(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		ticketID, e := parseOptionalInt(r.URL.Query().Get("ticketID"))
+		if e != nil {
+			writeError(w, r, errors.InvalidArgument("ticketID.invalid", ""))
+			return
+		}
+
+		issuer := r.URL.Query().Get("issuer")
+		importanceLevel := r.URL.Query().Get("importanceLevel")
+		status := r.URL.Query().Get("status")
+
+		streamTicketsRequest := &data.StreamTicketsRequest{TicketID: ticketID, Issuer: issuer,
+			Owner: r.URL.Query().Get("owner"), ImportanceLevel: models.TicketImportanceLevel(importanceLevel),
+			Status: models.TicketStatus(status)}
+
+		if owner != StreamTokenAllOwners {
+			if streamTicketsRequest.Owner != "" && streamTicketsRequest.Owner != owner {
+				writeError(w, r, errors.Unauthorized("token does not grant access to the owner"))
+				return
+			}
+
+			streamTicketsRequest.Owner = owner
+		}
+
+		if et := streamTicketsRequest.Validate(); et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		streamTicketsRequest, et = h.scope(r, streamTicketsRequest)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("lastEventID")
+		}
+
+		after, e := parseOptionalInt(lastEventID)
+		if e != nil {
+			writeError(w, r, errors.InvalidArgument("lastEventID.invalid", ""))
+			return
+		}
+
+		// Subscribe before replaying, so no event falls in between. Events seen in both are only pushed once.
+		events := make(chan *nc.Msg, h.bufferSize)
+		subscription, e := h.natsClient.ChanSubscribe(h.subjectPrefix+".>", events)
+		if e != nil {
+			et := errors.InternalServerError("unknown", "")
+			h.logger.Error(et.FingerPrint, ": ", e.Error())
+			writeError(w, r, et)
+			return
+		}
+		defer func() { _ = subscription.Unsubscribe() }()
+
+		var replayed []*data.EventEnvelope
+		reset := false
+		if after > 0 {
+			replayEventsResponse, et := h.replay(r, after)
+			if et != nil && et.Errors[0].Code != "event.expired" {
+				writeError(w, r, et)
+				return
+			}
+
+			if et != nil || replayEventsResponse.HasMore {
+				reset = true
+			} else {
+				replayed = replayEventsResponse.Events
+			}
+		}
+
+		h.stream(w, r, streamTicketsRequest, subscription, events, replayed, reset)
+	}
+}
+
+// stream pushes the events until the client goes away. A reset event tells the client that the events since the last
+// one it saw are no longer available, so it should reload the tickets.
+func (h *StreamHandler) stream(w http.ResponseWriter, r *http.Request,
+	streamTicketsRequest *data.StreamTicketsRequest, subscription *nc.Subscription, events chan *nc.Msg,
+	replayed []*data.EventEnvelope, reset bool) {
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.ServiceUnavailable(""))
+		return
+	}
+
+	// Streams outlive the write timeout of the server, so the deadline is extended by every write instead.
+	conn := writeConnOf(r)
+
+	send := func(message string) bool {
+		if conn != nil {
+			_ = conn.SetWriteDeadline(time.Now().Add(h.writeTimeout))
+		}
+
+		if _, e := io.WriteString(w, message); e != nil {
+			return false
+		}
+
+		flusher.Flush()
+		return true
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if reset && !send("event: reset\ndata: {}\n\n") {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, envelope := range replayed {
+		seen[envelope.ID] = true
+
+		if streamTicketsRequest.Matches(envelope) && !send(formatStreamEvent(envelope)) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(h.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if !send(": heartbeat\n\n") {
+				return
+			}
+		case msg := <-events:
+			if dropped, _ := subscription.Dropped(); dropped > 0 {
+				h.logger.Warn("StreamHandler: dropped ", dropped, " events of a slow client, closing the stream")
+				return
+			}
+
+			envelope := &data.EventEnvelope{}
+			if e := json.Unmarshal(msg.Data, envelope); e != nil {
+				continue
+			}
+
+			if seen[envelope.ID] {
+				delete(seen, envelope.ID)
+				continue
+			}
+
+			if streamTicketsRequest.Matches(envelope) && !send(formatStreamEvent(envelope)) {
+				return
+			}
+		}
+	}
+}
+
+// scope returns back the criteria values restricted to the scope of the caller by the policy.
+func (h *StreamHandler) scope(r *http.Request, streamTicketsRequest *data.StreamTicketsRequest) (
+	*data.StreamTicketsRequest, *errors.Type) {
+
+	in, _ := json.Marshal(streamTicketsRequest)
+	out, et := request(h.logger, h.natsClient, r, "kiosk.streams.tickets", in)
+	if et != nil {
+		return nil, et
+	}
+
+	scoped := &data.StreamTicketsRequest{}
+	_ = json.Unmarshal(out, scoped)
+	return scoped, nil
+}
+
+func (h *StreamHandler) replay(r *http.Request, after int64) (*data.ReplayEventsResponse, *errors.Type) {
+	in, _ := json.Marshal(data.ReplayEventsRequest{After: after})
+	out, et := request(h.logger, h.natsClient, r, "kiosk.streams.replay", in)
+	if et != nil {
+		return nil, et
+	}
+
+	replayEventsResponse := &data.ReplayEventsResponse{}
+	_ = json.Unmarshal(out, replayEventsResponse)
+	return replayEventsResponse, nil
+}
+
+// authorize returns back the owner granted by the token query parameter of the request.
+func (h *StreamHandler) authorize(r *http.Request) (string, *errors.Type) {
+	claims, ok := verifyStreamToken(h.secret, r.URL.Query().Get("token"), time.Now())
+	if !ok {
+		return "", errors.Unauthorized("")
+	}
+
+	return claims.Owner, nil
+}
+
+func formatStreamEvent(envelope *data.EventEnvelope) string {
+	out, _ := json.Marshal(envelope)
+	return "id: " + strconv.FormatInt(envelope.Sequence, 10) + "\nevent: " + envelope.Type + "\ndata: " + string(out) +
+		"\n\n"
+}
+
+func parseOptionalInt(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// streamTokenClaims are the claims of a stream token: the owner whose tickets are streamed and the identity of the
+// caller, if any, which the streams are authenticated by when the requests carry no credentials.
+type streamTokenClaims struct {
+	Owner    string         `json:"owner"`
+	Identity *data.Identity `json:"identity,omitempty"`
+}
+
+// SignStreamToken returns back a stream token of the owner and the identity, i.e. "<claims>.<expiry>.<signature>"
+// with the JSON claims base64 URL encoded and the signature the hex encoded HMAC-SHA256 of "<claims>.<expiry>" keyed
+// with the secret.
+func SignStreamToken(secret, owner string, identity *data.Identity, expiresAt time.Time) string {
+	claims, _ := json.Marshal(&streamTokenClaims{Owner: owner, Identity: identity})
+	payload := base64.RawURLEncoding.EncodeToString(claims) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(payload))
+
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyStreamToken returns back the claims of the token if it is signed with the secret and not expired at now.
+func verifyStreamToken(secret, token string, now time.Time) (*streamTokenClaims, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, e := hex.DecodeString(parts[2])
+	if e != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, false
+	}
+
+	expiresAt, e := strconv.ParseInt(parts[1], 10, 64)
+	if e != nil || now.Unix() >= expiresAt {
+		return nil, false
+	}
+
+	in, e := base64.RawURLEncoding.DecodeString(parts[0])
+	if e != nil {
+		return nil, false
+	}
+
+	claims := &streamTokenClaims{}
+	if e := json.Unmarshal(in, claims); e != nil {
+		return nil, false
+	}
+
+	return claims, true
+}
+
+type connKey struct{}
+
+// ConnContext stores the connection of the requests in their context, so the streams can extend its write deadline.
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// connOf returns back the connection of the request, if any.
+func connOf(ctx context.Context) net.Conn {
+	conn, _ := ctx.Value(connKey{}).(net.Conn)
+	return conn
+}
+
+// writeConnOf returns back the connection whose write deadline the request may extend, if any. HTTP/2 connections are
+// shared by other requests, so their deadline is left to the server.
+func writeConnOf(r *http.Request) net.Conn {
+	if r.ProtoMajor != 1 {
+		return nil
+	}
+
+	return connOf(r.Context())
+}
+
+// deadlineWriter extends the write deadline of the connection, if any, by the timeout before every write.
+type deadlineWriter struct {
+	writer  io.Writer
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	if w.conn != nil {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+
+	return w.writer.Write(p)
+}
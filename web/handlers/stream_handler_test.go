@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/jibitters/kiosk/web/handlers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("StreamHandler", func() {
+	streamHandler := handlers.NewStreamHandler(zap.NewNop().Sugar(), nil, "kiosk.events", "secret", time.Hour,
+		time.Second, time.Second, 1)
+
+	// handler replies with the status, so the handler that served a request is told apart by its status.
+	handler := func(status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(status) })
+	}
+
+	serve := func(token string, header http.Header) int {
+		r := httptest.NewRequest(http.MethodGet, "/v1/streams/tickets?token="+url.QueryEscape(token), nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+
+		w := httptest.NewRecorder()
+		streamHandler.Authenticated(handler(http.StatusUnauthorized), handler(http.StatusOK)).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	Context("When Authenticated called", func() {
+		identity := &data.Identity{Subject: "user@example.com", Roles: []string{"customer"}}
+
+		It("Should authenticate the requests without credentials by their stream token", func() {
+			token := handlers.SignStreamToken("secret", "user@example.com", identity, time.Now().Add(time.Minute))
+			Ω(serve(token, nil)).Should(Equal(http.StatusOK))
+		})
+
+		It("Should authenticate the other requests like the other routes", func() {
+			token := handlers.SignStreamToken("secret", "user@example.com", identity, time.Now().Add(time.Minute))
+			Ω(serve(token, http.Header{"Authorization": {"Bearer jwt"}})).Should(Equal(http.StatusUnauthorized))
+			Ω(serve(token, http.Header{"X-Api-Key": {"kiosk_key"}})).Should(Equal(http.StatusUnauthorized))
+
+			expired := handlers.SignStreamToken("secret", "user@example.com", identity, time.Now().Add(-time.Minute))
+			Ω(serve(expired, nil)).Should(Equal(http.StatusUnauthorized))
+
+			forged := handlers.SignStreamToken("forged", "user@example.com", identity, time.Now().Add(time.Minute))
+			Ω(serve(forged, nil)).Should(Equal(http.StatusUnauthorized))
+			Ω(serve("", nil)).Should(Equal(http.StatusUnauthorized))
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"path"
 	"strconv"
 
 	"github.com/jibitters/kiosk/errors"
@@ -29,24 +30,89 @@ func (h *TicketHandler) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		in, _ := ioutil.ReadAll(r.Body)
 
-		response, e := h.natsClient.RequestWithContext(r.Context(), "kiosk.tickets.create", in)
-		if e != nil {
-			if e == nc.ErrTimeout {
-				et := errors.RequestTimeout("")
-				writeError(w, et)
-			} else {
-				et := errors.InternalServerError("unknown", "")
-				h.logger.Error(et.FingerPrint, ": ", e.Error())
-				writeError(w, et)
-			}
+		out, et := request(h.logger, h.natsClient, r, "kiosk.tickets.create", in)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		id := &data.ID{}
+		_ = json.Unmarshal(out, id)
+		w.Header().Set("Location", path.Join(r.URL.Path, strconv.FormatInt(id.ID, 10)))
+		w.WriteHeader(http.StatusCreated)
+		write(w, id)
+	}
+}
+
+// Load returns back a ticket along with its comments.
+func (h *TicketHandler) Load() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ticketResponse, et := h.load(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		write(w, ticketResponse)
+	}
+}
+
+// Update replaces the changeable information of a ticket.
+func (h *TicketHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, et := parseID(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		updateTicketRequest := &data.UpdateTicketRequest{}
+		if ok := parse(h.logger, w, r, updateTicketRequest); !ok {
+			return
+		}
+		updateTicketRequest.ID = id
+
+		h.update(w, r, updateTicketRequest)
+	}
+}
+
+// Patch changes the provided information of a ticket, keeping the rest as they are.
+func (h *TicketHandler) Patch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, et := parseID(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		patchTicketRequest := &data.PatchTicketRequest{}
+		if ok := parse(h.logger, w, r, patchTicketRequest); !ok {
+			return
+		}
+		patchTicketRequest.ID = id
+
+		in, _ := json.Marshal(patchTicketRequest)
+		if _, et := request(h.logger, h.natsClient, r, "kiosk.tickets.patch", in); et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		writeNoContent(w)
+	}
+}
 
+// Delete deletes a ticket along with its comments.
+func (h *TicketHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, et := parseID(r)
+		if et != nil {
+			writeError(w, r, et)
 			return
 		}
 
-		et := &errors.Type{}
-		_ = json.Unmarshal(response.Data, et)
-		if et.FingerPrint != "" {
-			writeError(w, et)
+		in, _ := json.Marshal(data.ID{ID: id})
+		if _, et := request(h.logger, h.natsClient, r, "kiosk.tickets.delete", in); et != nil {
+			writeError(w, r, et)
 			return
 		}
 
@@ -54,6 +120,24 @@ func (h *TicketHandler) Create() http.HandlerFunc {
 	}
 }
 
+// Comments returns back the comments of a ticket.
+func (h *TicketHandler) Comments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ticketResponse, et := h.load(r)
+		if et != nil {
+			writeError(w, r, et)
+			return
+		}
+
+		comments := ticketResponse.Comments
+		if comments == nil {
+			comments = make([]*data.CommentResponse, 0)
+		}
+
+		write(w, comments)
+	}
+}
+
 // Filter filters tickets based on provided criteria values.
 func (h *TicketHandler) Filter() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -71,29 +155,41 @@ func (h *TicketHandler) Filter() http.HandlerFunc {
 			FromDate: fromDate, ToDate: toDate, PageNumber: pageNumber, PageSize: pageSize}
 
 		in, _ := json.Marshal(filterTicketsRequest)
-		response, e := h.natsClient.RequestWithContext(r.Context(), "kiosk.tickets.filter", in)
-		if e != nil {
-			if e == nc.ErrTimeout {
-				et := errors.RequestTimeout("")
-				writeError(w, et)
-			} else {
-				et := errors.InternalServerError("unknown", "")
-				h.logger.Error(et.FingerPrint, ": ", e.Error())
-				writeError(w, et)
-			}
-
-			return
-		}
-
-		et := &errors.Type{}
-		_ = json.Unmarshal(response.Data, et)
-		if et.FingerPrint != "" {
-			writeError(w, et)
+		out, et := request(h.logger, h.natsClient, r, "kiosk.tickets.filter", in)
+		if et != nil {
+			writeError(w, r, et)
 			return
 		}
 
 		filterTicketsResponse := &data.FilterTicketsResponse{}
-		_ = json.Unmarshal(response.Data, filterTicketsResponse)
+		_ = json.Unmarshal(out, filterTicketsResponse)
 		write(w, filterTicketsResponse)
 	}
 }
+
+func (h *TicketHandler) load(r *http.Request) (*data.TicketResponse, *errors.Type) {
+	id, et := parseID(r)
+	if et != nil {
+		return nil, et
+	}
+
+	in, _ := json.Marshal(data.ID{ID: id})
+	out, et := request(h.logger, h.natsClient, r, "kiosk.tickets.load", in)
+	if et != nil {
+		return nil, et
+	}
+
+	ticketResponse := &data.TicketResponse{}
+	_ = json.Unmarshal(out, ticketResponse)
+	return ticketResponse, nil
+}
+
+func (h *TicketHandler) update(w http.ResponseWriter, r *http.Request, updateTicketRequest *data.UpdateTicketRequest) {
+	in, _ := json.Marshal(updateTicketRequest)
+	if _, et := request(h.logger, h.natsClient, r, "kiosk.tickets.update", in); et != nil {
+		writeError(w, r, et)
+		return
+	}
+
+	writeNoContent(w)
+}
@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/jibitters/kiosk/web/handlers"
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	nc "github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("TicketHandler", func() {
+	var natsServer *server.Server
+	var natsClient *nc.Conn
+
+	BeforeEach(func() {
+		options := natsserver.DefaultTestOptions
+		options.Port = -1
+		natsServer = natsserver.RunServer(&options)
+
+		c, e := nc.Connect(natsServer.ClientURL())
+		Ω(e).Should(BeNil())
+		natsClient = c
+	})
+
+	AfterEach(func() {
+		natsClient.Close()
+		natsServer.Shutdown()
+	})
+
+	Context("When Create called", func() {
+		It("Should respond with the id and the location of the created ticket", func() {
+			_, e := natsClient.Subscribe("kiosk.tickets.create", func(msg *nc.Msg) {
+				_ = msg.Respond([]byte(`{"ID":7}`))
+			})
+			Ω(e).Should(BeNil())
+			Ω(natsClient.Flush()).Should(Succeed())
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/tickets", strings.NewReader(`{}`))
+			w := httptest.NewRecorder()
+			handlers.NewTicketHandler(zap.NewNop().Sugar(), natsClient).Create().ServeHTTP(w, r)
+
+			Ω(w.Code).Should(Equal(http.StatusCreated))
+			Ω(w.Header().Get("Location")).Should(Equal("/v1/tickets/7"))
+			Ω(w.Body.String()).Should(Equal(`{"ID":7}`))
+		})
+	})
+})
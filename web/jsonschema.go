@@ -0,0 +1,159 @@
+package web
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+)
+
+// enums holds the values of the string types of the models, which can not be found through reflection.
+var enums = map[reflect.Type][]string{
+	reflect.TypeOf(models.ActorType("")): {string(models.ActorTypeUser), string(models.ActorTypeClient),
+		string(models.ActorTypeRule), string(models.ActorTypeEscalation)},
+	reflect.TypeOf(models.EscalationAction("")): {string(models.EscalationActionRaiseImportanceLevel),
+		string(models.EscalationActionReassign), string(models.EscalationActionNotify)},
+	reflect.TypeOf(models.EventType("")): {string(models.EventTypeTicketCreated),
+		string(models.EventTypeTicketUpdated), string(models.EventTypeTicketDeleted),
+		string(models.EventTypeCommentCreated), string(models.EventTypeCommentUpdated),
+		string(models.EventTypeCommentDeleted)},
+	reflect.TypeOf(models.ExportFormat("")): {string(models.ExportFormatCSV), string(models.ExportFormatNDJSON)},
+	reflect.TypeOf(models.ExportJobStatus("")): {string(models.ExportJobStatusPending),
+		string(models.ExportJobStatusRunning), string(models.ExportJobStatusCompleted),
+		string(models.ExportJobStatusFailed)},
+	reflect.TypeOf(models.ImportFormat("")): {string(models.ImportFormatCSV), string(models.ImportFormatNDJSON)},
+	reflect.TypeOf(models.ImportStatus("")): {string(models.ImportStatusPending), string(models.ImportStatusRunning),
+		string(models.ImportStatusCompleted), string(models.ImportStatusFailed)},
+	reflect.TypeOf(models.MacroActionType("")): {string(models.MacroActionTypeSetStatus),
+		string(models.MacroActionTypeSetImportanceLevel), string(models.MacroActionTypeSetSubject),
+		string(models.MacroActionTypeAddTag), string(models.MacroActionTypeRemoveTag),
+		string(models.MacroActionTypeSetMetadata), string(models.MacroActionTypeAddComment)},
+	reflect.TypeOf(models.NotificationEvent("")): {string(models.NotificationEventTicketCreated),
+		string(models.NotificationEventCommentAdded), string(models.NotificationEventStatusChanged)},
+	reflect.TypeOf(models.NotificationStatus("")): {string(models.NotificationStatusPending),
+		string(models.NotificationStatusSent), string(models.NotificationStatusFailed)},
+	reflect.TypeOf(models.RuleActionType("")): {string(models.RuleActionTypeSetStatus),
+		string(models.RuleActionTypeSetImportanceLevel), string(models.RuleActionTypeSetSubject),
+		string(models.RuleActionTypeAddTag), string(models.RuleActionTypeRemoveTag),
+		string(models.RuleActionTypeSetMetadata), string(models.RuleActionTypeAddComment),
+		string(models.RuleActionTypePublishMessage), string(models.RuleActionTypeCallWebhook)},
+	reflect.TypeOf(models.RuleConditionOperator("")): {string(models.RuleConditionOperatorEquals),
+		string(models.RuleConditionOperatorNotEquals), string(models.RuleConditionOperatorContains),
+		string(models.RuleConditionOperatorMatches), string(models.RuleConditionOperatorGreaterThan),
+		string(models.RuleConditionOperatorLessThan)},
+	reflect.TypeOf(models.RuleExecutionStatus("")): {string(models.RuleExecutionStatusApplied),
+		string(models.RuleExecutionStatusSkipped), string(models.RuleExecutionStatusFailed)},
+	reflect.TypeOf(models.TicketImportanceLevel("")): {string(models.TicketImportanceLevelLow),
+		string(models.TicketImportanceLevelMedium), string(models.TicketImportanceLevelHigh),
+		string(models.TicketImportanceLevelCritical)},
+	reflect.TypeOf(models.TicketStatus("")): {string(models.TicketStatusNew), string(models.TicketStatusReplied),
+		string(models.TicketStatusResolved), string(models.TicketStatusClosed), string(models.TicketStatusBlocked)},
+	reflect.TypeOf(models.WebhookDeliveryStatus("")): {string(models.WebhookDeliveryStatusPending),
+		string(models.WebhookDeliveryStatusDelivered), string(models.WebhookDeliveryStatusFailed)},
+}
+
+// names holds the names of the definitions whose type names are not descriptive enough.
+var names = map[reflect.Type]string{
+	reflect.TypeOf(errors.Type{}):  "ErrorResponse",
+	reflect.TypeOf(errors.Error{}): "ErrorDetail",
+}
+
+// schemaGenerator generates JSON schemas of the request and response models, following their JSON encoding. Every
+// struct is generated once as a definition and is referenced by its name, prefixed with the reference prefix.
+type schemaGenerator struct {
+	referencePrefix string
+	definitions     map[string]interface{}
+}
+
+func newSchemaGenerator(referencePrefix string) *schemaGenerator {
+	return &schemaGenerator{referencePrefix: referencePrefix, definitions: make(map[string]interface{})}
+}
+
+// reference returns back the schema referencing the definition of the model, or nil when the model is nil.
+func (g *schemaGenerator) reference(model interface{}) map[string]interface{} {
+	if model == nil {
+		return nil
+	}
+
+	return g.schema(reflect.TypeOf(model))
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if values, ok := enums[t]; ok {
+		return map[string]interface{}{"type": "string", "enum": values}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem()), "minItems": t.Len(),
+			"maxItems": t.Len()}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Struct:
+		name := g.name(t)
+		if _, ok := g.definitions[name]; !ok {
+			// Reserve the name first, as models may refer to themselves.
+			g.definitions[name] = nil
+			g.definitions[name] = map[string]interface{}{"type": "object", "properties": g.properties(t)}
+		}
+
+		return map[string]interface{}{"$ref": g.referencePrefix + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (g *schemaGenerator) properties(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && name == field.Name {
+			for n, p := range g.properties(field.Type) {
+				properties[n] = p
+			}
+
+			continue
+		}
+
+		properties[name] = g.schema(field.Type)
+	}
+
+	return properties
+}
+
+func (g *schemaGenerator) name(t reflect.Type) string {
+	if name, ok := names[t]; ok {
+		return name
+	}
+
+	return t.Name()
+}
@@ -0,0 +1,180 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/web/data"
+)
+
+// operation describes a route of the HTTP API. Operations without response reply with no content on success.
+type operation struct {
+	method      string
+	path        string
+	summary     string
+	parameters  []parameter
+	request     interface{}
+	status      int
+	response    interface{}
+	contentType string
+	public      bool
+}
+
+// parameter describes a path or query parameter of an operation.
+type parameter struct {
+	name        string
+	in          string
+	schema      string
+	description string
+}
+
+var idParameter = parameter{"id", "path", "integer", "Identifier of the resource."}
+
+var operations = []operation{
+	{method: http.MethodPost, path: echo, summary: "Returns back the same message that receives.",
+		request: data.EchoRequest{}, status: http.StatusOK, response: data.EchoRequest{}},
+	{method: http.MethodPost, path: tickets, summary: "Creates a new ticket.",
+		request: data.CreateTicketRequest{}, status: http.StatusCreated, response: data.ID{}},
+	{method: http.MethodGet, path: tickets, summary: "Filters tickets based on provided criteria values.",
+		parameters: []parameter{
+			{"issuer", "query", "string", ""},
+			{"owner", "query", "string", ""},
+			{"importanceLevel", "query", "string", ""},
+			{"status", "query", "string", ""},
+			{"fromDate", "query", "string", "RFC 3339 date time."},
+			{"toDate", "query", "string", "RFC 3339 date time."},
+			{"pageNumber", "query", "integer", ""},
+			{"pageSize", "query", "integer", ""},
+		}, status: http.StatusOK, response: data.FilterTicketsResponse{}},
+	{method: http.MethodGet, path: tickets + "/{id}", summary: "Loads a ticket along with its comments.",
+		parameters: []parameter{idParameter}, status: http.StatusOK, response: data.TicketResponse{}},
+	{method: http.MethodPut, path: tickets + "/{id}", summary: "Replaces the changeable information of a ticket.",
+		parameters: []parameter{idParameter}, request: data.UpdateTicketRequest{}, status: http.StatusNoContent},
+	{method: http.MethodPatch, path: tickets + "/{id}", summary: "Changes the provided information of a ticket.",
+		parameters: []parameter{idParameter}, request: data.PatchTicketRequest{}, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: tickets + "/{id}", summary: "Deletes a ticket along with its comments.",
+		parameters: []parameter{idParameter}, status: http.StatusNoContent},
+	{method: http.MethodGet, path: tickets + "/{id}" + comments, summary: "Loads the comments of a ticket.",
+		parameters: []parameter{idParameter}, status: http.StatusOK, response: []data.CommentResponse{}},
+	{method: http.MethodPost, path: tickets + "/{id}" + comments, summary: "Creates a new comment for a ticket.",
+		parameters: []parameter{idParameter}, request: data.CreateCommentRequest{}, status: http.StatusNoContent},
+	{method: http.MethodPost, path: comments, summary: "Creates a new comment.",
+		request: data.CreateCommentRequest{}, status: http.StatusNoContent},
+	{method: http.MethodGet, path: comments + "/{id}", summary: "Loads a comment.",
+		parameters: []parameter{idParameter}, status: http.StatusOK, response: data.CommentResponse{}},
+	{method: http.MethodPut, path: comments + "/{id}", summary: "Replaces the changeable information of a comment.",
+		parameters: []parameter{idParameter}, request: data.UpdateCommentRequest{}, status: http.StatusNoContent},
+	{method: http.MethodPatch, path: comments + "/{id}", summary: "Changes the provided information of a comment.",
+		parameters: []parameter{idParameter}, request: data.PatchCommentRequest{}, status: http.StatusNoContent},
+	{method: http.MethodDelete, path: comments + "/{id}", summary: "Deletes a comment.",
+		parameters: []parameter{idParameter}, status: http.StatusNoContent},
+	{method: http.MethodPost, path: exports, summary: "Creates a new export job.",
+		request: data.CreateExportRequest{}, status: http.StatusAccepted, response: data.ExportResponse{}},
+	{method: http.MethodGet, path: exports + "/{id}", summary: "Loads the current status of an export job.",
+		parameters: []parameter{idParameter}, status: http.StatusOK, response: data.ExportResponse{}},
+	{method: http.MethodGet, path: exports + "/{id}/download", summary: "Downloads the file of a completed export job.",
+		parameters: []parameter{idParameter}, status: http.StatusOK, contentType: "application/octet-stream"},
+	{method: http.MethodPost, path: streams + tokens, summary: "Issues a stream token of the caller.",
+		status: http.StatusCreated, response: data.StreamTokenResponse{}},
+	{method: http.MethodGet, path: streams + tickets,
+		summary: "Streams the ticket and comment events as Server-Sent Events, each carrying an event envelope.",
+		parameters: []parameter{
+			{"ticketID", "query", "integer", ""},
+			{"issuer", "query", "string", ""},
+			{"owner", "query", "string", ""},
+			{"importanceLevel", "query", "string", ""},
+			{"status", "query", "string", ""},
+			{"lastEventID", "query", "integer", "Sequence of the last event seen, same as Last-Event-ID header."},
+			{"token", "query", "string", "Stream token signed with the secret of the streams."},
+		}, status: http.StatusOK, contentType: "text/event-stream"},
+	{method: http.MethodGet, path: metrics, summary: "Exposes prometheus metrics.",
+		status: http.StatusOK, contentType: "text/plain", public: true},
+	{method: http.MethodGet, path: openapi, summary: "Returns back this document.",
+		status: http.StatusOK, response: map[string]interface{}{}, public: true},
+	{method: http.MethodGet, path: schemas, summary: "Returns back the JSON schemas of the nats subjects.",
+		status: http.StatusOK, response: map[string]interface{}{}, public: true},
+}
+
+// OpenAPI returns back the OpenAPI 3 document of the HTTP API.
+func OpenAPI() map[string]interface{} {
+	generator := newSchemaGenerator("#/components/schemas/")
+	errorResponse := map[string]interface{}{"description": "Error", "content": map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": generator.reference(errors.Type{})}}}
+
+	paths := make(map[string]interface{})
+	for _, o := range operations {
+		responses := map[string]interface{}{"default": errorResponse}
+		success := map[string]interface{}{"description": http.StatusText(o.status)}
+
+		switch {
+		case o.contentType != "":
+			success["content"] = map[string]interface{}{o.contentType: map[string]interface{}{
+				"schema": map[string]interface{}{"type": "string"}}}
+		case o.response != nil:
+			success["content"] = map[string]interface{}{"application/json": map[string]interface{}{
+				"schema": generator.reference(o.response)}}
+		}
+		responses[strconv.Itoa(o.status)] = success
+
+		operation := map[string]interface{}{"summary": o.summary, "responses": responses}
+
+		if len(o.parameters) > 0 {
+			parameters := make([]interface{}, 0, len(o.parameters))
+			for _, p := range o.parameters {
+				parameter := map[string]interface{}{"name": p.name, "in": p.in, "required": p.in == "path",
+					"schema": map[string]interface{}{"type": p.schema}}
+				if p.description != "" {
+					parameter["description"] = p.description
+				}
+
+				parameters = append(parameters, parameter)
+			}
+
+			operation["parameters"] = parameters
+		}
+
+		if o.request != nil {
+			operation["requestBody"] = map[string]interface{}{"required": true, "content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": generator.reference(o.request)}}}
+		}
+
+		switch {
+		case o.path == streams+tickets:
+			operation["security"] = []interface{}{
+				map[string]interface{}{"bearerAuth": []interface{}{}, "streamToken": []interface{}{}},
+				map[string]interface{}{"apiKey": []interface{}{}, "streamToken": []interface{}{}},
+				map[string]interface{}{"streamToken": []interface{}{}}}
+		case !o.public:
+			operation["security"] = []interface{}{map[string]interface{}{"bearerAuth": []interface{}{}},
+				map[string]interface{}{"apiKey": []interface{}{}}}
+		}
+
+		path, ok := paths[v1+o.path].(map[string]interface{})
+		if !ok {
+			path = make(map[string]interface{})
+			paths[v1+o.path] = path
+		}
+		path[strings.ToLower(o.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Kiosk",
+			"version": "v1",
+			"description": "A typical ticketing system that is designed for micro services environment. The HTTP API " +
+				"proxies the requests to the nats subjects described on " + v1 + schemas + ".",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": generator.definitions,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth":  map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"streamToken": map[string]interface{}{"type": "apiKey", "in": "query", "name": "token"},
+				"apiKey":      map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web/data"
+)
+
+// subject describes the request and the response of a nats subject. Subjects without response reply with an empty
+// message on success. Every subject replies with an error response on failure.
+type subject struct {
+	name     string
+	request  interface{}
+	response interface{}
+}
+
+var subjects = []subject{
+	{"kiosk.tickets.create", data.CreateTicketRequest{}, data.ID{}},
+	{"kiosk.tickets.load", data.ID{}, data.TicketResponse{}},
+	{"kiosk.tickets.update", data.UpdateTicketRequest{}, nil},
+	{"kiosk.tickets.patch", data.PatchTicketRequest{}, nil},
+	{"kiosk.tickets.delete", data.ID{}, nil},
+	{"kiosk.tickets.filter", data.FilterTicketsRequest{}, data.FilterTicketsResponse{}},
+	{"kiosk.tickets.rate", data.RateTicketRequest{}, nil},
+	{"kiosk.comments.create", data.CreateCommentRequest{}, nil},
+	{"kiosk.comments.create_from_template", data.CreateCommentFromTemplateRequest{}, data.RenderedCommentResponse{}},
+	{"kiosk.comments.load", data.ID{}, data.CommentResponse{}},
+	{"kiosk.comments.update", data.UpdateCommentRequest{}, nil},
+	{"kiosk.comments.delete", data.ID{}, nil},
+	{"kiosk.exports.create", data.CreateExportRequest{}, data.ExportResponse{}},
+	{"kiosk.exports.load", data.ID{}, data.ExportResponse{}},
+	{"kiosk.imports.create", data.CreateImportRequest{}, data.ImportResponse{}},
+	{"kiosk.imports.load", data.ID{}, data.ImportResponse{}},
+	{"kiosk.canned_responses.create", data.CreateCannedResponseRequest{}, nil},
+	{"kiosk.canned_responses.load", data.ID{}, data.CannedResponseResponse{}},
+	{"kiosk.canned_responses.update", data.UpdateCannedResponseRequest{}, nil},
+	{"kiosk.canned_responses.delete", data.ID{}, nil},
+	{"kiosk.canned_responses.filter", data.FilterCannedResponsesRequest{}, data.FilterCannedResponsesResponse{}},
+	{"kiosk.macros.create", data.CreateMacroRequest{}, nil},
+	{"kiosk.macros.load", data.ID{}, data.MacroResponse{}},
+	{"kiosk.macros.update", data.UpdateMacroRequest{}, nil},
+	{"kiosk.macros.delete", data.ID{}, nil},
+	{"kiosk.macros.filter", data.FilterMacrosRequest{}, data.FilterMacrosResponse{}},
+	{"kiosk.macros.execute", data.ExecuteMacroRequest{}, data.TicketResponse{}},
+	{"kiosk.rules.create", data.CreateRuleRequest{}, nil},
+	{"kiosk.rules.load", data.ID{}, data.RuleResponse{}},
+	{"kiosk.rules.update", data.UpdateRuleRequest{}, nil},
+	{"kiosk.rules.delete", data.ID{}, nil},
+	{"kiosk.rules.filter", data.FilterRulesRequest{}, data.FilterRulesResponse{}},
+	{"kiosk.rules.test", data.TestRuleRequest{}, data.TestRuleResponse{}},
+	{"kiosk.rules.executions", data.FilterRuleExecutionsRequest{}, data.FilterRuleExecutionsResponse{}},
+	{"kiosk.escalation_policies.create", data.CreateEscalationPolicyRequest{}, nil},
+	{"kiosk.escalation_policies.load", data.ID{}, data.EscalationPolicyResponse{}},
+	{"kiosk.escalation_policies.update", data.UpdateEscalationPolicyRequest{}, nil},
+	{"kiosk.escalation_policies.delete", data.ID{}, nil},
+	{"kiosk.escalation_policies.filter", data.FilterEscalationPoliciesRequest{},
+		data.FilterEscalationPoliciesResponse{}},
+	{"kiosk.escalations.filter", data.FilterEscalationsRequest{}, data.EscalationsResponse{}},
+	{"kiosk.reports.csat", data.CSATReportRequest{}, data.CSATReportResponse{}},
+	{"kiosk.notifications.filter", data.FilterNotificationsRequest{}, data.FilterNotificationsResponse{}},
+	{"kiosk.webhooks.create", data.CreateWebhookRequest{}, nil},
+	{"kiosk.webhooks.load", data.ID{}, data.WebhookResponse{}},
+	{"kiosk.webhooks.update", data.UpdateWebhookRequest{}, nil},
+	{"kiosk.webhooks.delete", data.ID{}, nil},
+	{"kiosk.webhooks.filter", data.FilterWebhooksRequest{}, data.FilterWebhooksResponse{}},
+	{"kiosk.webhooks.deliveries", data.FilterWebhookDeliveriesRequest{}, data.FilterWebhookDeliveriesResponse{}},
+	{"kiosk.webhooks.redeliver", data.ID{}, nil},
+	{"kiosk.streams.tickets", data.StreamTicketsRequest{}, data.StreamTicketsRequest{}},
+	{"kiosk.streams.replay", data.ReplayEventsRequest{}, data.ReplayEventsResponse{}},
+	{"kiosk.api_keys.create", data.CreateAPIKeyRequest{}, data.APIKeyResponse{}},
+	{"kiosk.api_keys.rotate", data.RotateAPIKeyRequest{}, data.APIKeyResponse{}},
+	{"kiosk.api_keys.revoke", data.ID{}, nil},
+	{"kiosk.api_keys.filter", data.FilterAPIKeysRequest{}, data.FilterAPIKeysResponse{}},
+	{"kiosk.api_keys.authenticate", data.AuthenticateAPIKeyRequest{}, data.Identity{}},
+	{"kiosk.rate_limits.take", data.TakeRateLimitRequest{}, nil},
+}
+
+// events holds the types of the domain events published on nats, which are subjects under the events prefix.
+var events = []models.OutboxMessageType{
+	models.OutboxMessageTypeTicketCreated,
+	models.OutboxMessageTypeTicketUpdated,
+	models.OutboxMessageTypeTicketStatusChanged,
+	models.OutboxMessageTypeTicketDeleted,
+	models.OutboxMessageTypeCommentCreated,
+	models.OutboxMessageTypeCommentUpdated,
+	models.OutboxMessageTypeCommentDeleted,
+}
+
+// Schemas returns back the catalogue of the JSON schemas of the message protocol: the request and the response of
+// every nats subject, and the envelope of every domain event under the events prefix.
+func Schemas() map[string]interface{} {
+	generator := newSchemaGenerator("#/definitions/")
+	errorResponse := generator.reference(errors.Type{})
+
+	catalogue := make(map[string]interface{})
+	for _, s := range subjects {
+		entry := map[string]interface{}{"request": generator.reference(s.request), "error": errorResponse}
+		if s.response != nil {
+			entry["response"] = generator.reference(s.response)
+		}
+
+		catalogue[s.name] = entry
+	}
+
+	envelopes := make(map[string]interface{})
+	for _, e := range events {
+		envelopes[string(e)] = generator.reference(data.EventEnvelope{})
+	}
+
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"subjects":    catalogue,
+		"events":      envelopes,
+		"definitions": generator.definitions,
+	}
+}
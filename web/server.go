@@ -1,11 +1,13 @@
 package web
 
 import (
+	"crypto"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jibitters/kiosk/errors"
 	"github.com/jibitters/kiosk/web/handlers"
 	"github.com/lireza/lib/configuring"
 	nc "github.com/nats-io/nats.go"
@@ -18,11 +20,18 @@ const (
 	echo     = "/echo"
 	tickets  = "/tickets"
 	comments = "/comments"
+	exports  = "/exports"
+	streams  = "/streams"
+	tokens   = "/tokens"
 	metrics  = "/metrics"
+	openapi  = "/openapi.json"
+	schemas  = "/schemas"
 )
 
-// StartServer setups and then runs an HTTP server.
-func StartServer(logger *zap.SugaredLogger, config *configuring.Config, natsClient *nc.Conn) *http.Server {
+// StartServer setups and then runs an HTTP server. The messages of the errors are localized by the catalogue.
+func StartServer(logger *zap.SugaredLogger, config *configuring.Config, natsClient *nc.Conn,
+	catalogue *errors.Catalogue) (*http.Server, error) {
+
 	host := config.Get("web.server.host").StringOrElse("localhost")
 	port := config.Get("web.server.port").UintOrElse(8080)
 	readTimeout := config.Get("web.server.read_timeout").DurationOrElse(10 * time.Second)
@@ -37,7 +46,34 @@ func StartServer(logger *zap.SugaredLogger, config *configuring.Config, natsClie
 	logger.Info("web.server.write_timeout -> ", writeTimeout)
 	logger.Info("web.server.idle_timeout -> ", idleTimeout)
 
-	router := setupRoutes(logger, natsClient)
+	exportsStorageDirectory := config.Get("exports.storage_directory").StringOrElse("./exports")
+
+	eventsSubjectPrefix := config.Get("events.subject_prefix").StringOrElse("kiosk.events")
+	streamsSecret := config.Get("web.streams.secret").StringOrElse("")
+	streamsTokenTTL := config.Get("web.streams.token_ttl").DurationOrElse(time.Hour)
+	streamsHeartbeatInterval := config.Get("web.streams.heartbeat_interval").DurationOrElse(15 * time.Second)
+	streamsBufferSize := config.Get("web.streams.buffer_size").IntOrElse(256)
+
+	logger.Info("web.streams.token_ttl -> ", streamsTokenTTL)
+	logger.Info("web.streams.heartbeat_interval -> ", streamsHeartbeatInterval)
+	logger.Info("web.streams.buffer_size -> ", streamsBufferSize)
+	if streamsSecret == "" {
+		logger.Warn("web.streams.secret is empty, streams are disabled")
+	}
+
+	rateLimited := config.Get("web.rate_limits.enabled").StringOrElse("false") == "true"
+	logger.Info("web.rate_limits.enabled -> ", rateLimited)
+
+	authenticator, e := newAuthenticator(logger, config)
+	if e != nil {
+		return nil, e
+	}
+
+	streamHandler := handlers.NewStreamHandler(logger, natsClient, eventsSubjectPrefix, streamsSecret,
+		streamsTokenTTL, streamsHeartbeatInterval, writeTimeout, streamsBufferSize)
+
+	router := setupRoutes(logger, natsClient, exportsStorageDirectory, writeTimeout, authenticator, rateLimited,
+		catalogue, streamHandler)
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf("%v:%v", host, port),
@@ -46,40 +82,163 @@ func StartServer(logger *zap.SugaredLogger, config *configuring.Config, natsClie
 		ReadHeaderTimeout: readHeaderTimeout,
 		WriteTimeout:      writeTimeout,
 		IdleTimeout:       idleTimeout,
+		ConnContext:       handlers.ConnContext,
+	}
+
+	tlsConfig, reloader, e := newTLSConfig(logger, config)
+	if e != nil {
+		return nil, e
+	}
+
+	startMetricsServer(logger, config, host, server)
+
+	if tlsConfig == nil {
+		go func() { _ = server.ListenAndServe() }()
+
+		logger.Info("Web server started successfully and listening on ", host, ":", port)
+		return server, nil
 	}
 
-	go func() { _ = server.ListenAndServe() }()
+	server.TLSConfig = tlsConfig
+	server.RegisterOnShutdown(reloader.Stop)
+	reloader.Start()
 
-	logger.Info("Web server started successfully and listening on ", host, ":", port)
-	return server
+	go func() { _ = server.ListenAndServeTLS("", "") }()
+
+	logger.Info("Web server started successfully and listening with TLS on ", host, ":", port)
+	return server, nil
 }
 
-func setupRoutes(logger *zap.SugaredLogger, natsClient *nc.Conn) *mux.Router {
+// startMetricsServer starts a plaintext HTTP server of the metrics alone on web.server.metrics_port, if set, so they
+// can be scraped even when the web server requires client certificates.
+func startMetricsServer(logger *zap.SugaredLogger, config *configuring.Config, host string, server *http.Server) {
+	port := config.Get("web.server.metrics_port").UintOrElse(0)
+	logger.Info("web.server.metrics_port -> ", port)
+
+	if port == 0 {
+		return
+	}
+
+	router := mux.NewRouter()
+	router.Methods(http.MethodGet).Path(v1 + metrics).Handler(promhttp.Handler())
+
+	metricsServer := &http.Server{
+		Addr:              fmt.Sprintf("%v:%v", host, port),
+		Handler:           router,
+		ReadHeaderTimeout: server.ReadHeaderTimeout,
+		ReadTimeout:       server.ReadTimeout,
+		WriteTimeout:      server.WriteTimeout,
+		IdleTimeout:       server.IdleTimeout,
+	}
+
+	server.RegisterOnShutdown(func() { _ = metricsServer.Close() })
+	go func() { _ = metricsServer.ListenAndServe() }()
+
+	logger.Info("Metrics server started successfully and listening on ", host, ":", port)
+}
+
+// newAuthenticator returns back the authenticator of the bearer JWTs, or nil when neither a secret nor a key is
+// configured.
+func newAuthenticator(logger *zap.SugaredLogger, config *configuring.Config) (*handlers.Authenticator, error) {
+	secret := config.Get("web.auth.hmac_secret").StringOrElse("")
+	publicKeyFile := config.Get("web.auth.public_key_file").StringOrElse("")
+	jwksFile := config.Get("web.auth.jwks_file").StringOrElse("")
+	issuer := config.Get("web.auth.issuer").StringOrElse("")
+	audience := config.Get("web.auth.audience").StringOrElse("")
+	leeway := config.Get("web.auth.leeway").DurationOrElse(30 * time.Second)
+
+	logger.Info("web.auth.public_key_file -> ", publicKeyFile)
+	logger.Info("web.auth.jwks_file -> ", jwksFile)
+	logger.Info("web.auth.issuer -> ", issuer)
+	logger.Info("web.auth.audience -> ", audience)
+	logger.Info("web.auth.leeway -> ", leeway)
+
+	if secret == "" && publicKeyFile == "" && jwksFile == "" {
+		logger.Warn("web.auth has neither a secret nor a key, requests are not authenticated")
+		return nil, nil
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	if jwksFile != "" {
+		jwks, e := handlers.LoadJWKS(jwksFile)
+		if e != nil {
+			return nil, e
+		}
+
+		keys = jwks
+	}
+
+	if publicKeyFile != "" {
+		key, e := handlers.LoadPublicKey(publicKeyFile)
+		if e != nil {
+			return nil, e
+		}
+
+		keys[""] = key
+	}
+
+	return handlers.NewAuthenticator(secret, keys, issuer, audience, leeway), nil
+}
+
+func setupRoutes(logger *zap.SugaredLogger, natsClient *nc.Conn, exportsStorageDirectory string,
+	writeTimeout time.Duration, authenticator *handlers.Authenticator, rateLimited bool, catalogue *errors.Catalogue,
+	streamHandler *handlers.StreamHandler) *mux.Router {
+
 	// Router
-	router := mux.NewRouter().
-		PathPrefix(v1).
-		Methods(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete).
-		Subrouter()
+	root := mux.NewRouter()
+	router := root.PathPrefix(v1).Subrouter()
+	// Routes of the API, which are authenticated, as opposed to the documents and metrics.
+	api := router.NewRoute().Subrouter()
 
 	// Meddlers
-	meddlers := handlers.NewMeddlers()
-	router.Use(meddlers.JSONContentTypeHeaderMiddleware)
+	meddlers := handlers.NewMeddlers(logger, authenticator, natsClient, rateLimited, catalogue)
+	router.Use(meddlers.JSONContentTypeHeaderMiddleware, meddlers.LocalizationMiddleware)
+	api.Use(meddlers.AuthenticationMiddleware, meddlers.RateLimitMiddleware)
+	root.NotFoundHandler = meddlers.NotFoundHandler()
+	root.MethodNotAllowedHandler = meddlers.MethodNotAllowedHandler(root)
 
 	// Echo handler
 	echoHandler := handlers.NewEchoHandler(logger)
-	router.Methods(http.MethodPost).PathPrefix(echo).HandlerFunc(echoHandler.Echo())
+	api.Methods(http.MethodPost).Path(echo).HandlerFunc(echoHandler.Echo())
 
 	// Ticket handler
 	ticketHandler := handlers.NewTicketHandler(logger, natsClient)
-	router.Methods(http.MethodPost).PathPrefix(tickets).HandlerFunc(ticketHandler.Create())
-	router.Methods(http.MethodGet).PathPrefix(tickets).HandlerFunc(ticketHandler.Filter())
+	api.Methods(http.MethodPost).Path(tickets).HandlerFunc(ticketHandler.Create())
+	api.Methods(http.MethodGet).Path(tickets).HandlerFunc(ticketHandler.Filter())
+	api.Methods(http.MethodGet).Path(tickets + "/{id:[0-9]+}").HandlerFunc(ticketHandler.Load())
+	api.Methods(http.MethodPut).Path(tickets + "/{id:[0-9]+}").HandlerFunc(ticketHandler.Update())
+	api.Methods(http.MethodPatch).Path(tickets + "/{id:[0-9]+}").HandlerFunc(ticketHandler.Patch())
+	api.Methods(http.MethodDelete).Path(tickets + "/{id:[0-9]+}").HandlerFunc(ticketHandler.Delete())
+	api.Methods(http.MethodGet).Path(tickets + "/{id:[0-9]+}" + comments).HandlerFunc(ticketHandler.Comments())
 
 	// Comment handler
 	commentHandler := handlers.NewCommentHandler(logger, natsClient)
-	router.Methods(http.MethodPost).PathPrefix(comments).HandlerFunc(commentHandler.Create())
+	api.Methods(http.MethodPost).Path(comments).HandlerFunc(commentHandler.Create())
+	api.Methods(http.MethodPost).Path(tickets + "/{id:[0-9]+}" + comments).HandlerFunc(commentHandler.Create())
+	api.Methods(http.MethodGet).Path(comments + "/{id:[0-9]+}").HandlerFunc(commentHandler.Load())
+	api.Methods(http.MethodPut).Path(comments + "/{id:[0-9]+}").HandlerFunc(commentHandler.Update())
+	api.Methods(http.MethodPatch).Path(comments + "/{id:[0-9]+}").HandlerFunc(commentHandler.Patch())
+	api.Methods(http.MethodDelete).Path(comments + "/{id:[0-9]+}").HandlerFunc(commentHandler.Delete())
+
+	// Export handler
+	exportHandler := handlers.NewExportHandler(logger, natsClient, exportsStorageDirectory, writeTimeout)
+	api.Methods(http.MethodPost).Path(exports).HandlerFunc(exportHandler.Create())
+	api.Methods(http.MethodGet).Path(exports + "/{id:[0-9]+}").HandlerFunc(exportHandler.Load())
+	api.Methods(http.MethodGet).Path(exports + "/{id:[0-9]+}/download").HandlerFunc(exportHandler.Download())
+
+	// Stream handler, whose streams are authenticated by their tokens alone when the requests carry no credentials,
+	// since browsers can not send headers along with them.
+	api.Methods(http.MethodPost).Path(streams + tokens).HandlerFunc(streamHandler.Token())
+	ticketsStream := meddlers.RateLimitMiddleware(streamHandler.Tickets())
+	router.Methods(http.MethodGet).Path(streams + tickets).Handler(
+		streamHandler.Authenticated(meddlers.AuthenticationMiddleware(ticketsStream), ticketsStream))
 
 	// Metrics handler
-	router.Handle(metrics, promhttp.Handler())
+	router.Methods(http.MethodGet).Path(metrics).Handler(promhttp.Handler())
+
+	// Document handlers
+	router.Methods(http.MethodGet).Path(openapi).HandlerFunc(handlers.NewDocumentHandler(OpenAPI()).Serve())
+	router.Methods(http.MethodGet).Path(schemas).HandlerFunc(handlers.NewDocumentHandler(Schemas()).Serve())
 
-	return router
+	return root
 }
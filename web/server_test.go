@@ -0,0 +1,181 @@
+package web_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jibitters/kiosk/errors"
+	"github.com/jibitters/kiosk/models"
+	"github.com/jibitters/kiosk/web"
+	"github.com/jibitters/kiosk/web/data"
+	"github.com/lireza/lib/configuring"
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	nc "github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Server", func() {
+	var natsServer *server.Server
+	var natsClient *nc.Conn
+	var httpServer *http.Server
+	var messages chan *nc.Msg
+
+	BeforeEach(func() {
+		options := natsserver.DefaultTestOptions
+		options.Port = -1
+		natsServer = natsserver.RunServer(&options)
+
+		client, e := nc.Connect(natsServer.ClientURL())
+		Ω(e).Should(BeNil())
+		natsClient = client
+
+		directory, _ := ioutil.TempDir("", "web")
+		defer func() { _ = os.RemoveAll(directory) }()
+
+		configFile := filepath.Join(directory, "kiosk.json")
+		Ω(ioutil.WriteFile(configFile, []byte(`{"web": {"server": {"port": "0"}}}`), 0600)).Should(Succeed())
+
+		config := configuring.New()
+		_, e = config.LoadJSON(configFile)
+		Ω(e).Should(BeNil())
+
+		httpServer, e = web.StartServer(zap.NewNop().Sugar(), config, natsClient, nil)
+		Ω(e).Should(BeNil())
+
+		messages = make(chan *nc.Msg, 1)
+	})
+
+	AfterEach(func() {
+		_ = httpServer.Close()
+		natsClient.Close()
+		natsServer.Shutdown()
+	})
+
+	// respond replies the requests of the subject with the reply, or with no content when nil, and keeps the requests.
+	respond := func(subject string, reply interface{}) {
+		_, e := natsClient.Subscribe(subject, func(msg *nc.Msg) {
+			messages <- msg
+
+			out := []byte("")
+			if reply != nil {
+				out, _ = json.Marshal(reply)
+			}
+
+			_ = msg.Respond(out)
+		})
+		Ω(e).Should(BeNil())
+		Ω(natsClient.Flush()).Should(Succeed())
+	}
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	received := func(subject string, request interface{}) {
+		var msg *nc.Msg
+		Ω(messages).Should(Receive(&msg))
+		Ω(msg.Subject).Should(Equal(subject))
+		Ω(json.Unmarshal(msg.Data, request)).Should(Succeed())
+	}
+
+	Context("When a ticket route called", func() {
+		It("Should update the ticket of the path", func() {
+			respond("kiosk.tickets.update", nil)
+
+			w := serve(http.MethodPut, "/v1/tickets/1", `{"ID": 2, "subject": "Docs", "importanceLevel": "HIGH",
+				"status": "RESOLVED"}`)
+			Ω(w.Code).Should(Equal(http.StatusNoContent))
+
+			request := &data.UpdateTicketRequest{}
+			received("kiosk.tickets.update", request)
+			Ω(request.ID).Should(Equal(int64(1)))
+			Ω(request.Subject).Should(Equal("Docs"))
+		})
+
+		It("Should patch only the provided fields of the ticket of the path", func() {
+			respond("kiosk.tickets.patch", nil)
+
+			w := serve(http.MethodPatch, "/v1/tickets/5", `{"status": "RESOLVED"}`)
+			Ω(w.Code).Should(Equal(http.StatusNoContent))
+
+			request := &data.PatchTicketRequest{}
+			received("kiosk.tickets.patch", request)
+			Ω(request.ID).Should(Equal(int64(5)))
+			Ω(*request.Status).Should(Equal(models.TicketStatusResolved))
+			Ω(request.Subject).Should(BeNil())
+			Ω(request.Metadata).Should(BeNil())
+			Ω(request.ImportanceLevel).Should(BeNil())
+		})
+
+		It("Should reply the errors of the subject with their status", func() {
+			respond("kiosk.tickets.load", errors.NotFound("ticket.not_found", ""))
+
+			w := serve(http.MethodGet, "/v1/tickets/9", "")
+			Ω(w.Code).Should(Equal(http.StatusNotFound))
+
+			request := &data.ID{}
+			received("kiosk.tickets.load", request)
+			Ω(request.ID).Should(Equal(int64(9)))
+
+			et := &errors.Type{}
+			Ω(json.Unmarshal(w.Body.Bytes(), et)).Should(Succeed())
+			Ω(et.Errors[0].Code).Should(Equal("ticket.not_found"))
+		})
+
+		It("Should create a comment for the ticket of the path", func() {
+			respond("kiosk.comments.create", nil)
+
+			w := serve(http.MethodPost, "/v1/tickets/7/comments", `{"ticketID": 8, "owner": "user@example.com",
+				"content": "Hello"}`)
+			Ω(w.Code).Should(Equal(http.StatusNoContent))
+
+			request := &data.CreateCommentRequest{}
+			received("kiosk.comments.create", request)
+			Ω(request.TicketID).Should(Equal(int64(7)))
+			Ω(request.Content).Should(Equal("Hello"))
+		})
+	})
+
+	Context("When a comment route called", func() {
+		It("Should update the metadata of the comment of the path on patch", func() {
+			respond("kiosk.comments.update", nil)
+
+			w := serve(http.MethodPatch, "/v1/comments/3", `{"metadata": "{\"a\": 1}"}`)
+			Ω(w.Code).Should(Equal(http.StatusNoContent))
+
+			request := &data.UpdateCommentRequest{}
+			received("kiosk.comments.update", request)
+			Ω(request.ID).Should(Equal(int64(3)))
+			Ω(request.Metadata).Should(Equal(`{"a": 1}`))
+		})
+	})
+
+	Context("When a route called with an unsupported method", func() {
+		It("Should reply 405 along with the allowed methods", func() {
+			w := serve(http.MethodDelete, "/v1/tickets", "")
+			Ω(w.Code).Should(Equal(http.StatusMethodNotAllowed))
+			Ω(w.Header().Get("Allow")).Should(Equal("GET, POST"))
+
+			et := &errors.Type{}
+			Ω(json.Unmarshal(w.Body.Bytes(), et)).Should(Succeed())
+			Ω(et.Errors[0].Code).Should(Equal("method.not_allowed"))
+		})
+	})
+
+	Context("When an unknown route called", func() {
+		It("Should reply 404", func() {
+			w := serve(http.MethodGet, "/v1/unknown", "")
+			Ω(w.Code).Should(Equal(http.StatusNotFound))
+		})
+	})
+})
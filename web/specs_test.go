@@ -0,0 +1,69 @@
+package web_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/jibitters/kiosk/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Specifications", func() {
+	// The published specifications are generated from the request and response models, so they must be regenerated,
+	// by running this suite with --update, whenever the models change.
+	expectPublished := func(file string, document interface{}) {
+		out, e := json.MarshalIndent(document, "", "  ")
+		Ω(e).Should(BeNil())
+		out = append(out, '\n')
+
+		if update {
+			Ω(ioutil.WriteFile(file, out, 0644)).Should(Succeed())
+		}
+
+		published, e := ioutil.ReadFile(file)
+		Ω(e).Should(BeNil())
+		Ω(string(published)).Should(Equal(string(out)), "%v is out of date, run the web suite with --update", file)
+	}
+
+	Context("When the models change", func() {
+		It("Should have published the OpenAPI document", func() {
+			expectPublished("../api/openapi.json", web.OpenAPI())
+		})
+
+		It("Should have published the JSON schemas", func() {
+			expectPublished("../api/schemas.json", web.Schemas())
+		})
+	})
+
+	Context("When OpenAPI called", func() {
+		It("Should define every referenced schema", func() {
+			document := web.OpenAPI()
+			out, _ := json.Marshal(document)
+
+			definitions := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+			Ω(definitions).Should(HaveKey("TicketResponse"))
+			Ω(definitions).Should(HaveKey("ErrorResponse"))
+
+			for _, match := range regexp.MustCompile(`"#/components/schemas/(\w+)"`).FindAllStringSubmatch(string(out),
+				-1) {
+
+				Ω(definitions).Should(HaveKey(match[1]))
+			}
+		})
+	})
+
+	Context("When Schemas called", func() {
+		It("Should describe every subject along with its error", func() {
+			subjects := web.Schemas()["subjects"].(map[string]interface{})
+			Ω(subjects).Should(HaveKey("kiosk.tickets.create"))
+			Ω(subjects).Should(HaveKey("kiosk.streams.replay"))
+
+			for _, s := range subjects {
+				Ω(s).Should(HaveKey("request"))
+				Ω(s).Should(HaveKeyWithValue("error", map[string]interface{}{"$ref": "#/definitions/ErrorResponse"}))
+			}
+		})
+	})
+})
@@ -0,0 +1,234 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lireza/lib/configuring"
+	"go.uber.org/zap"
+)
+
+// CertificateReloader serves the certificate of a certificate and key file pair and loads it again once either of
+// the files changes, so renewed certificates are used without a restart.
+type CertificateReloader struct {
+	logger      *zap.SugaredLogger
+	certFile    string
+	keyFile     string
+	interval    time.Duration
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	modifiedAt  time.Time
+	stop        chan struct{}
+	once        sync.Once
+}
+
+// NewCertificateReloader returns back a newly created CertificateReloader along with the certificate loaded. The files
+// are checked for changes every interval once started.
+func NewCertificateReloader(logger *zap.SugaredLogger, certFile, keyFile string,
+	interval time.Duration) (*CertificateReloader, error) {
+
+	r := &CertificateReloader{
+		logger:   logger,
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	if e := r.Reload(); e != nil {
+		return nil, e
+	}
+
+	return r, nil
+}
+
+// Start starts checking the files for changes in background.
+func (r *CertificateReloader) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if r.lastModification().After(r.loadedModification()) {
+					if e := r.Reload(); e != nil {
+						r.logger.Error("Could not reload the certificate of ", r.certFile, ": ", e.Error())
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Reload loads the certificate of the files. The current certificate is kept when the files are not valid, e.g: when
+// only one of them is renewed yet.
+func (r *CertificateReloader) Reload() error {
+	modifiedAt := r.lastModification()
+
+	certificate, e := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if e != nil {
+		return e
+	}
+
+	r.mutex.Lock()
+	r.certificate = &certificate
+	r.modifiedAt = modifiedAt
+	r.mutex.Unlock()
+
+	r.logger.Info("Loaded the certificate of ", r.certFile)
+	return nil
+}
+
+// GetCertificate returns back the current certificate, it is the GetCertificate of tls.Config.
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.certificate, nil
+}
+
+// Stop stops checking the files for changes.
+func (r *CertificateReloader) Stop() {
+	r.once.Do(func() { close(r.stop) })
+}
+
+func (r *CertificateReloader) loadedModification() time.Time {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.modifiedAt
+}
+
+// lastModification returns back the latest modification time of the files, or zero if either of them is missing.
+func (r *CertificateReloader) lastModification() time.Time {
+	latest := time.Time{}
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, e := os.Stat(file)
+		if e != nil {
+			return time.Time{}
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest
+}
+
+// newTLSConfig returns back the TLS configuration of web.server.tls, along with the reloader of its certificate, or
+// nil for both when no certificate is configured.
+func newTLSConfig(logger *zap.SugaredLogger, config *configuring.Config) (*tls.Config, *CertificateReloader, error) {
+	certFile := config.Get("web.server.tls.cert_file").StringOrElse("")
+	keyFile := config.Get("web.server.tls.key_file").StringOrElse("")
+	minVersion := config.Get("web.server.tls.min_version").StringOrElse("1.2")
+	cipherSuites := config.Get("web.server.tls.cipher_suites").SliceOfStringOrElse([]string{})
+	clientAuth := config.Get("web.server.tls.client_auth").StringOrElse("NONE")
+	clientCAFile := config.Get("web.server.tls.client_ca_file").StringOrElse("")
+	reloadInterval := config.Get("web.server.tls.reload_interval").DurationOrElse(time.Minute)
+
+	logger.Info("web.server.tls.cert_file -> ", certFile)
+	logger.Info("web.server.tls.key_file -> ", keyFile)
+	logger.Info("web.server.tls.min_version -> ", minVersion)
+	logger.Info("web.server.tls.cipher_suites -> ", cipherSuites)
+	logger.Info("web.server.tls.client_auth -> ", clientAuth)
+	logger.Info("web.server.tls.client_ca_file -> ", clientCAFile)
+	logger.Info("web.server.tls.reload_interval -> ", reloadInterval)
+
+	if certFile == "" && keyFile == "" {
+		return nil, nil, nil
+	}
+
+	if certFile == "" || keyFile == "" {
+		return nil, nil, fmt.Errorf("web.server.tls.cert_file and web.server.tls.key_file must be set together")
+	}
+
+	tlsConfig := &tls.Config{}
+
+	switch minVersion {
+	case "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, nil, fmt.Errorf("invalid web.server.tls.min_version %v, expected 1.2 or 1.3", minVersion)
+	}
+
+	if len(cipherSuites) > 0 {
+		ids, e := cipherSuiteIDs(cipherSuites)
+		if e != nil {
+			return nil, nil, e
+		}
+
+		tlsConfig.CipherSuites = ids
+		tlsConfig.PreferServerCipherSuites = true
+	}
+
+	switch strings.ToUpper(clientAuth) {
+	case "NONE":
+		tlsConfig.ClientAuth = tls.NoClientCert
+	case "OPTIONAL":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "REQUIRED":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, nil, fmt.Errorf("invalid web.server.tls.client_auth %v, expected NONE, OPTIONAL or REQUIRED",
+			clientAuth)
+	}
+
+	if tlsConfig.ClientAuth != tls.NoClientCert {
+		if clientCAFile == "" {
+			return nil, nil, fmt.Errorf("web.server.tls.client_ca_file is required to verify the client certificates")
+		}
+
+		in, e := ioutil.ReadFile(clientCAFile)
+		if e != nil {
+			return nil, nil, e
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(in) {
+			return nil, nil, fmt.Errorf("no certificate in %v", clientCAFile)
+		}
+
+		tlsConfig.ClientCAs = pool
+	}
+
+	reloader, e := NewCertificateReloader(logger, certFile, keyFile, reloadInterval)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	tlsConfig.GetCertificate = reloader.GetCertificate
+	return tlsConfig, reloader, nil
+}
+
+// cipherSuiteIDs returns back the ids of the secure cipher suites by their names, e.g:
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. The cipher suites of TLS 1.3 are not configurable.
+func cipherSuiteIDs(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		suites[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %v", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
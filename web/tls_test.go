@@ -0,0 +1,100 @@
+package web_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jibitters/kiosk/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("CertificateReloader", func() {
+	var directory, certFile, keyFile string
+
+	// writeCertificate writes a self signed certificate of the common name along with its key, modified at modifiedAt.
+	writeCertificate := func(commonName string, modifiedAt time.Time) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		der, e := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Ω(e).Should(BeNil())
+		keyDER, e := x509.MarshalECPrivateKey(key)
+		Ω(e).Should(BeNil())
+
+		Ω(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).
+			Should(Succeed())
+		Ω(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)).
+			Should(Succeed())
+		Ω(os.Chtimes(certFile, modifiedAt, modifiedAt)).Should(Succeed())
+		Ω(os.Chtimes(keyFile, modifiedAt, modifiedAt)).Should(Succeed())
+	}
+
+	commonName := func(reloader *web.CertificateReloader) string {
+		certificate, e := reloader.GetCertificate(&tls.ClientHelloInfo{})
+		Ω(e).Should(BeNil())
+
+		leaf, e := x509.ParseCertificate(certificate.Certificate[0])
+		Ω(e).Should(BeNil())
+		return leaf.Subject.CommonName
+	}
+
+	BeforeEach(func() {
+		directory, _ = ioutil.TempDir("", "tls")
+		certFile = filepath.Join(directory, "cert.pem")
+		keyFile = filepath.Join(directory, "key.pem")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(directory)
+	})
+
+	Context("When the files change", func() {
+		It("Should serve the new certificate", func() {
+			writeCertificate("old", time.Now().Add(-time.Minute))
+
+			reloader, e := web.NewCertificateReloader(zap.NewNop().Sugar(), certFile, keyFile, 10*time.Millisecond)
+			Ω(e).Should(BeNil())
+			reloader.Start()
+			defer reloader.Stop()
+			Ω(commonName(reloader)).Should(Equal("old"))
+
+			writeCertificate("new", time.Now())
+			Eventually(func() string { return commonName(reloader) }).Should(Equal("new"))
+		})
+
+		It("Should keep the current certificate while the files are not valid", func() {
+			writeCertificate("old", time.Now().Add(-time.Minute))
+
+			reloader, e := web.NewCertificateReloader(zap.NewNop().Sugar(), certFile, keyFile, time.Hour)
+			Ω(e).Should(BeNil())
+
+			Ω(ioutil.WriteFile(keyFile, []byte("invalid"), 0600)).Should(Succeed())
+			Ω(reloader.Reload()).ShouldNot(Succeed())
+			Ω(commonName(reloader)).Should(Equal("old"))
+		})
+	})
+
+	Context("When the files are missing", func() {
+		It("Should fail", func() {
+			_, e := web.NewCertificateReloader(zap.NewNop().Sugar(), certFile, keyFile, time.Minute)
+			Ω(e).ShouldNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,23 @@
+package web_test
+
+import (
+	"flag"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var update bool
+
+func init() {
+	flag.BoolVar(&update, "update", false, "rewrite the published specifications")
+
+	// Passed to every suite by the test script.
+	flag.String("pg.host", "localhost", "")
+}
+
+func TestWeb(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Web Suite")
+}